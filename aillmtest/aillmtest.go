@@ -0,0 +1,330 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aillmtest provides fakes of the model, embedding and retrieval clients of a container, so applications
+// unit test their chat flows offline, without Redis or model servers.
+//
+// Script the completions and the retrieved documents, then ask the container as in production:
+//
+//	func TestWarrantyAnswer(t *testing.T) {
+//		model := aillmtest.NewFakeLLM("The warranty lasts two years.")
+//		retriever := aillmtest.NewFakeRetriever(aillmtest.Document("Warranty: 2 years.", "warranty.pdf"))
+//		llm := aillmtest.NewContainer(t, model, retriever)
+//		result, err := llm.AskLLM("How long is the warranty?")
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		if result.Text() != "The warranty lasts two years." || !strings.Contains(model.LastPrompt(), "Warranty: 2 years.") {
+//			t.Fatalf("unexpected answer %q", result.Text())
+//		}
+//	}
+//
+// Use the cassette package instead to replay the HTTP calls of real models against a Redis server.
+package aillmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	aillm "github.com/RezaArani/aillm/controller"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// FakeLLM is an aillm.LLMClient answering with scripted completions.
+//
+// Completions are returned in order and the last one is repeated, rules added with Respond answer the prompts
+// containing their text first. Streaming callers receive the completion as a single chunk.
+type FakeLLM struct {
+	mu          sync.Mutex
+	completions []string
+	rules       []fakeRule
+	err         error
+	prompts     []string
+}
+
+// fakeRule is a completion of the prompts containing a text, see FakeLLM.Respond.
+type fakeRule struct {
+	contains   string
+	completion string
+}
+
+// NewFakeLLM creates a FakeLLM answering with the completions in order.
+//
+// Parameters:
+//   - completions: The scripted completions, the last one answers every following call.
+//
+// Returns:
+//   - *FakeLLM: The fake, set as LLMClient of the container.
+func NewFakeLLM(completions ...string) *FakeLLM {
+	return &FakeLLM{completions: completions}
+}
+
+// Respond answers the prompts containing a text with a completion, before the scripted completions.
+func (f *FakeLLM) Respond(contains, completion string) *FakeLLM {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, fakeRule{contains: contains, completion: completion})
+	return f
+}
+
+// Fail makes every following call return err, nil answers again.
+func (f *FakeLLM) Fail(err error) *FakeLLM {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+	return f
+}
+
+// NewLLMClient returns the fake itself as model.
+func (f *FakeLLM) NewLLMClient() (llms.Model, error) {
+	return f, nil
+}
+
+// GetConfig returns a model name unique to the fake, so fakes don't share cached models or circuit breakers.
+func (f *FakeLLM) GetConfig() aillm.LLMConfig {
+	return aillm.LLMConfig{Apiurl: "aillmtest", AiModel: fmt.Sprintf("fake-%p", f)}
+}
+
+// GenerateContent answers the messages with the next completion.
+func (f *FakeLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	prompt := messagesText(messages)
+	completion, err := f.next(prompt)
+	if err != nil {
+		return nil, err
+	}
+	opts := llms.CallOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.StreamingFunc != nil && completion != "" {
+		if err := opts.StreamingFunc(ctx, []byte(completion)); err != nil {
+			return nil, err
+		}
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: completion, StopReason: "stop"}}}, nil
+}
+
+// Call answers a single prompt with the next completion.
+func (f *FakeLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, f, prompt, options...)
+}
+
+// next records the prompt and returns its completion.
+func (f *FakeLLM) next(prompt string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prompts = append(f.prompts, prompt)
+	if f.err != nil {
+		return "", f.err
+	}
+	for _, rule := range f.rules {
+		if strings.Contains(prompt, rule.contains) {
+			return rule.completion, nil
+		}
+	}
+	if len(f.completions) == 0 {
+		return "", errors.New("aillmtest: no scripted completion")
+	}
+	completion := f.completions[0]
+	if len(f.completions) > 1 {
+		f.completions = f.completions[1:]
+	}
+	return completion, nil
+}
+
+// Prompts returns the prompts of every call in order, the text of all messages of each call.
+func (f *FakeLLM) Prompts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.prompts...)
+}
+
+// LastPrompt returns the prompt of the last call, "" before the first call.
+func (f *FakeLLM) LastPrompt() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.prompts) == 0 {
+		return ""
+	}
+	return f.prompts[len(f.prompts)-1]
+}
+
+// messagesText joins the text parts of the messages of a call.
+func messagesText(messages []llms.MessageContent) string {
+	parts := []string{}
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				parts = append(parts, text.Text)
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// FakeEmbedder is an aillm.EmbeddingClient returning deterministic vectors hashed from the words of the texts.
+type FakeEmbedder struct {
+	// Dimensions is the length of the vectors, 768 when 0.
+	Dimensions int
+}
+
+// NewEmbedder returns the embedder of the fake.
+func (f FakeEmbedder) NewEmbedder() (embeddings.Embedder, error) {
+	dimensions := f.Dimensions
+	if dimensions <= 0 {
+		dimensions = 768
+	}
+	return hashEmbedder{dimensions: dimensions}, nil
+}
+
+// hashEmbedder hashes the words of a text into a normalized vector.
+type hashEmbedder struct {
+	dimensions int
+}
+
+func (e hashEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for idx, text := range texts {
+		vectors[idx], _ = e.EmbedQuery(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (e hashEmbedder) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	vector := make([]float32, e.dimensions)
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		hash := fnv.New32a()
+		hash.Write([]byte(word))
+		vector[hash.Sum32()%uint32(e.dimensions)]++
+	}
+	norm := 0.0
+	for _, value := range vector {
+		norm += float64(value * value)
+	}
+	if norm > 0 {
+		for idx := range vector {
+			vector[idx] /= float32(math.Sqrt(norm))
+		}
+	}
+	return vector, nil
+}
+
+// FakeRetriever is an aillm.Retriever returning canned documents.
+type FakeRetriever struct {
+	mu       sync.Mutex
+	docs     []aillm.RetrievedDocument
+	prefixes map[string][]aillm.RetrievedDocument
+	err      error
+	requests []aillm.RetrievalRequest
+}
+
+// NewFakeRetriever creates a FakeRetriever returning the documents for every prefix.
+//
+// Parameters:
+//   - docs: The documents of every retrieval, e.g. made with Document.
+//
+// Returns:
+//   - *FakeRetriever: The fake, set as Retriever of the container.
+func NewFakeRetriever(docs ...aillm.RetrievedDocument) *FakeRetriever {
+	return &FakeRetriever{docs: docs, prefixes: map[string][]aillm.RetrievedDocument{}}
+}
+
+// ForPrefix returns other documents for the retrievals of prefixes starting with prefix, e.g. "context:faq:".
+func (f *FakeRetriever) ForPrefix(prefix string, docs ...aillm.RetrievedDocument) *FakeRetriever {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prefixes[prefix] = docs
+	return f
+}
+
+// Fail makes every following retrieval return err, nil retrieves again.
+func (f *FakeRetriever) Fail(err error) *FakeRetriever {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+	return f
+}
+
+// Retrieve records the request and returns the documents of its prefix, at most RowCount of them.
+func (f *FakeRetriever) Retrieve(_ context.Context, request aillm.RetrievalRequest) ([]aillm.RetrievedDocument, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, request)
+	if f.err != nil {
+		return nil, f.err
+	}
+	docs, matched, longest := f.docs, false, 0
+	for prefix, prefixDocs := range f.prefixes {
+		if strings.HasPrefix(request.Prefix, prefix) && (!matched || len(prefix) > longest) {
+			docs, matched, longest = prefixDocs, true, len(prefix)
+		}
+	}
+	if request.RowCount > 0 && len(docs) > request.RowCount {
+		docs = docs[:request.RowCount]
+	}
+	return append([]aillm.RetrievedDocument(nil), docs...), nil
+}
+
+// Requests returns the retrievals of the container in order.
+func (f *FakeRetriever) Requests() []aillm.RetrievalRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]aillm.RetrievalRequest(nil), f.requests...)
+}
+
+// Document returns a retrieved document with a text and a source, scored 1.
+func Document(content, source string) aillm.RetrievedDocument {
+	return aillm.RetrievedDocument{Content: content, Score: 1, Source: source}
+}
+
+// NewContainer creates an initialized container answering with the model and retrieving from the retriever,
+// without Redis unless REDIS_HOST is set. The container is shut down when the test ends.
+//
+// Parameters:
+//   - t: The test.
+//   - model: The fake model, see NewFakeLLM.
+//   - retriever: The retriever, see NewFakeRetriever.
+//   - opts: More options, e.g. aillm.WithAnswerLanguage("pt").
+//
+// Returns:
+//   - *aillm.LLMContainer: The container, the test fails if it is invalid.
+func NewContainer(t testing.TB, model aillm.LLMClient, retriever aillm.Retriever, opts ...aillm.ContainerOption) *aillm.LLMContainer {
+	t.Helper()
+	options := append([]aillm.ContainerOption{
+		aillm.WithLLM(model),
+		aillm.WithEmbedder(FakeEmbedder{}),
+		aillm.WithSetup(func(llm *aillm.LLMContainer) {
+			llm.Retriever = retriever
+			llm.DisableSecurityCheck = true
+		}),
+	}, opts...)
+	llm, err := aillm.New(options...)
+	if err != nil {
+		t.Fatalf("aillmtest: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		llm.Shutdown(ctx)
+	})
+	return llm
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cassette records the LLM and embedding HTTP calls of a container to a file and replays
+// them, so integration tests of AskLLM pipelines run deterministically in CI without model servers.
+//
+// Record the cassette once against live endpoints, commit it with the tests and replay it afterwards:
+//
+//	func TestSupportAnswers(t *testing.T) {
+//		cassette.Use(t, "testdata/support.cassette.json")
+//		result, err := llm.AskLLM("How long is the warranty?")
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		cassette.AssertGolden(t, "testdata/support.golden", result.Response.Choices[0].Content)
+//	}
+//
+// Run the tests with AILLM_CASSETTE=record to record the cassettes and golden files again. Redis isn't recorded, tests
+// still need a Redis server holding the embedded documents.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	aillm "github.com/RezaArani/aillm/controller"
+)
+
+// Mode defines whether a cassette records or replays requests.
+type Mode int
+
+const (
+	ModeAuto   Mode = iota // Replays an existing cassette, records a missing one
+	ModeReplay             // Replays the cassette, unknown requests fail
+	ModeRecord             // Sends the requests and records them, overwriting the cassette
+)
+
+// ModeEnv is the environment variable selecting the mode of Use: "record", "replay" or "auto" (default).
+const ModeEnv = "AILLM_CASSETTE"
+
+// ErrNotRecorded is returned in replay mode for requests missing from the cassette.
+var ErrNotRecorded = errors.New("cassette: request not recorded")
+
+// Interaction is a recorded request and its response.
+//
+// Authorization headers and API keys are never recorded.
+type Interaction struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"requestBody,omitempty"`
+	StatusCode  int         `json:"statusCode"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body"`
+}
+
+// key identifies the request of an interaction, JSON bodies are compared without formatting and key order.
+func (i Interaction) key() string {
+	return i.Method + " " + i.URL + "\n" + i.RequestBody
+}
+
+// Cassette is an http.RoundTripper recording or replaying provider requests.
+type Cassette struct {
+	path         string
+	mode         Mode
+	base         http.RoundTripper
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     map[string]int
+	changed      bool
+}
+
+// Open loads a cassette file.
+//
+// Parameters:
+//   - path: The cassette file, created by Save in record mode.
+//   - mode: ModeAuto, ModeReplay or ModeRecord.
+//
+// Returns:
+//   - *Cassette: The cassette, install it with aillm.SetProviderTransport.
+//   - error: An error if the file can't be read in replay mode.
+func Open(path string, mode Mode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, base: http.DefaultTransport, replayed: map[string]int{}}
+	if mode == ModeRecord {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && mode == ModeAuto {
+		c.mode = ModeRecord
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.interactions); err != nil {
+		return nil, fmt.Errorf("cassette %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Use installs a cassette as the provider transport for the duration of a test.
+//
+// The mode is read from the AILLM_CASSETTE environment variable, the cassette is saved and the
+// previous transport restored when the test ends.
+//
+// Parameters:
+//   - tb: The test.
+//   - path: The cassette file.
+//
+// Returns:
+//   - *Cassette: The installed cassette.
+func Use(tb testing.TB, path string) *Cassette {
+	tb.Helper()
+	mode := ModeAuto
+	switch strings.ToLower(os.Getenv(ModeEnv)) {
+	case "record":
+		mode = ModeRecord
+	case "replay":
+		mode = ModeReplay
+	}
+	c, err := Open(path, mode)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	previous := aillm.SetProviderTransport(c)
+	tb.Cleanup(func() {
+		aillm.SetProviderTransport(previous)
+		if err := c.Save(); err != nil {
+			tb.Error(err)
+		}
+	})
+	return c
+}
+
+// Recording reports whether the cassette records requests.
+func (c *Cassette) Recording() bool {
+	return c.mode == ModeRecord
+}
+
+// RoundTrip replays the recorded response of a request, or sends and records it in record mode.
+//
+// Identical requests are replayed in the order they were recorded.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	request := Interaction{Method: req.Method, URL: req.URL.String(), RequestBody: normalizeBody(requestBody)}
+	if c.mode != ModeRecord {
+		return c.replay(req, request)
+	}
+	resp, err := c.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	request.StatusCode = resp.StatusCode
+	request.Body = string(body)
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		request.Header = http.Header{"Content-Type": {contentType}}
+	}
+	c.mu.Lock()
+	c.interactions = append(c.interactions, request)
+	c.changed = true
+	c.mu.Unlock()
+	return resp, nil
+}
+
+// replay returns the next recorded response of a request.
+func (c *Cassette) replay(req *http.Request, request Interaction) (*http.Response, error) {
+	key := request.key()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	skip := c.replayed[key]
+	for _, interaction := range c.interactions {
+		if interaction.key() != key {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		c.replayed[key]++
+		header := interaction.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+			StatusCode:    interaction.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(interaction.Body)),
+			ContentLength: int64(len(interaction.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, request.Method, request.URL)
+}
+
+// Save writes the recorded interactions to the cassette file, it does nothing when replaying.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.changed {
+		return nil
+	}
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, data, 0o644); err != nil {
+		return err
+	}
+	c.changed = false
+	return nil
+}
+
+// readBody reads a body and replaces it with a copy, so it can still be sent.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// normalizeBody re-encodes JSON bodies, which sorts their keys, so formatting changes don't break replays.
+func normalizeBody(body []byte) string {
+	var value interface{}
+	if json.Unmarshal(body, &value) != nil {
+		return string(body)
+	}
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return string(body)
+	}
+	return string(normalized)
+}
+
+// AssertGolden compares a result, e.g. a generated answer, with its golden file.
+//
+// With AILLM_CASSETTE=record, or when the file is missing, the golden file is written instead.
+//
+// Parameters:
+//   - tb: The test.
+//   - path: The golden file.
+//   - got: The result of the test.
+func AssertGolden(tb testing.TB, path, got string) {
+	tb.Helper()
+	want, err := os.ReadFile(path)
+	if strings.EqualFold(os.Getenv(ModeEnv), "record") || errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+		return
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if string(want) != got {
+		tb.Errorf("result differs from %s:\n got: %q\nwant: %q", path, got, string(want))
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cassette
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/RezaArani/aillm/aillmtest"
+	aillm "github.com/RezaArani/aillm/controller"
+)
+
+// countingServer answers every request with its sequence number.
+func countingServer(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&count, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"n":%d}`, n)
+	}))
+	t.Cleanup(server.Close)
+	return server, &count
+}
+
+func post(t *testing.T, client *http.Client, url, body string) (string, error) {
+	t.Helper()
+	resp, err := client.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server, count := countingServer(t)
+	path := filepath.Join(t.TempDir(), "test.cassette.json")
+
+	recorder, err := Open(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: recorder}
+	for _, body := range []string{`{"a":1,"b":2}`, `{"a":1,"b":2}`, `{"a":3}`} {
+		if _, err := post(t, client, server.URL+"/chat", body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	player, err := Open(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: player}
+	// Identical requests are replayed in order, JSON formatting and key order don't matter
+	for _, test := range []struct{ body, want string }{
+		{`{"b":2, "a":1}`, `{"n":1}`},
+		{`{"a":1,"b":2}`, `{"n":2}`},
+		{`{"a":3}`, `{"n":3}`},
+	} {
+		got, err := post(t, client, server.URL+"/chat", test.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("replay of %s = %s, want %s", test.body, got, test.want)
+		}
+	}
+	if _, err := post(t, client, server.URL+"/chat", `{"a":1,"b":2}`); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("replaying a request more often than recorded returned %v, want ErrNotRecorded", err)
+	}
+	if _, err := post(t, client, server.URL+"/other", `{"a":3}`); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("unknown request returned %v, want ErrNotRecorded", err)
+	}
+	if *count != 3 {
+		t.Errorf("server received %d requests, want 3", *count)
+	}
+}
+
+func TestOpenAuto(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.cassette.json")
+	c, err := Open(path, ModeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Recording() {
+		t.Error("a missing cassette isn't recorded in auto mode")
+	}
+	if _, err := Open(path, ModeReplay); err == nil {
+		t.Error("replaying a missing cassette succeeded")
+	}
+	// Nothing recorded, nothing written
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("empty cassette was saved: %v", err)
+	}
+}
+
+// TestDeterministicReplay replays the generations of a container, which sends a sampling seed with
+// every request under RetryPolicy.Deterministic.
+func TestDeterministicReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"chatcmpl-1","object":"chat.completion","created":0,"model":"test",`+
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"The warranty lasts two years."},"finish_reason":"stop"}],`+
+			`"usage":{"prompt_tokens":10,"completion_tokens":6,"total_tokens":16}}`)
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "deterministic.cassette.json")
+
+	ask := func(mode Mode) (string, error) {
+		c, err := Open(path, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous := aillm.SetProviderTransport(c)
+		defer aillm.SetProviderTransport(previous)
+		model := &aillm.OpenAIController{Config: aillm.LLMConfig{Apiurl: server.URL, AiModel: "test", APIToken: "test"}}
+		retriever := aillmtest.NewFakeRetriever(aillmtest.Document("Warranty: 2 years.", "warranty.pdf"))
+		llm := aillmtest.NewContainer(t, model, retriever, aillm.WithSetup(func(llm *aillm.LLMContainer) {
+			llm.RetryPolicy = aillm.RetryPolicy{MaxAttempts: 1, Deterministic: true}
+		}))
+		result, err := llm.AskLLM("How long is the warranty?", llm.WithIncludeDate(false))
+		if err != nil {
+			return "", err
+		}
+		if err := c.Save(); err != nil {
+			t.Fatal(err)
+		}
+		return result.Text(), nil
+	}
+
+	recorded, err := ask(ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `\"seed\":`) {
+		t.Fatalf("recorded request has no seed: %s", data)
+	}
+	server.Close()
+	replayed, err := ask(ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed != recorded {
+		t.Errorf("replayed answer %q, recorded %q", replayed, recorded)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answer.golden")
+	// A missing golden file is written
+	AssertGolden(t, path, "two years")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "two years" {
+		t.Errorf("golden file holds %q", data)
+	}
+	AssertGolden(t, path, "two years")
+}
@@ -0,0 +1,388 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command aillm manages the embeddings and sessions of an aillm deployment and asks questions from
+// the command line, e.g. in ops scripts and CI ingestion pipelines.
+//
+// Usage:
+//
+//	aillm [-config aillm.yaml] <command> [flags] [arguments]
+//
+// Commands:
+//
+//	embed file|url|dir <path>   Embeds a file, a web page or every file of a directory
+//	ask <query>                 Asks a question and streams the answer
+//	sessions list               Lists the persistent memory sessions
+//	embeddings list             Lists the embedded documents
+//	embeddings stats            Counts the documents and chunks of a prefix and their memory usage
+//	embeddings rm <index>       Removes the embeddings of an index
+//	embeddings gc               Removes the chunks no embedding object references
+//	embeddings export           Exports the embeddings of a prefix as JSON lines
+//	embeddings import <file>    Imports the embeddings of an export
+//	reindex [index...]          Embeds stored documents again, e.g. after changing the embedding model
+//	reindex -swap               Embeds a whole prefix into a new one and switches the prefix alias
+//	eval <dataset>              Measures retrieval and answer quality on a dataset, see package eval
+//
+// The configuration is read with aillm.LoadConfig from the YAML or JSON file given by -config or
+// AILLM_CONFIG, then overridden by the AILLM_* environment variables, e.g. AILLM_REDIS_HOST,
+// AILLM_LLM_URL, AILLM_LLM_MODEL and AILLM_EMBEDDER_MODEL.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	aillm "github.com/RezaArani/aillm/controller"
+	"github.com/RezaArani/aillm/eval"
+)
+
+const usage = `Usage: aillm [-config aillm.yaml] <command> [flags] [arguments]
+
+Commands:
+  embed file [-index name] [-title title] <path>   Embed a file
+  embed url [-index name] <url>                    Embed a web page
+  embed dir [-ext .pdf,.txt] <dir>                 Embed every file of a directory, indexed by relative path
+  ask [-session id] [-index name] <query>          Ask a question and stream the answer
+  sessions list                                    List the persistent memory sessions
+  embeddings list [-offset n] [-limit n]           List the embedded documents, -source and -sort filter and order them
+  embeddings stats                                 Count the documents and chunks of a prefix and their memory usage
+  embeddings rm <index>                            Remove the embeddings of an index
+  embeddings gc                                    Remove the chunks no embedding object references
+  embeddings export [-vectors] [-o file]           Export the embeddings of a prefix as JSON lines
+  embeddings import <file>                         Import the embeddings of an export, - reads stdin
+  reindex [-swap] [index...]                       Embed stored documents again, -swap without downtime
+  eval [-json] <dataset>                           Evaluate retrieval and answers on a YAML or JSON dataset
+
+embed, ask, embeddings and reindex accept -prefix and -language.
+Run "aillm <command> -h" for the flags of a command.
+`
+
+func main() {
+	flags := flag.NewFlagSet("aillm", flag.ExitOnError)
+	configPath := flags.String("config", "", "YAML or JSON configuration file, defaults to $AILLM_CONFIG")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flags.Parse(os.Args[1:])
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err := run(*configPath, flags.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "aillm:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes a command.
+func run(configPath string, args []string) error {
+	command, args := args[0], args[1:]
+	var subcommand string
+	switch command {
+	case "embed", "sessions", "embeddings":
+		if len(args) == 0 {
+			return fmt.Errorf("%s requires a subcommand, see aillm -h", command)
+		}
+		subcommand, args = args[0], args[1:]
+	case "ask", "reindex", "eval":
+	default:
+		return fmt.Errorf("unknown command %q, see aillm -h", command)
+	}
+
+	flags := flag.NewFlagSet(strings.TrimSpace(command+" "+subcommand), flag.ExitOnError)
+	prefix := flags.String("prefix", "", "embedding prefix")
+	language := flags.String("language", "", "content or answer language")
+	index := flags.String("index", "", "embedding index, defaults to the file name or URL")
+	title := flags.String("title", "", "document title")
+	extensions := flags.String("ext", "", "comma separated file extensions embedded from a directory, default all")
+	sessionID := flags.String("session", "", "session id, keeps the conversation in the persistent memory")
+	offset := flags.Int("offset", 0, "list offset")
+	limit := flags.Int("limit", 100, "list limit")
+	jsonOutput := flags.Bool("json", false, "print the evaluation report as JSON")
+	source := flags.String("source", "", "lists the documents whose sources contain this text")
+	sortOrder := flags.String("sort", "", "list order: index (default), updated or updated_asc")
+	vectors := flags.Bool("vectors", false, "export the vectors, the import doesn't embed the chunks again")
+	output := flags.String("o", "", "export file, defaults to stdout")
+	swap := flags.Bool("swap", false, "reindex the whole prefix into a new one and switch its alias, see ReembedAll")
+	flags.Parse(args)
+	args = flags.Args()
+
+	llm, err := aillm.LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if err := llm.Init(); err != nil {
+		return err
+	}
+	options := []aillm.LLMCallOption{llm.WithEmbeddingPrefix(*prefix), llm.WithLanguage(aillm.LanguageTag(*language))}
+	tc := aillm.TranscribeConfig{Language: *language}
+
+	switch command + " " + subcommand {
+	case "embed file":
+		if len(args) != 1 {
+			return errors.New("usage: aillm embed file [flags] <path>")
+		}
+		return embedFile(llm, firstNonEmpty(*index, args[0]), *title, args[0], tc, options)
+	case "embed url":
+		if len(args) != 1 {
+			return errors.New("usage: aillm embed url [flags] <url>")
+		}
+		result, err := llm.EmbeddURL(firstNonEmpty(*index, args[0]), args[0], tc, options...)
+		if err != nil {
+			return err
+		}
+		return printJSON(summary(result))
+	case "embed dir":
+		if len(args) != 1 {
+			return errors.New("usage: aillm embed dir [flags] <dir>")
+		}
+		return embedDir(llm, args[0], *extensions, tc, options)
+	case "ask ":
+		if len(args) == 0 {
+			return errors.New("usage: aillm ask [flags] <query>")
+		}
+		if *sessionID != "" {
+			options = append(options, llm.WithSessionID(*sessionID), llm.WithPersistentMemory(true))
+		}
+		options = append(options, llm.WithEmbeddingIndex(*index), llm.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			_, err := os.Stdout.Write(chunk)
+			return err
+		}))
+		_, err := llm.AskLLM(strings.Join(args, " "), options...)
+		fmt.Println()
+		return err
+	case "sessions list":
+		sessions, err := llm.PersistentMemoryManager.ListSessions()
+		if err != nil {
+			return err
+		}
+		for _, session := range sessions {
+			fmt.Println(session)
+		}
+		return nil
+	case "embeddings list":
+		page, err := llm.QueryEmbeddings(aillm.EmbeddingQuery{
+			Prefix:   *prefix,
+			Language: *language,
+			Source:   *source,
+			Sort:     *sortOrder,
+			Offset:   *offset,
+			Limit:    *limit,
+		})
+		if err != nil {
+			return err
+		}
+		return printJSON(page)
+	case "embeddings stats":
+		stats, err := llm.EmbeddingStats(*prefix)
+		if err != nil {
+			return err
+		}
+		return printJSON(stats)
+	case "embeddings rm":
+		if len(args) == 0 {
+			return errors.New("usage: aillm embeddings rm [flags] <index>...")
+		}
+		for _, index := range args {
+			if err := llm.RemoveEmbedding(index, options...); err != nil {
+				return fmt.Errorf("%s: %v", index, err)
+			}
+		}
+		return nil
+	case "embeddings gc":
+		report, err := llm.GC(*prefix)
+		if err != nil {
+			return err
+		}
+		return printJSON(report)
+	case "embeddings export":
+		return exportEmbeddings(llm, *prefix, *output, *vectors)
+	case "embeddings import":
+		if len(args) != 1 {
+			return errors.New("usage: aillm embeddings import <file>")
+		}
+		return importEmbeddings(llm, args[0])
+	case "reindex ":
+		if *swap {
+			if len(args) > 0 {
+				return errors.New("reindex -swap reindexes the whole prefix, remove the indexes")
+			}
+			report, err := llm.ReembedAll(llm.WithEmbeddingPrefix(*prefix))
+			if err != nil {
+				return err
+			}
+			return printJSON(report)
+		}
+		return reindex(llm, *prefix, args)
+	case "eval ":
+		if len(args) != 1 {
+			return errors.New("usage: aillm eval [flags] <dataset>")
+		}
+		dataset, err := eval.LoadDataset(args[0])
+		if err != nil {
+			return err
+		}
+		if dataset.Prefix == "" {
+			dataset.Prefix = *prefix
+		}
+		report, err := (&eval.Runner{LLM: llm}).Run(context.Background(), dataset)
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return report.WriteJSON(os.Stdout)
+		}
+		return report.WriteText(os.Stdout)
+	}
+	return fmt.Errorf("unknown command %q, see aillm -h", command+" "+subcommand)
+}
+
+func embedFile(llm *aillm.LLMContainer, index, title, path string, tc aillm.TranscribeConfig, options []aillm.LLMCallOption) error {
+	if title == "" {
+		title = filepath.Base(path)
+	}
+	result, err := llm.EmbeddFile(index, title, path, tc, options...)
+	if err != nil {
+		return err
+	}
+	return printJSON(summary(result))
+}
+
+// embedDir embeds the files of a directory tree, a failed file is reported and doesn't stop the others.
+func embedDir(llm *aillm.LLMContainer, dir, extensions string, tc aillm.TranscribeConfig, options []aillm.LLMCallOption) error {
+	allowed := map[string]bool{}
+	for _, ext := range strings.Split(extensions, ",") {
+		if ext = strings.ToLower(strings.TrimSpace(ext)); ext != "" {
+			allowed["."+strings.TrimPrefix(ext, ".")] = true
+		}
+	}
+	failed := 0
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		if len(allowed) > 0 && !allowed[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		index, _ := filepath.Rel(dir, path)
+		if err := embedFile(llm, filepath.ToSlash(index), "", path, tc, options); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d files failed", failed)
+	}
+	return nil
+}
+
+// reindex embeds the stored documents of the indexes again, all indexes of the prefix if none is given.
+func reindex(llm *aillm.LLMContainer, prefix string, indexes []string) error {
+	page, err := llm.ListEmbeddingsByPrefix(prefix, 0, 0)
+	if err != nil {
+		return err
+	}
+	selected := map[string]bool{}
+	for _, index := range indexes {
+		selected[index] = true
+	}
+	for _, row := range page.Rows {
+		if len(selected) > 0 && !selected[row.Index] {
+			continue
+		}
+		for _, document := range row.Documents {
+			if _, err := llm.ReembedDocument(row.Index, document.Id, llm.WithEmbeddingPrefix(prefix)); err != nil {
+				return fmt.Errorf("%s: %v", row.Index, err)
+			}
+		}
+		fmt.Printf("%s: %d documents\n", row.Index, len(row.Documents))
+	}
+	return nil
+}
+
+// exportEmbeddings writes the embeddings of a prefix to a file, or to stdout with the counts on stderr.
+func exportEmbeddings(llm *aillm.LLMContainer, prefix, path string, vectors bool) error {
+	out := os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	transfer, err := llm.ExportEmbeddings(prefix, out, vectors)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d objects, %d chunks\n", transfer.Objects, transfer.Chunks)
+	return nil
+}
+
+// importEmbeddings imports an export file, - reads stdin.
+func importEmbeddings(llm *aillm.LLMContainer, path string) error {
+	in := os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	transfer, err := llm.ImportEmbeddings(in)
+	if err != nil {
+		return err
+	}
+	return printJSON(transfer)
+}
+
+// summary describes an embedding object without its texts.
+func summary(object aillm.LLMEmbeddingObject) map[string]interface{} {
+	documents := []map[string]interface{}{}
+	for _, content := range object.Contents {
+		documents = append(documents, map[string]interface{}{
+			"id":       content.Id,
+			"title":    content.Title,
+			"language": content.Language,
+			"sources":  content.Sources,
+			"chunks":   len(content.Keys),
+		})
+	}
+	return map[string]interface{}{"prefix": object.EmbeddingPrefix, "index": object.Index, "documents": documents}
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
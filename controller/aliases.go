@@ -0,0 +1,381 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// prefixAliasKey is the Redis hash mapping the prefix aliases to their embedding prefix.
+const prefixAliasKey = "aillm:prefixAliases"
+
+// prefixAliasRefresh is how long the aliases read from Redis are used before they are read again, so every
+// container sharing the Redis server follows a switch within this delay.
+const prefixAliasRefresh = time.Second
+
+// prefixAliasRegistry caches the prefix aliases stored in Redis.
+type prefixAliasRegistry struct {
+	llm     *LLMContainer
+	mu      sync.RWMutex
+	aliases map[string]string
+	loaded  time.Time
+}
+
+// prefixAliasRegistry returns the prefix aliases of the container, creating the cache on first use.
+func (llm *LLMContainer) prefixAliasRegistry() *prefixAliasRegistry {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.prefixAliases == nil {
+		llm.prefixAliases = &prefixAliasRegistry{llm: llm, aliases: map[string]string{}}
+	}
+	return llm.prefixAliases
+}
+
+// resolve returns the embedding prefix an alias points to, other prefixes are returned unchanged.
+func (pa *prefixAliasRegistry) resolve(prefix string) string {
+	pa.mu.RLock()
+	target, ok := pa.aliases[prefix]
+	fresh := time.Since(pa.loaded) < prefixAliasRefresh
+	pa.mu.RUnlock()
+	if !fresh {
+		target, ok = pa.refresh(prefix)
+	}
+	if !ok {
+		return prefix
+	}
+	return target
+}
+
+// refresh reads the aliases from Redis and resolves a prefix with them. The cached aliases are kept when Redis
+// can't be read.
+func (pa *prefixAliasRegistry) refresh(prefix string) (string, bool) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+	if time.Since(pa.loaded) >= prefixAliasRefresh {
+		pa.loaded = time.Now()
+		if rdb := pa.llm.RedisClient.redisClient; rdb != nil {
+			if aliases, err := rdb.HGetAll(context.Background(), prefixAliasKey).Result(); err == nil {
+				pa.aliases = aliases
+			}
+		}
+	}
+	target, ok := pa.aliases[prefix]
+	return target, ok
+}
+
+// set updates the cached alias after a switch.
+func (pa *prefixAliasRegistry) set(alias, prefix string) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+	if prefix == "" {
+		delete(pa.aliases, alias)
+		return
+	}
+	pa.aliases[alias] = prefix
+}
+
+// SetPrefixAlias points an alias to an embedding prefix, or removes the alias.
+//
+// Calls using the alias as WithEmbeddingPrefix, including the tenant part added by WithTenant, read and write the
+// embeddings of the prefix the alias points to. The alias is stored in Redis and switched with a single write,
+// every container sharing the Redis server follows the switch within a second. ReembedAll sets the alias itself.
+//
+// Parameters:
+//   - alias: The prefix used by the calls, e.g. "docs" or TenantPrefix("acme", "docs").
+//   - prefix: The embedding prefix holding the embeddings, "" removes the alias.
+//
+// Returns:
+//   - error: An error if Redis isn't configured or can't be written.
+//
+// Example Usage:
+//
+//	err := llm.SetPrefixAlias("docs", "docs:v2")
+func (llm *LLMContainer) SetPrefixAlias(alias, prefix string) error {
+	rdb := llm.RedisClient.redisClient
+	if rdb == nil {
+		return ErrNoRedis
+	}
+	ctx := context.Background()
+	var err error
+	if prefix == "" || prefix == alias {
+		prefix = ""
+		err = rdb.HDel(ctx, prefixAliasKey, alias).Err()
+	} else {
+		err = rdb.HSet(ctx, prefixAliasKey, alias, prefix).Err()
+	}
+	if err != nil {
+		return err
+	}
+	llm.prefixAliasRegistry().set(alias, prefix)
+	return nil
+}
+
+// PrefixAliases returns the prefix aliases stored in Redis, see SetPrefixAlias.
+//
+// Returns:
+//   - map[string]string: The embedding prefix of each alias.
+//   - error: An error if Redis isn't configured or can't be read.
+func (llm *LLMContainer) PrefixAliases() (map[string]string, error) {
+	rdb := llm.RedisClient.redisClient
+	if rdb == nil {
+		return nil, ErrNoRedis
+	}
+	return rdb.HGetAll(context.Background(), prefixAliasKey).Result()
+}
+
+// withPhysicalPrefix makes an internal call read and write the embeddings of a prefix, ignoring the aliases and
+// the tenant prefix.
+func withPhysicalPrefix(prefix string) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.physicalPrefix = prefix
+		o.tenantID = ""
+		o.internal = true
+	}
+}
+
+// withSourceUpdatedAt makes an internal EmbeddText call keep the UpdatedAt of the content, e.g. for the copies of
+// ReembedAll.
+func withSourceUpdatedAt() LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.keepUpdatedAt = true
+	}
+}
+
+// ReembedReport describes a reindexing done by ReembedAll.
+//
+// Fields:
+//   - Alias: The prefix used by the calls.
+//   - From: The embedding prefix served before the switch, its embeddings are removed.
+//   - To: The embedding prefix served after the switch.
+//   - Objects: The number of embedding objects embedded again.
+//   - Documents: The number of content entries embedded again.
+type ReembedReport struct {
+	Alias     string `json:"Alias"`
+	From      string `json:"From"`
+	To        string `json:"To"`
+	Objects   int    `json:"Objects"`
+	Documents int    `json:"Documents"`
+}
+
+// ReembedAll embeds every document of a prefix again without interrupting retrieval, e.g. after changing the
+// embedding model, the chunking or the vector storage.
+//
+// The documents are embedded into a new embedding prefix while calls keep using the current one, then the documents
+// changed in the meantime are copied again and the prefix alias is switched to the new prefix with a single Redis
+// write. Containers still using the previous alias may write to the previous prefix for up to a second, so the
+// documents written there since the second copy are copied once more before the previous embeddings are removed.
+// Copies keep the UpdatedAt of their source, a document changed in both prefixes keeps its latest version. Images
+// embedded with EmbeddImage aren't copied.
+//
+// Parameters:
+//   - options: WithEmbeddingPrefix and WithTenant select the prefix, other embedding options apply as in EmbeddText.
+//
+// Returns:
+//   - ReembedReport: The switched prefixes and the number of embedded objects and documents.
+//   - error: An error if the prefix is missing or embedding fails, the previous prefix is still served when the
+//     error happens before the switch.
+//
+// Example Usage:
+//
+//	llm.EmbeddingConfig.VectorType = aillm.VectorInt8
+//	report, err := llm.ReembedAll(llm.WithEmbeddingPrefix("docs"))
+//	fmt.Printf("%s now served from %s\n", report.Alias, report.To)
+func (llm *LLMContainer) ReembedAll(options ...LLMCallOption) (ReembedReport, error) {
+	o := LLMCallOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+	report := ReembedReport{Alias: TenantPrefix(o.tenantID, o.Prefix)}
+	if err := llm.checkTenant(&o); err != nil {
+		return report, err
+	}
+	if report.Alias == "" {
+		return report, errors.New("ReembedAll needs WithEmbeddingPrefix or WithTenant")
+	}
+	rdb := llm.RedisClient.redisClient
+	if rdb == nil {
+		return report, ErrNoRedis
+	}
+	ctx := context.Background()
+	from, err := rdb.HGet(ctx, prefixAliasKey, report.Alias).Result()
+	if err == redis.Nil {
+		from = report.Alias
+	} else if err != nil {
+		return report, err
+	}
+	report.From = from
+	report.To = report.Alias + ":gen" + strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	// The second pass copies the documents changed during the first one
+	var secondPass time.Time
+	for pass := 0; pass < 2; pass++ {
+		secondPass = time.Now().UTC()
+		objects, documents, err := llm.syncPrefix(ctx, report.From, report.To, time.Time{}, time.Time{}, options)
+		if err != nil {
+			llm.removePrefixEmbeddings(ctx, report.To)
+			return report, err
+		}
+		if pass == 0 {
+			report.Objects, report.Documents = objects, documents
+		}
+	}
+	switched := time.Now().UTC()
+	if err := llm.SetPrefixAlias(report.Alias, report.To); err != nil {
+		llm.removePrefixEmbeddings(ctx, report.To)
+		return report, err
+	}
+	// Other containers may use the previous prefix until they read the alias again, their writes and the ones made
+	// during the second pass are copied once they stopped
+	time.Sleep(2 * prefixAliasRefresh)
+	if _, _, err := llm.syncPrefix(ctx, report.From, report.To, secondPass, switched, options); err != nil {
+		return report, err
+	}
+	return report, llm.removePrefixEmbeddings(ctx, report.From)
+}
+
+// syncPrefix embeds the documents of a prefix into another one, keeping their UpdatedAt.
+//
+// Documents missing from the target are copied when they were updated at or after since, documents already copied
+// are embedded again when the source is newer. Documents missing from the source are removed from the target when
+// they were updated before switched, so documents written to the target after the alias switch are kept. Zero
+// times copy and remove every missing document.
+func (llm *LLMContainer) syncPrefix(ctx context.Context, from, to string, since, switched time.Time, options []LLMCallOption) (int, int, error) {
+	rdb := llm.RedisClient.redisClient
+	sources, err := prefixObjects(ctx, rdb, from)
+	if err != nil {
+		return 0, 0, err
+	}
+	targets, err := prefixObjects(ctx, rdb, to)
+	if err != nil {
+		return 0, 0, err
+	}
+	targetOptions := append(append([]LLMCallOption{}, options...), withPhysicalPrefix(to), withSourceUpdatedAt())
+	stale := func(content LLMEmbeddingContent) bool {
+		return switched.IsZero() || content.UpdatedAt.Before(switched)
+	}
+	objects, documents := 0, 0
+	for index, source := range sources {
+		if err := llm.decryptObject(&source); err != nil {
+			return objects, documents, err
+		}
+		target := targets[index]
+		embedded := false
+		for id, content := range source.Contents {
+			if copied, exists := target.Contents[id]; exists && !content.UpdatedAt.After(copied.UpdatedAt) {
+				continue
+			} else if !exists && content.UpdatedAt.Before(since) {
+				// Removed from the target after the switch
+				continue
+			}
+			content.Keys, content.GeneralKeys = nil, nil
+			if _, err := llm.EmbeddText(index, content, targetOptions...); err != nil {
+				return objects, documents, err
+			}
+			documents++
+			embedded = true
+		}
+		for id, content := range target.Contents {
+			if _, exists := source.Contents[id]; !exists && stale(content) {
+				if err := llm.RemoveEmbeddingSubKey(index, id, targetOptions...); err != nil {
+					return objects, documents, err
+				}
+			}
+		}
+		if embedded {
+			objects++
+		}
+	}
+	for index, target := range targets {
+		if _, exists := sources[index]; exists {
+			continue
+		}
+		removed := []string{}
+		for id, content := range target.Contents {
+			if stale(content) {
+				removed = append(removed, id)
+			}
+		}
+		if len(removed) == len(target.Contents) {
+			if err := llm.RemoveEmbedding(index, targetOptions...); err != nil {
+				return objects, documents, err
+			}
+			continue
+		}
+		for _, id := range removed {
+			if err := llm.RemoveEmbeddingSubKey(index, id, targetOptions...); err != nil {
+				return objects, documents, err
+			}
+		}
+	}
+	return objects, documents, nil
+}
+
+// prefixObjects loads the embedding objects stored with exactly this prefix, by index.
+func prefixObjects(ctx context.Context, rdb *redis.Client, prefix string) (map[string]LLMEmbeddingObject, error) {
+	keys, err := scanKeys(ctx, rdb, escapeGlob(LLMEmbeddingObject{EmbeddingPrefix: prefix}.getRawDocRedisId())+"*")
+	if err != nil {
+		return nil, err
+	}
+	objects := map[string]LLMEmbeddingObject{}
+	for _, key := range keys {
+		var obj LLMEmbeddingObject
+		if err := obj.load(rdb, key); err != nil {
+			continue
+		}
+		// rawDocs:<prefix>:* also matches the objects of longer prefixes, e.g. the generations of ReembedAll
+		if obj.EmbeddingPrefix == prefix {
+			objects[obj.Index] = obj
+		}
+	}
+	return objects, nil
+}
+
+// removePrefixEmbeddings removes the objects and chunks stored with exactly this prefix and drops their vector
+// indexes. Unlike CleanEmbeddings, the embeddings of longer prefixes are kept.
+func (llm *LLMContainer) removePrefixEmbeddings(ctx context.Context, prefix string) error {
+	rdb := llm.RedisClient.redisClient
+	objects, err := prefixObjects(ctx, rdb, prefix)
+	if err != nil {
+		return err
+	}
+	keys := []string{}
+	indexNames := map[string]bool{}
+	for _, obj := range objects {
+		keys = append(keys, obj.getRawDocRedisId())
+		for _, content := range obj.Contents {
+			for _, chunkKey := range content.chunkKeys() {
+				keys = append(keys, chunkKey)
+				if indexName, _, err := splitChunkKey(chunkKey); err == nil {
+					indexNames[indexName] = true
+				}
+			}
+		}
+	}
+	if _, err := unlinkKeys(ctx, rdb, keys); err != nil {
+		return err
+	}
+	for indexName := range indexNames {
+		if err := rdb.Do(ctx, "FT.DROPINDEX", indexName).Err(); err != nil && !isIndexNotFound(err) {
+			return err
+		}
+		llm.forgetVectorStores(indexName)
+	}
+	return nil
+}
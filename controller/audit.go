@@ -0,0 +1,252 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AuditRecord describes a single AskLLM call for compliance audits.
+//
+// Fields:
+//   - RequestID: The correlation ID set with WithRequestID.
+//   - TenantID: The tenant set with WithTenant.
+//   - SessionID: The session of the call.
+//   - Query: The user query.
+//   - DocumentIDs: The IDs of the retrieved documents, in retrieval order.
+//   - AnswerHash: SHA-256 hash of the final answer (the answer itself is not stored).
+//   - FailedToRespond: True if the model couldn't answer from the documents.
+//   - Error: The error of the call, empty on success.
+//   - Timings: Latency breakdown of the call.
+//   - TimeStamp: The time the call started.
+type AuditRecord struct {
+	RequestID       string     `json:"requestId,omitempty"`
+	TenantID        string     `json:"tenantId,omitempty"`
+	SessionID       string     `json:"sessionId"`
+	Query           string     `json:"query"`
+	DocumentIDs     []string   `json:"documentIds"`
+	AnswerHash      string     `json:"answerHash"`
+	FailedToRespond bool       `json:"failedToRespond"`
+	Error           string     `json:"error,omitempty"`
+	Timings         LLMTimings `json:"timings"`
+	TimeStamp       time.Time  `json:"timestamp"`
+}
+
+// AuditSink defines a destination for the audit records of AskLLM calls.
+//
+// Implementations must be safe for concurrent use.
+type AuditSink interface {
+	// WriteAudit persists a single audit record.
+	WriteAudit(ctx context.Context, record AuditRecord) error
+}
+
+// RedisAuditSink stores audit records in a Redis stream.
+//
+// Fields:
+//   - Stream: The Redis stream key records are appended to.
+//   - MaxLen: Approximate maximum stream length, 0 keeps every record.
+type RedisAuditSink struct {
+	Stream      string
+	MaxLen      int64
+	redisClient *redis.Client
+}
+
+// NewRedisAuditSink creates an audit sink backed by the container's Redis connection.
+//
+// Init must be called before the sink is created.
+//
+// Parameters:
+//   - stream: The Redis stream key, defaults to "aillm:audit".
+//
+// Returns:
+//   - *RedisAuditSink: The Redis backed sink.
+func (llm *LLMContainer) NewRedisAuditSink(stream string) *RedisAuditSink {
+	if stream == "" {
+		stream = "aillm:audit"
+	}
+	return &RedisAuditSink{
+		Stream:      stream,
+		redisClient: llm.RedisClient.redisClient,
+	}
+}
+
+// WriteAudit appends the record to the Redis stream.
+func (rs *RedisAuditSink) WriteAudit(ctx context.Context, record AuditRecord) error {
+	if rs.redisClient == nil {
+		return fmt.Errorf("redis audit sink is not initialized")
+	}
+	args := &redis.XAddArgs{
+		Stream: rs.Stream,
+		Values: map[string]interface{}{
+			"requestId":       record.RequestID,
+			"tenantId":        record.TenantID,
+			"sessionId":       record.SessionID,
+			"query":           record.Query,
+			"documentIds":     strings.Join(record.DocumentIDs, ","),
+			"answerHash":      record.AnswerHash,
+			"failedToRespond": record.FailedToRespond,
+			"error":           record.Error,
+			"retrievalMs":     record.Timings.Retrieval.Milliseconds(),
+			"generationMs":    record.Timings.Generation.Milliseconds(),
+			"totalMs":         record.Timings.Total.Milliseconds(),
+			"timestamp":       record.TimeStamp.Format(time.RFC3339Nano),
+		},
+	}
+	if rs.MaxLen > 0 {
+		args.MaxLen = rs.MaxLen
+		args.Approx = true
+	}
+	return rs.redisClient.XAdd(ctx, args).Err()
+}
+
+// FileAuditSink appends audit records to a file as JSON lines.
+type FileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileAuditSink opens, or creates, the audit file.
+//
+// Parameters:
+//   - path: The file records are appended to.
+//
+// Returns:
+//   - *FileAuditSink: The file backed sink, closed with Close.
+//   - error: An error if the file can't be opened.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{file: file}, nil
+}
+
+// WriteAudit appends the record as a JSON line.
+func (fs *FileAuditSink) WriteAudit(ctx context.Context, record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	_, err = fs.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the audit file.
+func (fs *FileAuditSink) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.file.Close()
+}
+
+// HTTPAuditSink posts audit records as JSON to an HTTP endpoint, e.g. a SIEM collector.
+//
+// Fields:
+//   - URL: The endpoint receiving the records.
+//   - Secret: Signs the body with HMAC-SHA256 in the WebhookSignatureHeader header when set.
+//   - Client: The HTTP client, defaults to a client with a 10 second timeout.
+type HTTPAuditSink struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// WriteAudit posts the record to the endpoint.
+func (hs *HTTPAuditSink) WriteAudit(ctx context.Context, record AuditRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hs.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if hs.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(hs.Secret))
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+	client := hs.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("audit endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// auditQuery sends the record of an AskLLM call to the configured AuditSink.
+//
+// Failures of the sink never fail the query; they are reported as warnings.
+func (llm *LLMContainer) auditQuery(ctx context.Context, Query string, start time.Time, result LLMResult, queryErr error, options []LLMCallOption) {
+	if llm.AuditSink == nil {
+		return
+	}
+	o := LLMCallOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+	record := AuditRecord{
+		RequestID:       result.RequestID,
+		TenantID:        o.tenantID,
+		SessionID:       o.SessionID,
+		Query:           Query,
+		DocumentIDs:     []string{},
+		FailedToRespond: result.FailedToRespond,
+		Timings:         result.Timings,
+		TimeStamp:       start,
+	}
+	seen := map[string]bool{}
+	for _, doc := range result.RagDocs {
+		id := doc.DocumentID
+		if id == "" {
+			// Chunks without document metadata are identified by their key
+			id = doc.ChunkID
+		}
+		if id != "" && !seen[id] {
+			seen[id] = true
+			record.DocumentIDs = append(record.DocumentIDs, id)
+		}
+	}
+	if result.Response != nil && len(result.Response.Choices) > 0 {
+		record.AnswerHash = sha256Hex(result.Response.Choices[0].Content)
+	}
+	if queryErr != nil {
+		record.Error = queryErr.Error()
+	}
+	if err := llm.AuditSink.WriteAudit(ctx, record); err != nil {
+		llm.requestLogger(result.RequestID).Warn("unable to write audit record", "error", err)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// benchmarkText returns a multi paragraph text of roughly the given size in bytes.
+func benchmarkText(size int) string {
+	paragraph := "Retrieval augmented generation combines a vector search over embedded documents with a large language model. " +
+		"The retrieved chunks are added to the prompt as <b>contextual knowledge</b>, so the model answers from the stored data.\n\n"
+	return strings.Repeat(paragraph, size/len(paragraph)+1)[:size]
+}
+
+// benchmarkDocuments returns count retrieved documents with reference metadata.
+func benchmarkDocuments(count int) []schema.Document {
+	docs := make([]schema.Document, count)
+	for idx := range docs {
+		rawKey, _ := json.Marshal(LLMEmbeddingContent{Id: "doc-" + strconv.Itoa(idx)})
+		docs[idx] = schema.Document{
+			PageContent: benchmarkText(2048),
+			Metadata:    map[string]any{"id": "doc:" + strconv.Itoa(idx), "rawkey": string(rawKey)},
+			Score:       float32(idx) / float32(count),
+		}
+	}
+	return docs
+}
+
+// benchmarkEmbeddingClient is an EmbeddingClient returning deterministic vectors without a model server.
+type benchmarkEmbeddingClient struct{}
+
+func (benchmarkEmbeddingClient) NewEmbedder() (embeddings.Embedder, error) {
+	return benchmarkEmbedder{}, nil
+}
+
+func (benchmarkEmbeddingClient) initialized() bool {
+	return true
+}
+
+// benchmarkEmbedder hashes the words of a text into a 768 dimensional vector.
+type benchmarkEmbedder struct{}
+
+func (e benchmarkEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for idx, text := range texts {
+		vectors[idx], _ = e.EmbedQuery(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (benchmarkEmbedder) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	vector := make([]float32, 768)
+	for _, word := range strings.Fields(text) {
+		hash := fnv.New32a()
+		hash.Write([]byte(word))
+		vector[hash.Sum32()%768]++
+	}
+	return vector, nil
+}
+
+func BenchmarkSplitText(b *testing.B) {
+	textEmbedding := LLMTextEmbedding{ChunkSize: 2048, ChunkOverlap: 100, Text: benchmarkText(64 * 1024)}
+	b.SetBytes(int64(len(textEmbedding.Text)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := textEmbedding.SplitText(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEmbedDocuments(b *testing.B) {
+	llm := &LLMContainer{Embedder: benchmarkEmbeddingClient{}, RetryPolicy: RetryPolicy{MaxAttempts: 3}}
+	embedder, err := llm.getEmbedder()
+	if err != nil {
+		b.Fatal(err)
+	}
+	textEmbedding := LLMTextEmbedding{ChunkSize: 2048, ChunkOverlap: 100, Text: benchmarkText(64 * 1024)}
+	docs, err := textEmbedding.SplitText()
+	if err != nil {
+		b.Fatal(err)
+	}
+	texts := make([]string, len(docs))
+	for idx, doc := range docs {
+		texts[idx] = doc.PageContent
+	}
+	b.SetBytes(int64(len(textEmbedding.Text)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := embedder.EmbedDocuments(context.Background(), texts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCombineSearchResults(b *testing.B) {
+	llm := &LLMContainer{}
+	docs := benchmarkDocuments(50)
+	vectorResults := make([]HybridSearchResult, 0, len(docs))
+	lexicalResults := make([]HybridSearchResult, 0, len(docs))
+	for idx, doc := range docs {
+		vectorResults = append(vectorResults, HybridSearchResult{Document: doc, VectorScore: float64(doc.Score)})
+		// Half of the lexical results overlap with the vector results
+		if idx%2 == 0 {
+			lexicalResults = append(lexicalResults, HybridSearchResult{Document: doc, LexicalScore: float64(idx)})
+		}
+	}
+	for _, useRRF := range []bool{true, false} {
+		config := DefaultHybridSearchConfig()
+		config.UseRRF = useRRF
+		b.Run("RRF="+strconv.FormatBool(useRRF), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				llm.combineSearchResults(vectorResults, lexicalResults, &config)
+			}
+		})
+	}
+}
+
+func BenchmarkBuildContextChunks(b *testing.B) {
+	docs := benchmarkDocuments(5)
+	for _, cleanup := range []bool{false, true} {
+		b.Run("Cleanup="+strconv.FormatBool(cleanup), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				buildContextChunks(docs, true, cleanup)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"sort"
+	"sync"
+)
+
+// characterRegistry keeps the named characters of the container.
+type characterRegistry struct {
+	mu         sync.RWMutex
+	characters map[string]string
+}
+
+// characterRegistry returns the named characters, creating them on first use.
+func (llm *LLMContainer) characterRegistry() *characterRegistry {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.characters == nil {
+		llm.characters = &characterRegistry{characters: map[string]string{}}
+	}
+	return llm.characters
+}
+
+// RegisterCharacter registers a named character of the assistant, selected per call with WithCharacterName.
+//
+// Named characters let concurrent callers use different personas without changing the Character field shared by
+// all calls. Registering a name again replaces its prompt.
+//
+// Parameters:
+//   - name: The name of the character, e.g. "support".
+//   - prompt: The character, completing "You are a ... AI assistant", an empty prompt removes the character.
+//
+// Example Usage:
+//
+//	llm.RegisterCharacter("support", "patient customer support")
+//	llm.RegisterCharacter("sales", "friendly sales")
+//	result, err := llm.AskLLM(query, llm.WithCharacterName("support"))
+func (llm *LLMContainer) RegisterCharacter(name, prompt string) {
+	registry := llm.characterRegistry()
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if prompt == "" {
+		delete(registry.characters, name)
+		return
+	}
+	registry.characters[name] = prompt
+}
+
+// CharacterPrompt returns the prompt of a named character, false if it isn't registered.
+func (llm *LLMContainer) CharacterPrompt(name string) (string, bool) {
+	registry := llm.characterRegistry()
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	prompt, ok := registry.characters[name]
+	return prompt, ok
+}
+
+// CharacterNames returns the names of the registered characters in alphabetical order.
+func (llm *LLMContainer) CharacterNames() []string {
+	registry := llm.characterRegistry()
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	names := make([]string, 0, len(registry.characters))
+	for name := range registry.characters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithCharacterName selects a character registered with RegisterCharacter for a call. WithCharacter takes
+// precedence, the Character of the container is used when no character is selected.
+//
+// Parameters:
+//   - name: The name of the character, "" keeps the default character.
+//
+// Returns:
+//   - LLMCallOption: An option that selects the character.
+func (llm *LLMContainer) WithCharacterName(name string) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.characterName = name
+	}
+}
+
+// callCharacter returns the character of a call: WithCharacter, else the character of WithCharacterName, else
+// the Character of the container.
+func (llm *LLMContainer) callCharacter(o *LLMCallOptions) string {
+	if o.character != "" {
+		return o.character
+	}
+	if o.characterName != "" {
+		if prompt, ok := llm.CharacterPrompt(o.characterName); ok {
+			return prompt
+		}
+	}
+	return llm.Character
+}
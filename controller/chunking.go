@@ -0,0 +1,145 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// cjkCharacterWeight is the length of a Chinese, Japanese or Korean character in ChunkSize units.
+//
+// ChunkSize counts the characters of alphabetic scripts, about four per token, while a CJK character is about one
+// token, so a chunk of CJK text of ChunkSize characters would hold several times the tokens of an English chunk.
+const cjkCharacterWeight = 3
+
+// spacelessScripts are the scripts, as named by dominantScript, written without spaces between words.
+var spacelessScripts = map[string]bool{"Han": true, "Kana": true}
+
+// cjkSentenceEnd matches the end of a sentence of a spaceless script, with its closing quotes.
+var cjkSentenceEnd = regexp.MustCompile(`[。！？；!?]+[」』）"')]*`)
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// chunkLength returns the length of a text in ChunkSize units, CJK characters count cjkCharacterWeight.
+func chunkLength(text string) int {
+	length := 0
+	for _, r := range text {
+		if isCJK(r) {
+			length += cjkCharacterWeight
+		} else {
+			length++
+		}
+	}
+	return length
+}
+
+// breakIndex returns where to cut a text so the part before fits a chunk length, after the last sentence end,
+// else the last comma, else at the last space, else between characters. The whole text is returned if it fits.
+func breakIndex(text string, limit int) int {
+	length, sentence, comma, space := 0, 0, 0, 0
+	for idx, r := range text {
+		weight := 1
+		if isCJK(r) {
+			weight = cjkCharacterWeight
+		}
+		if length+weight > limit {
+			switch {
+			case idx > 0 && (r == ' ' || r == '\n'):
+				return idx
+			case sentence > 0:
+				return sentence
+			case comma > 0:
+				return comma
+			case space > 0:
+				return space
+			case idx > 0:
+				return idx
+			}
+			// A single character longer than the limit
+			return idx + utf8.RuneLen(r)
+		}
+		length += weight
+		next := idx + utf8.RuneLen(r)
+		switch r {
+		case '。', '！', '？', '；':
+			sentence = next
+		case '，', '、':
+			comma = next
+		case ' ', '\n':
+			space = idx
+		}
+	}
+	return len(text)
+}
+
+// splitSpacelessText splits a text of a spaceless script into chunks of sentences, sentences longer than a chunk
+// are cut at commas or between characters. Consecutive chunks share the sentences fitting the overlap.
+func splitSpacelessText(text string, chunkSize, chunkOverlap int) []schema.Document {
+	segments := []string{}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		last := 0
+		for _, loc := range cjkSentenceEnd.FindAllStringIndex(line, -1) {
+			segments = append(segments, line[last:loc[1]])
+			last = loc[1]
+		}
+		segments = append(segments, line[last:])
+	}
+
+	chunks := []string{}
+	current, length := []string{}, 0
+	add := func(segment string) {
+		segmentLength := chunkLength(segment)
+		if len(current) > 0 && length+segmentLength > chunkSize {
+			chunks = append(chunks, strings.Join(current, ""))
+			// The next chunk starts with the last sentences that fit the overlap
+			start, kept := len(current), 0
+			for start > 0 {
+				previous := chunkLength(current[start-1])
+				if kept+previous > chunkOverlap || kept+previous+segmentLength > chunkSize {
+					break
+				}
+				start--
+				kept += previous
+			}
+			current, length = append([]string(nil), current[start:]...), kept
+		}
+		current = append(current, segment)
+		length += segmentLength
+	}
+	for _, segment := range segments {
+		for segment != "" {
+			cut := breakIndex(segment, chunkSize)
+			add(segment[:cut])
+			segment = segment[cut:]
+		}
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.Join(current, ""))
+	}
+
+	docs := []schema.Document{}
+	for _, chunk := range chunks {
+		if chunk = strings.TrimSpace(chunk); chunk != "" {
+			docs = append(docs, schema.Document{PageContent: chunk, Metadata: map[string]any{}})
+		}
+	}
+	return docs
+}
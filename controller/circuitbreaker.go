@@ -0,0 +1,155 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when an endpoint failed repeatedly and its circuit breaker rejects requests.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreakerConfig configures the circuit breakers of the provider endpoints.
+//
+// After FailureThreshold consecutive failures an endpoint is considered unhealthy and requests fail
+// immediately with ErrCircuitOpen (or go to the FailoverLLMClient) instead of waiting for timeouts.
+// After OpenTimeout a single trial request is let through; its success closes the circuit again. The breakers
+// are kept per container, containers using the same endpoint track its health separately.
+//
+// Fields:
+//   - FailureThreshold: Consecutive failures that open the circuit, Init sets 5 when it is 0. A negative
+//     threshold, or 0 after Init, disables the breaker.
+//   - OpenTimeout: Time the circuit stays open before a trial request (default 30s).
+type CircuitBreakerConfig struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+// circuit breaker states
+const (
+	circuitClosed = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker tracks the health of a single endpoint.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	config   CircuitBreakerConfig
+	state    int
+	failures int
+	openedAt time.Time
+}
+
+// circuitBreakerRegistry keeps the breakers of the endpoints of a container, shared by all clients using the same
+// endpoint and model.
+type circuitBreakerRegistry struct {
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// circuitBreakerRegistry returns the breakers of the container, creating them on first use.
+func (llm *LLMContainer) circuitBreakerRegistry() *circuitBreakerRegistry {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.circuitBreakers == nil {
+		llm.circuitBreakers = &circuitBreakerRegistry{breakers: map[string]*circuitBreaker{}}
+	}
+	return llm.circuitBreakers
+}
+
+// getCircuitBreaker returns the breaker of an endpoint with the current CircuitBreaker configuration, or nil if
+// breakers are disabled.
+func (llm *LLMContainer) getCircuitBreaker(config LLMConfig) *circuitBreaker {
+	breakerConfig := llm.CircuitBreaker
+	if breakerConfig.FailureThreshold <= 0 {
+		return nil
+	}
+	if breakerConfig.OpenTimeout <= 0 {
+		breakerConfig.OpenTimeout = 30 * time.Second
+	}
+	registry := llm.circuitBreakerRegistry()
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	key := config.Apiurl + "|" + config.AiModel
+	breaker, ok := registry.breakers[key]
+	if !ok {
+		breaker = &circuitBreaker{}
+		registry.breakers[key] = breaker
+	}
+	breaker.mu.Lock()
+	breaker.config = breakerConfig
+	breaker.mu.Unlock()
+	return breaker
+}
+
+// allow reports whether a request may be sent to the endpoint.
+func (cb *circuitBreaker) allow() bool {
+	if cb == nil {
+		return true
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch cb.state {
+	case circuitOpen:
+		if time.Since(cb.openedAt) < cb.config.OpenTimeout {
+			return false
+		}
+		// Let a single trial request through
+		cb.state = circuitHalfOpen
+		return true
+	case circuitHalfOpen:
+		return false
+	}
+	return true
+}
+
+// record updates the breaker with the outcome of a request.
+func (cb *circuitBreaker) record(err error) {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if errors.Is(err, context.Canceled) {
+		// A canceled trial request tells nothing about the endpoint, the next request is the trial
+		if cb.state == circuitHalfOpen {
+			cb.state = circuitOpen
+		}
+		return
+	}
+	if err == nil {
+		cb.state = circuitClosed
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.state == circuitHalfOpen || cb.failures >= cb.config.FailureThreshold {
+		cb.state = circuitOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+// call runs fn if the circuit allows it and records its outcome.
+func (cb *circuitBreaker) call(fn func() error) error {
+	if !cb.allow() {
+		return ErrCircuitOpen
+	}
+	err := fn()
+	cb.record(err)
+	return err
+}
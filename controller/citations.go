@@ -0,0 +1,140 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// Citation is a reference of an answer generated with WithRagReferences.
+//
+// Fields:
+//   - ID: The referenced document ID.
+//   - Quote: The span of the document quoted by the model, if any.
+//   - Verified: True if the ID is a retrieved document and the quote appears in it.
+//   - Reason: Why the citation isn't verified, e.g. "unknown reference" or "quote not found".
+type Citation struct {
+	ID       string `json:"id"`
+	Quote    string `json:"quote,omitempty"`
+	Verified bool   `json:"verified"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// documentReferenceID returns the ID of an embedded document, which the model uses to reference it.
+func documentReferenceID(doc schema.Document) string {
+	rawKey, ok := doc.Metadata["rawkey"].(string)
+	if !ok {
+		return ""
+	}
+	rawKeyObject := LLMEmbeddingContent{}
+	if err := json.Unmarshal([]byte(rawKey), &rawKeyObject); err != nil {
+		return ""
+	}
+	return rawKeyObject.Id
+}
+
+// normalizeQuote lowercases a text and collapses its markup and spaces, so quotes match reformatted sources.
+func normalizeQuote(text string) string {
+	text = htmlTagPattern.ReplaceAllString(text, " ")
+	text = strings.Trim(text, " \t\n\"'“”«»")
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// parseCitations reads the reference line of an answer, references are either IDs or {"id","quote"} objects.
+func parseCitations(referencesStr string) []Citation {
+	references := struct {
+		References []json.RawMessage `json:"references"`
+	}{}
+	referencesStr = strings.TrimSpace(referencesStr)
+	// Models sometimes add text or a code block around the object
+	if start, end := strings.Index(referencesStr, "{"), strings.LastIndex(referencesStr, "}"); start >= 0 && end > start {
+		referencesStr = referencesStr[start : end+1]
+	}
+	json.Unmarshal([]byte(referencesStr), &references)
+	citations := []Citation{}
+	for _, raw := range references.References {
+		citation := Citation{}
+		if err := json.Unmarshal(raw, &citation.ID); err != nil {
+			json.Unmarshal(raw, &citation)
+		}
+		if citation.ID = strings.TrimSpace(citation.ID); citation.ID != "" {
+			citations = append(citations, citation)
+		}
+	}
+	return citations
+}
+
+// verifyCitations checks that every citation references a retrieved document and that its quote appears in it.
+//
+// Parameters:
+//   - citations: The references of the answer.
+//   - docs: The documents sent to the model.
+//
+// Returns:
+//   - []Citation: The citations with Verified and Reason set, duplicates removed.
+func verifyCitations(citations []Citation, docs []schema.Document) []Citation {
+	sources := map[string][]string{}
+	for _, doc := range docs {
+		if id := documentReferenceID(doc); id != "" {
+			sources[id] = append(sources[id], normalizeQuote(doc.PageContent))
+		}
+	}
+	verified := []Citation{}
+	seen := map[string]bool{}
+	for _, citation := range citations {
+		key := citation.ID + "\x00" + citation.Quote
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		contents, ok := sources[citation.ID]
+		switch {
+		case !ok:
+			citation.Reason = "unknown reference"
+		case citation.Quote != "":
+			quote := normalizeQuote(citation.Quote)
+			for _, content := range contents {
+				if strings.Contains(content, quote) {
+					citation.Verified = true
+					break
+				}
+			}
+			if !citation.Verified {
+				citation.Reason = "quote not found"
+			}
+		default:
+			citation.Verified = true
+		}
+		verified = append(verified, citation)
+	}
+	return verified
+}
+
+// referenceIDs returns the IDs of the citations returned in LLMResult.LLMReferences, fabricated ones are
+// dropped unless keepUnverified is set.
+func referenceIDs(citations []Citation, keepUnverified bool) []string {
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, citation := range citations {
+		if seen[citation.ID] || (!citation.Verified && !keepUnverified) {
+			continue
+		}
+		seen[citation.ID] = true
+		ids = append(ids, citation.ID)
+	}
+	return ids
+}
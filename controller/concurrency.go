@@ -0,0 +1,40 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import "sync"
+
+// lazyInitMu guards the container fields created on first use, e.g. when Init wasn't called.
+//
+// The container is copied by value by some methods, so the lock can't be a field of it.
+var lazyInitMu sync.Mutex
+
+// clientInitMu serializes the initialization of the LLM and embedding clients, which set the model of
+// their controller and may share it, e.g. one OllamaController used as LLMClient and Embedder.
+var clientInitMu sync.Mutex
+
+// ensureEmbedder initializes the embedding client on first use, concurrent requests initialize it once.
+// Embedding clients implemented outside the package are expected to be ready when NewEmbedder is called.
+//
+// Returns:
+//   - error: An error if the embedding model can't be initialized.
+func (llm *LLMContainer) ensureEmbedder() error {
+	clientInitMu.Lock()
+	defer clientInitMu.Unlock()
+	client, ok := llm.Embedder.(interface{ initialized() bool })
+	if !ok || client.initialized() {
+		return nil
+	}
+	return llm.InitEmbedding()
+}
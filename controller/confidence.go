@@ -0,0 +1,112 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"math"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// Confidence estimates how likely an answer is correct, so applications can route low confidence answers to humans.
+//
+// The estimate combines the similarity of the best retrieved chunk, how clearly it stands out from the other chunks
+// and, when checked, the groundedness of the answer. It is a heuristic to rank answers, not a probability.
+//
+// Fields:
+//   - Score: The confidence from 0 to 1, 0 when the model failed to respond or no chunk was retrieved.
+//   - TopSimilarity: The cosine similarity of the best chunk found by the vector search.
+//   - ScoreSpread: The similarity of the best chunk minus the mean similarity of the retrieved chunks.
+//   - Groundedness: The groundedness score of the answer, only set by the groundedness check.
+//   - Low: True if Score is below LowConfidenceThreshold.
+type Confidence struct {
+	Score         float64  `json:"score"`
+	TopSimilarity float64  `json:"topSimilarity"`
+	ScoreSpread   float64  `json:"scoreSpread"`
+	Groundedness  *float64 `json:"groundedness,omitempty"`
+	Low           bool     `json:"low"`
+}
+
+// chunkSimilarity returns the cosine similarity of a retrieved chunk, vector searches report the cosine distance.
+//
+// Chunks found by the lexical search only have no similarity.
+func chunkSimilarity(doc schema.Document) (float64, bool) {
+	switch doc.Metadata["search_type"] {
+	case "lexical":
+		return 0, false
+	case "vector", "hybrid":
+		distance, ok := doc.Metadata["vector_score"].(float64)
+		return 1 - distance, ok
+	}
+	return 1 - float64(doc.Score), true
+}
+
+// lowConfidenceThreshold returns LowConfidenceThreshold or its default.
+func (llm *LLMContainer) lowConfidenceThreshold() float64 {
+	if llm.LowConfidenceThreshold > 0 {
+		return llm.LowConfidenceThreshold
+	}
+	return 0.5
+}
+
+// answerConfidence estimates the confidence of an answer.
+//
+// Parameters:
+//   - docs: The retrieved chunks.
+//   - failedToRespond: True if the model couldn't answer from the chunks.
+//   - groundedness: The groundedness report of the answer, nil if it wasn't checked.
+//
+// Returns:
+//   - *Confidence: The confidence of the answer.
+func (llm *LLMContainer) answerConfidence(docs []schema.Document, failedToRespond bool, groundedness *GroundednessReport) *Confidence {
+	confidence := &Confidence{}
+	similarities := []float64{}
+	for _, doc := range docs {
+		if similarity, ok := chunkSimilarity(doc); ok {
+			similarities = append(similarities, math.Max(0, math.Min(1, similarity)))
+		}
+	}
+	if len(similarities) > 0 {
+		sum := 0.0
+		for _, similarity := range similarities {
+			confidence.TopSimilarity = math.Max(confidence.TopSimilarity, similarity)
+			sum += similarity
+		}
+		confidence.ScoreSpread = confidence.TopSimilarity - sum/float64(len(similarities))
+	}
+	if groundedness != nil {
+		score := groundedness.Score
+		confidence.Groundedness = &score
+	}
+
+	if !failedToRespond && len(docs) > 0 {
+		// A best chunk standing out by 0.2 or more counts as a clear match
+		spread := math.Min(1, confidence.ScoreSpread/0.2)
+		if len(similarities) == 1 {
+			spread = 1
+		}
+		retrieval := 0.8*confidence.TopSimilarity + 0.2*spread
+		if len(similarities) == 0 {
+			// Lexical matches only, without a similarity the groundedness decides
+			retrieval = 0.5
+		}
+		confidence.Score = retrieval
+		if confidence.Groundedness != nil {
+			confidence.Score = 0.4*retrieval + 0.6*(*confidence.Groundedness)
+		}
+	}
+	confidence.Score = math.Round(confidence.Score*1000) / 1000
+	confidence.Low = confidence.Score < llm.lowConfidenceThreshold()
+	return confidence
+}
@@ -0,0 +1,459 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Duration is a time.Duration read from configuration files as a string such as "30s" or "2m".
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler, plain numbers are seconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ProviderConfig configures an LLM or embedding endpoint.
+//
+// Fields:
+//   - Provider: "ollama" or "openai" (any OpenAI-compatible endpoint), default "ollama".
+//   - URL, Model, Token: The endpoint, the model name and the API key.
+//   - RequestsPerMinute, TokensPerMinute: Optional client-side rate limits.
+type ProviderConfig struct {
+	Provider          string `json:"provider"`
+	URL               string `json:"url"`
+	Model             string `json:"model"`
+	Token             string `json:"token"`
+	RequestsPerMinute int    `json:"requestsPerMinute"`
+	TokensPerMinute   int    `json:"tokensPerMinute"`
+}
+
+// Config is the file representation of an LLMContainer, see LoadConfig.
+//
+// Unset values keep the defaults of Init. The embedder defaults to the LLM endpoint.
+type Config struct {
+	LLM      ProviderConfig  `json:"llm"`
+	Embedder *ProviderConfig `json:"embedder"`
+	Vision   *ProviderConfig `json:"vision"`
+	Failover *ProviderConfig `json:"failover"`
+	Redis    struct {
+		Host     string `json:"host"`
+		Password string `json:"password"`
+	} `json:"redis"`
+	TikaURL           string  `json:"tikaUrl"`
+	SearchAlgorithm   string  `json:"searchAlgorithm"` // similarity, knn, hybrid, lexical, semantic or none
+	ScoreThreshold    float32 `json:"scoreThreshold"`
+	RagRowCount       int     `json:"ragRowCount"`
+	Temperature       float64 `json:"temperature"`
+	TopP              float64 `json:"topP"`
+	AllowHallucinate  bool    `json:"allowHallucinate"`
+	LanguageDetection bool    `json:"languageDetection"`
+	AnswerLanguage    string  `json:"answerLanguage"`
+	FallbackLanguage  string  `json:"fallbackLanguage"`
+	Prompts           struct {
+		Character         string            `json:"character"`
+		NoRagErrorMessage string            `json:"noRagErrorMessage"`
+		NotRelatedAnswer  string            `json:"notRelatedAnswer"`
+		Characters        map[string]string `json:"characters"`
+	} `json:"prompts"`
+	Chunking struct {
+		ChunkSize    int `json:"chunkSize"`
+		ChunkOverlap int `json:"chunkOverlap"`
+	} `json:"chunking"`
+	Retry struct {
+		MaxAttempts    int      `json:"maxAttempts"`
+		InitialBackoff Duration `json:"initialBackoff"`
+		MaxBackoff     Duration `json:"maxBackoff"`
+		Deterministic  bool     `json:"deterministic"`
+	} `json:"retry"`
+	VisionTimeout        Duration           `json:"visionTimeout"`
+	IngestionWorkers     int                `json:"ingestionWorkers"`
+	IngestionWebhooks    []IngestionWebhook `json:"ingestionWebhooks"`
+	ShowWarnings         bool               `json:"showWarnings"`
+	Tenants              []Tenant           `json:"tenants"`
+	RequireTenant        bool               `json:"requireTenant"`
+	SessionQuota         Quota              `json:"sessionQuota"`
+	UsageRetention       Duration           `json:"usageRetention"`
+	PII                  PIIConfig          `json:"pii"`
+	DisableSecurityCheck bool               `json:"disableSecurityCheck"`
+	GroundednessCheck    bool               `json:"groundednessCheck"`
+	Moderation           struct {
+		URL           string `json:"url"` // OpenAI compatible moderation endpoint, enabled when url or token is set
+		Token         string `json:"token"`
+		Model         string `json:"model"`
+		Action        string `json:"action"`
+		OnQuery       bool   `json:"onQuery"`
+		OnAnswer      bool   `json:"onAnswer"`
+		RedactionText string `json:"redactionText"`
+	} `json:"moderation"`
+	Retention struct {
+		Interval     Duration `json:"interval"`
+		MemoryMaxAge Duration `json:"memoryMaxAge"`
+		UsageMaxAge  Duration `json:"usageMaxAge"`
+		AuditMaxAge  Duration `json:"auditMaxAge"`
+		TraceMaxAge  Duration `json:"traceMaxAge"`
+	} `json:"retention"`
+	Audit struct {
+		File   string `json:"file"` // JSON lines file receiving the audit records
+		URL    string `json:"url"`  // HTTP endpoint receiving the audit records, used when file is empty
+		Secret string `json:"secret"`
+	} `json:"audit"`
+	Encryption struct {
+		KeyID string `json:"keyId"`
+		Key   string `json:"key"` // Base64 encoded 32 byte master key, e.g. "${AILLM_MASTER_KEY}"
+	} `json:"encryption"`
+	Timeouts struct {
+		Request   Duration `json:"request"` // Deadline of AskLLM calls, unset means no deadline
+		LLM       Duration `json:"llm"`
+		Embedding Duration `json:"embedding"`
+		Tika      Duration `json:"tika"`
+		Redis     Duration `json:"redis"`
+	} `json:"timeouts"`
+	InputLimits InputLimits `json:"inputLimits"`
+	Traces      struct {
+		Enabled bool     `json:"enabled"` // Stores the trace of every AskLLM call in Redis
+		TTL     Duration `json:"ttl"`
+	} `json:"traces"`
+	LowConfidenceThreshold float64 `json:"lowConfidenceThreshold"`
+	WebFallback            struct {
+		SearXNGURL    string  `json:"searxngUrl"` // Enables the web search fallback with a SearXNG instance
+		MaxResults    int     `json:"maxResults"`
+		ChunkCount    int     `json:"chunkCount"`
+		MinSimilarity float64 `json:"minSimilarity"`
+	} `json:"webFallback"`
+	IndexBoosts map[string]float64 `json:"indexBoosts"` // Score multipliers of indexes and embedding prefixes searched together
+	MapReduce   struct {
+		ContextTokens int `json:"contextTokens"` // Enables map-reduce answering of retrievals exceeding this budget
+		Concurrency   int `json:"concurrency"`
+	} `json:"mapReduce"`
+	CrossLingual struct {
+		Languages []string `json:"languages"` // Languages of the documents queries finding nothing are translated to
+	} `json:"crossLingual"`
+	FallbackLanguages []string `json:"fallbackLanguages"` // Languages searched in order when the call language finds nothing
+	Timezone          string   `json:"timezone"`          // IANA timezone of the dates given to the model, e.g. "Europe/Berlin"
+	Vectors           struct {
+		Type string      `json:"type"` // FLOAT32 (default), FLOAT16 or INT8
+		HNSW *HNSWConfig `json:"hnsw"` // HNSW index parameters, FLAT indexes when unset
+	} `json:"vectors"`
+}
+
+// configEnvOverrides maps environment variables to the Config fields they override.
+func (c *Config) configEnvOverrides() map[string]*string {
+	return map[string]*string{
+		"AILLM_REDIS_HOST":     &c.Redis.Host,
+		"AILLM_REDIS_PASSWORD": &c.Redis.Password,
+		"AILLM_LLM_PROVIDER":   &c.LLM.Provider,
+		"AILLM_LLM_URL":        &c.LLM.URL,
+		"AILLM_LLM_MODEL":      &c.LLM.Model,
+		"AILLM_LLM_TOKEN":      &c.LLM.Token,
+		"TikaURL":              &c.TikaURL,
+	}
+}
+
+// LoadConfig builds an LLMContainer from a YAML or JSON configuration file.
+//
+// ${VAR} and ${VAR:-default} references in the file are replaced with environment variables before
+// parsing, so secrets don't have to be stored in the file. The AILLM_REDIS_HOST, AILLM_REDIS_PASSWORD,
+// AILLM_LLM_PROVIDER, AILLM_LLM_URL, AILLM_LLM_MODEL, AILLM_LLM_TOKEN and TikaURL environment
+// variables override the file, the embedder can be overridden with AILLM_EMBEDDER_PROVIDER,
+// AILLM_EMBEDDER_URL, AILLM_EMBEDDER_MODEL and AILLM_EMBEDDER_TOKEN.
+//
+// Parameters:
+//   - path: The configuration file, empty uses $AILLM_CONFIG or, if it's unset, only the environment.
+//
+// Returns:
+//   - *LLMContainer: The configured container, set optional fields such as Logger or Tracer and call Init.
+//   - error: An error if the file can't be read or is invalid.
+//
+// Example Usage:
+//
+//	# aillm.yaml
+//	llm:
+//	  provider: openai
+//	  url: https://api.openai.com/v1
+//	  model: gpt-4o-mini
+//	  token: ${OPENAI_API_KEY}
+//	embedder:
+//	  model: text-embedding-3-small
+//	redis:
+//	  host: ${REDIS_HOST:-localhost:6379}
+//	searchAlgorithm: hybrid
+//	scoreThreshold: 0.6
+//
+//	llm, err := aillm.LoadConfig("aillm.yaml")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	err = llm.Init()
+func LoadConfig(path string) (*LLMContainer, error) {
+	config, err := ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.NewContainer()
+}
+
+// ReadConfig reads a configuration file without building the container, see LoadConfig.
+func ReadConfig(path string) (Config, error) {
+	var config Config
+	if path == "" {
+		path = os.Getenv("AILLM_CONFIG")
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return config, err
+		}
+		// YAML is a superset of JSON, both formats are converted the same way
+		jsonData, err := yaml.YAMLToJSON([]byte(expandEnv(string(data))))
+		if err != nil {
+			return config, fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+		if err := json.Unmarshal(jsonData, &config); err != nil {
+			return config, fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+	}
+	for env, field := range config.configEnvOverrides() {
+		if value := os.Getenv(env); value != "" {
+			*field = value
+		}
+	}
+	// The embedder is optional, it is only created if a variable is set
+	embedder := ProviderConfig{}
+	if config.Embedder != nil {
+		embedder = *config.Embedder
+	}
+	for env, field := range map[string]*string{
+		"AILLM_EMBEDDER_PROVIDER": &embedder.Provider,
+		"AILLM_EMBEDDER_URL":      &embedder.URL,
+		"AILLM_EMBEDDER_MODEL":    &embedder.Model,
+		"AILLM_EMBEDDER_TOKEN":    &embedder.Token,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*field = value
+			config.Embedder = &embedder
+		}
+	}
+	return config, nil
+}
+
+// NewContainer builds the LLMContainer described by the configuration, Init must be called before use.
+func (c Config) NewContainer() (*LLMContainer, error) {
+	llmClient, err := c.LLM.newClient()
+	if err != nil {
+		return nil, fmt.Errorf("llm: %v", err)
+	}
+	if err := checkLanguage(c.AnswerLanguage); err != nil {
+		return nil, fmt.Errorf("answerLanguage: %v", err)
+	}
+	if err := (EmbeddingConfig{VectorType: VectorType(c.Vectors.Type), HNSW: c.Vectors.HNSW}).validateVectors(); err != nil {
+		return nil, fmt.Errorf("vectors: %v", err)
+	}
+	llm := &LLMContainer{
+		LLMClient:                           llmClient,
+		Embedder:                            llmClient,
+		RedisClient:                         RedisClient{Host: c.Redis.Host, Password: c.Redis.Password},
+		ScoreThreshold:                      c.ScoreThreshold,
+		RagRowCount:                         c.RagRowCount,
+		Temperature:                         c.Temperature,
+		TopP:                                c.TopP,
+		AllowHallucinate:                    c.AllowHallucinate,
+		LLMModelLanguageDetectionCapability: c.LanguageDetection,
+		AnswerLanguage:                      LanguageTag(c.AnswerLanguage),
+		FallbackLanguage:                    c.FallbackLanguage,
+		Character:                           c.Prompts.Character,
+		NoRagErrorMessage:                   c.Prompts.NoRagErrorMessage,
+		NotRelatedAnswer:                    c.Prompts.NotRelatedAnswer,
+		EmbeddingConfig: EmbeddingConfig{
+			ChunkSize:    c.Chunking.ChunkSize,
+			ChunkOverlap: c.Chunking.ChunkOverlap,
+			VectorType:   VectorType(c.Vectors.Type),
+			HNSW:         c.Vectors.HNSW,
+		},
+		RetryPolicy: RetryPolicy{
+			MaxAttempts:    c.Retry.MaxAttempts,
+			InitialBackoff: time.Duration(c.Retry.InitialBackoff),
+			MaxBackoff:     time.Duration(c.Retry.MaxBackoff),
+			Deterministic:  c.Retry.Deterministic,
+		},
+		VisionTimeout:        time.Duration(c.VisionTimeout),
+		IngestionWorkers:     c.IngestionWorkers,
+		IngestionWebhooks:    c.IngestionWebhooks,
+		ShowWarnings:         c.ShowWarnings,
+		Tenants:              c.Tenants,
+		RequireTenant:        c.RequireTenant,
+		SessionQuota:         c.SessionQuota,
+		UsageRetention:       time.Duration(c.UsageRetention),
+		PII:                  c.PII,
+		DisableSecurityCheck: c.DisableSecurityCheck,
+		GroundednessCheck:    c.GroundednessCheck,
+		Retention: RetentionPolicy{
+			Interval:     time.Duration(c.Retention.Interval),
+			MemoryMaxAge: time.Duration(c.Retention.MemoryMaxAge),
+			UsageMaxAge:  time.Duration(c.Retention.UsageMaxAge),
+			AuditMaxAge:  time.Duration(c.Retention.AuditMaxAge),
+			TraceMaxAge:  time.Duration(c.Retention.TraceMaxAge),
+		},
+		Timeouts: Timeouts{
+			Request:   time.Duration(c.Timeouts.Request),
+			LLM:       time.Duration(c.Timeouts.LLM),
+			Embedding: time.Duration(c.Timeouts.Embedding),
+			Tika:      time.Duration(c.Timeouts.Tika),
+			Redis:     time.Duration(c.Timeouts.Redis),
+		},
+		InputLimits:            c.InputLimits,
+		LowConfidenceThreshold: c.LowConfidenceThreshold,
+		IndexBoosts:            c.IndexBoosts,
+		MapReduce:              MapReduceConfig{ContextTokens: c.MapReduce.ContextTokens, Concurrency: c.MapReduce.Concurrency},
+		CrossLingual:           CrossLingualConfig{Languages: c.CrossLingual.Languages},
+		FallbackLanguages:      c.FallbackLanguages,
+	}
+	llm.Transcriber.TikaURL = c.TikaURL
+	if c.Moderation.URL != "" || c.Moderation.Token != "" {
+		llm.Moderation = ModerationConfig{
+			Moderator:     &OpenAIModerator{APIToken: c.Moderation.Token, Apiurl: c.Moderation.URL, Model: c.Moderation.Model},
+			Action:        c.Moderation.Action,
+			OnQuery:       c.Moderation.OnQuery,
+			OnAnswer:      c.Moderation.OnAnswer,
+			RedactionText: c.Moderation.RedactionText,
+		}
+	}
+	if c.Audit.File != "" {
+		if llm.AuditSink, err = NewFileAuditSink(c.Audit.File); err != nil {
+			return nil, fmt.Errorf("audit: %v", err)
+		}
+	} else if c.Audit.URL != "" {
+		llm.AuditSink = &HTTPAuditSink{URL: c.Audit.URL, Secret: c.Audit.Secret}
+	}
+	if c.WebFallback.SearXNGURL != "" {
+		llm.WebFallback = WebFallbackConfig{
+			Searcher:      &SearXNGSearcher{URL: c.WebFallback.SearXNGURL},
+			MaxResults:    c.WebFallback.MaxResults,
+			ChunkCount:    c.WebFallback.ChunkCount,
+			MinSimilarity: c.WebFallback.MinSimilarity,
+		}
+	}
+	if c.Traces.Enabled {
+		llm.TraceStore = llm.NewRedisTraceStore(time.Duration(c.Traces.TTL))
+	}
+	if c.Encryption.Key != "" {
+		key, err := base64.StdEncoding.DecodeString(c.Encryption.Key)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("encryption: the key must be 32 bytes encoded in base64")
+		}
+		keyID := c.Encryption.KeyID
+		if keyID == "" {
+			keyID = "default"
+		}
+		llm.KeyProvider = NewStaticKeyProvider(keyID, key)
+	}
+	if c.Embedder != nil {
+		embedder := *c.Embedder
+		// Unset embedder fields default to the LLM endpoint
+		if embedder.Provider == "" && embedder.URL == "" {
+			embedder.Provider, embedder.URL, embedder.Token = c.LLM.Provider, c.LLM.URL, c.LLM.Token
+		}
+		if llm.Embedder, err = embedder.newClient(); err != nil {
+			return nil, fmt.Errorf("embedder: %v", err)
+		}
+	}
+	if c.Vision != nil {
+		if llm.VisionClient, err = c.Vision.newClient(); err != nil {
+			return nil, fmt.Errorf("vision: %v", err)
+		}
+	}
+	if c.Failover != nil {
+		if llm.FailoverLLMClient, err = c.Failover.newClient(); err != nil {
+			return nil, fmt.Errorf("failover: %v", err)
+		}
+	}
+	if c.SearchAlgorithm != "" {
+		if llm.SearchAlgorithm, err = ParseSearchAlgorithm(c.SearchAlgorithm); err != nil {
+			return nil, err
+		}
+	}
+	if c.Timezone != "" {
+		if llm.Location, err = time.LoadLocation(c.Timezone); err != nil {
+			return nil, fmt.Errorf("timezone: %v", err)
+		}
+	}
+	for name, prompt := range c.Prompts.Characters {
+		llm.RegisterCharacter(name, prompt)
+	}
+	return llm, nil
+}
+
+// providerClient is implemented by the controllers of the providers, they serve completions and embeddings.
+type providerClient interface {
+	LLMClient
+	EmbeddingClient
+}
+
+// newClient creates the controller of an endpoint.
+func (pc ProviderConfig) newClient() (providerClient, error) {
+	config := LLMConfig{
+		Apiurl:            pc.URL,
+		AiModel:           pc.Model,
+		APIToken:          pc.Token,
+		RequestsPerMinute: pc.RequestsPerMinute,
+		TokensPerMinute:   pc.TokensPerMinute,
+	}
+	switch strings.ToLower(pc.Provider) {
+	case "", "ollama":
+		if config.Apiurl == "" {
+			config.Apiurl = "http://127.0.0.1:11434"
+		}
+		return &OllamaController{Config: config}, nil
+	case "openai":
+		return &OpenAIController{Config: config}, nil
+	}
+	return nil, fmt.Errorf("unknown provider %q, use ollama or openai", pc.Provider)
+}
+
+// envReferencePattern matches ${VAR} and ${VAR:-default}, a bare $ is kept so prompts can contain it.
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// expandEnv replaces ${VAR} and ${VAR:-default} with environment variables.
+func expandEnv(text string) string {
+	return envReferencePattern.ReplaceAllStringFunc(text, func(reference string) string {
+		match := envReferencePattern.FindStringSubmatch(reference)
+		if value := os.Getenv(match[1]); value != "" {
+			return value
+		}
+		return match[2]
+	})
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ContainerOption configures the container built by New, an error fails New.
+type ContainerOption func(*LLMContainer) error
+
+// New builds, validates and initializes an LLMContainer.
+//
+// Unlike a struct literal followed by Init, invalid settings such as a chunk overlap larger than the chunk size or
+// a score threshold above 1 are reported instead of silently replaced by defaults, and the container is ready to
+// use once New returns. Unset settings keep the defaults of Init.
+//
+// Parameters:
+//   - opts: The settings of the container, e.g. WithLLM, WithRedis or WithConfig.
+//
+// Returns:
+//   - *LLMContainer: The initialized container.
+//   - error: An error if an option is invalid, the LLM client is missing or Init fails, e.g. Redis is unreachable.
+//
+// Example Usage:
+//
+//	llm, err := aillm.New(
+//		aillm.WithLLM(&aillm.OllamaController{Config: aillm.LLMConfig{Apiurl: "http://127.0.0.1:11434", AiModel: "llama3.1"}}),
+//		aillm.WithRedis("localhost:6379", ""),
+//		aillm.WithChunking(1024, 100),
+//	)
+func New(opts ...ContainerOption) (*LLMContainer, error) {
+	llm := &LLMContainer{}
+	for _, opt := range opts {
+		if err := opt(llm); err != nil {
+			return nil, err
+		}
+	}
+	if err := llm.validate(); err != nil {
+		return nil, err
+	}
+	if err := llm.Init(); err != nil {
+		return nil, err
+	}
+	return llm, nil
+}
+
+// validate reports the settings of a container that Init would otherwise replace or ignore.
+func (llm *LLMContainer) validate() error {
+	if llm.LLMClient == nil {
+		return errors.New("missing LLM client, see WithLLM")
+	}
+	if llm.EmbeddingConfig.ChunkSize < 0 || llm.EmbeddingConfig.ChunkOverlap < 0 {
+		return errors.New("chunk size and overlap can't be negative")
+	}
+	if llm.EmbeddingConfig.ChunkSize > 0 && llm.EmbeddingConfig.ChunkOverlap >= llm.EmbeddingConfig.ChunkSize {
+		return fmt.Errorf("chunk overlap %d must be smaller than the chunk size %d", llm.EmbeddingConfig.ChunkOverlap, llm.EmbeddingConfig.ChunkSize)
+	}
+	if err := llm.EmbeddingConfig.validateVectors(); err != nil {
+		return err
+	}
+	if llm.ScoreThreshold < 0 || llm.ScoreThreshold > 1 {
+		return fmt.Errorf("score threshold %v must be between 0 and 1", llm.ScoreThreshold)
+	}
+	if llm.RagRowCount < 0 {
+		return fmt.Errorf("invalid RAG row count %d", llm.RagRowCount)
+	}
+	if llm.Temperature < 0 || llm.TopP < 0 || llm.TopP > 1 {
+		return fmt.Errorf("invalid temperature %v or top-p %v", llm.Temperature, llm.TopP)
+	}
+	if err := checkLanguage(string(llm.AnswerLanguage)); err != nil {
+		return fmt.Errorf("answer language: %v", err)
+	}
+	return nil
+}
+
+// WithConfig starts the container from a configuration, see Config.NewContainer. It replaces the settings of the
+// options before it, so it is usually the first option.
+func WithConfig(c Config) ContainerOption {
+	return func(llm *LLMContainer) error {
+		configured, err := c.NewContainer()
+		if err != nil {
+			return err
+		}
+		*llm = *configured
+		return nil
+	}
+}
+
+// WithLLM sets the client generating the answers, it embeds the documents too if it can unless WithEmbedder is used.
+func WithLLM(client LLMClient) ContainerOption {
+	return func(llm *LLMContainer) error {
+		if client == nil {
+			return errors.New("nil LLM client")
+		}
+		llm.LLMClient = client
+		if embedder, ok := client.(EmbeddingClient); ok && llm.Embedder == nil {
+			llm.Embedder = embedder
+		}
+		return nil
+	}
+}
+
+// WithEmbedder sets the client embedding the documents and queries.
+func WithEmbedder(client EmbeddingClient) ContainerOption {
+	return func(llm *LLMContainer) error {
+		if client == nil {
+			return errors.New("nil embedding client")
+		}
+		llm.Embedder = client
+		return nil
+	}
+}
+
+// WithRedis sets the Redis server storing the vectors, memories and usage, e.g. "localhost:6379".
+func WithRedis(host, password string) ContainerOption {
+	return func(llm *LLMContainer) error {
+		if host == "" {
+			return errors.New("missing redis host")
+		}
+		llm.RedisClient = RedisClient{Host: host, Password: password}
+		return nil
+	}
+}
+
+// WithChunking sets the size and the overlap of the chunks documents are split into.
+func WithChunking(size, overlap int) ContainerOption {
+	return func(llm *LLMContainer) error {
+		llm.EmbeddingConfig.ChunkSize, llm.EmbeddingConfig.ChunkOverlap = size, overlap
+		return nil
+	}
+}
+
+// WithVectorStorage sets how the chunk vectors are stored and indexed, see VectorType and HNSWConfig.
+func WithVectorStorage(vectorType VectorType, hnsw *HNSWConfig) ContainerOption {
+	return func(llm *LLMContainer) error {
+		llm.EmbeddingConfig.VectorType, llm.EmbeddingConfig.HNSW = vectorType, hnsw
+		return nil
+	}
+}
+
+// WithRetrieval sets the minimum similarity score and the number of documents retrieved per question.
+func WithRetrieval(scoreThreshold float32, ragRowCount int) ContainerOption {
+	return func(llm *LLMContainer) error {
+		llm.ScoreThreshold, llm.RagRowCount = scoreThreshold, ragRowCount
+		return nil
+	}
+}
+
+// WithAnswerLanguage sets the default answer language, a BCP-47 code or a language name, see ParseLanguage.
+func WithAnswerLanguage(language string) ContainerOption {
+	return func(llm *LLMContainer) error {
+		tag, err := ParseLanguage(language)
+		if err != nil {
+			return fmt.Errorf("answer language: %v", err)
+		}
+		llm.AnswerLanguage = tag
+		return nil
+	}
+}
+
+// WithTimezone sets the IANA timezone of the dates given to the model, e.g. "Europe/Berlin", see Location.
+func WithTimezone(name string) ContainerOption {
+	return func(llm *LLMContainer) error {
+		location, err := time.LoadLocation(name)
+		if err != nil {
+			return fmt.Errorf("timezone: %v", err)
+		}
+		llm.Location = location
+		return nil
+	}
+}
+
+// WithTika sets the Apache Tika server transcribing PDF and office documents.
+func WithTika(url string) ContainerOption {
+	return func(llm *LLMContainer) error {
+		llm.Transcriber.TikaURL = url
+		return nil
+	}
+}
+
+// WithSetup changes fields of the container no option covers, it runs in order with the other options.
+//
+// Example Usage:
+//
+//	aillm.WithSetup(func(llm *aillm.LLMContainer) { llm.AllowHallucinate = true })
+func WithSetup(setup func(*LLMContainer)) ContainerOption {
+	return func(llm *LLMContainer) error {
+		setup(llm)
+		return nil
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ContentBuilder builds a validated LLMEmbeddingContent, see NewContent.
+type ContentBuilder struct {
+	content LLMEmbeddingContent
+	err     error
+}
+
+// NewContent starts the content of a document to embed with EmbeddText.
+//
+// Parameters:
+//   - text: The text of the document.
+//
+// Returns:
+//   - *ContentBuilder: The builder, finished with Build.
+//
+// Example Usage:
+//
+//	content, err := aillm.NewContent(text).Title("Opening hours").Language("pt").Keywords("horário").Source(url).Build()
+//	if err != nil {
+//		return err
+//	}
+//	llm.EmbeddText("faq", content)
+func NewContent(text string) *ContentBuilder {
+	return &ContentBuilder{content: LLMEmbeddingContent{Text: text}}
+}
+
+// Title sets the title of the document, it is embedded with the text.
+func (cb *ContentBuilder) Title(title string) *ContentBuilder {
+	cb.content.Title = title
+	return cb
+}
+
+// Language sets the language of the document, a BCP-47 code or a language name, see ParseLanguage.
+func (cb *ContentBuilder) Language(language string) *ContentBuilder {
+	tag, err := ParseLanguage(language)
+	if err != nil {
+		cb.fail(err)
+		return cb
+	}
+	cb.content.Language = string(tag)
+	return cb
+}
+
+// ID sets the ID of the document, embedding content with an existing ID replaces it.
+func (cb *ContentBuilder) ID(id string) *ContentBuilder {
+	if strings.TrimSpace(id) == "" {
+		cb.fail(errors.New("empty document ID"))
+		return cb
+	}
+	cb.content.Id = id
+	return cb
+}
+
+// Keywords adds keywords to the document, they are matched by lexical searches.
+func (cb *ContentBuilder) Keywords(keywords ...string) *ContentBuilder {
+	for _, keyword := range keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			cb.content.Keywords = append(cb.content.Keywords, keyword)
+		}
+	}
+	return cb
+}
+
+// Source sets the origin of the document, e.g. a URL or a file name, returned in RetrievedDocument.Source.
+func (cb *ContentBuilder) Source(source string) *ContentBuilder {
+	cb.content.Sources = source
+	return cb
+}
+
+// Table adds a table to the document, see Table.
+func (cb *ContentBuilder) Table(table Table) *ContentBuilder {
+	if len(table.Headers) == 0 {
+		cb.fail(fmt.Errorf("table %q has no headers", table.Caption))
+		return cb
+	}
+	cb.content.Tables = append(cb.content.Tables, table)
+	return cb
+}
+
+// fail keeps the first error of the builder, it is returned by Build.
+func (cb *ContentBuilder) fail(err error) {
+	if cb.err == nil {
+		cb.err = err
+	}
+}
+
+// Build validates the content and returns it.
+//
+// A document needs a text or a table and a language. Without ID, a new one is generated so it is known before
+// embedding, e.g. to update or remove the document later.
+//
+// Returns:
+//   - LLMEmbeddingContent: The content to embed.
+//   - error: The first invalid value given to the builder, or the missing text or language.
+func (cb *ContentBuilder) Build() (LLMEmbeddingContent, error) {
+	if cb.err != nil {
+		return LLMEmbeddingContent{}, cb.err
+	}
+	if strings.TrimSpace(cb.content.Text) == "" && len(cb.content.Tables) == 0 {
+		return LLMEmbeddingContent{}, errors.New("content has no text")
+	}
+	if cb.content.Language == "" {
+		return LLMEmbeddingContent{}, errors.New("content has no language, see ContentBuilder.Language")
+	}
+	content := cb.content
+	if content.Id == "" {
+		content.Id = uuid.New().String()
+	}
+	content.Keywords = append([]string(nil), content.Keywords...)
+	content.Tables = append([]Table(nil), content.Tables...)
+	return content, nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// CrossLingualConfig searches with the query translated into the languages of the documents when the query
+// finds nothing, e.g. a Persian question over English documents.
+//
+// Multilingual embedding models match queries and documents of different languages without translation, the
+// translation is meant for embedding models trained on a single language.
+//
+// Fields:
+//   - Languages: The languages of the embedded documents, as set with WithLanguage, e.g. "en". They are tried in
+//     order until the translated query finds documents, query translation is disabled when empty.
+type CrossLingualConfig struct {
+	Languages []string
+}
+
+// WithCrossLingual enables or disables the query translation of a call, overriding the container default,
+// which is enabled when CrossLingual.Languages is set.
+//
+// Parameters:
+//   - enabled: True translates queries finding nothing into the document languages, false never does.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the query translation.
+func (llm *LLMContainer) WithCrossLingual(enabled bool) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.crossLingual = &enabled
+	}
+}
+
+// useCrossLingual reports whether a call without retrieved documents translates its query.
+func (llm *LLMContainer) useCrossLingual(o LLMCallOptions) bool {
+	if len(llm.CrossLingual.Languages) == 0 {
+		return false
+	}
+	return o.crossLingual == nil || *o.crossLingual
+}
+
+// searchTranslatedQuery translates a query into the document languages in order and searches the documents of
+// each language until some are found.
+//
+// Returns:
+//   - []schema.Document: The documents found, nil if no translation finds any.
+//   - Translation: The translation that found the documents, or the last one tried.
+//   - TokenUsage: Tokens used by the translations.
+//   - error: An error if a translation or a search fails.
+func (llm *LLMContainer) searchTranslatedQuery(ctx context.Context, searchAlgorithm SearchAlgorithm, query string, o LLMCallOptions) ([]schema.Document, Translation, TokenUsage, error) {
+	usage := TokenUsage{}
+	translation := Translation{}
+	for _, language := range llm.CrossLingual.Languages {
+		if language == o.Language {
+			// The query was already searched in its language
+			continue
+		}
+		var err error
+		translation, err = llm.translate(ctx, query, language, o)
+		usage.OutputTokens += translation.TokenUsage.OutputTokens
+		if err != nil {
+			return nil, translation, usage, err
+		}
+		languageOptions := o
+		languageOptions.Language = language
+		prefixes, boosts := llm.retrievalPrefixes(&languageOptions)
+		docs, err := llm.retrieveFromPrefixes(ctx, searchAlgorithm, prefixes, boosts, llm.callIndexBoosts(&o), translation.Text, o.rowCount(), o.indexCap)
+		if err != nil {
+			return nil, translation, usage, err
+		}
+		if len(docs) > 0 {
+			return docs, translation, usage, nil
+		}
+	}
+	return nil, translation, usage, nil
+}
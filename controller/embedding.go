@@ -24,7 +24,6 @@ import (
 	"github.com/tmc/langchaingo/llms/ollama"
 	"github.com/tmc/langchaingo/llms/openai"
 	"github.com/tmc/langchaingo/schema"
-	"github.com/tmc/langchaingo/vectorstores/redisvector"
 )
 
 // LLMTextEmbedding is a struct designed to handle text processing and splitting operations.
@@ -46,15 +45,13 @@ type LLMTextEmbedding struct {
 //
 // This interface provides methods to initialize and retrieve an embedding model,
 // ensuring a standard contract for different embedding providers such as Ollama and OpenAI.
+// It can be implemented outside the package, e.g. by aillmtest.FakeEmbedder to test without a model server.
 //
 // Methods:
 //   - NewEmbedder(): Initializes and returns an embedding model instance, or an error if the operation fails.
-//   - initialized(): Checks if the embedding model has been initialized and is ready for use.
 type EmbeddingClient interface {
 	// NewEmbedder initializes and returns an embedding model instance.
 	NewEmbedder() (embeddings.Embedder, error)
-	// initialized checks whether the embedding model has been successfully initialized.
-	initialized() bool
 }
 
 // InitEmbedding initializes the embedding model based on the type of embedding provider.
@@ -73,6 +70,7 @@ func (llm *LLMContainer) InitEmbedding() error {
 		ollamaLLM, err := ollama.New(
 			ollama.WithServerURL(llm.Embedder.(*OllamaController).Config.Apiurl),
 			ollama.WithModel(llm.Embedder.(*OllamaController).Config.AiModel),
+			ollama.WithHTTPClient(providerHTTPClient),
 		)
 		if err != nil {
 			return err
@@ -85,6 +83,7 @@ func (llm *LLMContainer) InitEmbedding() error {
 		openaiLLM, err := openai.New(
 			openai.WithToken(llm.Embedder.(*OpenAIController).Config.APIToken),
 			openai.WithModel(llm.Embedder.(*OpenAIController).Config.AiModel),
+			openai.WithHTTPClient(providerHTTPClient),
 		)
 		if err != nil {
 			return err
@@ -105,6 +104,7 @@ func (llm *LLMContainer) InitEmbedding() error {
 // It initializes the embedding model if not already initialized.
 //
 // Parameters:
+//   - ctx: The context of the embedding call, e.g. the span of EmbeddText.
 //   - prefix: A string used as a prefix for storing the embedded content, typically indicating object context.
 //   - contents: The text content to be embedded and stored in the vector store.
 // 	 - title: The title associated with the content to be embedded inline with contents for better retrival.
@@ -117,16 +117,14 @@ func (llm *LLMContainer) InitEmbedding() error {
 //   - []string: A slice of keys representing the stored embeddings in the vector database.
 //   - int: The number of chunks the text was split into.
 //   - error: An error if the embedding process fails.
-func (llm *LLMContainer) embedText(prefix, language, index, title, contents string, sources string, metaData LLMEmbeddingContent, GeneralEmbeddingDenied, rawKey, useLLM bool) (docList []string, generalDocList []string, docLen int, inconsistentChunks map[int]string, err error) {
+func (llm *LLMContainer) embedText(ctx context.Context, prefix, language, index, title, contents string, sources string, metaData LLMEmbeddingContent, GeneralEmbeddingDenied, rawKey, useLLM bool) (docList []string, generalDocList []string, docLen int, inconsistentChunks map[int]string, err error) {
 	// Check if the embedding model is available
 	if llm.Embedder == nil {
-		return nil, nil, docLen, inconsistentChunks, errors.New("missing embedding model")
+		return nil, nil, docLen, inconsistentChunks, ErrMissingEmbedder
 	} else {
 		// Initialize embedding model if it hasn't been initialized yet
 
-		if !llm.Embedder.initialized() {
-			llm.InitEmbedding()
-		}
+		llm.ensureEmbedder()
 	}
 	// Prepare the document text embedding configuration
 	textEmbedding := LLMTextEmbedding{
@@ -138,11 +136,14 @@ func (llm *LLMContainer) embedText(prefix, language, index, title, contents stri
 	// Split the text content into chunks
 	docs, splitErr := []schema.Document{}, error(nil)
 
-	if useLLM {
+	switch {
+	case strings.TrimSpace(contents) == "" && len(metaData.Tables) > 0:
+		// Documents made of tables only have no text chunks
+	case useLLM:
 		var keywords []string
 		docs, keywords, inconsistentChunks, splitErr = textEmbedding.SplitTextWithLLM()
 		metaData.Keywords = keywords
-	} else {
+	default:
 		docs, splitErr = textEmbedding.SplitText()
 	}
 	if splitErr != nil {
@@ -150,29 +151,54 @@ func (llm *LLMContainer) embedText(prefix, language, index, title, contents stri
 		return docList, generalDocList, docLen, inconsistentChunks, splitErr
 	}
 
+	// The chunks of a call share a data key when encryption is enabled
+	chunkSealer, err := llm.newSealer(ctx)
+	if err != nil {
+		return docList, generalDocList, docLen, inconsistentChunks, err
+	}
+	metaData.Text = ""
+	metaData.Keywords = append([]string(nil), metaData.Keywords...)
+	keywords := append([]string(nil), metaData.Keywords...)
+	// Each table is split into chunks of rows, their metadata refers to the table stored once in the raw document
+	tableDocs, tableOf := tableDocuments(metaData.Tables, llm.EmbeddingConfig.ChunkSize)
+	textChunks := len(docs)
+	docs = append(docs, tableDocs...)
+	rawDocKey := LLMEmbeddingObject{EmbeddingPrefix: prefix, Index: index}.getRawDocRedisId()
+	metaData.Tables = nil
+	if !rawKey {
+		// Chunks of "all:" indexes are boosted by the index they were embedded with
+		metaData.Index = index
+	}
+	if err = chunkSealer.sealContent(&metaData); err != nil {
+		return docList, generalDocList, docLen, inconsistentChunks, err
+	}
 	// Add metadata to each chunk by prepending the source
 	for idx, doc := range docs {
 		// doc.PageContent = "source: " + source + "\n" + doc.PageContent
 		doc.Metadata = make(map[string]any)
-		metaData.Text = ""
-		jsonMeta, _ := json.Marshal(metaData)
+		chunkMetaData := metaData
+		if idx >= textChunks {
+			chunkMetaData.TableRef = tableRef(rawDocKey, tableOf[idx-textChunks])
+		}
+		jsonMeta, _ := json.Marshal(chunkMetaData)
 		doc.Metadata["rawkey"] = string(jsonMeta)
 		doc.Metadata["sources"] = sources
+		if redisearchLanguage := lexicalLanguage(language); redisearchLanguage != "" {
+			// The lexical index stems the chunk in its language
+			doc.Metadata[lexicalLanguageField] = redisearchLanguage
+		}
 		if title != "" {
 			doc.PageContent = "Title: " + title + "\n" + doc.PageContent
 		}
-		if len(metaData.Keywords) > 0 {
-			doc.PageContent += "\nKeywords: " + strings.Join(metaData.Keywords, ", ")
+		if len(keywords) > 0 {
+			doc.PageContent += "\nKeywords: " + strings.Join(keywords, ", ")
+		}
+		if doc.PageContent, err = chunkSealer.seal(doc.PageContent); err != nil {
+			return docList, generalDocList, docLen, inconsistentChunks, err
 		}
 		docs[idx] = doc
 	}
 
-	// Get the embedding model from the initialized client
-	embedder, err := llm.Embedder.NewEmbedder()
-	if err != nil {
-		return docList, generalDocList, docLen, inconsistentChunks, splitErr
-	}
-
 	// Setup Redis vector store with index name and embedding model
 	keyName := prefix
 	if keyName != "" {
@@ -190,28 +216,18 @@ func (llm *LLMContainer) embedText(prefix, language, index, title, contents stri
 		}
 		keyName += ":aillm_vector_idx"
 	}
-	redisVector := redisvector.WithIndexName(keyName, true)
-	embedderVector := redisvector.WithEmbedder(embedder)
-
-	// Retrieve Redis host URL for connection
-	redisHostURL, redisConnectionErr := llm.getRedisHost()
-	if redisConnectionErr != nil {
-		return docList, generalDocList, docLen, inconsistentChunks, splitErr
-	}
-
-	// Create a new vector store using Redis and embedding model
-
-	store, err := redisvector.New(context.TODO(), redisvector.WithConnectionURL(redisHostURL), redisVector, embedderVector)
+	// Get the cached vector store using Redis and the embedding model
+	store, _, err := llm.getVectorStore(keyName, true)
 	if err != nil {
-		return docList, generalDocList, docLen, inconsistentChunks, splitErr
+		return docList, generalDocList, docLen, inconsistentChunks, redisIndexError(err)
 	}
 
 	// Store the document chunks into the Redis vector store
 	docLen = len(docs)
 	if docLen > 0 {
-		docList, err = store.AddDocuments(context.Background(), docs)
+		docList, err = store.AddDocuments(ctx, docs)
 		if err != nil {
-			return docList, generalDocList, docLen, inconsistentChunks, splitErr
+			return docList, generalDocList, docLen, inconsistentChunks, redisIndexError(err)
 		}
 		if !GeneralEmbeddingDenied && !rawKey {
 			allKey := "all:"
@@ -223,15 +239,14 @@ func (llm *LLMContainer) embedText(prefix, language, index, title, contents stri
 				allKey += language + ":"
 			}
 			allKey += "aillm_vector_idx"
-			generalRedisVector := redisvector.WithIndexName(allKey, true)
-			generalStore, err := redisvector.New(context.TODO(), redisvector.WithConnectionURL(redisHostURL), generalRedisVector, embedderVector)
+			generalStore, _, err := llm.getVectorStore(allKey, true)
 			if err != nil {
-				return docList, generalDocList, 0, inconsistentChunks, splitErr
+				return docList, generalDocList, 0, inconsistentChunks, redisIndexError(err)
 			}
 
-			generalDocList, err = generalStore.AddDocuments(context.Background(), docs)
+			generalDocList, err = generalStore.AddDocuments(ctx, docs)
 			if err != nil {
-				return docList, generalDocList, 0, inconsistentChunks, splitErr
+				return docList, generalDocList, 0, inconsistentChunks, redisIndexError(err)
 			}
 		}
 
@@ -242,26 +257,29 @@ func (llm *LLMContainer) embedText(prefix, language, index, title, contents stri
 
 // cleanEmbeddings cleans the embeddings from the Redis database.
 //
+// Keys are deleted in batches with SCAN and UNLINK, so purging a large prefix doesn't block Redis.
+//
 // Parameters:
 //   - Confirm: The confirmation string to clean the embeddings.
 //   - prefix: The prefix of the embeddings to clean.
-//   - index: The index of the embeddings to clean.
+//   - options: WithDeleteProgress reports the progress of large purges.
 //
 // Returns:
 //   - error: An error if the cleaning fails.
-func (llm *LLMContainer) CleanEmbeddings(Confirm, prefix string) error {
+func (llm *LLMContainer) CleanEmbeddings(Confirm, prefix string, options ...LLMCallOption) error {
 	if Confirm == "yes" {
-		_, err := llm.deleteRedisWildCard(llm.RedisClient.redisClient, "doc:all:"+prefix, true)
-		if err != nil {
-			return err
+		o := LLMCallOptions{}
+		for _, opt := range options {
+			opt(&o)
 		}
-		_, err = llm.deleteRedisWildCard(llm.RedisClient.redisClient, "doc:context:"+prefix, true)
-		if err != nil {
-			return err
-		}
-		_, err = llm.deleteRedisWildCard(llm.RedisClient.redisClient, "rawDocs:"+prefix, true)
-		if err != nil {
-			return err
+		for _, pattern := range []string{"doc:all:" + prefix, "doc:context:" + prefix, "rawDocs:" + prefix} {
+			var progress func(int)
+			if o.deleteProgress != nil {
+				progress = func(deleted int) { o.deleteProgress(pattern, deleted) }
+			}
+			if _, err := deleteRedisKeys(context.Background(), llm.RedisClient.redisClient, pattern, true, progress); err != nil {
+				return err
+			}
 		}
 
 		res, err := llm.RedisClient.redisClient.Do(context.TODO(), "FT._LIST").Result()
@@ -304,6 +322,7 @@ func (llm *LLMContainer) deleteIndexes(indexes []interface{}, prefix string) err
 			if err != nil {
 				return err
 			}
+			llm.forgetVectorStores(indexName)
 		}
 	}
 	return nil
@@ -0,0 +1,363 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// Record types of an embedding export, see EmbeddingRecord.
+const (
+	EmbeddingRecordObject = "object"
+	EmbeddingRecordChunk  = "chunk"
+)
+
+// vectorField is the field of the chunk vectors in the Redis vector store.
+const vectorField = "content_vector"
+
+// EmbeddingRecord is a line of an embedding export, see ExportEmbeddings.
+//
+// Fields:
+//   - Type: EmbeddingRecordObject for an embedding object, EmbeddingRecordChunk for a chunk.
+//   - Key: The Redis key of the object or the chunk.
+//   - Object: The embedding object with its content entries, as stored.
+//   - Fields: The fields of the chunk as stored, e.g. "content", "rawkey" and "sources".
+//   - Vector: The vector of the chunk, only exported on request.
+type EmbeddingRecord struct {
+	Type   string              `json:"type"`
+	Key    string              `json:"key"`
+	Object *LLMEmbeddingObject `json:"object,omitempty"`
+	Fields map[string]string   `json:"fields,omitempty"`
+	Vector []float32           `json:"vector,omitempty"`
+}
+
+// EmbeddingTransfer counts the records of an export or an import.
+type EmbeddingTransfer struct {
+	Objects int `json:"objects"`
+	Chunks  int `json:"chunks"`
+}
+
+// ExportEmbeddings writes the embedding objects of a prefix and their chunks as JSON lines, see EmbeddingRecord.
+//
+// Every object is followed by its chunks, including the general chunks. Objects and chunks are exported as stored,
+// so encrypted documents stay encrypted and need the same KeyProvider keys to be read after ImportEmbeddings.
+// Exports without vectors are smaller, the chunks are embedded again when they are imported.
+//
+// Parameters:
+//   - prefix: The embedding prefix or its alias, empty exports the objects stored without prefix.
+//   - w: The destination of the JSON lines.
+//   - includeVectors: Exports the vectors of the chunks.
+//
+// Returns:
+//   - EmbeddingTransfer: The number of exported objects and chunks.
+//   - error: An error if Redis can't be read or w can't be written.
+//
+// Example Usage:
+//
+//	file, _ := os.Create("faq.jsonl")
+//	defer file.Close()
+//	transfer, err := llm.ExportEmbeddings("faq", file, true)
+func (llm *LLMContainer) ExportEmbeddings(prefix string, w io.Writer, includeVectors bool) (EmbeddingTransfer, error) {
+	transfer := EmbeddingTransfer{}
+	rdb := llm.RedisClient.redisClient
+	if rdb == nil {
+		return transfer, ErrNoRedis
+	}
+	prefix = llm.prefixAliasRegistry().resolve(prefix)
+	ctx := context.Background()
+	keyPrefix := LLMEmbeddingObject{EmbeddingPrefix: prefix}.getRawDocRedisId()
+	keys := []string{}
+	iter := rdb.Scan(ctx, 0, keyPrefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return transfer, err
+	}
+	sort.Strings(keys)
+
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+	indexTypes := indexVectorTypes{}
+	for _, key := range keys {
+		var obj LLMEmbeddingObject
+		if err := obj.load(rdb, key); err != nil {
+			continue
+		}
+		// rawDocs:<prefix>:* also matches the objects of longer prefixes
+		if obj.EmbeddingPrefix != prefix {
+			continue
+		}
+		if err := encoder.Encode(EmbeddingRecord{Type: EmbeddingRecordObject, Key: key, Object: &obj}); err != nil {
+			return transfer, err
+		}
+		transfer.Objects++
+		ids := make([]string, 0, len(obj.Contents))
+		for id := range obj.Contents {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			content := obj.Contents[id]
+			for _, chunkKey := range append(append([]string{}, content.Keys...), content.GeneralKeys...) {
+				fields, err := rdb.HGetAll(ctx, chunkKey).Result()
+				if err != nil {
+					return transfer, err
+				}
+				// Chunks removed from Redis are skipped
+				if len(fields) == 0 {
+					continue
+				}
+				record := EmbeddingRecord{Type: EmbeddingRecordChunk, Key: chunkKey, Fields: fields}
+				if includeVectors {
+					vectorType, err := indexTypes.get(ctx, rdb, chunkKey, llm.EmbeddingConfig.VectorType)
+					if err != nil {
+						return transfer, err
+					}
+					record.Vector = decodeVector(fields[vectorField], vectorType)
+				}
+				delete(fields, vectorField)
+				if err := encoder.Encode(record); err != nil {
+					return transfer, err
+				}
+				transfer.Chunks++
+			}
+		}
+	}
+	return transfer, writer.Flush()
+}
+
+// indexVectorTypes caches the vector types of the indexes of exported chunks.
+type indexVectorTypes map[string]VectorType
+
+// get returns the vector type of the index of a chunk, read with FT.INFO, the configured type if the index doesn't
+// exist anymore.
+func (it indexVectorTypes) get(ctx context.Context, rdb *redis.Client, chunkKey string, configured VectorType) (VectorType, error) {
+	indexName, _, err := splitChunkKey(chunkKey)
+	if err != nil {
+		return "", err
+	}
+	if vectorType, ok := it[indexName]; ok {
+		return vectorType, nil
+	}
+	index, exists, err := readVectorIndex(ctx, rdb, indexName)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		index.vectorType = configured
+	}
+	it[indexName] = index.vectorType
+	return index.vectorType, nil
+}
+
+// ImportEmbeddings stores the embedding objects and chunks written by ExportEmbeddings, e.g. in another environment.
+//
+// Objects and chunks keep their keys, existing ones are replaced. The search indexes are created when missing.
+// Chunks exported with their vector are stored without calling the embedding model, the others are embedded
+// again with the Embedder of the container.
+//
+// Parameters:
+//   - r: The JSON lines of an export.
+//
+// Returns:
+//   - EmbeddingTransfer: The number of imported objects and chunks.
+//   - error: An error if a line is invalid or Redis can't be written.
+func (llm *LLMContainer) ImportEmbeddings(r io.Reader) (EmbeddingTransfer, error) {
+	transfer := EmbeddingTransfer{}
+	rdb := llm.RedisClient.redisClient
+	if rdb == nil {
+		return transfer, ErrNoRedis
+	}
+	importer := &embeddingImporter{llm: llm, stores: map[string]*importStore{}}
+	scanner := bufio.NewScanner(r)
+	// Chunks with vectors and embedding objects with many entries exceed the default line length
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record EmbeddingRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return transfer, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch record.Type {
+		case EmbeddingRecordObject:
+			if record.Object == nil {
+				return transfer, fmt.Errorf("line %d: object record without object", line)
+			}
+			if err := record.Object.save(rdb, record.Object.getRawDocRedisId()); err != nil {
+				return transfer, fmt.Errorf("line %d: %w", line, err)
+			}
+			transfer.Objects++
+		case EmbeddingRecordChunk:
+			if err := importer.add(record); err != nil {
+				return transfer, fmt.Errorf("line %d: %w", line, err)
+			}
+			transfer.Chunks++
+		default:
+			return transfer, fmt.Errorf("line %d: unknown record type %q", line, record.Type)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return transfer, err
+	}
+	return transfer, importer.flush()
+}
+
+// importBatchSize is the number of chunks of an index stored at once by ImportEmbeddings.
+const importBatchSize = 100
+
+// embeddingImporter stores the imported chunks in batches per vector index.
+type embeddingImporter struct {
+	llm    *LLMContainer
+	stores map[string]*importStore
+}
+
+// importStore is the vector store of an index and its pending chunks.
+type importStore struct {
+	store    vectorstores.VectorStore
+	embedder *presetEmbedder
+	docs     []schema.Document
+	keys     []string
+	vectors  [][]float32
+}
+
+// add queues a chunk, the batch of its index is stored when full.
+func (ei *embeddingImporter) add(record EmbeddingRecord) error {
+	index, id, err := splitChunkKey(record.Key)
+	if err != nil {
+		return err
+	}
+	pending, ok := ei.stores[index]
+	if !ok {
+		pending = &importStore{embedder: &presetEmbedder{llm: ei.llm}}
+		pending.store, err = ei.llm.newVectorStore(index, pending.embedder)
+		if err != nil {
+			return err
+		}
+		ei.stores[index] = pending
+	}
+	metadata := map[string]any{}
+	for field, value := range record.Fields {
+		if field != "content" && field != vectorField {
+			metadata[field] = value
+		}
+	}
+	// The vector store keeps the ID of the chunk key
+	metadata["keys"] = id
+	pending.docs = append(pending.docs, schema.Document{PageContent: record.Fields["content"], Metadata: metadata})
+	pending.keys = append(pending.keys, record.Key)
+	pending.vectors = append(pending.vectors, record.Vector)
+	if len(pending.docs) >= importBatchSize {
+		return ei.store(pending)
+	}
+	return nil
+}
+
+// flush stores the pending chunks of every index.
+func (ei *embeddingImporter) flush() error {
+	for _, pending := range ei.stores {
+		if err := ei.store(pending); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// store adds the pending chunks of an index to its vector store.
+func (ei *embeddingImporter) store(pending *importStore) error {
+	if len(pending.docs) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	pending.embedder.vectors = pending.vectors
+	if _, err := pending.store.AddDocuments(ctx, pending.docs); err != nil {
+		return err
+	}
+	// The ID is only needed to name the key
+	pipe := ei.llm.RedisClient.redisClient.Pipeline()
+	for _, key := range pending.keys {
+		pipe.HDel(ctx, key, "keys")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	pending.docs, pending.keys, pending.vectors = nil, nil, nil
+	return nil
+}
+
+// presetEmbedder returns the exported vectors of a batch of chunks, the chunks without vector are embedded with
+// the embedder of the container.
+type presetEmbedder struct {
+	llm     *LLMContainer
+	vectors [][]float32
+}
+
+func (pe *presetEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) != len(pe.vectors) {
+		return nil, errors.New("the imported chunks and vectors don't match")
+	}
+	vectors := make([][]float32, len(texts))
+	missing, missingTexts := []int{}, []string{}
+	for idx, vector := range pe.vectors {
+		if len(vector) > 0 {
+			vectors[idx] = vector
+			continue
+		}
+		missing = append(missing, idx)
+		missingTexts = append(missingTexts, texts[idx])
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+	embedder, err := pe.llm.getEmbedder()
+	if err != nil {
+		return nil, err
+	}
+	// Encrypted chunks are embedded from their plain text
+	embedded, err := decryptingEmbedder{Embedder: embedder, llm: pe.llm}.EmbedDocuments(ctx, missingTexts)
+	if err != nil {
+		return nil, err
+	}
+	for idx, position := range missing {
+		vectors[position] = embedded[idx]
+	}
+	return vectors, nil
+}
+
+func (pe *presetEmbedder) EmbedQuery(context.Context, string) ([]float32, error) {
+	return nil, errors.New("the import embedder doesn't embed queries")
+}
+
+// splitChunkKey splits the Redis key of a chunk, doc:<index>:<id>, into its vector index and its ID.
+func splitChunkKey(key string) (string, string, error) {
+	rest, found := strings.CutPrefix(key, "doc:")
+	separator := strings.LastIndex(rest, ":")
+	if !found || separator <= 0 || separator == len(rest)-1 {
+		return "", "", fmt.Errorf("invalid chunk key %q", key)
+	}
+	return rest[:separator], rest[separator+1:], nil
+}
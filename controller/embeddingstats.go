@@ -0,0 +1,143 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LanguageStats counts the documents and chunks of a language, see EmbeddingStats.
+type LanguageStats struct {
+	Documents int `json:"Documents"`
+	Chunks    int `json:"Chunks"`
+}
+
+// EmbeddingStats describes the embeddings of a prefix for capacity planning and dashboards.
+//
+// Fields:
+//   - EmbeddingPrefix: The prefix, the one its alias points to for an alias.
+//   - ObjectCount: The number of embedding objects (indexes) of the prefix.
+//   - DocumentCount: The number of content entries.
+//   - ChunkCount: The number of chunks, without the copies in the general indexes.
+//   - GeneralChunkCount: The number of chunk copies in the general indexes, see SearchAll.
+//   - MemoryBytes: The approximate memory used by the objects and the chunks, as reported by Redis MEMORY USAGE.
+//     The search indexes are not included.
+//   - Languages: The documents and chunks per content language, "" for documents without language.
+//   - IndexNames: The vector search indexes holding the chunks, sorted.
+type EmbeddingStats struct {
+	EmbeddingPrefix   string                   `json:"EmbeddingPrefix"`
+	ObjectCount       int                      `json:"ObjectCount"`
+	DocumentCount     int                      `json:"DocumentCount"`
+	ChunkCount        int                      `json:"ChunkCount"`
+	GeneralChunkCount int                      `json:"GeneralChunkCount"`
+	MemoryBytes       int64                    `json:"MemoryBytes"`
+	Languages         map[string]LanguageStats `json:"Languages"`
+	IndexNames        []string                 `json:"IndexNames"`
+}
+
+// EmbeddingStats counts the embedding objects, documents and chunks of a prefix and estimates their memory usage.
+//
+// Only objects stored with exactly this prefix are counted, as in ListEmbeddingsByPrefix. The stats don't need the
+// KeyProvider of encrypted documents.
+//
+// Parameters:
+//   - prefix: The embedding prefix or its alias, empty counts the objects stored without prefix.
+//
+// Returns:
+//   - EmbeddingStats: The stats of the prefix.
+//   - error: An error if Redis can't be read.
+//
+// Example Usage:
+//
+//	stats, err := llm.EmbeddingStats("faq")
+//	fmt.Printf("%d documents, %d chunks, %d MB\n", stats.DocumentCount, stats.ChunkCount, stats.MemoryBytes>>20)
+func (llm *LLMContainer) EmbeddingStats(prefix string) (EmbeddingStats, error) {
+	prefix = llm.prefixAliasRegistry().resolve(prefix)
+	stats := EmbeddingStats{EmbeddingPrefix: prefix, Languages: map[string]LanguageStats{}, IndexNames: []string{}}
+	rdb := llm.RedisClient.redisClient
+	if rdb == nil {
+		return stats, ErrNoRedis
+	}
+	ctx := context.Background()
+	keyPrefix := LLMEmbeddingObject{EmbeddingPrefix: prefix}.getRawDocRedisId()
+	keys := []string{}
+	iter := rdb.Scan(ctx, 0, keyPrefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return stats, err
+	}
+
+	indexNames := map[string]bool{}
+	for _, key := range keys {
+		var obj LLMEmbeddingObject
+		if err := obj.load(rdb, key); err != nil {
+			continue
+		}
+		// rawDocs:<prefix>:* also matches the objects of longer prefixes
+		if obj.EmbeddingPrefix != prefix {
+			continue
+		}
+		stats.ObjectCount++
+		memoryKeys := []string{key}
+		for _, content := range obj.Contents {
+			stats.DocumentCount++
+			stats.ChunkCount += len(content.Keys)
+			stats.GeneralChunkCount += len(content.GeneralKeys)
+			language := stats.Languages[content.Language]
+			language.Documents++
+			language.Chunks += len(content.Keys)
+			stats.Languages[content.Language] = language
+			for _, chunkKey := range append(append([]string{}, content.Keys...), content.GeneralKeys...) {
+				if index, _, err := splitChunkKey(chunkKey); err == nil {
+					indexNames[index] = true
+				}
+				memoryKeys = append(memoryKeys, chunkKey)
+			}
+		}
+		memory, err := memoryUsage(ctx, rdb, memoryKeys)
+		if err != nil {
+			return stats, err
+		}
+		stats.MemoryBytes += memory
+	}
+	for index := range indexNames {
+		stats.IndexNames = append(stats.IndexNames, index)
+	}
+	sort.Strings(stats.IndexNames)
+	return stats, nil
+}
+
+// memoryUsage sums the memory used by Redis keys, missing keys are skipped.
+func memoryUsage(ctx context.Context, rdb *redis.Client, keys []string) (int64, error) {
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for idx, key := range keys {
+		cmds[idx] = pipe.MemoryUsage(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, err
+	}
+	var total int64
+	for _, cmd := range cmds {
+		if cmd.Err() == nil {
+			total += cmd.Val()
+		}
+	}
+	return total, nil
+}
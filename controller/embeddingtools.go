@@ -35,6 +35,11 @@ import (
 //   - []schema.Document: A slice containing the split document chunks.
 //   - error: An error if the text splitting process encounters any issues.
 func (emb *LLMTextEmbedding) SplitText() ([]schema.Document, error) {
+	// Texts written without spaces, e.g. Chinese and Japanese, are split at sentence ends
+	if spacelessScripts[dominantScript(emb.Text)] {
+		emb.EmbeddedDocuments = splitSpacelessText(emb.Text, emb.ChunkSize, emb.ChunkOverlap)
+		return emb.EmbeddedDocuments, nil
+	}
 	// Create a new text loader with the provided input text
 	p := documentloaders.NewText(strings.NewReader(emb.Text))
 	// Initialize a recursive character-based text splitter
@@ -42,6 +47,7 @@ func (emb *LLMTextEmbedding) SplitText() ([]schema.Document, error) {
 	split := textsplitter.NewRecursiveCharacter()
 	split.ChunkSize = emb.ChunkSize       // Define the maximum size of each chunk
 	split.ChunkOverlap = emb.ChunkOverlap // Define the overlap between chunks
+	split.LenFunc = chunkLength           // CJK characters count more than letters
 	// Split the text using the specified chunking parameters
 	docs, err := p.LoadAndSplit(context.Background(), split)
 	// Store the resulting chunks in the EmbeddedDocuments field
@@ -75,36 +81,19 @@ func splitTextIntoFixedSizedChunks(rawText string, chunkSize int) []string {
 	var chunks []string
 
 	// check if the text is smaller than the chunk size
-	if len(rawText) <= chunkSize {
+	if chunkLength(rawText) <= chunkSize {
 		return []string{rawText}
 	}
 
-	start := 0
-	for start < len(rawText) {
-		// set the end of the chunk, if it is greater than the text length, set it to the end of the text
-		end := start + chunkSize
-		if end > len(rawText) {
-			end = len(rawText)
-		}
-
-		// find the nearest point to the end of the chunk
-		chunk := rawText[start:end]
-
-		// check if the chunk is not finished with a "."
-		if end < len(rawText) && rawText[end] != ' ' {
-			// find the last space to prevent the word from being split
-			lastSpace := strings.LastIndex(chunk, " ")
-			if lastSpace != -1 {
-				end = start + lastSpace
-				chunk = rawText[start:end]
-			}
-		}
+	for rawText != "" {
+		// cut at the last sentence end, comma or space to prevent words from being split, CJK text has no spaces
+		end := breakIndex(rawText, chunkSize)
 
 		// add the chunk to the list
-		chunks = append(chunks, strings.TrimSpace(chunk))
+		chunks = append(chunks, strings.TrimSpace(rawText[:end]))
 
 		// set the start of the next chunk
-		start = end
+		rawText = rawText[end:]
 	}
 
 	return chunks
@@ -119,7 +108,7 @@ func (emb *LLMTextEmbedding) SplitTextWithLLM() (docs []schema.Document, keyword
 	for _, chunk := range chunks {
 		// Use the new prompt with both chunking and keyword extraction
 		prompt := fmt.Sprintf(splitPrompt, emb.ChunkSize, emb.ChunkSize, chunk)
-		resp, err := emb.lLMContainer.AskLLM("", emb.lLMContainer.WithExactPrompt(prompt), emb.lLMContainer.WithAllowHallucinate(true))
+		resp, err := emb.lLMContainer.AskLLM("", emb.lLMContainer.WithExactPrompt(prompt), emb.lLMContainer.WithAllowHallucinate(true), withInternalCall())
 		if err != nil {
 			return nil, keywords, inconsistentChunks, err
 		}
@@ -181,40 +170,61 @@ func (llm LLMEmbeddingObject) sanitizeRedisKey(input string) string {
 	return sanitized
 }
 
-// deleteRedisWildCard deletes keys matching a given wildcard pattern in Redis.
+// deleteBatchSize is the number of keys unlinked at once by deleteRedisKeys.
+const deleteBatchSize = 500
+
+// deleteRedisKeys deletes a key, or the keys below it with addWildCard. Keys are found with SCAN and unlinked in
+// batches, so large purges don't block Redis as KEYS and DEL would.
 //
 // Parameters:
+//   - ctx: The context of the deletion.
 //   - redisClient: The Redis client instance.
-//   - k: The key pattern to search and delete.
+//   - k: The key, sanitized as stored.
+//   - addWildCard: Deletes the keys matching k:* instead of k.
+//   - progress: Called with the number of keys deleted so far after each batch, may be nil.
 //
 // Returns:
 //   - int: The number of keys deleted.
-//   - error: An error if the deletion fails.
-func (llm *LLMContainer) deleteRedisWildCard(redisClient *redis.Client, k string, addWildCard bool) (int, error) {
-	var ctx = context.Background()
+//   - error: An error if the deletion fails, the keys of the previous batches are deleted.
+func deleteRedisKeys(ctx context.Context, redisClient *redis.Client, k string, addWildCard bool, progress func(deleted int)) (int, error) {
 	// Replace spaces with underscores for key pattern matching
 	// k = strings.ReplaceAll(k, " ", "____")
 	re := regexp.MustCompile(`[^a-zA-Z0-9:_-]`)
 	k = re.ReplaceAllString(k, "_")
 	k = strings.ReplaceAll(k, "__", "_")
 
-	if addWildCard {
-		k = k + ":*"
+	if !addWildCard {
+		deleted, err := redisClient.Unlink(ctx, k).Result()
+		return int(deleted), err
 	}
-	// Retrieve matching keys
-	keys, err := redisClient.Keys(ctx, k).Result()
-	if err != nil {
-		return 0, err
+	deleted := 0
+	batch := make([]string, 0, deleteBatchSize)
+	unlink := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		count, err := redisClient.Unlink(ctx, batch...).Result()
+		if err != nil {
+			return err
+		}
+		deleted += int(count)
+		batch = batch[:0]
+		if progress != nil {
+			progress(deleted)
+		}
+		return nil
 	}
-	// Delete the matching keys
-	keyCount := len(keys)
-	if len(keys) > 0 {
-		_, delErr := redisClient.Del(ctx, keys...).Result()
-		if delErr != nil {
-			return 0, delErr
+	iter := redisClient.Scan(ctx, 0, k+":*", deleteBatchSize).Iterator()
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == deleteBatchSize {
+			if err := unlink(); err != nil {
+				return deleted, err
+			}
 		}
-
 	}
-	// CacheObject.Delete(k)
-	return keyCount, nil
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, unlink()
 }
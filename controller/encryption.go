@@ -0,0 +1,372 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"container/list"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// encryptedPrefix starts the stored values encrypted by the container.
+const encryptedPrefix = "aillm:enc:v1:"
+
+// KeyProvider protects the data keys used to encrypt stored documents, e.g. with a KMS or an HSM.
+//
+// Every write encrypts its documents with a new random data key, only the data key wrapped by the
+// provider is stored next to them (envelope encryption).
+type KeyProvider interface {
+	// WrapKey encrypts a data key and returns it with the ID of the key encrypting it.
+	WrapKey(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+	// UnwrapKey decrypts a data key wrapped by the key keyID.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// StaticKeyProvider wraps data keys with AES-256-GCM master keys held in memory, e.g. read from a secret store.
+//
+// Fields:
+//   - KeyID: The ID of the key wrapping new data keys.
+//   - Keys: The master keys by ID, 32 bytes each. Old keys are kept to read documents written before a rotation.
+type StaticKeyProvider struct {
+	KeyID string
+	Keys  map[string][]byte
+}
+
+// NewStaticKeyProvider creates a StaticKeyProvider with a single master key.
+//
+// Parameters:
+//   - keyID: The ID stored with the encrypted documents.
+//   - key: The 32 byte master key.
+//
+// Example Usage:
+//
+//	key, _ := base64.StdEncoding.DecodeString(os.Getenv("AILLM_MASTER_KEY"))
+//	llm.KeyProvider = aillm.NewStaticKeyProvider("master-2025", key)
+func NewStaticKeyProvider(keyID string, key []byte) *StaticKeyProvider {
+	return &StaticKeyProvider{KeyID: keyID, Keys: map[string][]byte{keyID: key}}
+}
+
+// WrapKey encrypts a data key with the current master key.
+func (skp *StaticKeyProvider) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	key, ok := skp.Keys[skp.KeyID]
+	if !ok {
+		return "", nil, fmt.Errorf("master key %s not found", skp.KeyID)
+	}
+	wrapped, err := sealAESGCM(key, dataKey)
+	return skp.KeyID, wrapped, err
+}
+
+// UnwrapKey decrypts a data key with the master key keyID.
+func (skp *StaticKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := skp.Keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("master key %s not found", keyID)
+	}
+	return openAESGCM(key, wrapped)
+}
+
+// sealAESGCM encrypts data with AES-GCM, the random nonce is prepended to the ciphertext.
+func sealAESGCM(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// openAESGCM decrypts data encrypted by sealAESGCM.
+func openAESGCM(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+// envelope is the stored form of an encrypted value.
+type envelope struct {
+	KeyID   string `json:"k"`
+	Wrapped []byte `json:"w"`
+	Data    []byte `json:"d"`
+}
+
+// checkLexicalSearch rejects lexical and hybrid searches of encrypted chunks, the text index only holds ciphertext
+// and would silently match nothing.
+func (llm *LLMContainer) checkLexicalSearch() error {
+	if llm.KeyProvider == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: lexical and hybrid search can't match chunks encrypted by the KeyProvider, use a vector search", ErrInvalidOptions)
+}
+
+// maxCachedDataKeys is the number of unwrapped data keys kept in memory, every embedded document has its own key.
+const maxCachedDataKeys = 1024
+
+// dataKeyCache keeps the recently used unwrapped data keys, so reading documents doesn't call the provider every
+// time. The least recently used key is evicted above maxCachedDataKeys.
+type dataKeyCache struct {
+	mu    sync.Mutex
+	keys  map[string]*list.Element
+	order list.List
+}
+
+// dataKeyEntry is a cached data key, the elements of dataKeyCache.order.
+type dataKeyEntry struct {
+	cacheKey string
+	dataKey  []byte
+}
+
+// get returns a cached data key and marks it as recently used.
+func (c *dataKeyCache) get(cacheKey string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.keys[cacheKey]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*dataKeyEntry).dataKey, true
+}
+
+// put caches a data key, evicting the least recently used key when the cache is full.
+func (c *dataKeyCache) put(cacheKey string, dataKey []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.keys == nil {
+		c.keys = make(map[string]*list.Element)
+	}
+	if element, ok := c.keys[cacheKey]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.keys[cacheKey] = c.order.PushFront(&dataKeyEntry{cacheKey: cacheKey, dataKey: dataKey})
+	if c.order.Len() > maxCachedDataKeys {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.keys, oldest.Value.(*dataKeyEntry).cacheKey)
+	}
+}
+
+func (llm *LLMContainer) dataKeyCache() *dataKeyCache {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.dataKeys == nil {
+		llm.dataKeys = &dataKeyCache{}
+	}
+	return llm.dataKeys
+}
+
+// sealer encrypts the values of a write with a single data key.
+type sealer struct {
+	keyID   string
+	wrapped []byte
+	key     []byte
+}
+
+// newSealer creates a data key and wraps it with the key provider, it returns nil if encryption is disabled.
+func (llm *LLMContainer) newSealer(ctx context.Context) (*sealer, error) {
+	if llm.KeyProvider == nil {
+		return nil, nil
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	keyID, wrapped, err := llm.KeyProvider.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("wrapping data key failed: %w", err)
+	}
+	return &sealer{keyID: keyID, wrapped: wrapped, key: dataKey}, nil
+}
+
+// seal encrypts a value, empty and already encrypted values are returned unchanged.
+func (s *sealer) seal(text string) (string, error) {
+	if s == nil || text == "" || strings.HasPrefix(text, encryptedPrefix) {
+		return text, nil
+	}
+	data, err := sealAESGCM(s.key, []byte(text))
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(envelope{KeyID: s.keyID, Wrapped: s.wrapped, Data: data})
+	if err != nil {
+		return "", err
+	}
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(encoded), nil
+}
+
+// decryptText decrypts a stored value, values that aren't encrypted are returned unchanged.
+func (llm *LLMContainer) decryptText(text string) (string, error) {
+	if !strings.HasPrefix(text, encryptedPrefix) {
+		return text, nil
+	}
+	if llm.KeyProvider == nil {
+		return "", errors.New("document is encrypted but no key provider is set")
+	}
+	encoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(text, encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+	value := envelope{}
+	if err := json.Unmarshal(encoded, &value); err != nil {
+		return "", err
+	}
+	cache := llm.dataKeyCache()
+	cacheKey := value.KeyID + ":" + base64.StdEncoding.EncodeToString(value.Wrapped)
+	dataKey, ok := cache.get(cacheKey)
+	if !ok {
+		dataKey, err = llm.KeyProvider.UnwrapKey(context.Background(), value.KeyID, value.Wrapped)
+		if err != nil {
+			return "", fmt.Errorf("unwrapping data key failed: %w", err)
+		}
+		cache.put(cacheKey, dataKey)
+	}
+	plain, err := openAESGCM(dataKey, value.Data)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// sealContent encrypts the text, title, keywords and tables of a content entry.
+func (s *sealer) sealContent(content *LLMEmbeddingContent) error {
+	var err error
+	if content.Text, err = s.seal(content.Text); err != nil {
+		return err
+	}
+	if content.Title, err = s.seal(content.Title); err != nil {
+		return err
+	}
+	for idx, keyword := range content.Keywords {
+		if content.Keywords[idx], err = s.seal(keyword); err != nil {
+			return err
+		}
+	}
+	content.Tables, err = s.sealTables(content.Tables)
+	return err
+}
+
+// decryptContent decrypts the text, title, keywords and tables of a content entry.
+func (llm *LLMContainer) decryptContent(content *LLMEmbeddingContent) error {
+	var err error
+	if content.Text, err = llm.decryptText(content.Text); err != nil {
+		return err
+	}
+	if content.Title, err = llm.decryptText(content.Title); err != nil {
+		return err
+	}
+	for idx, keyword := range content.Keywords {
+		if content.Keywords[idx], err = llm.decryptText(keyword); err != nil {
+			return err
+		}
+	}
+	return llm.decryptTables(content.Tables)
+}
+
+// decryptObject decrypts all content entries of an embedding object.
+func (llm *LLMContainer) decryptObject(obj *LLMEmbeddingObject) error {
+	for id, content := range obj.Contents {
+		if err := llm.decryptContent(&content); err != nil {
+			return err
+		}
+		obj.Contents[id] = content
+	}
+	return nil
+}
+
+// sealObject encrypts the content entries of an embedding object before it is saved, it returns a copy
+// so the caller keeps the plain text.
+func (llm *LLMContainer) sealObject(obj LLMEmbeddingObject) (LLMEmbeddingObject, error) {
+	s, err := llm.newSealer(context.Background())
+	if err != nil || s == nil {
+		return obj, err
+	}
+	contents := make(map[string]LLMEmbeddingContent, len(obj.Contents))
+	for id, content := range obj.Contents {
+		content.Keywords = append([]string(nil), content.Keywords...)
+		if err := s.sealContent(&content); err != nil {
+			return obj, err
+		}
+		contents[id] = content
+	}
+	obj.Contents = contents
+	return obj, nil
+}
+
+// decryptDocuments decrypts the page content and the rawkey of retrieved chunks.
+func (llm *LLMContainer) decryptDocuments(docs []schema.Document) ([]schema.Document, error) {
+	for idx, doc := range docs {
+		content, err := llm.decryptText(doc.PageContent)
+		if err != nil {
+			return docs, err
+		}
+		docs[idx].PageContent = content
+		if rawKey, ok := doc.Metadata["rawkey"].(string); ok {
+			rawKeyObject := LLMEmbeddingContent{}
+			if json.Unmarshal([]byte(rawKey), &rawKeyObject) == nil && strings.Contains(rawKey, encryptedPrefix) {
+				if err := llm.decryptContent(&rawKeyObject); err != nil {
+					return docs, err
+				}
+				decrypted, _ := json.Marshal(rawKeyObject)
+				docs[idx].Metadata["rawkey"] = string(decrypted)
+			}
+		}
+	}
+	return docs, nil
+}
+
+// decryptingEmbedder embeds the plain text of encrypted chunks, so vectors stay searchable while the
+// stored content is encrypted.
+type decryptingEmbedder struct {
+	embeddings.Embedder
+	llm *LLMContainer
+}
+
+// EmbedDocuments decrypts the texts and embeds them.
+func (de decryptingEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	plain := make([]string, len(texts))
+	for idx, text := range texts {
+		var err error
+		if plain[idx], err = de.llm.decryptText(text); err != nil {
+			return nil, err
+		}
+	}
+	return de.Embedder.EmbedDocuments(ctx, plain)
+}
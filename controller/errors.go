@@ -0,0 +1,58 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors returned by the container, match them with errors.Is. The underlying Redis and provider errors stay
+// available with errors.As.
+var (
+	// ErrNoRedis is returned when the Redis host is not configured or unreachable.
+	ErrNoRedis = errors.New("redis is not available")
+	// ErrMissingEmbedder is returned by embedding and retrieval calls on a container without Embedder.
+	ErrMissingEmbedder = errors.New("missing embedding model")
+	// ErrIndexNotFound is returned when a Redis search index doesn't exist, e.g. nothing was embedded with the prefix.
+	ErrIndexNotFound = errors.New("index not found")
+	// ErrEmbeddingNotFound is returned when an index has no stored embedding object, e.g. GetEmbedding of an index
+	// that was never embedded or was deleted.
+	ErrEmbeddingNotFound = errors.New("key not found")
+	// ErrNoRagResults is returned by AskLLM when retrieval finds nothing and hallucination is disallowed, either
+	// because the searched index doesn't exist, then the error wraps ErrIndexNotFound too, or because
+	// NoRagErrorMessage was emptied after Init. Otherwise the call answers with NotRelatedAnswer.
+	ErrNoRagResults = errors.New("rag query has no results")
+)
+
+// isIndexNotFound reports whether a Redis error is about a missing search index.
+func isIndexNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrIndexNotFound) {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "no such index") || strings.Contains(message, "unknown index")
+}
+
+// redisIndexError wraps the Redis errors about missing search indexes with ErrIndexNotFound.
+func redisIndexError(err error) error {
+	if isIndexNotFound(err) && !errors.Is(err, ErrIndexNotFound) {
+		return fmt.Errorf("%w: %w", ErrIndexNotFound, err)
+	}
+	return err
+}
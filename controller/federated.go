@@ -0,0 +1,264 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// WithIndexes searches several indexes in a single call, e.g. "Company" and "Agriculture".
+//
+// The results of the indexes are merged by score, see WithIndexCap to limit the share of each index.
+//
+// Parameters:
+//   - indexes: The indexes to search, they replace the index set with WithEmbeddingIndex.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the searched indexes.
+func (llm *LLMContainer) WithIndexes(indexes []string) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.indexes = indexes
+	}
+}
+
+// WithPrefixes searches the indexes of several embedding prefixes in a single call.
+//
+// Combined with WithIndexes every index is searched in every prefix.
+//
+// Parameters:
+//   - prefixes: The embedding prefixes to search, they replace the prefix set with WithEmbeddingPrefix.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the searched prefixes.
+func (llm *LLMContainer) WithPrefixes(prefixes []string) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.prefixes = prefixes
+	}
+}
+
+// WithIndexCap limits the number of documents each searched index contributes to the merged results of
+// WithIndexes and WithPrefixes, so a large index can't crowd out the others.
+//
+// Parameters:
+//   - limit: The maximum number of documents of an index, 0 means no limit.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the cap.
+func (llm *LLMContainer) WithIndexCap(limit int) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.indexCap = limit
+	}
+}
+
+// WithIndexBoosts multiplies the scores of the documents of indexes or embedding prefixes before the results of
+// WithIndexes and WithPrefixes are merged, e.g. to rank a curated FAQ above crawled web pages.
+//
+// The boosts replace the container IndexBoosts for the call.
+//
+// Parameters:
+//   - boosts: The multipliers by index or embedding prefix, a search of both is boosted by their product.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the boosts.
+//
+// Example Usage:
+//
+//	result, err := llm.AskLLM("How do I reset my password?",
+//		llm.WithIndexes([]string{"FAQ", "Crawled"}),
+//		llm.WithIndexBoosts(map[string]float64{"FAQ": 1.5, "Crawled": 0.8}),
+//	)
+func (llm *LLMContainer) WithIndexBoosts(boosts map[string]float64) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.indexBoosts = boosts
+	}
+}
+
+// callIndexBoosts returns the boosts of a call by index or embedding prefix, WithIndexBoosts or else IndexBoosts.
+func (llm *LLMContainer) callIndexBoosts(o *LLMCallOptions) map[string]float64 {
+	if o.indexBoosts != nil {
+		return o.indexBoosts
+	}
+	return llm.IndexBoosts
+}
+
+// indexBoost returns the boost of an embedding prefix and index, 1 when neither is boosted.
+func (llm *LLMContainer) indexBoost(o *LLMCallOptions, prefix, index string) float64 {
+	boosts := llm.callIndexBoosts(o)
+	boost := 1.0
+	for _, name := range []string{prefix, index} {
+		if multiplier, ok := boosts[name]; ok && name != "" {
+			boost *= multiplier
+		}
+	}
+	return boost
+}
+
+// retrievalPrefixes returns the key prefixes searched by a call, one per index and embedding prefix.
+//
+// Returns:
+//   - []string: The searched prefixes.
+//   - map[string]float64: The boosts of the searched prefixes, prefixes without boost are omitted.
+func (llm *LLMContainer) retrievalPrefixes(o *LLMCallOptions) ([]string, map[string]float64) {
+	if len(o.indexes) == 0 && len(o.prefixes) == 0 {
+		retrievalPrefix := llm.retrievalPrefix(o)
+		boosts := map[string]float64{}
+		if boost := llm.indexBoost(o, o.Prefix, o.Index); boost != 1 {
+			boosts[retrievalPrefix] = boost
+		}
+		return []string{retrievalPrefix}, boosts
+	}
+	prefixes := o.prefixes
+	if len(prefixes) == 0 {
+		prefixes = []string{o.Prefix}
+	}
+	indexes := o.indexes
+	if len(indexes) == 0 {
+		indexes = []string{o.Index}
+	}
+	searched := []string{}
+	boosts := map[string]float64{}
+	seen := map[string]bool{}
+	for _, prefix := range prefixes {
+		for _, index := range indexes {
+			call := *o
+			call.Prefix, call.Index = prefix, index
+			call.searchAll = index == ""
+			retrievalPrefix := llm.retrievalPrefix(&call)
+			// The fallback language chosen for the indexes applies to the whole call
+			o.Language = call.Language
+			if !seen[retrievalPrefix] {
+				seen[retrievalPrefix] = true
+				searched = append(searched, retrievalPrefix)
+				if boost := llm.indexBoost(o, prefix, index); boost != 1 {
+					boosts[retrievalPrefix] = boost
+				}
+			}
+		}
+	}
+	return searched, boosts
+}
+
+// rankingScore returns a score of a retrieved document where higher is better.
+//
+// Vector searches report the cosine distance, hybrid and lexical searches a relevance score.
+func rankingScore(doc schema.Document) float64 {
+	if _, ok := doc.Metadata["search_type"]; ok {
+		return float64(doc.Score)
+	}
+	return 1 - float64(doc.Score)
+}
+
+// chunkIndex returns the index a retrieved chunk was embedded with, empty for chunks embedded by older versions.
+func chunkIndex(doc schema.Document) string {
+	rawKey, _ := doc.Metadata["rawkey"].(string)
+	content := LLMEmbeddingContent{}
+	if !strings.Contains(rawKey, `"Index"`) || json.Unmarshal([]byte(rawKey), &content) != nil {
+		return ""
+	}
+	return content.Index
+}
+
+// retrieveFromPrefixes searches several prefixes concurrently and merges their documents by score.
+//
+// The documents are tagged with their "retrieval_prefix" and ranked by their score multiplied by the boost of their
+// prefix. The chunks of "all:" prefixes, which hold every index, are boosted by indexBoosts of the index they were
+// embedded with too. Boosted documents also carry a "boost". Duplicates are removed and each prefix contributes at
+// most indexCap documents. rowCount, when set, replaces the chunk count of the prefixes. A prefix whose search fails
+// is skipped unless every search fails. A single prefix without boosts is searched as is.
+func (llm *LLMContainer) retrieveFromPrefixes(ctx context.Context, searchAlgorithm SearchAlgorithm, prefixes []string, boosts, indexBoosts map[string]float64, query string, rowCount, indexCap int) ([]schema.Document, error) {
+	if len(prefixes) == 1 && len(boosts) == 0 && (len(indexBoosts) == 0 || !strings.HasPrefix(prefixes[0], "all:")) {
+		return llm.retrieveDocuments(ctx, searchAlgorithm, prefixes[0], query, rowCount)
+	}
+	results := make([][]schema.Document, len(prefixes))
+	errs := make([]error, len(prefixes))
+	var wait sync.WaitGroup
+	for i, prefix := range prefixes {
+		wait.Add(1)
+		go func(i int, prefix string) {
+			defer wait.Done()
+			results[i], errs[i] = llm.retrieveDocuments(ctx, searchAlgorithm, prefix, query, rowCount)
+		}(i, prefix)
+	}
+	wait.Wait()
+
+	type candidate struct {
+		doc    schema.Document
+		prefix string
+		score  float64
+	}
+	candidates := []candidate{}
+	limit, failed := 0, 0
+	for i, prefix := range prefixes {
+		if errs[i] != nil {
+			failed++
+			continue
+		}
+		if rowCount > 0 {
+			limit = rowCount
+		} else {
+			limit = max(limit, llm.RetrievalSettings(prefix).RagRowCount)
+		}
+		for _, doc := range results[i] {
+			if doc.Metadata == nil {
+				doc.Metadata = map[string]interface{}{}
+			}
+			doc.Metadata["retrieval_prefix"] = prefix
+			score := rankingScore(doc)
+			boost, ok := boosts[prefix]
+			if !ok {
+				boost = 1
+			}
+			if index := chunkIndex(doc); index != "" && strings.HasPrefix(prefix, "all:") {
+				if multiplier, ok := indexBoosts[index]; ok {
+					boost *= multiplier
+				}
+			}
+			if boost != 1 {
+				doc.Metadata["boost"] = boost
+				score *= boost
+			}
+			candidates = append(candidates, candidate{doc: doc, prefix: prefix, score: score})
+		}
+	}
+	if failed == len(prefixes) {
+		return nil, errors.Join(errs...)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	docs := []schema.Document{}
+	seen := map[string]bool{}
+	perPrefix := map[string]int{}
+	for _, candidate := range candidates {
+		if len(docs) == limit {
+			break
+		}
+		id := llm.getDocumentID(candidate.doc)
+		if seen[id] || (indexCap > 0 && perPrefix[candidate.prefix] >= indexCap) {
+			continue
+		}
+		seen[id] = true
+		perPrefix[candidate.prefix]++
+		docs = append(docs, candidate.doc)
+	}
+	return docs, nil
+}
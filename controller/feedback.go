@@ -0,0 +1,307 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	FeedbackPositive = 1  // Rating of a thumbs-up
+	FeedbackNegative = -1 // Rating of a thumbs-down
+)
+
+// Feedback is the rating of an answer by a user.
+//
+// Fields:
+//   - TraceID: The trace of the rated answer.
+//   - Rating: Positive ratings are above 0 and negative ratings below, e.g. FeedbackPositive or FeedbackNegative.
+//   - Comment: Optional free text of the user.
+//   - TimeStamp: The time the feedback was submitted.
+type Feedback struct {
+	TraceID   string    `json:"traceId"`
+	Rating    int       `json:"rating"`
+	Comment   string    `json:"comment,omitempty"`
+	TimeStamp time.Time `json:"timestamp"`
+}
+
+// FeedbackStats aggregates a group of feedback.
+//
+// Fields:
+//   - Count: The number of ratings.
+//   - Positive: The number of ratings above 0.
+//   - Negative: The number of ratings below 0.
+//   - AverageRating: The mean rating.
+type FeedbackStats struct {
+	Count         int     `json:"count"`
+	Positive      int     `json:"positive"`
+	Negative      int     `json:"negative"`
+	AverageRating float64 `json:"averageRating"`
+}
+
+// add counts a rating, the average is computed by finish.
+func (fs *FeedbackStats) add(rating int) {
+	fs.Count++
+	fs.AverageRating += float64(rating)
+	if rating > 0 {
+		fs.Positive++
+	} else if rating < 0 {
+		fs.Negative++
+	}
+}
+
+func (fs *FeedbackStats) finish() {
+	if fs.Count > 0 {
+		fs.AverageRating /= float64(fs.Count)
+	}
+}
+
+// FeedbackReport correlates feedback with the retrieval settings of the rated answers.
+//
+// Fields:
+//   - Overall: All ratings.
+//   - BySearchAlgorithm: Ratings grouped by the search algorithm of the answer.
+//   - ByDocument: Ratings grouped by the documents retrieved for the answer, documents of badly rated answers are candidates for review.
+//   - ByTenant: Ratings grouped by tenant, calls without tenant are grouped under "".
+//   - MissingTraces: Feedback whose trace expired or was removed, counted in Overall only, skipped in the report of a tenant.
+type FeedbackReport struct {
+	Overall           FeedbackStats            `json:"overall"`
+	BySearchAlgorithm map[string]FeedbackStats `json:"bySearchAlgorithm"`
+	ByDocument        map[string]FeedbackStats `json:"byDocument"`
+	ByTenant          map[string]FeedbackStats `json:"byTenant"`
+	MissingTraces     int                      `json:"missingTraces"`
+}
+
+// SaveFeedback adds the feedback to a sorted set scored by time, feedback older than TTL is removed.
+func (rs *RedisTraceStore) SaveFeedback(ctx context.Context, feedback Feedback) error {
+	rdb, err := rs.redisClient()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(feedback)
+	if err != nil {
+		return err
+	}
+	key := rs.Prefix + "feedback"
+	pipe := rdb.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(feedback.TimeStamp.UnixMilli()), Member: data})
+	if rs.TTL > 0 {
+		pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(time.Now().Add(-rs.TTL).UnixMilli(), 10))
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// ListFeedback returns the feedback submitted since a time, oldest first.
+func (rs *RedisTraceStore) ListFeedback(ctx context.Context, since time.Time) ([]Feedback, error) {
+	rdb, err := rs.redisClient()
+	if err != nil {
+		return nil, err
+	}
+	members, err := rdb.ZRangeByScore(ctx, rs.Prefix+"feedback", &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	feedback := make([]Feedback, 0, len(members))
+	for _, member := range members {
+		var entry Feedback
+		if err := json.Unmarshal([]byte(member), &entry); err != nil {
+			return feedback, err
+		}
+		feedback = append(feedback, entry)
+	}
+	return feedback, nil
+}
+
+// CreateFeedbackTable creates the feedback table, named after the trace table with a "_feedback" suffix, if it doesn't exist.
+func (ss *SQLTraceStore) CreateFeedbackTable(ctx context.Context) error {
+	table, err := ss.table()
+	if err != nil {
+		return err
+	}
+	_, err = ss.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+table+"_feedback"+
+		" (trace_id VARCHAR(128), rating INTEGER, comment TEXT, created_at TIMESTAMP)")
+	return err
+}
+
+// SaveFeedback inserts the feedback.
+func (ss *SQLTraceStore) SaveFeedback(ctx context.Context, feedback Feedback) error {
+	table, err := ss.table()
+	if err != nil {
+		return err
+	}
+	_, err = ss.DB.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s_feedback (trace_id, rating, comment, created_at) VALUES (%s, %s, %s, %s)",
+		table, ss.placeholder(1), ss.placeholder(2), ss.placeholder(3), ss.placeholder(4)),
+		feedback.TraceID, feedback.Rating, feedback.Comment, feedback.TimeStamp.UTC())
+	return err
+}
+
+// ListFeedback returns the feedback submitted since a time, oldest first.
+func (ss *SQLTraceStore) ListFeedback(ctx context.Context, since time.Time) ([]Feedback, error) {
+	table, err := ss.table()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ss.DB.QueryContext(ctx, "SELECT trace_id, rating, comment, created_at FROM "+table+"_feedback"+
+		" WHERE created_at >= "+ss.placeholder(1)+" ORDER BY created_at", since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	feedback := []Feedback{}
+	for rows.Next() {
+		var entry Feedback
+		if err := rows.Scan(&entry.TraceID, &entry.Rating, &entry.Comment, &entry.TimeStamp); err != nil {
+			return feedback, err
+		}
+		feedback = append(feedback, entry)
+	}
+	return feedback, rows.Err()
+}
+
+// SubmitFeedback stores the rating of an answer next to its trace, e.g. a thumbs-up or down from a chat UI.
+//
+// Parameters:
+//   - traceID: The TraceID of the rated LLMResult.
+//   - rating: FeedbackPositive, FeedbackNegative or any other scale, ratings above 0 are counted as positive.
+//   - comment: Optional free text of the user.
+//
+// Returns:
+//   - error: ErrTraceNotFound if the trace doesn't exist, or an error if no TraceStore is configured.
+//
+// Example Usage:
+//
+//	result, _ := llm.AskLLM("What is the warranty period?")
+//	err := llm.SubmitFeedback(result.TraceID, aillm.FeedbackNegative, "The warranty is 3 years, not 2")
+func (llm *LLMContainer) SubmitFeedback(traceID string, rating int, comment string) error {
+	if llm.TraceStore == nil {
+		return fmt.Errorf("trace store is not configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), llm.Timeouts.redisTimeout())
+	defer cancel()
+	if _, err := llm.TraceStore.GetTrace(ctx, traceID); err != nil {
+		return err
+	}
+	return llm.TraceStore.SaveFeedback(ctx, Feedback{
+		TraceID:   traceID,
+		Rating:    rating,
+		Comment:   comment,
+		TimeStamp: time.Now(),
+	})
+}
+
+// ListFeedback returns the feedback submitted since a time, oldest first.
+//
+// Parameters:
+//   - since: The start of the period, the zero time returns all feedback.
+//
+// Returns:
+//   - []Feedback: The submitted feedback.
+//   - error: An error if the store can't be read or no TraceStore is configured.
+func (llm *LLMContainer) ListFeedback(since time.Time) ([]Feedback, error) {
+	if llm.TraceStore == nil {
+		return nil, fmt.Errorf("trace store is not configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), llm.Timeouts.redisTimeout())
+	defer cancel()
+	return llm.TraceStore.ListFeedback(ctx, since)
+}
+
+// FeedbackReport aggregates the feedback submitted since a time with the traces of the rated answers.
+//
+// Parameters:
+//   - since: The start of the period, the zero time aggregates all feedback.
+//   - options: WithTenant only aggregates the answers of a tenant.
+//
+// Returns:
+//   - FeedbackReport: The ratings grouped by search algorithm, document and tenant.
+//   - error: An error if the store can't be read or no TraceStore is configured.
+//
+// Example Usage:
+//
+//	report, err := llm.FeedbackReport(time.Now().AddDate(0, 0, -7))
+//	for algorithm, stats := range report.BySearchAlgorithm {
+//		fmt.Printf("%s: %d/%d positive\n", algorithm, stats.Positive, stats.Count)
+//	}
+func (llm *LLMContainer) FeedbackReport(since time.Time, options ...LLMCallOption) (FeedbackReport, error) {
+	o := LLMCallOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+	report := FeedbackReport{
+		BySearchAlgorithm: map[string]FeedbackStats{},
+		ByDocument:        map[string]FeedbackStats{},
+		ByTenant:          map[string]FeedbackStats{},
+	}
+	feedback, err := llm.ListFeedback(since)
+	if err != nil {
+		return report, err
+	}
+	traces := map[string]*Trace{}
+	for _, entry := range feedback {
+		trace, loaded := traces[entry.TraceID]
+		if !loaded {
+			ctx, cancel := context.WithTimeout(context.Background(), llm.Timeouts.redisTimeout())
+			stored, err := llm.TraceStore.GetTrace(ctx, entry.TraceID)
+			cancel()
+			if err == nil {
+				trace = &stored
+			} else if err != ErrTraceNotFound {
+				return report, err
+			}
+			traces[entry.TraceID] = trace
+		}
+		if o.tenantID != "" && (trace == nil || trace.TenantID != o.tenantID) {
+			continue
+		}
+		report.Overall.add(entry.Rating)
+		if trace == nil {
+			report.MissingTraces++
+			continue
+		}
+		addFeedback(report.BySearchAlgorithm, trace.SearchAlgorithm, entry.Rating)
+		addFeedback(report.ByTenant, trace.TenantID, entry.Rating)
+		seen := map[string]bool{}
+		for _, chunk := range trace.Chunks {
+			if chunk.DocumentID != "" && !seen[chunk.DocumentID] {
+				seen[chunk.DocumentID] = true
+				addFeedback(report.ByDocument, chunk.DocumentID, entry.Rating)
+			}
+		}
+	}
+	report.Overall.finish()
+	for _, groups := range []map[string]FeedbackStats{report.BySearchAlgorithm, report.ByDocument, report.ByTenant} {
+		for key, stats := range groups {
+			stats.finish()
+			groups[key] = stats
+		}
+	}
+	return report, nil
+}
+
+// addFeedback counts a rating in a group.
+func addFeedback(groups map[string]FeedbackStats, key string, rating int) {
+	stats := groups[key]
+	stats.add(rating)
+	groups[key] = stats
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"errors"
+)
+
+// GCReport counts the chunk keys checked and removed by GC.
+//
+// Fields:
+//   - EmbeddingPrefix: The collected prefix.
+//   - ObjectCount: The number of embedding objects referencing chunks.
+//   - ChunkKeys: The number of chunk keys found, including the general chunks.
+//   - ReferencedKeys: The number of chunk keys referenced by an embedding object.
+//   - RemovedKeys: The number of orphaned chunk keys removed.
+type GCReport struct {
+	EmbeddingPrefix string `json:"EmbeddingPrefix"`
+	ObjectCount     int    `json:"ObjectCount"`
+	ChunkKeys       int    `json:"ChunkKeys"`
+	ReferencedKeys  int    `json:"ReferencedKeys"`
+	RemovedKeys     int    `json:"RemovedKeys"`
+}
+
+// GC removes the chunks of a prefix that no embedding object references anymore, e.g. the chunks left by a failed
+// embedding or an interrupted delete.
+//
+// The prefix matches like in CleanEmbeddings: the chunks and objects of longer prefixes, e.g. "docs2" for "docs",
+// are collected too and an empty prefix collects every chunk. Chunks being stored by EmbeddText are only referenced
+// once the text is embedded, so GC should run while no document of the prefix is being embedded, e.g. from a
+// maintenance job.
+//
+// Parameters:
+//   - prefix: The embedding prefix.
+//
+// Returns:
+//   - GCReport: The number of checked and removed chunk keys.
+//   - error: An error if Redis isn't configured or can't be read or written.
+//
+// Example Usage:
+//
+//	report, err := llm.GC("docs")
+//	fmt.Printf("%d of %d chunks were orphaned\n", report.RemovedKeys, report.ChunkKeys)
+func (llm *LLMContainer) GC(prefix string) (GCReport, error) {
+	report := GCReport{EmbeddingPrefix: prefix}
+	rdb := llm.RedisClient.redisClient
+	if rdb == nil {
+		return report, ErrNoRedis
+	}
+	ctx := context.Background()
+	// The chunks are listed before the objects, so chunks referenced by an object saved in between aren't removed
+	chunkKeys := []string{}
+	for _, pattern := range []string{"doc:context:" + escapeGlob(prefix) + "*", "doc:all:" + escapeGlob(prefix) + "*"} {
+		keys, err := scanKeys(ctx, rdb, pattern)
+		if err != nil {
+			return report, err
+		}
+		chunkKeys = append(chunkKeys, keys...)
+	}
+	report.ChunkKeys = len(chunkKeys)
+
+	objectKeys, err := scanKeys(ctx, rdb, "rawDocs:"+escapeGlob(prefix)+"*")
+	if err != nil {
+		return report, err
+	}
+	referenced := map[string]bool{}
+	for _, key := range objectKeys {
+		var obj LLMEmbeddingObject
+		if err := obj.load(rdb, key); err != nil {
+			// Objects deleted since the scan are skipped, the chunks of unreadable ones must not be removed
+			if errors.Is(err, ErrEmbeddingNotFound) {
+				continue
+			}
+			return report, err
+		}
+		report.ObjectCount++
+		for _, content := range obj.Contents {
+			for _, chunkKey := range content.chunkKeys() {
+				referenced[chunkKey] = true
+			}
+		}
+	}
+
+	orphaned := []string{}
+	for _, key := range chunkKeys {
+		if referenced[key] {
+			report.ReferencedKeys++
+		} else {
+			orphaned = append(orphaned, key)
+		}
+	}
+	report.RemovedKeys, err = unlinkKeys(ctx, rdb, orphaned)
+	return report, err
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GlossaryTerm is the approved rendering of a term, e.g. a product name or a regulated term.
+//
+// Fields:
+//   - Translations: The approved rendering of the term per language code, e.g. {"de": "Kundenkonto"}. Languages without
+//     a translation keep the term as is, e.g. for product names.
+//   - Definition: The meaning of the term given to the model, if any.
+//   - Variants: Wrong renderings replaced by the approved one when the glossary is enforced, e.g. "Cloudvault".
+type GlossaryTerm struct {
+	Translations map[string]string `json:"translations,omitempty"`
+	Definition   string            `json:"definition,omitempty"`
+	Variants     []string          `json:"variants,omitempty"`
+}
+
+// rendering returns the approved rendering of a term in a language, "pt" translations apply to "pt-BR".
+func (gt GlossaryTerm) rendering(term, language string) string {
+	if translation, ok := gt.Translations[language]; ok {
+		return translation
+	}
+	if base, _, found := strings.Cut(language, "-"); found {
+		if translation, ok := gt.Translations[base]; ok {
+			return translation
+		}
+	}
+	return term
+}
+
+// Glossary maps terms to their approved rendering, see SetGlossary.
+type Glossary map[string]GlossaryTerm
+
+// prefixGlossary is the glossary of an embedding prefix.
+type prefixGlossary struct {
+	glossary Glossary
+	enforce  bool
+}
+
+// glossaryRegistry keeps the glossary of each embedding prefix.
+type glossaryRegistry struct {
+	mu         sync.RWMutex
+	glossaries map[string]prefixGlossary
+}
+
+// glossaryRegistry returns the glossaries of the prefixes, creating it on first use.
+func (llm *LLMContainer) glossaryRegistry() *glossaryRegistry {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.glossaries == nil {
+		llm.glossaries = &glossaryRegistry{glossaries: map[string]prefixGlossary{}}
+	}
+	return llm.glossaries
+}
+
+// SetGlossary sets the glossary of an embedding prefix.
+//
+// The terms found in the query or the retrieved context of a call on the prefix are listed in the prompt with their
+// approved rendering in the answer language and their definition. Translate uses the glossary of its prefix too.
+//
+// Enforcement replaces the variants of the terms with their approved rendering in the answer after generation and
+// reports the corrected terms in LLMResult.GlossaryFixes. The streamed chunks are not corrected.
+//
+// Parameters:
+//   - prefix: The embedding prefix as set with WithEmbeddingPrefix, TenantPrefix(tenant, prefix) for the prefix of a tenant.
+//   - glossary: The terms of the prefix, nil removes the glossary.
+//   - enforce: Corrects the terms of the answers after generation.
+//
+// Example Usage:
+//
+//	llm.SetGlossary("products", aillm.Glossary{
+//		"Cloud Vault": {Definition: "The encrypted storage product", Variants: []string{"CloudVault", "Cloud-Vault"}},
+//		"account":     {Translations: map[string]string{"de": "Kundenkonto"}},
+//	}, true)
+func (llm *LLMContainer) SetGlossary(prefix string, glossary Glossary, enforce bool) {
+	registry := llm.glossaryRegistry()
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if glossary == nil {
+		delete(registry.glossaries, prefix)
+		return
+	}
+	registry.glossaries[prefix] = prefixGlossary{glossary: glossary, enforce: enforce}
+}
+
+// Glossary returns the glossary of an embedding prefix, nil if it has none.
+func (llm *LLMContainer) Glossary(prefix string) Glossary {
+	registry := llm.glossaryRegistry()
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return registry.glossaries[prefix].glossary
+}
+
+// callGlossary returns the glossary of a call: the glossaries of its prefixes, the first prefix defining a term
+// wins, and the terms of WithGlossary rendered as given in every language.
+//
+// Returns:
+//   - Glossary: The terms of the call, nil if it has none.
+//   - bool: Whether one of the prefixes enforces its glossary.
+func (llm *LLMContainer) callGlossary(o *LLMCallOptions) (Glossary, bool) {
+	prefixes := []string{o.getEmbeddingPrefix()}
+	for _, prefix := range o.prefixes {
+		prefixes = append(prefixes, TenantPrefix(o.tenantID, prefix))
+	}
+	registry := llm.glossaryRegistry()
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	var glossary Glossary
+	enforce := false
+	add := func(term string, entry GlossaryTerm) {
+		if glossary == nil {
+			glossary = Glossary{}
+		}
+		if _, ok := glossary[term]; !ok {
+			glossary[term] = entry
+		}
+	}
+	for term, rendering := range o.glossary {
+		add(term, GlossaryTerm{Translations: map[string]string{"*": rendering}})
+	}
+	for _, prefix := range prefixes {
+		entry, ok := registry.glossaries[prefix]
+		if !ok {
+			continue
+		}
+		enforce = enforce || entry.enforce
+		for term, glossaryTerm := range entry.glossary {
+			add(term, glossaryTerm)
+		}
+	}
+	return glossary, enforce
+}
+
+// glossaryLanguage resolves the answer language of a prompt to the code the translations are keyed by, "" when the
+// language is unknown, e.g. detected by the model.
+func glossaryLanguage(language string) string {
+	tag, err := ParseLanguage(language)
+	if err != nil {
+		return ""
+	}
+	return string(tag)
+}
+
+// renderingIn returns the approved rendering of a term, and whether it is known for the language. The terms of
+// WithGlossary have a single rendering, keyed by "*".
+func (gt GlossaryTerm) renderingIn(term, language string) (string, bool) {
+	if rendering, ok := gt.Translations["*"]; ok {
+		return rendering, true
+	}
+	if language == "" && len(gt.Translations) > 0 {
+		return "", false
+	}
+	return gt.rendering(term, language), true
+}
+
+// relevantTerms returns the sorted terms of a glossary whose term, translations or variants appear in a text.
+func (g Glossary) relevantTerms(text string) []string {
+	lowerText := strings.ToLower(text)
+	terms := []string{}
+	for term, entry := range g {
+		forms := append([]string{term}, entry.Variants...)
+		for _, translation := range entry.Translations {
+			forms = append(forms, translation)
+		}
+		for _, form := range forms {
+			if form != "" && strings.Contains(lowerText, strings.ToLower(form)) {
+				terms = append(terms, term)
+				break
+			}
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// glossaryInstructions lists the terms of a glossary found in a text for the prompt of a call.
+func glossaryInstructions(glossary Glossary, language, text string) string {
+	terms := glossary.relevantTerms(text)
+	if len(terms) == 0 {
+		return ""
+	}
+	var prompt strings.Builder
+	prompt.WriteString("### Glossary:\n- Always write these terms in the answer as given:\n")
+	for _, term := range terms {
+		entry := glossary[term]
+		line := "  - " + term
+		if rendering, ok := entry.renderingIn(term, language); ok {
+			line += " → " + rendering
+		} else {
+			// The answer language is unknown, every approved translation is listed
+			languages := make([]string, 0, len(entry.Translations))
+			for code := range entry.Translations {
+				languages = append(languages, code)
+			}
+			sort.Strings(languages)
+			translations := []string{}
+			for _, code := range languages {
+				translations = append(translations, code+": "+entry.Translations[code])
+			}
+			line += " (" + strings.Join(translations, ", ") + ", other languages: " + term + ")"
+		}
+		if entry.Definition != "" {
+			line += ": " + entry.Definition
+		}
+		prompt.WriteString(line + "\n")
+	}
+	return prompt.String()
+}
+
+// translationGlossary returns the approved renderings of the call glossary in a target language, see glossaryPrompt.
+func (llm *LLMContainer) translationGlossary(o *LLMCallOptions, targetLanguage string) map[string]string {
+	glossary, _ := llm.callGlossary(o)
+	if glossary == nil {
+		return nil
+	}
+	language := glossaryLanguage(targetLanguage)
+	renderings := make(map[string]string, len(glossary))
+	for term, entry := range glossary {
+		if rendering, ok := entry.renderingIn(term, language); ok {
+			renderings[term] = rendering
+		}
+	}
+	return renderings
+}
+
+// isWordRune reports whether a rune is part of a word of a spaced script.
+func isWordRune(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsDigit(r)) && !isCJK(r)
+}
+
+// replaceWord replaces the case insensitive occurrences of a word that aren't part of a longer word.
+func replaceWord(text, word, replacement string) (string, bool) {
+	pattern, err := regexp.Compile(`(?i)` + regexp.QuoteMeta(word))
+	if err != nil || word == "" {
+		return text, false
+	}
+	var replaced strings.Builder
+	last, changed := 0, false
+	for _, loc := range pattern.FindAllStringIndex(text, -1) {
+		before, _ := utf8.DecodeLastRuneInString(text[:loc[0]])
+		after, _ := utf8.DecodeRuneInString(text[loc[1]:])
+		if isWordRune(before) || isWordRune(after) || text[loc[0]:loc[1]] == replacement {
+			continue
+		}
+		replaced.WriteString(text[last:loc[0]] + replacement)
+		last, changed = loc[1], true
+	}
+	if !changed {
+		return text, false
+	}
+	replaced.WriteString(text[last:])
+	return replaced.String(), true
+}
+
+// enforceGlossary replaces the variants of the terms of a glossary in an answer with their approved rendering.
+//
+// Returns:
+//   - string: The corrected answer.
+//   - []string: The corrected terms, sorted.
+func enforceGlossary(answer string, glossary Glossary, language string) (string, []string) {
+	fixed := []string{}
+	for _, term := range glossary.relevantTerms(answer) {
+		entry := glossary[term]
+		rendering, ok := entry.renderingIn(term, language)
+		if !ok {
+			continue
+		}
+		changed := false
+		for _, wrong := range entry.Variants {
+			var replaced bool
+			answer, replaced = replaceWord(answer, wrong, rendering)
+			changed = changed || replaced
+		}
+		if changed {
+			fixed = append(fixed, term)
+		}
+	}
+	return answer, fixed
+}
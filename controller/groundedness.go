@@ -0,0 +1,144 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// GroundednessReport tells how well an answer is supported by the retrieved documents.
+//
+// Fields:
+//   - Score: The share of answer sentences supported by the documents, from 0 to 1.
+//   - Sentences: The number of sentences checked.
+//   - Unsupported: The sentences the documents don't support, possible hallucinations.
+type GroundednessReport struct {
+	Score       float64  `json:"score"`
+	Sentences   int      `json:"sentences"`
+	Unsupported []string `json:"unsupported"`
+}
+
+// sentenceEnd matches the end of a sentence followed by whitespace.
+var sentenceEnd = regexp.MustCompile(`[.!?。！？]+["')\]]*\s+`)
+
+// splitSentences splits a text into its non empty sentences and lines.
+func splitSentences(text string) []string {
+	sentences := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		last := 0
+		for _, loc := range sentenceEnd.FindAllStringIndex(line+" ", -1) {
+			end := loc[1]
+			if end > len(line) {
+				end = len(line)
+			}
+			if sentence := strings.TrimSpace(line[last:end]); sentence != "" {
+				sentences = append(sentences, sentence)
+			}
+			last = end
+		}
+		if last < len(line) {
+			if sentence := strings.TrimSpace(line[last:]); sentence != "" {
+				sentences = append(sentences, sentence)
+			}
+		}
+	}
+	return sentences
+}
+
+// CheckGroundedness verifies each sentence of an answer against documents with the LLM as an NLI judge.
+//
+// Parameters:
+//   - ctx: The context of the judge call.
+//   - answer: The answer to verify.
+//   - docs: The documents the answer should be based on, e.g. LLMResult.RagDocs.
+//
+// Returns:
+//   - GroundednessReport: The score and the unsupported sentences.
+//   - TokenUsage: Tokens used by the judge.
+//   - error: An error if the judge can't be called or its verdict can't be parsed.
+//
+// Example Usage:
+//
+//	report, _, err := llm.CheckGroundedness(ctx, answer, result.RagDocs)
+//	if err == nil && report.Score < 0.8 {
+//		log.Println("possible hallucinations:", report.Unsupported)
+//	}
+func (llm *LLMContainer) CheckGroundedness(ctx context.Context, answer string, docs []RetrievedDocument) (GroundednessReport, TokenUsage, error) {
+	usage := TokenUsage{}
+	sentences := splitSentences(answer)
+	report := GroundednessReport{Sentences: len(sentences), Unsupported: []string{}}
+	if len(sentences) == 0 {
+		report.Score = 1
+		return report, usage, nil
+	}
+	if len(docs) == 0 {
+		// Nothing can support the answer
+		report.Unsupported = sentences
+		return report, usage, nil
+	}
+	llmclient, err := llm.newLLMClient(llm.LLMClient, nil)
+	if err != nil {
+		return report, usage, err
+	}
+	var documents, numbered strings.Builder
+	for idx, doc := range docs {
+		documents.WriteString("[" + strconv.Itoa(idx+1) + "] " + doc.Content + "\n")
+	}
+	for idx, sentence := range sentences {
+		numbered.WriteString(strconv.Itoa(idx+1) + ". " + sentence + "\n")
+	}
+	prompt := strings.NewReplacer("{{documents}}", documents.String(), "{{sentences}}", numbered.String()).Replace(groundednessCheckPrompt)
+	response, err := llmclient.GenerateContent(ctx,
+		[]llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeHuman, prompt),
+		},
+		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			usage.OutputTokens++
+			return nil
+		}),
+		llms.WithTemperature(0))
+	if err != nil {
+		return report, usage, err
+	}
+	if len(response.Choices) == 0 {
+		return report, usage, errors.New("groundedness judge returned no verdict")
+	}
+	content := response.Choices[0].Content
+	unsupported := []int{}
+	// A verdict that can't be parsed fails the check instead of reporting the answer as grounded
+	start, end := strings.Index(content, "["), strings.LastIndex(content, "]")
+	if start < 0 || end < start {
+		return report, usage, fmt.Errorf("groundedness judge returned no sentence list: %q", content)
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &unsupported); err != nil {
+		return report, usage, fmt.Errorf("invalid groundedness verdict: %w", err)
+	}
+	flagged := map[int]bool{}
+	for _, number := range unsupported {
+		if number >= 1 && number <= len(sentences) && !flagged[number] {
+			flagged[number] = true
+			report.Unsupported = append(report.Unsupported, sentences[number-1])
+		}
+	}
+	report.Score = float64(len(sentences)-len(report.Unsupported)) / float64(len(sentences))
+	return report, usage, nil
+}
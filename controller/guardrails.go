@@ -0,0 +1,215 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ErrGuardrailFailed matches the *GuardrailError of an answer that never passed its validators, with errors.Is.
+var ErrGuardrailFailed = errors.New("answer failed validation")
+
+// AnswerValidator checks a completed answer, the returned error is sent back to the model when it is retried.
+type AnswerValidator interface {
+	Validate(answer string) error
+}
+
+// AnswerValidatorFunc adapts a function to the AnswerValidator interface.
+type AnswerValidatorFunc func(answer string) error
+
+// Validate calls f.
+func (f AnswerValidatorFunc) Validate(answer string) error {
+	return f(answer)
+}
+
+// GuardrailConfig validates the answers of AskLLM.
+//
+// Fields:
+//   - Validators: Validators run over every completed answer, in order.
+//   - MaxRetries: How many times the model is asked again with the validation error, default 2, negative disables retries.
+type GuardrailConfig struct {
+	Validators []AnswerValidator
+	MaxRetries int
+}
+
+// GuardrailError is returned by AskLLM when an answer still fails validation after the retries.
+//
+// Fields:
+//   - Attempts: The number of answers generated.
+//   - Answer: The last answer.
+//   - Err: The validation error of the last answer.
+type GuardrailError struct {
+	Attempts int
+	Answer   string
+	Err      error
+}
+
+func (e *GuardrailError) Error() string {
+	return fmt.Sprintf("%s after %d attempts: %v", ErrGuardrailFailed, e.Attempts, e.Err)
+}
+
+// Is reports whether target is ErrGuardrailFailed.
+func (e *GuardrailError) Is(target error) bool {
+	return target == ErrGuardrailFailed
+}
+
+// Unwrap returns the validation error.
+func (e *GuardrailError) Unwrap() error {
+	return e.Err
+}
+
+// RegexValidator requires answers to match a regular expression.
+//
+// Parameters:
+//   - pattern: The expression the answer must match.
+//   - description: Describes the expected format to the model, e.g. "a single ISO 8601 date".
+func RegexValidator(pattern *regexp.Regexp, description string) AnswerValidator {
+	return AnswerValidatorFunc(func(answer string) error {
+		if !pattern.MatchString(answer) {
+			return fmt.Errorf("the answer must be %s", description)
+		}
+		return nil
+	})
+}
+
+// LengthValidator limits the number of characters of answers.
+//
+// Parameters:
+//   - min: The minimum number of characters, 0 for none.
+//   - max: The maximum number of characters, 0 for none.
+func LengthValidator(min, max int) AnswerValidator {
+	return AnswerValidatorFunc(func(answer string) error {
+		length := utf8.RuneCountInString(strings.TrimSpace(answer))
+		if min > 0 && length < min {
+			return fmt.Errorf("the answer has %d characters, it must have at least %d", length, min)
+		}
+		if max > 0 && length > max {
+			return fmt.Errorf("the answer has %d characters, it must have at most %d", length, max)
+		}
+		return nil
+	})
+}
+
+// JSONSchemaValidator requires answers to be JSON matching a schema, using the same subset of JSON schema
+// as tool parameters: type, properties, required, additionalProperties, items and enum.
+//
+// A JSON code block around the answer is accepted.
+//
+// Parameters:
+//   - schema: The schema, any JSON serializable value.
+//
+// Example Usage:
+//
+//	llm.Guardrails = aillm.GuardrailConfig{Validators: []aillm.AnswerValidator{
+//		aillm.JSONSchemaValidator(map[string]any{
+//			"type":     "object",
+//			"required": []string{"city"},
+//		}),
+//	}}
+func JSONSchemaValidator(schema interface{}) AnswerValidator {
+	var schemaMap map[string]interface{}
+	raw, err := json.Marshal(schema)
+	if err == nil {
+		err = json.Unmarshal(raw, &schemaMap)
+	}
+	return AnswerValidatorFunc(func(answer string) error {
+		if err != nil {
+			return fmt.Errorf("invalid JSON schema: %v", err)
+		}
+		var value interface{}
+		if decodeErr := json.Unmarshal([]byte(trimCodeBlock(answer)), &value); decodeErr != nil {
+			return fmt.Errorf("the answer must be valid JSON: %v", decodeErr)
+		}
+		return validateJSONSchema(schemaMap, value, "$")
+	})
+}
+
+// trimCodeBlock removes a markdown code block around a text.
+func trimCodeBlock(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	// Drop the language of the block, e.g. ```json
+	if newline := strings.Index(text, "\n"); newline >= 0 {
+		text = text[newline+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// validateAnswer runs the validators of a call over an answer.
+func validateAnswer(validators []AnswerValidator, answer string) error {
+	for _, validator := range validators {
+		if err := validator.Validate(answer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// guardrailRetryPrompt asks the model to correct an answer that failed validation.
+func guardrailRetryPrompt(err error) string {
+	return "Your previous answer is not valid: " + err.Error() + ". Answer the question again and fix this problem. Reply only with the corrected answer."
+}
+
+// applyGuardrails validates an answer and generates it again with the validation error until it passes.
+//
+// Parameters:
+//   - msgs, calloptions: The prompt and the call options of the answer.
+//   - response: The generated answer.
+//   - reset: Resets the streaming state of the call before a retry.
+//
+// Returns:
+//   - *llms.ContentResponse: The valid answer.
+//   - []llms.MessageContent: The prompt with the retries.
+//   - error: A *GuardrailError if the answer is still invalid after the retries, or a generation error.
+func (llm *LLMContainer) applyGuardrails(ctx context.Context, llmclient llms.Model, msgs []llms.MessageContent, calloptions []llms.CallOption, response *llms.ContentResponse, validators []AnswerValidator, result *LLMResult, actionCallFunc func(action LLMAction), reset func()) (*llms.ContentResponse, []llms.MessageContent, error) {
+	maxRetries := llm.Guardrails.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = 2
+	}
+	for attempt := 1; response != nil && len(response.Choices) > 0; attempt++ {
+		// References are appended after the answer
+		answer, _ := stripRefusalMarker(strings.Split(response.Choices[0].Content, "⧉")[0])
+		validationErr := validateAnswer(validators, answer)
+		if validationErr == nil {
+			return response, msgs, nil
+		}
+		if attempt > maxRetries {
+			return response, msgs, &GuardrailError{Attempts: attempt, Answer: answer, Err: validationErr}
+		}
+		result.addAction("Guardrail validation failed: "+validationErr.Error(), actionCallFunc)
+		reset()
+		msgs = append(msgs,
+			llms.TextParts(llms.ChatMessageTypeAI, response.Choices[0].Content),
+			llms.TextParts(llms.ChatMessageTypeHuman, guardrailRetryPrompt(validationErr)),
+		)
+		var err error
+		response, err = llmclient.GenerateContent(ctx, msgs, calloptions...)
+		if err != nil {
+			llm.metrics.observeProviderError("llm")
+			return response, msgs, err
+		}
+	}
+	return response, msgs, nil
+}
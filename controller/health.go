@@ -0,0 +1,141 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/go-tika/tika"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// DependencyStatus is the health of a single dependency.
+//
+// Fields:
+//   - Name: The dependency name (redis, embedder, llm or tika).
+//   - Healthy: Whether the dependency answered successfully.
+//   - Skipped: True if the dependency is not configured, a skipped dependency is reported healthy.
+//   - Latency: Time taken by the check.
+//   - Error: The error message of a failed check.
+type DependencyStatus struct {
+	Name    string        `json:"name"`
+	Healthy bool          `json:"healthy"`
+	Skipped bool          `json:"skipped,omitempty"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// HealthStatus is the result of HealthCheck.
+//
+// Fields:
+//   - Healthy: True if all dependencies are healthy.
+//   - Dependencies: The status of Redis, the embedder, the LLM endpoint and Tika, in this order.
+type HealthStatus struct {
+	Healthy      bool               `json:"healthy"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// HealthCheck checks the dependencies of the container, e.g. for readiness probes.
+//
+// Redis is pinged, the embedder embeds a short text, the LLM endpoint generates a single token for a
+// tiny prompt and Tika reports its version. Dependencies that are not configured are skipped.
+// The checks run concurrently and bypass retries and circuit breakers, so the result reflects the
+// current state of every endpoint.
+//
+// Parameters:
+//   - ctx: Bounds the duration of the checks.
+//
+// Returns:
+//   - HealthStatus: The overall and per dependency status.
+//
+// Example Usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	status := llm.HealthCheck(ctx)
+//	if !status.Healthy {
+//		w.WriteHeader(http.StatusServiceUnavailable)
+//	}
+func (llm *LLMContainer) HealthCheck(ctx context.Context) HealthStatus {
+	checks := []struct {
+		name       string
+		configured bool
+		check      func(ctx context.Context) error
+	}{
+		{"redis", true, llm.checkRedis},
+		{"embedder", llm.Embedder != nil, llm.checkEmbedder},
+		{"llm", llm.LLMClient != nil, llm.checkLLM},
+		{"tika", llm.Transcriber.TikaURL != "", llm.checkTika},
+	}
+	status := HealthStatus{Healthy: true, Dependencies: make([]DependencyStatus, len(checks))}
+	var wg sync.WaitGroup
+	for idx, check := range checks {
+		status.Dependencies[idx] = DependencyStatus{Name: check.name, Healthy: true, Skipped: !check.configured}
+		if !check.configured {
+			continue
+		}
+		wg.Add(1)
+		go func(dependency *DependencyStatus, check func(ctx context.Context) error) {
+			defer wg.Done()
+			start := time.Now()
+			err := check(ctx)
+			dependency.Latency = time.Since(start)
+			if err != nil {
+				dependency.Healthy = false
+				dependency.Error = err.Error()
+			}
+		}(&status.Dependencies[idx], check.check)
+	}
+	wg.Wait()
+	for _, dependency := range status.Dependencies {
+		status.Healthy = status.Healthy && dependency.Healthy
+	}
+	return status
+}
+
+func (llm *LLMContainer) checkRedis(ctx context.Context) error {
+	if llm.RedisClient.redisClient == nil {
+		return errors.New("redis client is not initialized, call Init first")
+	}
+	return llm.RedisClient.redisClient.Ping(ctx).Err()
+}
+
+func (llm *LLMContainer) checkEmbedder(ctx context.Context) error {
+	if err := llm.ensureEmbedder(); err != nil {
+		return err
+	}
+	embedder, err := llm.Embedder.NewEmbedder()
+	if err != nil {
+		return err
+	}
+	_, err = embedder.EmbedQuery(ctx, "health check")
+	return err
+}
+
+func (llm *LLMContainer) checkLLM(ctx context.Context) error {
+	model, err := llm.getLLMModel(llm.LLMClient)
+	if err != nil {
+		return err
+	}
+	_, err = model.GenerateContent(ctx, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "ping")}, llms.WithMaxTokens(1))
+	return err
+}
+
+func (llm *LLMContainer) checkTika(ctx context.Context) error {
+	_, err := tika.NewClient(nil, llm.Transcriber.TikaURL).Version(ctx)
+	return err
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// BeforeRetrieveHook is called by AskLLM before the vector search.
+//
+// It receives the retrieval query (the user query extended with session memory and attachment
+// descriptions) and returns the query to search with, e.g. a rewritten or expanded query.
+// Returning an error aborts the request.
+type BeforeRetrieveHook func(ctx context.Context, query string) (string, error)
+
+// AfterRetrieveHook is called by AskLLM after the vector search, including the fallback language search.
+//
+// It receives the retrieval query and the retrieved documents and returns the documents to use as
+// context, e.g. after filtering by permissions or adding documents from another source.
+// Returning an error aborts the request.
+type AfterRetrieveHook func(ctx context.Context, query string, docs []schema.Document) ([]schema.Document, error)
+
+// BeforePromptHook is called by AskLLM with the final messages before they are sent to the model.
+//
+// It returns the messages to send, e.g. with an extra system instruction.
+// Returning an error aborts the request.
+type BeforePromptHook func(ctx context.Context, messages []llms.MessageContent) ([]llms.MessageContent, error)
+
+// AfterGenerateHook is called by AskLLM with the model response before it is stored in the session memory.
+//
+// It receives the user query and the response and returns the response to use, e.g. after redacting content.
+// Chunks already delivered to the streaming function are not affected.
+// Returning an error aborts the request.
+type AfterGenerateHook func(ctx context.Context, query string, response *llms.ContentResponse) (*llms.ContentResponse, error)
@@ -0,0 +1,456 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImageEmbeddingClient defines an interface for models that map images and text queries into the
+// same vector space (CLIP-style models).
+//
+// Methods:
+//   - EmbedImages(): Returns one vector per image.
+//   - EmbedQuery(): Returns the vector of a text query, comparable with image vectors.
+type ImageEmbeddingClient interface {
+	EmbedImages(ctx context.Context, images []ImageInput) ([][]float32, error)
+	EmbedQuery(ctx context.Context, text string) ([]float32, error)
+}
+
+// CLIPEmbeddingController calls a CLIP-style embeddings API (e.g., Jina CLIP or a self-hosted
+// clip-as-service) that accepts mixed text and image inputs:
+//
+//	POST {Apiurl}embeddings {"model": "...", "input": [{"image": "data:..."}, {"text": "..."}]}
+//
+// Fields:
+//   - Config: API endpoint, model name and token.
+//   - HTTPClient: Optional HTTP client, defaults to a client with a 60 second timeout.
+type CLIPEmbeddingController struct {
+	Config     LLMConfig
+	HTTPClient *http.Client
+}
+
+// EmbeddedImage represents an image stored in the image vector index.
+//
+// Fields:
+//   - Id: Unique identifier of the image.
+//   - Index: The index the image belongs to.
+//   - Source: The origin of the image (path or URL).
+//   - Description: A textual description of the image, used in RAG prompts.
+//   - MimeType: The MIME type of the image.
+//   - Score: Similarity score of a search result (higher is better).
+type EmbeddedImage struct {
+	Id          string
+	Index       string
+	Source      string
+	Description string
+	MimeType    string
+	Score       float32
+}
+
+// EmbedImages implements ImageEmbeddingClient.
+func (cc *CLIPEmbeddingController) EmbedImages(ctx context.Context, images []ImageInput) ([][]float32, error) {
+	inputs := make([]map[string]string, 0, len(images))
+	for _, image := range images {
+		encodedImage, err := loadImageInput(ctx, image, defaultMaxImageSize)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, map[string]string{"image": encodedImage})
+	}
+	return cc.embed(ctx, inputs)
+}
+
+// EmbedQuery implements ImageEmbeddingClient.
+func (cc *CLIPEmbeddingController) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := cc.embed(ctx, []map[string]string{{"text": text}})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (cc *CLIPEmbeddingController) embed(ctx context.Context, inputs []map[string]string) ([][]float32, error) {
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"model": cc.Config.AiModel,
+		"input": inputs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error converting request to json: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", cc.Config.Apiurl+"embeddings", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cc.Config.APIToken != "" {
+		req.Header.Set("Authorization", "Bearer "+cc.Config.APIToken)
+	}
+	client := cc.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("api error: status code %d\nresponse: %s", resp.StatusCode, string(body))
+	}
+	response := struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing JSON response: %w", err)
+	}
+	if len(response.Data) != len(inputs) {
+		return nil, errors.New("embedding api returned an unexpected number of vectors")
+	}
+	sort.Slice(response.Data, func(i, j int) bool { return response.Data[i].Index < response.Data[j].Index })
+	vectors := make([][]float32, len(response.Data))
+	for idx, item := range response.Data {
+		vectors[idx] = item.Embedding
+	}
+	return vectors, nil
+}
+
+// EmbeddImage stores an image in the image vector index so AskLLM can retrieve it.
+//
+// When `llm.ImageEmbedder` is configured the image itself is embedded (CLIP-style). Otherwise the
+// image is described by `llm.VisionClient` (e.g., llava on Ollama) and the description is embedded
+// with the text `llm.Embedder`. A description is always stored so the image can be referenced
+// in prompts; if ImageInput.ID is empty a new id is generated.
+//
+// Parameters:
+//   - Index: The index the image belongs to.
+//   - image: The image to embed.
+//   - description: Optional description, generated by the VisionClient when empty.
+//   - options: WithEmbeddingPrefix selects the prefix of the index, WithTimeout bounds the download and the embedding.
+//
+// Returns:
+//   - EmbeddedImage: The stored image record.
+//   - error: An error if the image cannot be described, embedded or stored.
+func (llm *LLMContainer) EmbeddImage(Index string, image ImageInput, description string, options ...LLMCallOption) (EmbeddedImage, error) {
+	o := LLMCallOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+	result := EmbeddedImage{
+		Id:          image.ID,
+		Index:       Index,
+		Source:      image.Path,
+		Description: description,
+		MimeType:    image.MimeType,
+	}
+	if err := llm.checkTenant(&o); err != nil {
+		return result, err
+	}
+	if result.Id == "" {
+		result.Id = uuid.New().String()
+	}
+	if result.Source == "" {
+		result.Source = image.URL
+	}
+	if llm.RedisClient.redisClient == nil {
+		return result, fmt.Errorf("%w: missing redis client, call Init first", ErrNoRedis)
+	}
+	ctx, cancel := llm.requestContext(context.Background(), &o)
+	defer cancel()
+	if image.URL != "" && image.Path == "" && len(image.Data) == 0 {
+		// The image is downloaded once for its description, its vector and its MIME type
+		imageData, err := llm.fetchImage(ctx, image.URL)
+		if err != nil {
+			return result, err
+		}
+		image.Data = imageData
+	}
+
+	if result.Description == "" && llm.VisionClient != nil {
+		response, err := llm.describeImageInput(image, "Describe this image in detail, including any visible text.")
+		if err != nil {
+			return result, err
+		}
+		if len(response.Choices) > 0 {
+			result.Description = response.Choices[0].Message.Content
+		}
+	}
+
+	var vector []float32
+	if llm.ImageEmbedder != nil {
+		vectors, err := llm.ImageEmbedder.EmbedImages(ctx, []ImageInput{image})
+		if err != nil {
+			return result, err
+		}
+		vector = vectors[0]
+	} else {
+		if result.Description == "" {
+			return result, errors.New("missing image embedding model and vision client")
+		}
+		var err error
+		vector, err = llm.embedImageQueryText(ctx, result.Description)
+		if err != nil {
+			return result, err
+		}
+	}
+	if result.MimeType == "" {
+		if encodedImage, err := loadImageInput(ctx, image, llm.maxImageSize()); err == nil {
+			result.MimeType, _, _ = strings.Cut(strings.TrimPrefix(encodedImage, "data:"), ";")
+		}
+	}
+
+	rdb := llm.RedisClient.redisClient
+	if err := createImageIndex(ctx, llm, o.getEmbeddingPrefix(), len(vector)); err != nil {
+		return result, err
+	}
+	key := imageKeyPrefix(o.getEmbeddingPrefix()) + LLMEmbeddingObject{}.sanitizeRedisKey(Index) + ":" + result.Id
+	err := rdb.HSet(ctx, key, map[string]interface{}{
+		"id":          result.Id,
+		"index":       Index,
+		"source":      result.Source,
+		"description": result.Description,
+		"mime":        result.MimeType,
+		"vector":      float32VectorBytes(vector),
+	}).Err()
+	return result, err
+}
+
+// SearchImages finds the images most related to a text query.
+//
+// Parameters:
+//   - prefix: The embedding prefix the images were stored with.
+//   - query: The text query.
+//   - rowCount: Maximum number of images to return.
+//   - ScoreThreshold: Minimum similarity (0..1) of returned images.
+//
+// Returns:
+//   - []EmbeddedImage: The matching images ordered by similarity.
+//   - error: An error if the search fails.
+func (llm *LLMContainer) SearchImages(prefix, query string, rowCount int, ScoreThreshold float32) ([]EmbeddedImage, error) {
+	return llm.searchImages(context.Background(), prefix, query, rowCount, ScoreThreshold)
+}
+
+// searchImages implements SearchImages, the search stops with ctx.
+func (llm *LLMContainer) searchImages(ctx context.Context, prefix, query string, rowCount int, ScoreThreshold float32) ([]EmbeddedImage, error) {
+	if llm.RedisClient.redisClient == nil {
+		return nil, fmt.Errorf("%w: missing redis client, call Init first", ErrNoRedis)
+	}
+	ctx, cancel := llm.searchContext(ctx)
+	defer cancel()
+	var vector []float32
+	var err error
+	if llm.ImageEmbedder != nil {
+		vector, err = llm.ImageEmbedder.EmbedQuery(ctx, query)
+	} else {
+		vector, err = llm.embedImageQueryText(ctx, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	searchResults, err := llm.RedisClient.redisClient.Do(ctx,
+		"FT.SEARCH", imageIndexName(prefix),
+		fmt.Sprintf("*=>[KNN %d @vector $vec AS distance]", rowCount),
+		"PARAMS", "2", "vec", float32VectorBytes(vector),
+		"SORTBY", "distance", "ASC",
+		"RETURN", "6", "id", "index", "source", "description", "mime", "distance",
+		"DIALECT", "2").Result()
+	if err != nil {
+		if isIndexNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("image search error: %w", err)
+	}
+
+	var images []EmbeddedImage
+	resultMap, ok := searchResults.(map[interface{}]interface{})
+	if !ok {
+		return images, nil
+	}
+	resultsSlice, _ := resultMap["results"].([]interface{})
+	for _, resultItem := range resultsSlice {
+		item, ok := resultItem.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		values, ok := item["extra_attributes"].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		image := EmbeddedImage{}
+		for fieldName, fieldValue := range values {
+			value := fmt.Sprintf("%v", fieldValue)
+			switch fmt.Sprintf("%v", fieldName) {
+			case "id":
+				image.Id = value
+			case "index":
+				image.Index = value
+			case "source":
+				image.Source = value
+			case "description":
+				image.Description = value
+			case "mime":
+				image.MimeType = value
+			case "distance":
+				distance, _ := strconv.ParseFloat(value, 64)
+				image.Score = float32(1 - distance)
+			}
+		}
+		if image.Score < ScoreThreshold {
+			continue
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+// RemoveImageEmbedding deletes an image from the image vector index.
+//
+// Parameters:
+//   - Index: The index the image belongs to.
+//   - id: The id of the image.
+//   - options: WithEmbeddingPrefix selects the prefix of the index.
+//
+// Returns:
+//   - error: An error if deletion fails.
+func (llm *LLMContainer) RemoveImageEmbedding(Index, id string, options ...LLMCallOption) error {
+	o := LLMCallOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+	if err := llm.checkTenant(&o); err != nil {
+		return err
+	}
+	key := imageKeyPrefix(o.getEmbeddingPrefix()) + LLMEmbeddingObject{}.sanitizeRedisKey(Index) + ":" + id
+	return llm.RedisClient.redisClient.Del(context.TODO(), key).Err()
+}
+
+// imageRagContext builds the prompt section describing retrieved images.
+func imageRagContext(images []EmbeddedImage) string {
+	if len(images) == 0 {
+		return ""
+	}
+	imageContext := "### Related Images:\n"
+	for idx, image := range images {
+		imageContext += fmt.Sprintf("Image %d:\n- Reference: {\"image\":\"%s\"}\n- Source: %s\n- Description: %s\n", idx+1, image.Id, image.Source, image.Description)
+	}
+	return imageContext + "- If an image is relevant to the answer, mention it by its source.\n"
+}
+
+// embedImageQueryText embeds text with the container text embedder.
+func (llm *LLMContainer) embedImageQueryText(ctx context.Context, text string) ([]float32, error) {
+	if llm.Embedder == nil {
+		return nil, ErrMissingEmbedder
+	}
+	llm.ensureEmbedder()
+	embedder, err := llm.getEmbedder()
+	if err != nil {
+		return nil, err
+	}
+	return embedder.EmbedQuery(ctx, text)
+}
+
+// createImageIndex creates the image vector index of a prefix if it doesn't exist.
+func createImageIndex(ctx context.Context, llm *LLMContainer, prefix string, dimension int) error {
+	rdb := llm.RedisClient.redisClient
+	indexName := imageIndexName(prefix)
+	if _, err := rdb.Do(ctx, "FT.INFO", indexName).Result(); err == nil {
+		return nil
+	}
+	err := rdb.Do(ctx, "FT.CREATE", indexName,
+		"ON", "HASH",
+		"PREFIX", "1", imageKeyPrefix(prefix),
+		"SCHEMA",
+		"id", "TAG",
+		"index", "TAG",
+		"source", "TEXT",
+		"description", "TEXT",
+		"mime", "TAG",
+		"vector", "VECTOR", "FLAT", "6", "TYPE", "FLOAT32", "DIM", dimension, "DISTANCE_METRIC", "COSINE",
+	).Err()
+	if err != nil {
+		return fmt.Errorf("error creating image index: %w", err)
+	}
+	return nil
+}
+
+// imageIndexName returns the name of the image vector index of a prefix.
+func imageIndexName(prefix string) string {
+	indexName := "imagesIdx"
+	if prefix != "" {
+		indexName += ":" + prefix
+	}
+	return indexName
+}
+
+// imageKeyPrefix returns the key prefix of the image records of a prefix.
+func imageKeyPrefix(prefix string) string {
+	key := "images:"
+	if prefix != "" {
+		key += prefix + ":"
+	}
+	return key
+}
+
+// loadImageInput returns an ImageInput as a Base64 data URL, remote images larger than limit bytes are rejected.
+func loadImageInput(ctx context.Context, image ImageInput, limit int64) (string, error) {
+	switch {
+	case len(image.Data) > 0:
+		return encodeImage(image.Data, image.MimeType)
+	case image.Path != "":
+		imageData, err := os.ReadFile(image.Path)
+		if err != nil {
+			return "", fmt.Errorf("error reading file: %w", err)
+		}
+		return encodeImage(imageData, image.MimeType)
+	case image.URL != "":
+		imageData, err := fetchImageData(ctx, image.URL, limit)
+		if err != nil {
+			return "", err
+		}
+		return encodeImage(imageData, image.MimeType)
+	}
+	return "", errors.New("image input has no path, url or data")
+}
+
+// float32VectorBytes converts a vector into the little endian FLOAT32 blob used by Redis vector fields.
+func float32VectorBytes(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for idx, value := range vector {
+		binary.LittleEndian.PutUint32(buf[idx*4:], math.Float32bits(value))
+	}
+	return buf
+}
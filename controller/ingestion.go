@@ -0,0 +1,344 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Ingestion job statuses.
+const (
+	IngestionPending   = "pending"
+	IngestionRunning   = "running"
+	IngestionCompleted = "completed"
+	IngestionFailed    = "failed"
+)
+
+// Ingestion webhook events.
+const (
+	IngestionCompletedEvent = "ingestion.completed"
+	IngestionFailedEvent    = "ingestion.failed"
+)
+
+// WebhookSignatureHeader carries the hex HMAC-SHA256 of the webhook body, computed with the webhook secret.
+const WebhookSignatureHeader = "X-Aillm-Signature"
+
+// IngestionJob is the state of an asynchronous embedding started by EmbeddTextAsync, EmbeddFileAsync or EmbeddURLAsync.
+//
+// Fields:
+//   - ID: The job ID.
+//   - Status: pending, running, completed or failed.
+//   - Index, EmbeddingPrefix: Where the document is embedded.
+//   - DocumentId: The Id of the embedded content entry.
+//   - Source: The file name or URL, empty for texts.
+//   - ChunkCount: The number of chunks of the document, set when the job completes.
+//   - Error: The error message of a failed job.
+//   - CreatedAt, CompletedAt: Job timestamps, CompletedAt is zero until the job ends.
+type IngestionJob struct {
+	ID              string    `json:"id"`
+	Status          string    `json:"status"`
+	Index           string    `json:"index"`
+	EmbeddingPrefix string    `json:"prefix,omitempty"`
+	DocumentId      string    `json:"documentId"`
+	Source          string    `json:"source,omitempty"`
+	ChunkCount      int       `json:"chunkCount"`
+	Error           string    `json:"error,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	CompletedAt     time.Time `json:"completedAt,omitempty"`
+}
+
+// IngestionWebhook is called with a POST request when an ingestion job completes or fails.
+//
+// The JSON body has an "event" field (ingestion.completed or ingestion.failed) and the job in "job".
+// If Secret is set, the X-Aillm-Signature header holds the hex HMAC-SHA256 of the body, so the
+// receiver can verify the sender. Failed deliveries are retried 3 times.
+type IngestionWebhook struct {
+	URL    string
+	Secret string
+}
+
+// ingestionWebhookPayload is the body sent to ingestion webhooks.
+type ingestionWebhookPayload struct {
+	Event string       `json:"event"`
+	Job   IngestionJob `json:"job"`
+}
+
+// ingestionManager runs the asynchronous embedding jobs of a container.
+type ingestionManager struct {
+	mu       sync.Mutex
+	jobs     map[string]*IngestionJob
+	webhooks []IngestionWebhook
+	slots    chan struct{}
+	wg       sync.WaitGroup
+	client   *http.Client
+	closed   bool
+}
+
+// completedJobTTL is how long finished jobs can be queried with GetIngestionJob.
+const completedJobTTL = time.Hour
+
+func newIngestionManager(workers int, webhooks []IngestionWebhook) *ingestionManager {
+	// Without a slot no job could ever start
+	if workers <= 0 {
+		workers = 2
+	}
+	return &ingestionManager{
+		jobs:     make(map[string]*IngestionJob),
+		webhooks: append([]IngestionWebhook{}, webhooks...),
+		slots:    make(chan struct{}, workers),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// RegisterIngestionWebhook adds a webhook called when asynchronous ingestion jobs complete or fail.
+//
+// Parameters:
+//   - url: The endpoint receiving the POST requests.
+//   - secret: Optional key signing the requests, see IngestionWebhook.
+//
+// Example Usage:
+//
+//	llm.RegisterIngestionWebhook("https://cms.example.com/hooks/aillm", os.Getenv("WEBHOOK_SECRET"))
+//	jobID := llm.EmbeddURLAsync("Pricing", "https://example.com/pricing", aillm.TranscribeConfig{Language: "en"})
+func (llm *LLMContainer) RegisterIngestionWebhook(url, secret string) {
+	manager := llm.ingestionManager()
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.webhooks = append(manager.webhooks, IngestionWebhook{URL: url, Secret: secret})
+}
+
+// GetIngestionJob returns the state of an asynchronous ingestion job.
+//
+// Finished jobs are kept for an hour.
+//
+// Returns:
+//   - IngestionJob: A copy of the job state.
+//   - bool: False if the job is unknown or expired.
+func (llm *LLMContainer) GetIngestionJob(jobID string) (IngestionJob, bool) {
+	manager := llm.ingestionManager()
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	job, exists := manager.jobs[jobID]
+	if !exists {
+		return IngestionJob{}, false
+	}
+	return *job, true
+}
+
+// EmbeddTextAsync embeds a text in the background, see EmbeddText.
+//
+// Returns:
+//   - string: The job ID, see GetIngestionJob and RegisterIngestionWebhook.
+func (llm *LLMContainer) EmbeddTextAsync(Index string, Contents LLMEmbeddingContent, options ...LLMCallOption) string {
+	return llm.startIngestion(Index, Contents.Id, "", options, func(options []LLMCallOption) (LLMEmbeddingObject, error) {
+		return llm.EmbeddText(Index, Contents, options...)
+	})
+}
+
+// EmbeddFileAsync transcribes and embeds a file in the background, see EmbeddFile.
+//
+// Returns:
+//   - string: The job ID, see GetIngestionJob and RegisterIngestionWebhook.
+func (llm *LLMContainer) EmbeddFileAsync(Index, Title, fileName string, tc TranscribeConfig, options ...LLMCallOption) string {
+	return llm.startIngestion(Index, "", fileName, options, func(options []LLMCallOption) (LLMEmbeddingObject, error) {
+		return llm.EmbeddFile(Index, Title, fileName, tc, options...)
+	})
+}
+
+// EmbeddURLAsync transcribes and embeds a web page in the background, see EmbeddURL.
+//
+// Returns:
+//   - string: The job ID, see GetIngestionJob and RegisterIngestionWebhook.
+func (llm *LLMContainer) EmbeddURLAsync(Index, url string, tc TranscribeConfig, options ...LLMCallOption) string {
+	return llm.startIngestion(Index, "", url, options, func(options []LLMCallOption) (LLMEmbeddingObject, error) {
+		return llm.EmbeddURL(Index, url, tc, options...)
+	})
+}
+
+// startIngestion registers a job and runs embed when a worker is free.
+//
+// The document Id is fixed before the job starts, so the chunk count of the document can be read
+// from the embedding object returned by embed.
+func (llm *LLMContainer) startIngestion(Index, documentID, source string, options []LLMCallOption, embed func(options []LLMCallOption) (LLMEmbeddingObject, error)) string {
+	manager := llm.ingestionManager()
+	o := LLMCallOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+	if documentID == "" {
+		documentID = uuid.New().String()
+	}
+	job := &IngestionJob{
+		ID:              uuid.New().String(),
+		Status:          IngestionPending,
+		Index:           Index,
+		EmbeddingPrefix: o.getEmbeddingPrefix(),
+		DocumentId:      documentID,
+		Source:          source,
+		CreatedAt:       time.Now(),
+	}
+	manager.mu.Lock()
+	manager.jobs[job.ID] = job
+	if manager.closed {
+		job.Status = IngestionFailed
+		job.Error = ErrShutdown.Error()
+		job.CompletedAt = time.Now()
+		manager.mu.Unlock()
+		return job.ID
+	}
+	// Added under the lock, so close can't miss the job
+	manager.wg.Add(1)
+	manager.mu.Unlock()
+
+	options = append(options, withDocumentID(documentID))
+	go func() {
+		defer manager.wg.Done()
+		manager.slots <- struct{}{}
+		defer func() { <-manager.slots }()
+		var result LLMEmbeddingObject
+		var err error
+		manager.update(job, func(job *IngestionJob) {
+			// Jobs still waiting for a worker don't start after Shutdown
+			if manager.closed {
+				err = ErrShutdown
+				return
+			}
+			job.Status = IngestionRunning
+		})
+		if err == nil {
+			result, err = embed(options)
+		}
+		event := IngestionCompletedEvent
+		finished := manager.update(job, func(job *IngestionJob) {
+			job.CompletedAt = time.Now()
+			if err != nil {
+				job.Status = IngestionFailed
+				job.Error = err.Error()
+				return
+			}
+			job.Status = IngestionCompleted
+			job.ChunkCount = len(result.Contents[documentID].Keys)
+		})
+		if err != nil {
+			event = IngestionFailedEvent
+			llm.logger().Error("ingestion job failed", "job", job.ID, "index", Index, "error", err)
+		}
+		manager.notify(llm, ingestionWebhookPayload{Event: event, Job: finished})
+		time.AfterFunc(completedJobTTL, func() {
+			manager.mu.Lock()
+			delete(manager.jobs, job.ID)
+			manager.mu.Unlock()
+		})
+	}()
+	return job.ID
+}
+
+// close rejects new jobs and returns a channel closed when the started jobs are finished.
+func (m *ingestionManager) close() <-chan struct{} {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// update changes a job under the lock and returns a copy of it.
+func (m *ingestionManager) update(job *IngestionJob, change func(job *IngestionJob)) IngestionJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	change(job)
+	return *job
+}
+
+// notify delivers the payload to every registered webhook, a failing webhook doesn't affect the others.
+func (m *ingestionManager) notify(llm *LLMContainer, payload ingestionWebhookPayload) {
+	m.mu.Lock()
+	webhooks := append([]IngestionWebhook{}, m.webhooks...)
+	m.mu.Unlock()
+	if len(webhooks) == 0 {
+		return
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		llm.logger().Error("error converting webhook payload to json", "error", err)
+		return
+	}
+	for _, webhook := range webhooks {
+		if err := m.deliver(webhook, body); err != nil {
+			llm.logger().Warn("ingestion webhook failed", "url", webhook.URL, "job", payload.Job.ID, "error", err)
+		}
+	}
+}
+
+// deliver posts the body to a webhook, retrying 3 times with an increasing delay.
+func (m *ingestionManager) deliver(webhook IngestionWebhook, body []byte) error {
+	var err error
+	for attempt := 0; attempt < 4; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		err = m.post(webhook, body)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (m *ingestionManager) post(webhook IngestionWebhook, body []byte) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if webhook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(webhook.Secret))
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// ingestionManager returns the job manager of the container, created by Init.
+func (llm *LLMContainer) ingestionManager() *ingestionManager {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.ingestion == nil {
+		llm.ingestion = newIngestionManager(llm.IngestionWorkers, llm.IngestionWebhooks)
+	}
+	return llm.ingestion
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInputTooLarge is matched by the *InputLimitError returned when an input exceeds InputLimits.
+var ErrInputTooLarge = errors.New("input exceeds the configured limit")
+
+const (
+	InputReject   = "reject"   // Fails the call with an *InputLimitError
+	InputTruncate = "truncate" // Cuts the input to the limit
+)
+
+// InputLimits bounds the inputs AskLLM puts in the prompt, so oversized prompts are handled before
+// they reach the provider instead of being rejected by it.
+//
+// Lengths are counted in characters. The session history is always truncated, its oldest interactions
+// are dropped first; the query and the extra context follow Policy.
+//
+// Fields:
+//   - MaxQueryLength: Maximum length of the query (or the exact prompt), 0 means unlimited.
+//   - MaxExtraContextLength: Maximum length of the text set with WithExtraContext, 0 means unlimited.
+//   - MaxMemoryLength: Maximum length of the session history added to the prompt, 0 means unlimited.
+//   - Policy: InputReject (default) or InputTruncate, which keeps the start of the query and the extra context.
+type InputLimits struct {
+	MaxQueryLength        int    `json:"maxQueryLength"`
+	MaxExtraContextLength int    `json:"maxExtraContextLength"`
+	MaxMemoryLength       int    `json:"maxMemoryLength"`
+	Policy                string `json:"policy"`
+}
+
+// InputLimitError is returned by AskLLM when an input exceeds InputLimits with the InputReject policy.
+//
+// Fields:
+//   - Input: "query" or "extraContext".
+//   - Length: The length of the input in characters.
+//   - Limit: The configured limit.
+type InputLimitError struct {
+	Input  string
+	Length int
+	Limit  int
+}
+
+func (e *InputLimitError) Error() string {
+	return fmt.Sprintf("%s is %d characters long, the limit is %d", e.Input, e.Length, e.Limit)
+}
+
+// Is reports whether target is ErrInputTooLarge.
+func (e *InputLimitError) Is(target error) bool {
+	return target == ErrInputTooLarge
+}
+
+// truncateStart keeps the first limit characters of text.
+func truncateStart(text string, limit int) string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit])
+}
+
+// truncateHistory keeps the most recent part of a session history, cut at a line start when possible.
+func truncateHistory(history string, limit int) string {
+	if limit <= 0 || utf8.RuneCountInString(history) <= limit {
+		return history
+	}
+	runes := []rune(history)
+	recent := string(runes[len(runes)-limit:])
+	if idx := strings.Index(recent, "\n"); idx >= 0 && idx < len(recent)-1 {
+		recent = recent[idx+1:]
+	}
+	return recent
+}
+
+// limitInput applies a limit to an input according to the policy.
+func (il InputLimits) limitInput(name, text string, limit int) (string, error) {
+	length := utf8.RuneCountInString(text)
+	if limit <= 0 || length <= limit {
+		return text, nil
+	}
+	if il.Policy == InputTruncate {
+		return truncateStart(text, limit), nil
+	}
+	return text, &InputLimitError{Input: name, Length: length, Limit: limit}
+}
+
+// checkInputLimits applies InputLimits to the query and the extra context of a call. The calls the container makes
+// for itself, e.g. the summaries of the persistent memory, aren't limited.
+//
+// Returns:
+//   - string: The query, truncated with the InputTruncate policy.
+//   - error: An *InputLimitError if an input is too long with the InputReject policy.
+func (llm *LLMContainer) checkInputLimits(Query string, o *LLMCallOptions) (string, error) {
+	if o.internal {
+		return Query, nil
+	}
+	var err error
+	if o.ExactPrompt != "" {
+		if o.ExactPrompt, err = llm.InputLimits.limitInput("query", o.ExactPrompt, llm.InputLimits.MaxQueryLength); err != nil {
+			return Query, err
+		}
+	}
+	if Query, err = llm.InputLimits.limitInput("query", Query, llm.InputLimits.MaxQueryLength); err != nil {
+		return Query, err
+	}
+	o.ExtraContext, err = llm.InputLimits.limitInput("extraContext", o.ExtraContext, llm.InputLimits.MaxExtraContextLength)
+	return Query, err
+}
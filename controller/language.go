@@ -0,0 +1,161 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// LanguageTag is a BCP-47 language code, e.g. "en", "pt-BR" or "zh-Hant".
+//
+// Language codes are validated by AskLLM and EmbeddText, so a typo such as "portugese" fails the call instead of
+// silently reaching the prompt. Prompts name the language with DisplayName, e.g. "Brazilian Portuguese".
+type LanguageTag string
+
+// namedLanguages are the languages whose English and native names ParseLanguage accepts in place of their code.
+var namedLanguages = []string{"af", "am", "ar", "az", "be", "bg", "bn", "bs", "ca", "cs", "cy", "da", "de", "el", "en",
+	"es", "et", "eu", "fa", "fi", "fil", "fr", "ga", "gl", "gu", "he", "hi", "hr", "hu", "hy", "id", "is", "it", "ja", "ka",
+	"kk", "km", "kn", "ko", "ku", "ky", "lo", "lt", "lv", "mk", "ml", "mn", "mr", "ms", "my", "nb", "ne", "nl", "no", "pa",
+	"pl", "ps", "pt", "ro", "ru", "si", "sk", "sl", "so", "sq", "sr", "sv", "sw", "ta", "te", "tg", "th", "tk", "tr", "uk",
+	"ur", "uz", "vi", "yo", "zh", "zu"}
+
+var (
+	languageNamesOnce sync.Once
+	languageNames     map[string]string // Lower case English and native names of namedLanguages to their code
+)
+
+func languageByName(name string) (string, bool) {
+	languageNamesOnce.Do(func() {
+		languageNames = make(map[string]string)
+		for _, code := range namedLanguages {
+			tag := language.Make(code)
+			for _, name := range []string{display.English.Languages().Name(tag), display.Self.Name(tag)} {
+				if name != "" {
+					languageNames[strings.ToLower(name)] = code
+				}
+			}
+		}
+	})
+	code, ok := languageNames[strings.ToLower(name)]
+	return code, ok
+}
+
+// ParseLanguage validates a language and returns its canonical BCP-47 code.
+//
+// Besides codes, the English and native names of common languages are accepted, e.g. "Portuguese" or "Deutsch",
+// so configurations written with language names keep working.
+//
+// Parameters:
+//   - value: The language code or name.
+//
+// Returns:
+//   - LanguageTag: The canonical code, e.g. "pt" for "Portuguese" and "en-US" for "en-us".
+//   - error: An error naming the closest known language if the value is neither a code nor a known name.
+//
+// Example Usage:
+//
+//	tag, err := aillm.ParseLanguage("portugese")
+//	// err: invalid language "portugese", did you mean "Portuguese" (pt)?
+func ParseLanguage(value string) (LanguageTag, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("missing language")
+	}
+	if code, ok := languageByName(value); ok {
+		return LanguageTag(code), nil
+	}
+	tag, err := language.Parse(value)
+	if err == nil {
+		return LanguageTag(tag.String()), nil
+	}
+	if suggestion := closestLanguageName(value); suggestion != "" {
+		code, _ := languageByName(suggestion)
+		return "", fmt.Errorf("invalid language %q, did you mean %q (%s)?", value, suggestion, code)
+	}
+	return "", fmt.Errorf("invalid language %q, expected a BCP-47 code such as \"en\" or \"pt-BR\"", value)
+}
+
+// DisplayName returns the English name of the language used in prompts, e.g. "Brazilian Portuguese" for "pt-BR".
+//
+// Values that aren't valid languages are returned unchanged.
+func (t LanguageTag) DisplayName() string {
+	parsed, err := ParseLanguage(string(t))
+	if err != nil {
+		return string(t)
+	}
+	if name := display.English.Tags().Name(language.Make(string(parsed))); name != "" {
+		return name
+	}
+	return string(t)
+}
+
+// baseLanguage returns the base language code of a language code or name, "pt" for "pt-BR", "" if it isn't a language.
+func baseLanguage(language string) string {
+	tag, err := ParseLanguage(language)
+	if err != nil {
+		return ""
+	}
+	base, _, _ := strings.Cut(string(tag), "-")
+	return base
+}
+
+// checkLanguage validates the language of a call, an empty language is left to the defaults.
+func checkLanguage(value string) error {
+	if value == "" {
+		return nil
+	}
+	_, err := ParseLanguage(value)
+	return err
+}
+
+// closestLanguageName returns the English name of namedLanguages closest to a misspelled name, "" if none is close.
+func closestLanguageName(value string) string {
+	value = strings.ToLower(value)
+	best, bestDistance := "", 3 // More than two edits is not a typo
+	for _, code := range namedLanguages {
+		name := display.English.Languages().Name(language.Make(code))
+		if distance := editDistance(value, strings.ToLower(name)); distance < bestDistance {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance of two strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(rb)]
+}
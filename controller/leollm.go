@@ -16,11 +16,14 @@ package aillm
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/schema"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // LLMConfig struct holds configuration details for the embedding and AI model service.
@@ -33,10 +36,17 @@ import (
 //   - Apiurl: The API endpoint URL of the LLM service for sending requests.
 //   - AiModel: The specific AI model to be used for embedding or inference operations.
 //   - APIToken: Authentication token required to access the API (e.g., for OpenAI services).
+//   - RequestsPerMinute: Optional client-side limit of requests per minute, requests above it are queued.
+//   - TokensPerMinute: Optional client-side limit of (estimated) prompt tokens per minute.
+//
+// Rate limits are kept per container and shared by all its clients using the same Apiurl and AiModel. Changed
+// limits apply to the next request.
 type LLMConfig struct {
-	Apiurl   string // API endpoint for the LLM service
-	AiModel  string // Name of the AI model to be used
-	APIToken string // API key required for authorization (e.g., for OpenAI or OVHCloud)
+	Apiurl            string // API endpoint for the LLM service
+	AiModel           string // Name of the AI model to be used
+	APIToken          string // API key required for authorization (e.g., for OpenAI or OVHCloud)
+	RequestsPerMinute int    // Client-side request rate limit, 0 disables it
+	TokensPerMinute   int    // Client-side token rate limit, 0 disables it
 }
 
 // LLMResult represents the result of an LLM query, including the generated response, retrieved documents, and logged actions.
@@ -47,14 +57,27 @@ type LLMConfig struct {
 //
 // Fields:
 //   - Prompt: A slice of MessageContent representing the constructed query prompt sent to the LLM.
-//   - RagDocs: An interface containing the retrieved documents (e.g., schema.Document) used in the RAG process.
+//   - RagDocs: The retrieved documents used in the RAG process, see RetrievedDocument.
 //   - Response: A pointer to the ContentResponse generated by the LLM, containing the AI's output and metadata.
 //   - Memory: A slice of strings representing session-based memory for context-aware interactions.
 //   - Actions: A slice of LLMAction structs, each representing a logged action or milestone during the query lifecycle.
 //   - FailedToRespond: A boolean indicating if the LLM failed to respond.
+//   - RagImages: Images retrieved from the image index when WithImageRetrieval is used.
+//   - Timings: Latency breakdown of retrieval, time-to-first-token and generation.
+//   - DebugInfo: The rendered prompt and retrieval settings, only set with WithDebug(true).
+//   - RequestID: The correlation ID set with WithRequestID.
+//   - PIIMatches: Personal data found in the answer when PII.OnOutput is set, see PIIConfig.
+//   - Moderation: The moderation results of the query and the answer, see ModerationConfig.
+//   - Groundedness: The support of the answer by the retrieved documents, set by WithGroundednessCheck.
+//   - Citations: The references of the answer with their verification, set by WithRagReferences.
+//   - TraceID: The ID of the trace persisted in the TraceStore, read it with GetTrace.
+//   - Confidence: The estimated confidence of the answer, set when documents were searched.
+//   - WebSources: The web pages the answer is based on when the web search fallback answered, see WebFallbackConfig.
+//   - GlossaryFixes: The glossary terms corrected in the answer when the glossary is enforced, see SetGlossary.
+//   - MatchedLanguage: The language of the retrieved documents, the call language or the fallback language that found them.
 type LLMResult struct {
 	Prompt          []llms.MessageContent
-	RagDocs         []schema.Document
+	RagDocs         []RetrievedDocument
 	Response        *llms.ContentResponse
 	Memory          []MemoryData
 	MemorySummary   string
@@ -63,6 +86,42 @@ type LLMResult struct {
 	TokenReport     TokenReport
 	FailedToRespond bool
 	Warning         string
+	RagImages       []EmbeddedImage
+	Timings         LLMTimings
+	DebugInfo       *LLMDebugInfo
+	RequestID       string
+	PIIMatches      []PIIMatch
+	Moderation      []ModerationReport
+	Groundedness    *GroundednessReport
+	Citations       []Citation
+	TraceID         string
+	Confidence      *Confidence
+	WebSources      []WebSearchResult
+	GlossaryFixes   []string
+	MatchedLanguage string
+	retrievalQuery  string          // The retrieval text recorded in the trace
+	retrievalPrefix string          // The retrieval prefix recorded in the trace
+	searchAlgorithm SearchAlgorithm // The search algorithm recorded in the trace
+}
+
+// LLMDebugInfo describes exactly what AskLLM sent to the model, it is only set with WithDebug(true).
+//
+// Fields:
+//   - SystemPrompt: The fully rendered system messages, including the RAG context.
+//   - RetrievalPrefix: The vector store prefix used for retrieval, comma separated when several indexes are searched.
+//   - FallbackRetrievalPrefix: The prefixes of the fallback languages searched when retrieval finds nothing, comma separated.
+//   - RetrievalQuery: The text used for retrieval (query plus session memory and attachments).
+//   - SearchAlgorithm: The search algorithm chosen for the query.
+//   - SearchAlgorithmName: The readable name of the search algorithm.
+//   - TranslatedQuery: The translated query that found the documents, see CrossLingualConfig.
+type LLMDebugInfo struct {
+	SystemPrompt            string `json:"systemPrompt"`
+	RetrievalPrefix         string `json:"retrievalPrefix"`
+	FallbackRetrievalPrefix string `json:"fallbackRetrievalPrefix,omitempty"`
+	RetrievalQuery          string `json:"retrievalQuery"`
+	SearchAlgorithm         int    `json:"searchAlgorithm"`
+	SearchAlgorithmName     string `json:"searchAlgorithmName"`
+	TranslatedQuery         string `json:"translatedQuery,omitempty"`
 }
 
 // TokenUsage represents the usage of tokens in a specific context.
@@ -88,22 +147,50 @@ type TokenUsage struct {
 //   - TextChunkingTokens: The number of tokens used in the text chunking.
 //   - LanguageDetectionTokens: The number of tokens used in the language detection.
 //   - MemorySummarizationTokens: The number of tokens used in the memory summarization.
+//   - VisionTokens: The number of tokens used by vision (image description) requests.
+//   - GroundednessTokens: The number of tokens used by the groundedness check.
+//   - MapReduceTokens: The number of tokens used to extract facts from large retrievals, see MapReduceConfig.
+//   - TranslationTokens: The number of tokens used to translate the query, see CrossLingualConfig.
 type TokenReport struct {
 	CompletionTokens          TokenUsage
 	TextChunkingTokens        TokenUsage
 	LanguageDetectionTokens   TokenUsage
 	MemorySummarizationTokens TokenUsage
 	SecurityCheckTokens       TokenUsage
-}
-
-type llmReference struct {
-	References []string `json:"references"`
+	VisionTokens              TokenUsage
+	GroundednessTokens        TokenUsage
+	MapReduceTokens           TokenUsage
+	TranslationTokens         TokenUsage
 }
 
 // Each action should be a timestamp for benchmarking or output management
+//
+// Fields:
+//   - Action: The action label.
+//   - TimeStamp: The time the action happened.
+//   - Elapsed: Time since the first action of the query.
+//   - Duration: Time since the previous action, i.e. the duration of the previous stage.
+//   - RequestID: The correlation ID set with WithRequestID.
 type LLMAction struct {
-	Action    interface{} `json:"action"`
-	TimeStamp time.Time   `json:"timestamp"`
+	Action    interface{}   `json:"action"`
+	TimeStamp time.Time     `json:"timestamp"`
+	Elapsed   time.Duration `json:"elapsed"`
+	Duration  time.Duration `json:"duration"`
+	RequestID string        `json:"requestId,omitempty"`
+}
+
+// LLMTimings summarizes the latency of the stages of an AskLLM call.
+//
+// Fields:
+//   - Retrieval: Time spent retrieving documents.
+//   - TimeToFirstToken: Time between sending the request to the LLM and receiving the first chunk.
+//   - Generation: Time between sending the request to the LLM and receiving the complete response.
+//   - Total: Total duration of the AskLLM call.
+type LLMTimings struct {
+	Retrieval        time.Duration `json:"retrieval"`
+	TimeToFirstToken time.Duration `json:"timeToFirstToken"`
+	Generation       time.Duration `json:"generation"`
+	Total            time.Duration `json:"total"`
 }
 
 // LangchainGo tools plus handlers
@@ -139,16 +226,47 @@ type LLMCallOptions struct {
 	UseLLMToSplitText        bool
 	IncludeDate              bool
 	RagReferences            bool
-	SearchAlgorithm          int
+	SearchAlgorithm          SearchAlgorithm
 	ignoreSecurityCheck      bool
+	securityCheck            *bool
+	validators               []AnswerValidator
+	groundednessCheck        *bool
 	debug                    bool
 	maxWords                 int
 	customModel              string
 	asyncMemorySummarization bool
+	toolErrorRecovery        bool
+	imageRowCount            int
+	imageAttachments         []ImageInput
+	requestID                string
+	timeout                  time.Duration
+	seed                     *int
+	webFallback              *bool
+	documentID               string
+	tenantID                 string
+	internal                 bool
+	indexes                  []string
+	prefixes                 []string
+	indexCap                 int
+	indexBoosts              map[string]float64
+	mapReduce                *bool
+	glossary                 map[string]string
+	crossLingual             *bool
+	outputSchema             map[string]any
+	temperature              *float64
+	topP                     *float64
+	ragRowCount              *int
+	characterName            string
+	deleteProgress           func(pattern string, deleted int)
+	aliases                  *prefixAliasRegistry
+	physicalPrefix           string
+	keepUpdatedAt            bool
 }
 
 // LLMClient defines an interface for creating a new LLM (Large Language Model) client instance.
 //
+// Every model call of the container goes through it, replace it with aillmtest.FakeLLM to test chat flows offline.
+//
 // Methods:
 //   - NewLLMClient(): Creates and returns an instance of an LLM model, or returns an error if the initialization fails.
 //   - GetConfig(): returns configuration of current instance of LLM model.
@@ -163,9 +281,13 @@ type LLMClient interface {
 // Fields:
 //   - ChunkSize: The size of each chunk to be created when splitting text for embedding purposes.
 //   - ChunkOverlap: The number of overlapping characters between consecutive chunks to maintain context.
+//   - VectorType: How the chunk vectors are stored, FLOAT32 by default, see VectorType.
+//   - HNSW: The parameters of HNSW vector indexes, nil for the default FLAT indexes, see HNSWConfig.
 type EmbeddingConfig struct {
-	ChunkSize    int // Size of each text chunk for embedding
-	ChunkOverlap int // Number of overlapping characters between chunks
+	ChunkSize    int         // Size of each text chunk for embedding
+	ChunkOverlap int         // Number of overlapping characters between chunks
+	VectorType   VectorType  // Storage type of the vectors
+	HNSW         *HNSWConfig // HNSW index parameters
 }
 
 // RedisClient manages the connection details for a Redis database instance used for storing embeddings.
@@ -180,63 +302,192 @@ type RedisClient struct {
 	redisClient *redis.Client // Redis client instance for operations
 }
 
-const (
-	NotDefinedSearch  = 0 // Default search - will use system default
-	SimilaritySearch  = 1 // Cosine similarity search only
-	KNearestNeighbors = 2 // K-Nearest Neighbors search only
-	NoSearch          = 3 // No search performed
-	HybridSearch      = 4 // Hybrid search (vector + lexical search with RRF)
-	LexicalSearch     = 5 // Lexical/keyword search only
-	SemanticSearch    = 6 // Enhanced semantic search (auto-selects best algorithm)
+// SearchAlgorithm selects how AskLLM retrieves the documents of a question, see WithSimilaritySearch, WithKNNSearch,
+// WithHybridSearch, WithLexicalSearch and WithSemanticSearch.
+type SearchAlgorithm int
 
+const (
+	NotDefinedSearch  SearchAlgorithm = 0 // Default search - will use system default
+	SimilaritySearch  SearchAlgorithm = 1 // Cosine similarity search only
+	KNearestNeighbors SearchAlgorithm = 2 // K-Nearest Neighbors search only
+	NoSearch          SearchAlgorithm = 3 // No search performed
+	HybridSearch      SearchAlgorithm = 4 // Hybrid search (vector + lexical search with RRF)
+	LexicalSearch     SearchAlgorithm = 5 // Lexical/keyword search only
+	SemanticSearch    SearchAlgorithm = 6 // Enhanced semantic search (auto-selects best algorithm)
 )
 
+// String returns the name of a search algorithm used in metrics, traces and configuration files, e.g. "hybrid".
+func (sa SearchAlgorithm) String() string {
+	switch sa {
+	case NotDefinedSearch:
+		return "default"
+	case SimilaritySearch:
+		return "similarity"
+	case KNearestNeighbors:
+		return "knn"
+	case NoSearch:
+		return "none"
+	case HybridSearch:
+		return "hybrid"
+	case LexicalSearch:
+		return "lexical"
+	case SemanticSearch:
+		return "semantic"
+	}
+	return "unknown"
+}
+
+// ParseSearchAlgorithm returns the search algorithm of a name returned by SearchAlgorithm.String, e.g. "knn".
+func ParseSearchAlgorithm(name string) (SearchAlgorithm, error) {
+	for algorithm := SimilaritySearch; algorithm <= SemanticSearch; algorithm++ {
+		if algorithm.String() == strings.ToLower(strings.TrimSpace(name)) {
+			return algorithm, nil
+		}
+	}
+	return NotDefinedSearch, fmt.Errorf("unknown search algorithm %q", name)
+}
+
 // LLMContainer serves as the main struct that manages LLM operations, embedding configurations, and data storage.
 //
 // It acts as a container for managing various components required for interacting with
 // an AI model, embedding data, and handling queries and responses.
 //
+// After Init a single container is safe for concurrent use, e.g. by all requests of an HTTP server. Its
+// configuration fields must not be changed while requests are running; call Reconnect after changing clients.
+//
 // Fields:
 //   - Embedder: The embedding client responsible for processing and storing text embeddings.
 //   - EmbeddingConfig: Configuration settings for text chunking operations.
 //   - LLMClient: The LLM client that provides access to the AI model for generating responses.
 //   - MemoryManager: A memory management component that stores session-related data.
 //   - LLMModelLanguageDetectionCapability: A boolean indicating if the model supports automatic language detection.
-//   - AnswerLanguage: The preferred language for responses from the model.
+//   - AnswerLanguage: The preferred language for responses from the model, a BCP-47 code such as "en" or "pt-BR".
 //   - RedisClient: Redis client for caching embeddings and retrieval operations.
 //   - Temperature: Controls the randomness of the AI's responses (lower values = more deterministic).
 //   - TopP: Probability threshold for response generation (higher values = more diverse responses).
 //   - ScoreThreshold: The similarity threshold for retrieval-augmented generation (RAG).
 //   - RagRowCount: The number of RAG rows to retrieve and analyze for context.
 //   - AllowHallucinate: Determines if the model can generate responses without relevant data (true/false).
-//   - FallbackLanguage: The default language to use if the primary language is unavailable.
+//   - FallbackLanguage: The default language to use if the primary language is unavailable, see FallbackLanguages.
 //   - NoRagErrorMessage: The error message to display if no relevant data is found during retrieval.
 //   - NotRelatedAnswer: A predefined response when the model cannot find relevant information.
 //   - Character: A personality trait or characteristic assigned to the AI assistant (e.g., formal, friendly).
 //   - Transcriber: Component responsible for converting speech or text inputs into usable data.
+//   - ToolAuditSink: Optional sink receiving a record for every tool invocation.
+//   - AuditSink: Optional sink receiving the query, retrieved document IDs, answer hash and timings of every AskLLM call.
+//   - TraceStore: Optional store persisting the trace of every AskLLM call (retrieved chunks, prompt hash, answer), see GetTrace.
+//   - Logger: Structured logger for warnings, errors and debug output (prompts and chunks are logged at debug level with WithDebug).
+//   - Tracer: Optional OpenTelemetry tracer, e.g. otel.Tracer("aillm"), used to trace each stage of the pipeline.
+//   - FailoverLLMClient: Optional secondary provider receiving completions when LLMClient is unhealthy.
+//   - BeforeRetrieve, AfterRetrieve, BeforePrompt, AfterGenerate: Optional hooks to inject custom logic into AskLLM.
+//   - IngestionWebhooks: Endpoints notified when EmbeddTextAsync, EmbeddFileAsync or EmbeddURLAsync jobs end.
+//   - Tenants, RequireTenant: Isolate the documents and sessions of the customers of a shared container, see Tenant.
+//   - SessionQuota: Request and token limits applied to each SessionID, see Quota.
+//   - UsageRetention: How long the daily usage read by GetUsage is kept, 0 keeps it forever.
+//   - PII: Masks, drops or flags personal data in embedded documents and answers, see PIIConfig.
+//   - DisableSecurityCheck: Disables the content-safety and prompt-injection classifier run on each query by default.
+//   - Moderation: Blocks, redacts or annotates harmful queries and answers, see ModerationConfig.
+//   - Guardrails: Validates answers and asks the model to correct invalid ones, see GuardrailConfig.
+//   - GroundednessCheck: Verifies every answer against the retrieved documents, see WithGroundednessCheck.
+//   - KeepUnverifiedReferences: Keeps references that aren't retrieved documents, or whose quotes aren't found, in
+//     LLMResult.LLMReferences. They are dropped by default and always flagged in LLMResult.Citations.
+//   - Retention: Max ages of personal data enforced in the background, see RetentionPolicy and PurgeUserData.
+//   - KeyProvider: Enables envelope encryption of the stored documents (rawDocs) and chunk texts, see KeyProvider.
+//     Encrypted chunks can only be found by a vector search, lexical and hybrid searches fail.
+//   - Timeouts: Bounds every call to the LLM, embedding, Tika and Redis services, see WithTimeout.
+//   - InputLimits: Rejects or truncates oversized queries, extra contexts and session histories, see InputLimits.
+//   - LowConfidenceThreshold: Answers with a lower Confidence.Score are flagged as Low (default 0.5).
+//   - WebFallback: Answers from a web search when retrieval finds nothing and hallucination is disallowed, see WebFallbackConfig.
+//   - IndexBoosts: Score multipliers of indexes or embedding prefixes searched together, see WithIndexBoosts.
+//   - MapReduce: Answers from retrievals larger than a token budget in two steps, see MapReduceConfig.
+//   - CrossLingual: Translates queries finding nothing into the languages of the documents, see CrossLingualConfig.
+//   - FallbackLanguages: The languages searched in order until one finds documents when the call language finds none,
+//     e.g. []string{"pt", "en", "es"}. It replaces FallbackLanguage, and its first language is used by index searches without language.
+//   - ConcurrentFallbackSearch: Searches the call language and the fallback languages concurrently and uses the
+//     documents of the language with the best scoring document, so the worst case costs one search instead of one per language.
+//   - Location: The timezone of the users, WithIncludeDate gives the model the current date in it (default: the server's).
+//   - Retriever: Searches the documents in place of the Redis vector store when set, see Retriever.
+//   - SessionMemory: Stores the session history in place of MemoryManager when set, see MemoryStore.
 type LLMContainer struct {
-	Embedder                            EmbeddingClient   // Embedding client to handle text processing
-	EmbeddingConfig                     EmbeddingConfig   // Configuration for text chunking
-	LLMClient                           LLMClient         // AI model client for generating responses
-	VisionClient                        LLMClient         // AI model client for image vision responses
-	MemoryManager                       *MemoryManager    // Session-based memory management
-	LLMModelLanguageDetectionCapability bool              // Language detection capability flag
-	userLanguage                        map[string]string // User session language
-	AnswerLanguage                      string            // Default answer language - will be ignored if  LLMModelLanguageDetectionCapability = true
-	RedisClient                         RedisClient       // Redis client for caching and retrieval
-	SearchAlgorithm                     int               // Semantic search algorithm Cosine Similarity or The k-nearest neighbors
-	Temperature                         float64           // Controls randomness of model output
-	TopP                                float64           // Probability threshold for response diversity
-	ScoreThreshold                      float32           // Threshold for RAG-based responses
-	RagRowCount                         int               // Number of RAG rows to retrieve for context
-	AllowHallucinate                    bool              // Enables/disables AI-generated responses when data is
-	FallbackLanguage                    string            // Default language fallback
-	NoRagErrorMessage                   string            // Message shown when RAG results are empty
-	NotRelatedAnswer                    string            // Predefined response for unrelated queries
-	Character                           string            // AI assistant's character/personality settings
-	Transcriber                         Transcriber       // Responsible for processing and transcribing content
-	PersistentMemoryManager             PersistentMemory  // Advanced Memory manager controller
-	ShowWarnings                        bool              // Mute warnings
+	Embedder                            EmbeddingClient          // Embedding client to handle text processing
+	EmbeddingConfig                     EmbeddingConfig          // Configuration for text chunking
+	LLMClient                           LLMClient                // AI model client for generating responses
+	VisionClient                        LLMClient                // AI model client for image vision responses
+	MemoryManager                       *MemoryManager           // Session-based memory management
+	LLMModelLanguageDetectionCapability bool                     // Language detection capability flag
+	AnswerLanguage                      LanguageTag              // Default answer language - will be ignored if  LLMModelLanguageDetectionCapability = true
+	RedisClient                         RedisClient              // Redis client for caching and retrieval
+	SearchAlgorithm                     SearchAlgorithm          // Semantic search algorithm Cosine Similarity or The k-nearest neighbors
+	Temperature                         float64                  // Controls randomness of model output
+	TopP                                float64                  // Probability threshold for response diversity
+	ScoreThreshold                      float32                  // Threshold for RAG-based responses
+	RagRowCount                         int                      // Number of RAG rows to retrieve for context
+	AllowHallucinate                    bool                     // Enables/disables AI-generated responses when data is
+	FallbackLanguage                    string                   // Default language fallback
+	NoRagErrorMessage                   string                   // Message shown when RAG results are empty
+	NotRelatedAnswer                    string                   // Predefined response for unrelated queries
+	Character                           string                   // AI assistant's character/personality settings
+	Transcriber                         Transcriber              // Responsible for processing and transcribing content
+	PersistentMemoryManager             PersistentMemory         // Advanced Memory manager controller
+	ShowWarnings                        bool                     // Mute warnings
+	ToolAuditSink                       ToolAuditSink            // Optional destination for tool invocation audit records
+	AuditSink                           AuditSink                // Optional destination for the audit records of AskLLM calls
+	TraceStore                          TraceStore               // Optional store of the traces of AskLLM calls
+	MaxImageSize                        int64                    // Maximum accepted image size in bytes for vision calls (default 20MB)
+	VisionWorkers                       int                      // Number of concurrent requests used by DescribeImages (default 4)
+	VisionTimeout                       time.Duration            // Timeout of a single vision request (default 60s), local models on CPU may need more
+	TTSClient                           TTSClient                // Optional text-to-speech engine used by SpeakResponse
+	VisionCacheTTL                      time.Duration            // Cache lifetime of image descriptions in Redis, 0 disables the cache
+	Tracer                              trace.Tracer             // Optional OpenTelemetry tracer for AskLLM, retrieval, embedding, transcription and Redis spans
+	metrics                             *llmMetrics              // Prometheus metrics exposed by Metrics()
+	Logger                              *slog.Logger             // Structured logger, defaults to slog.Default()
+	RetryPolicy                         RetryPolicy              // Retries of transient LLM, embedding and vision failures (default 3 attempts)
+	ImageEmbedder                       ImageEmbeddingClient     // Optional CLIP-style model for image embeddings
+	CircuitBreaker                      CircuitBreakerConfig     // Short-circuits requests to endpoints that failed repeatedly (default 5 failures, 30s)
+	FailoverLLMClient                   LLMClient                // Optional provider used when LLMClient fails or its circuit is open
+	storeCache                          *vectorStoreCache        // Embedder and redisvector stores reused between calls
+	modelCache                          *llmModelCache           // LLM models reused between calls, reset by Reconnect
+	BeforeRetrieve                      BeforeRetrieveHook       // Optional hook rewriting the retrieval query
+	AfterRetrieve                       AfterRetrieveHook        // Optional hook filtering or augmenting the retrieved documents
+	BeforePrompt                        BeforePromptHook         // Optional hook adjusting the messages sent to the model
+	AfterGenerate                       AfterGenerateHook        // Optional hook post-processing the model response
+	IngestionWebhooks                   []IngestionWebhook       // Webhooks called when asynchronous ingestion jobs complete or fail
+	IngestionWorkers                    int                      // Number of asynchronous ingestion jobs running concurrently (default 2)
+	ingestion                           *ingestionManager        // Asynchronous ingestion jobs
+	lifecycle                           *containerLifecycle      // In-flight calls awaited by Shutdown
+	Tenants                             []Tenant                 // Tenants registered by Init, see WithTenant
+	RequireTenant                       bool                     // Rejects calls without WithTenant, for multi-tenant services
+	SessionQuota                        Quota                    // Limits of each session, zero means unlimited
+	UsageRetention                      time.Duration            // Retention of the usage accounting, 0 keeps it forever
+	PII                                 PIIConfig                // Detection and redaction of personal data
+	DisableSecurityCheck                bool                     // Skips the content-safety classifier unless WithSecurityCheck(true) is used
+	Moderation                          ModerationConfig         // Moderation of queries and answers
+	Guardrails                          GuardrailConfig          // Validators of the answers, failing answers are generated again
+	GroundednessCheck                   bool                     // Verifies answers against the retrieved documents
+	KeepUnverifiedReferences            bool                     // Keeps fabricated references in LLMReferences, they are only flagged in Citations
+	KeyProvider                         KeyProvider              // Encrypts stored documents and chunk texts when set, vectors stay plain
+	dataKeys                            *dataKeyCache            // Unwrapped data keys of encrypted documents
+	Retention                           RetentionPolicy          // Max ages of memories, usage accounting and audit records
+	retention                           *retentionWorker         // Enforces Retention in the background
+	tenants                             *tenantRegistry          // Registered tenants
+	Timeouts                            Timeouts                 // Deadlines of LLM, embedding, Tika and Redis calls
+	InputLimits                         InputLimits              // Maximum query, extra context and memory lengths
+	prefixRetrieval                     *prefixSettings          // Retrieval settings of prefixes, see SetRetrievalSettings
+	LowConfidenceThreshold              float64                  // Confidence score below which answers are flagged as low confidence
+	WebFallback                         WebFallbackConfig        // Web search used when retrieval finds no document
+	IndexBoosts                         map[string]float64       // Score multipliers of indexes and embedding prefixes
+	MapReduce                           MapReduceConfig          // Map-reduce answering of retrievals exceeding a token budget
+	CrossLingual                        CrossLingualConfig       // Translation of queries finding nothing into the document languages
+	glossaries                          *glossaryRegistry        // Glossaries of embedding prefixes, see SetGlossary
+	FallbackLanguages                   []string                 // Languages searched in order when the call language finds nothing
+	Location                            *time.Location           // Timezone of the dates of WithIncludeDate, the server's when nil
+	Retriever                           Retriever                // Document search replacing the Redis vector store
+	SessionMemory                       MemoryStore              // Session history replacing MemoryManager
+	characters                          *characterRegistry       // Named characters, see RegisterCharacter
+	prefixAliases                       *prefixAliasRegistry     // Cached prefix aliases, see SetPrefixAlias
+	circuitBreakers                     *circuitBreakerRegistry  // Circuit breakers of the endpoints, see CircuitBreaker
+	providerLimiters                    *providerLimiterRegistry // Rate limiters of the endpoints, see LLMConfig
+	ConcurrentFallbackSearch            bool                     // Searches all the languages at once and picks the best scoring
 }
 
 // getRedisHost constructs the Redis connection URL based on the stored Redis host and password.
@@ -282,6 +533,11 @@ func (la *LLMResult) addAction(action interface{}, callback func(action LLMActio
 	curAction := LLMAction{
 		Action:    action,
 		TimeStamp: time.Now(),
+		RequestID: la.RequestID,
+	}
+	if len(la.Actions) > 0 {
+		curAction.Elapsed = curAction.TimeStamp.Sub(la.Actions[0].TimeStamp)
+		curAction.Duration = curAction.TimeStamp.Sub(la.Actions[len(la.Actions)-1].TimeStamp)
 	}
 	if callback != nil {
 		callback(curAction)
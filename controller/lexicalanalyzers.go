@@ -0,0 +1,94 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// lexicalLanguageField is the hash field holding the RediSearch language of a chunk, see lexicalLanguage.
+const lexicalLanguageField = "language"
+
+// redisearchLanguages are the languages RediSearch stems, by language code.
+var redisearchLanguages = map[string]string{
+	"ar": "arabic", "ca": "catalan", "da": "danish", "de": "german", "el": "greek", "en": "english", "es": "spanish",
+	"eu": "basque", "fi": "finnish", "fr": "french", "ga": "irish", "hi": "hindi", "hu": "hungarian", "hy": "armenian",
+	"id": "indonesian", "it": "italian", "lt": "lithuanian", "nb": "norwegian", "ne": "nepali", "nl": "dutch",
+	"no": "norwegian", "pt": "portuguese", "ro": "romanian", "ru": "russian", "sr": "serbian", "sv": "swedish",
+	"ta": "tamil", "tr": "turkish", "yi": "yiddish", "zh": "chinese",
+}
+
+// lexicalStopwords are the words left out of lexical searches, by language code. RediSearch only ships English
+// stopwords, the list of the language of a text index replaces them.
+var lexicalStopwords = map[string][]string{
+	"en": {"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "if", "in", "into", "is", "it", "no", "not",
+		"of", "on", "or", "such", "that", "the", "their", "then", "there", "these", "they", "this", "to", "was", "will",
+		"with", "what", "which", "who", "how", "does", "do"},
+	"de": {"der", "die", "das", "den", "dem", "des", "ein", "eine", "einen", "einem", "einer", "und", "oder", "aber",
+		"ist", "sind", "war", "mit", "von", "zu", "zum", "zur", "im", "in", "auf", "für", "an", "als", "auch", "nicht",
+		"es", "wie", "was", "wer", "ich", "sie", "wir"},
+	"es": {"el", "la", "los", "las", "un", "una", "unos", "unas", "y", "o", "pero", "de", "del", "al", "en", "con",
+		"por", "para", "que", "es", "son", "se", "su", "sus", "lo", "como", "qué", "cuál", "no", "más"},
+	"fr": {"le", "la", "les", "un", "une", "des", "du", "de", "et", "ou", "mais", "est", "sont", "en", "dans", "avec",
+		"pour", "par", "sur", "que", "qui", "quoi", "ce", "cette", "ces", "il", "elle", "ne", "pas", "au", "aux"},
+	"it": {"il", "lo", "la", "i", "gli", "le", "un", "uno", "una", "e", "o", "ma", "di", "del", "della", "in", "con",
+		"per", "su", "da", "che", "chi", "è", "sono", "non", "come", "al", "alla"},
+	"nl": {"de", "het", "een", "en", "of", "maar", "van", "in", "op", "met", "voor", "aan", "is", "zijn", "was", "dat",
+		"die", "wat", "wie", "hoe", "niet", "te", "er", "om"},
+	"pt": {"o", "a", "os", "as", "um", "uma", "uns", "umas", "e", "ou", "mas", "de", "do", "da", "dos", "das", "em",
+		"no", "na", "nos", "nas", "com", "por", "para", "que", "qual", "é", "são", "se", "não", "como", "ao"},
+	"fa": {"و", "در", "به", "از", "که", "این", "آن", "با", "برای", "را", "است", "هست", "بود", "شود", "می", "ها", "های",
+		"یک", "تا", "بر", "هم", "نیز", "چه", "چی", "کدام"},
+}
+
+// lexicalLanguage returns the RediSearch language of a language code, "" if RediSearch doesn't stem it.
+func lexicalLanguage(language string) string {
+	return redisearchLanguages[baseLanguage(language)]
+}
+
+// prefixLanguage returns the language of a retrieval prefix, its last segment, e.g. "pt" for "context:docs:faq:pt:".
+func prefixLanguage(prefix string) string {
+	segments := strings.Split(strings.TrimSuffix(prefix, ":"), ":")
+	return segments[len(segments)-1]
+}
+
+// isLexicalStopword reports whether a word of a query is a stopword of a language.
+func isLexicalStopword(language, word string) bool {
+	word = strings.ToLower(word)
+	for _, stopword := range lexicalStopwords[baseLanguage(language)] {
+		if word == stopword {
+			return true
+		}
+	}
+	return false
+}
+
+// textIndexOptions returns the FT.CREATE options of the text index of a retrieval prefix: the stemming language of
+// the prefix as default, the language field of the chunks and the stopwords of the language.
+func textIndexOptions(prefix string) []any {
+	language := prefixLanguage(prefix)
+	options := []any{}
+	if redisearchLanguage := lexicalLanguage(language); redisearchLanguage != "" {
+		options = append(options, "LANGUAGE", redisearchLanguage)
+	}
+	options = append(options, "LANGUAGE_FIELD", lexicalLanguageField)
+	if stopwords := lexicalStopwords[baseLanguage(language)]; len(stopwords) > 0 {
+		options = append(options, "STOPWORDS", strconv.Itoa(len(stopwords)))
+		for _, stopword := range stopwords {
+			options = append(options, stopword)
+		}
+	}
+	return options
+}
@@ -15,17 +15,18 @@ package aillm
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"math"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/schema"
@@ -62,9 +63,17 @@ func (llm *LLMContainer) Init() error {
 
 	// Retrieve Tika service URL from environment variables for text processing
 
-	llm.Transcriber.TikaURL = os.Getenv("TikaURL")
+	if llm.Transcriber.TikaURL == "" {
+		llm.Transcriber.TikaURL = os.Getenv("TikaURL")
+	}
+	if llm.Transcriber.Logger == nil {
+		llm.Transcriber.Logger = llm.logger()
+	}
+	if llm.Transcriber.Timeout == 0 {
+		llm.Transcriber.Timeout = llm.Timeouts.tikaTimeout()
+	}
 	if llm.Transcriber.TikaURL == "" && llm.ShowWarnings {
-		log.Println("Warning: Tika host configuration is missing. As a result, the transcriber will be restricted to processing only text and HTML files.")
+		llm.logger().Warn("Tika host configuration is missing. As a result, the transcriber will be restricted to processing only text and HTML files.")
 
 	}
 	// Initialize the transcriber component
@@ -76,24 +85,57 @@ func (llm *LLMContainer) Init() error {
 		llm.RedisClient.Host = os.Getenv("REDIS_HOST")
 		llm.RedisClient.Password = os.Getenv("REDIS_PASSWORD")
 	}
-	// Check if Redis host is configured, return an error if missing
+	// Check if Redis host is configured, return an error if missing. A container with its own Retriever
+	// runs without Redis, e.g. in tests.
 
-	if llm.RedisClient.Host == "" {
-		return errors.New("missing redis host configuration")
+	if llm.RedisClient.Host == "" && llm.Retriever == nil {
+		return fmt.Errorf("%w: missing redis host configuration", ErrNoRedis)
 	}
 
 	// Establish a connection to the Redis server
-	llm.RedisClient.redisClient = redis.NewClient(&redis.Options{
-		Addr:        llm.RedisClient.Host,
-		Password:    llm.RedisClient.Password,
-		DB:          0,
-		DialTimeout: 5 * time.Second,
-	})
+	if llm.RedisClient.Host != "" {
+		llm.RedisClient.redisClient = redis.NewClient(&redis.Options{
+			Addr:         llm.RedisClient.Host,
+			Password:     llm.RedisClient.Password,
+			DB:           0,
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  llm.Timeouts.redisTimeout(),
+			WriteTimeout: llm.Timeouts.redisTimeout(),
+		})
+	}
 	ctx := context.TODO()
-	// Test Redis connection
-	_, err = llm.RedisClient.redisClient.Ping(ctx).Result()
-	if err != nil {
-		return fmt.Errorf("unable to connect to redis host. \n%v", err)
+	if llm.metrics == nil {
+		llm.metrics = newLLMMetrics()
+	}
+	// Vector stores, the embedder and LLM models are created on first use and reused afterwards
+	llm.storeCache = &vectorStoreCache{}
+	llm.modelCache = &llmModelCache{}
+	if llm.IngestionWorkers <= 0 {
+		llm.IngestionWorkers = 2
+	}
+	// Webhooks registered before Init are kept, a closed manager is replaced with its webhooks
+	manager := llm.ingestionManager()
+	manager.mu.Lock()
+	if manager.closed {
+		llm.ingestion = newIngestionManager(llm.IngestionWorkers, manager.webhooks)
+	}
+	manager.mu.Unlock()
+	llm.lifecycle = &containerLifecycle{}
+	llm.dataKeys = &dataKeyCache{}
+	for _, tenant := range llm.Tenants {
+		if err = llm.RegisterTenant(tenant); err != nil {
+			return err
+		}
+	}
+	if llm.RedisClient.redisClient != nil {
+		if llm.Tracer != nil {
+			llm.RedisClient.redisClient.AddHook(redisTracingHook{tracer: llm.Tracer})
+		}
+		// Test Redis connection
+		_, err = llm.RedisClient.redisClient.Ping(ctx).Result()
+		if err != nil {
+			return fmt.Errorf("%w: unable to connect to redis host. \n%w", ErrNoRedis, err)
+		}
 	}
 	// predefine basic values
 	if llm.Temperature == 0 {
@@ -111,8 +153,24 @@ func (llm *LLMContainer) Init() error {
 		llm.RagRowCount = 5
 	}
 
+	if llm.RetryPolicy.MaxAttempts == 0 {
+		llm.RetryPolicy.MaxAttempts = 3
+	}
+
+	if llm.CircuitBreaker.FailureThreshold == 0 {
+		llm.CircuitBreaker.FailureThreshold = 5
+	}
+
 	if llm.AnswerLanguage == "" {
-		llm.AnswerLanguage = "English"
+		llm.AnswerLanguage = "en"
+	}
+	if llm.AnswerLanguage, err = ParseLanguage(string(llm.AnswerLanguage)); err != nil {
+		return fmt.Errorf("answer language: %v", err)
+	}
+	for _, language := range llm.fallbackLanguages() {
+		if err = checkLanguage(language); err != nil {
+			return fmt.Errorf("fallback language: %v", err)
+		}
 	}
 
 	if llm.NoRagErrorMessage == "" {
@@ -123,6 +181,8 @@ func (llm *LLMContainer) Init() error {
 		llm.NotRelatedAnswer = "I can't find any answer regarding your question."
 	}
 	llm.initPersistentMemoryManager()
+	// The worker is only started once the container is usable, a failed Init leaves nothing running
+	llm.startRetentionWorker()
 
 	return err
 }
@@ -138,13 +198,18 @@ func (llm *LLMContainer) Init() error {
 //   - error: An error if the query fails or if essential components are missing.
 
 func (llm *LLMContainer) GetQueryLanguage(Query, sessionId string, languageChannel chan<- string) (string, TokenUsage, error) {
-	llmclient, err := llm.LLMClient.NewLLMClient()
+	return llm.getQueryLanguage(context.Background(), Query, sessionId, languageChannel)
+}
+
+// getQueryLanguage implements GetQueryLanguage, the detection stops with ctx.
+func (llm *LLMContainer) getQueryLanguage(ctx context.Context, Query, sessionId string, languageChannel chan<- string) (string, TokenUsage, error) {
+	llmclient, err := llm.newLLMClient(llm.LLMClient, nil)
 	tokenReport := TokenUsage{}
 	if err != nil {
 		return "", tokenReport, err
 	}
 
-	langResponse, langErr := llmclient.GenerateContent(context.TODO(),
+	langResponse, langErr := llmclient.GenerateContent(ctx,
 		[]llms.MessageContent{
 
 			llms.TextParts(llms.ChatMessageTypeHuman, `What language is "`+Query+`" in? Say just it in one word without "." and just return "NONE" if you can't detect it.`),
@@ -169,29 +234,34 @@ func (llm *LLMContainer) GetQueryLanguage(Query, sessionId string, languageChann
 	return language, tokenReport, nil
 
 }
-func (llm *LLMContainer) setupResponseLanguage(Query, SessionId string, languageChannel chan<- string) (languageCapabilityDetectionFunction, languageCapabilityDetectionText string, LanguageDetectionTokens TokenUsage) {
-	if llm.userLanguage == nil {
-		llm.userLanguage = make(map[string]string)
+func (llm *LLMContainer) setupResponseLanguage(ctx context.Context, Query, SessionId string, languageChannel chan<- string) (languageCapabilityDetectionFunction, languageCapabilityDetectionText string, LanguageDetectionTokens TokenUsage) {
+	languages := llm.languageMemory()
+	sessionLanguage, sessionScript := languages.sessionLanguage(SessionId)
+	queryScript := dominantScript(Query)
+	if sessionLanguage != "" && sessionScript != "" && queryScript != "" && queryScript != sessionScript {
+		// The user switched to a language written in another script, it is detected again
+		sessionLanguage = ""
 	}
-	if llm.userLanguage[SessionId] == "" {
+	if sessionLanguage == "" {
 
-		userQueryLanguage, queryLanguageDetectionTokens, detectionError := llm.GetQueryLanguage(Query, SessionId, languageChannel)
+		userQueryLanguage, queryLanguageDetectionTokens, detectionError := llm.getQueryLanguage(ctx, Query, SessionId, languageChannel)
 		LanguageDetectionTokens = queryLanguageDetectionTokens
 		if detectionError == nil && userQueryLanguage != "NONE" {
-			llm.userLanguage[SessionId] = userQueryLanguage
+			sessionLanguage = userQueryLanguage
+			languages.setSessionLanguage(SessionId, sessionLanguage, queryScript)
 		}
-		if detectionError != nil || llm.userLanguage[SessionId] == "" {
+		if detectionError != nil || sessionLanguage == "" {
 			//unable to detect language
 			languageCapabilityDetectionFunction = `{language} = detect_language("` + Query + `") without mentionning in response.`
 			languageCapabilityDetectionText = "{language}"
 		} else {
 			// language detected, will be saved for the session.
 			languageCapabilityDetectionFunction = ""
-			languageCapabilityDetectionText = llm.userLanguage[SessionId]
+			languageCapabilityDetectionText = sessionLanguage
 		}
 	} else {
 		languageCapabilityDetectionFunction = ""
-		languageCapabilityDetectionText = llm.userLanguage[SessionId]
+		languageCapabilityDetectionText = sessionLanguage
 
 	}
 	if languageChannel != nil && SessionId != "" {
@@ -199,10 +269,10 @@ func (llm *LLMContainer) setupResponseLanguage(Query, SessionId string, language
 			defer func() {
 				if r := recover(); r != nil {
 					// ok = false
-					log.Printf("sending language to closed channel, panic recovered: %v\n", r)
+					llm.logger().Error("sending language to closed channel, panic recovered", "panic", r)
 				}
 			}()
-			languageChannel <- llm.userLanguage[SessionId]
+			languageChannel <- sessionLanguage
 		}()
 
 	}
@@ -227,6 +297,48 @@ func (llm *LLMContainer) setupResponseLanguage(Query, SessionId string, language
 //   - LLMResult: Struct containing the AI-generated response, retrieved documents, session memory, and logged actions.
 //   - error: An error if the query fails or if essential components are missing.
 func (llm *LLMContainer) AskLLM(Query string, options ...LLMCallOption) (LLMResult, error) {
+	return llm.askWithContext(context.Background(), Query, options...)
+}
+
+// AskLLMContext is AskLLM bound to a context, canceling ctx or reaching its deadline stops the query at its
+// current stage, e.g. when the client of a server disconnects.
+//
+// Parameters:
+//   - ctx: The context of the query, e.g. the context of an HTTP request.
+//   - Query: The user's input query.
+//   - options: The options of AskLLM.
+//
+// Returns:
+//   - LLMResult: The result of the query, see AskLLM.
+//   - error: An error if the query fails or ctx is canceled.
+func (llm *LLMContainer) AskLLMContext(ctx context.Context, Query string, options ...LLMCallOption) (LLMResult, error) {
+	return llm.askWithContext(ctx, Query, options...)
+}
+
+// askWithContext implements AskLLM, canceling parent stops the query, see AskLLMStream.
+func (llm *LLMContainer) askWithContext(parent context.Context, Query string, options ...LLMCallOption) (LLMResult, error) {
+	if !llm.lifecycle.begin() {
+		return LLMResult{}, ErrShutdown
+	}
+	defer llm.lifecycle.end()
+	start := time.Now()
+	ctx, span := llm.startSpan(parent, "aillm.AskLLM")
+	result, err := llm.askLLM(ctx, Query, options...)
+	result.Timings.Total = time.Since(start)
+	llm.metrics.observeRequest(start, result.TokenReport, err)
+	llm.auditQuery(ctx, Query, start, result, err, options)
+	llm.saveTrace(Query, start, &result, err, options)
+	span.SetAttributes(
+		attribute.Int("aillm.rag_docs", len(result.RagDocs)),
+		attribute.Int("aillm.output_tokens", result.TokenReport.CompletionTokens.OutputTokens),
+		attribute.String("aillm.request_id", result.RequestID),
+	)
+	endSpan(span, err)
+	return result, err
+}
+
+// askLLM implements AskLLM, ctx carries the tracing span of the query.
+func (llm *LLMContainer) askLLM(ctx context.Context, Query string, options ...LLMCallOption) (LLMResult, error) {
 
 	result := LLMResult{}
 	totalTokens := 0
@@ -236,6 +348,42 @@ func (llm *LLMContainer) AskLLM(Query string, options ...LLMCallOption) (LLMResu
 	for _, opt := range options {
 		opt(&o)
 	}
+	if o.debug {
+		result.DebugInfo = &LLMDebugInfo{}
+	}
+	result.RequestID = o.requestID
+	if err := llm.validateCallOptions(&o); err != nil {
+		return result, err
+	}
+	ctx = contextWithRequestID(ctx, o.requestID)
+	ctx, cancel := llm.requestContext(ctx, &o)
+	defer cancel()
+	logger := llm.requestLogger(o.requestID)
+	if err := llm.checkTenant(&o); err != nil {
+		return result, err
+	}
+	if err := checkLanguage(o.Language); err != nil {
+		return result, err
+	}
+	// Memory and language detection are kept per tenant
+	o.SessionID = o.getSessionID()
+	Query, err := llm.checkInputLimits(Query, &o)
+	if err != nil {
+		return result, err
+	}
+	if err := llm.checkQuota(ctx, &o); err != nil {
+		return result, err
+	}
+	defer func() {
+		llm.addQuotaTokens(&o, result.TokenReport)
+		llm.recordUsage(&o, result.TokenReport)
+	}()
+	var piiOutput *piiStream
+	if llm.PII.enabled() && llm.PII.OnOutput && llm.PII.Mode != PIIFlag && o.StreamingFunc != nil {
+		// Streamed lines are redacted before they reach the caller
+		piiOutput = &piiStream{llm: llm, next: o.StreamingFunc}
+		o.StreamingFunc = piiOutput.write
+	}
 	if o.Index == "" {
 		o.searchAll = true
 	}
@@ -247,11 +395,12 @@ func (llm *LLMContainer) AskLLM(Query string, options ...LLMCallOption) (LLMResu
 
 	SecurityCheckTokens := TokenUsage{}
 
-	if !o.ignoreSecurityCheck && o.ExactPrompt == "" {
+	if llm.securityCheckEnabled(&o) {
 		var isSecure bool
 		var err error
 		var warning string
-		isSecure, SecurityCheckTokens, warning, err = llm.IsQuerySafe(Query, o.debug)
+		isSecure, SecurityCheckTokens, warning, err = llm.isQuerySafe(ctx, Query, o.debug)
+		result.TokenReport.SecurityCheckTokens = SecurityCheckTokens
 		if err != nil {
 			return result, err
 		}
@@ -259,13 +408,32 @@ func (llm *LLMContainer) AskLLM(Query string, options ...LLMCallOption) (LLMResu
 			if o.debug && warning != "" {
 				result.Warning = warning
 			}
-			return result, errors.New("query is not secure")
+			return result, &SecurityCheckError{Reason: warning}
 
 		}
 		if warning != "" {
 			result.Warning = warning
 		}
 	}
+	if llm.Moderation.Moderator != nil && llm.Moderation.OnQuery {
+		redact, err := llm.moderate(ctx, ModerationQuery, Query, &result)
+		if err != nil {
+			return result, err
+		}
+		if redact {
+			// Flagged queries are answered with the redaction text without calling the LLM
+			text := llm.Moderation.redactionText()
+			if o.StreamingFunc != nil {
+				o.StreamingFunc(ctx, []byte(text))
+			}
+			if piiOutput != nil {
+				piiOutput.flush(ctx)
+			}
+			result.Response = &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: text}}}
+			result.FailedToRespond = true
+			return result, nil
+		}
+	}
 	maxWordsPrompt := ""
 	if o.maxWords > 0 {
 		maxWordsPrompt = "\n- You should answer in " + strconv.Itoa(o.maxWords) + " words or less."
@@ -280,7 +448,7 @@ func (llm *LLMContainer) AskLLM(Query string, options ...LLMCallOption) (LLMResu
 	if o.SessionID != "" {
 
 		if !o.PersistentMemory {
-			mem, smExists := llm.MemoryManager.GetMemory(o.SessionID)
+			mem, smExists := llm.sessionMemory().GetMemory(o.SessionID)
 			for _, memoryItem := range mem.Questions {
 				KNNMemoryStr += "\n" + memoryItem.Question
 			}
@@ -295,26 +463,28 @@ func (llm *LLMContainer) AskLLM(Query string, options ...LLMCallOption) (LLMResu
 			MemorySummary = usermemory.Summary
 			KNNMemoryStr += lastQuery.Question
 		}
+		// The oldest interactions are dropped from oversized histories
+		memoryStr = truncateHistory(memoryStr, llm.InputLimits.MaxMemoryLength)
+		KNNMemoryStr = truncateHistory(KNNMemoryStr, llm.InputLimits.MaxMemoryLength)
 	}
-	ctx := context.Background()
 	memoryAddAllowed := false
-	llmclient, err := llm.LLMClient.NewLLMClient()
+	llmclient, err := llm.newLLMClient(llm.LLMClient, func(retry int, retryErr error) {
+		result.addAction("LLM Retry "+strconv.Itoa(retry)+": "+retryErr.Error(), o.ActionCallFunc)
+	})
 	var msgs []llms.MessageContent
 	hasRag := false
 	var resDocs []schema.Document
-	// Set Date and Time
+	// Set Date and Time, written in the answer language once it is known
 	datePrompt := ""
-	if o.IncludeDate {
-
-		datePrompt = "- It is " + time.Now().Format("Monday, 2006-01-02 15:04") + ". Adjust your response based on the current date and time.\n"
-	}
 	ragReferencesPrompt := ""
 	if o.RagReferences {
 
 		ragReferencesPrompt = `### Output Formatting Rules:
 - First, output the **full natural language answer**, formatted clearly.  
 - Then, on a **new line after the full answer**, add the **reference line** that begins with **⧉**, followed by a single valid JSON object in this format:  
-  ⧉ {"references":["Reference_id_1","Reference_id_2"]}
+  ⧉ {"references":[{"id":"Reference_id_1","quote":"exact sentence copied from the chunk"},{"id":"Reference_id_2","quote":"exact sentence copied from the chunk"}]}
+
+- Only use Reference ids given in the chunks, and copy each quote word for word from the referenced chunk.
 
 - The **⧉ line must come immediately after the answer**, with no additional explanation or text.  
 - If no references are applicable, **omit the ⧉ line completely** — do not include an empty or placeholder reference object.
@@ -323,6 +493,8 @@ func (llm *LLMContainer) AskLLM(Query string, options ...LLMCallOption) (LLMResu
 
 `
 	}
+	glossary, glossaryEnforced := llm.callGlossary(&o)
+	answerLanguage := ""
 	// check exact prompt provided or not
 	if o.ExactPrompt == "" {
 		// Check if LLM client is available
@@ -330,16 +502,14 @@ func (llm *LLMContainer) AskLLM(Query string, options ...LLMCallOption) (LLMResu
 		if llm.LLMClient == nil {
 			return result, errors.New("missing llm client")
 		}
-		// Check if embedding model is available
+		// Check if embedding model is available, a Retriever searches without it
 
-		if llm.Embedder == nil {
-			return result, errors.New("missing embedding model")
-		} else {
+		if llm.Embedder == nil && llm.Retriever == nil {
+			return result, ErrMissingEmbedder
+		} else if llm.Embedder != nil {
 			// Initialize embedding model if not already initialized
 
-			if !llm.Embedder.initialized() {
-				llm.InitEmbedding()
-			}
+			llm.ensureEmbedder()
 		}
 		// Initialize the LLM client for processing
 		result.addAction("Vector Search Start", o.ActionCallFunc)
@@ -348,44 +518,26 @@ func (llm *LLMContainer) AskLLM(Query string, options ...LLMCallOption) (LLMResu
 			return result, err
 		}
 		// Add AI assistant's character/personality setting
-		character := ""
-		if o.character != "" {
-			character = o.character
-		} else {
-			character = llm.Character
-		}
+		character := llm.callCharacter(&o)
 		if character == "" {
 			character = "an AI assistant"
 		}
 		// Construct the query prefix for the embedding store
-		KNNPrefix := "context:"
-		if o.getEmbeddingPrefix() != "" {
-			KNNPrefix += o.getEmbeddingPrefix() + ":"
-		}
-		if o.Index == "" {
-			o.searchAll = true
-		}
-		if o.searchAll {
-			// o.Prefix =
-			KNNPrefix = "all:"
-			if o.getEmbeddingPrefix() != "" {
-				KNNPrefix += o.getEmbeddingPrefix() + ":"
-			}
+		KNNPrefixes, KNNBoosts := llm.retrievalPrefixes(&o)
+		KNNPrefix := strings.Join(KNNPrefixes, ",")
+		KNNQuery := Query
 
-		} else {
-			KNNPrefix += o.Index + ":"
-			if o.Language == "" {
-				if llm.FallbackLanguage != "" {
-					o.Language = llm.FallbackLanguage
-				}
+		// Describe attached images so they can be used for retrieval and in the prompt
+		if len(o.imageAttachments) > 0 {
+			result.addAction("Image Attachment Description Start", o.ActionCallFunc)
+			attachmentDescription, visionTokens, attachmentErr := llm.describeImageAttachments(o.imageAttachments, Query)
+			result.TokenReport.VisionTokens = visionTokens
+			if attachmentErr != nil {
+				return result, attachmentErr
 			}
-
-		}
-		// Issue with forced language. Interference with vector search index!!!! Will be fixed in the future.
-		if o.Language != "" && !o.ForceLanguage {
-			KNNPrefix += o.Language + ":"
+			KNNQuery += "\n" + attachmentDescription
+			o.ExtraContext += "\n### Attached Image (provided by the user):\n" + attachmentDescription
 		}
-		KNNQuery := Query
 
 		// Append past session queries to provide context
 		if KNNMemoryStr != "" {
@@ -394,68 +546,170 @@ func (llm *LLMContainer) AskLLM(Query string, options ...LLMCallOption) (LLMResu
 		// KNNQuery += Query
 
 		/*** Change algorithm to The k-nearest neighbors (KNN) algorithm **/
-		var KNNGetErr error
+		// missingIndexErr is kept when the searched index doesn't exist, the call then fails if nothing else is found
+		var KNNGetErr, missingIndexErr error
 		searchAlgorithm := o.SearchAlgorithm
 		if searchAlgorithm == NotDefinedSearch {
 			searchAlgorithm = llm.SearchAlgorithm
 		}
+		if llm.BeforeRetrieve != nil && searchAlgorithm != NoSearch {
+			KNNQuery, err = llm.BeforeRetrieve(ctx, KNNQuery)
+			if err != nil {
+				return result, err
+			}
+		}
+		result.retrievalQuery, result.retrievalPrefix, result.searchAlgorithm = KNNQuery, KNNPrefix, searchAlgorithm
+		if result.DebugInfo != nil {
+			result.DebugInfo.RetrievalPrefix = KNNPrefix
+			result.DebugInfo.RetrievalQuery = KNNQuery
+			result.DebugInfo.SearchAlgorithm = int(searchAlgorithm)
+			result.DebugInfo.SearchAlgorithmName = searchAlgorithm.String()
+		}
+		retrievalStart := time.Now()
+		retrievalCtx, retrievalSpan := llm.startSpan(ctx, "aillm.Retrieve", attribute.Int("aillm.search_algorithm", int(searchAlgorithm)))
 		if searchAlgorithm != NoSearch {
-			switch searchAlgorithm {
-
-			case SimilaritySearch:
-				// Retrieve related documents using cosine similarity search
-
-				resDocs, KNNGetErr = llm.CosineSimilarity(KNNPrefix, KNNQuery, llm.RagRowCount, llm.ScoreThreshold)
-			case KNearestNeighbors:
-				// Retrieve related documents using KNN search
-				resDocs, KNNGetErr = llm.FindKNN(KNNPrefix, KNNQuery, llm.RagRowCount, llm.ScoreThreshold)
-			case HybridSearch:
-				// Retrieve related documents using hybrid search (vector + lexical)
-				resDocs, KNNGetErr = llm.HybridSearch(KNNPrefix, KNNQuery, llm.RagRowCount, llm.ScoreThreshold, nil)
-			case LexicalSearch:
-				// Retrieve related documents using lexical search only
-				resDocs, KNNGetErr = llm.performLexicalSearchOnly(KNNPrefix, KNNQuery, llm.RagRowCount, llm.ScoreThreshold)
-			case SemanticSearch:
-				// Retrieve related documents using enhanced semantic search
-				resDocs, KNNGetErr = llm.SemanticSearch(KNNPrefix, KNNQuery, llm.RagRowCount, llm.ScoreThreshold)
-			default:
+			if searchAlgorithm.String() == "unknown" {
+				endSpan(retrievalSpan, errors.New("unknown search algorithm"))
 				return result, errors.New("unknown search algorithm")
 			}
-
-			if KNNGetErr != nil {
-				if !llm.AllowHallucinate && !o.AllowHallucinate {
-					return result, KNNGetErr
+			// The call language is searched first, then the fallback languages in order
+			searches := []*languageSearch{{language: o.Language, prefixes: KNNPrefixes, boosts: KNNBoosts}}
+			for _, language := range llm.fallbackLanguages() {
+				if language == o.Language {
+					continue
 				}
-			}
-			// Check if relevant documents were retrieved
-			hasRag = len(resDocs) > 0
-
-			if !hasRag && llm.FallbackLanguage != "" && llm.FallbackLanguage != o.Language {
-				searchPrefix := o.getEmbeddingPrefix() + ":" + llm.FallbackLanguage + ":"
+				searchPrefix := o.getEmbeddingPrefix() + ":" + language + ":"
 				if o.searchAll {
 					// o.Prefix =
-					searchPrefix = "all:" + o.Prefix + ":" + llm.FallbackLanguage + ":"
+					searchPrefix = "all:" + o.getEmbeddingPrefix() + ":" + language + ":"
 				}
-				switch searchAlgorithm {
-				case SimilaritySearch:
-					resDocs, KNNGetErr = llm.CosineSimilarity(searchPrefix, KNNQuery, llm.RagRowCount, llm.ScoreThreshold)
-				case KNearestNeighbors:
-					resDocs, KNNGetErr = llm.FindKNN(searchPrefix, KNNQuery, llm.RagRowCount, llm.ScoreThreshold)
-				case HybridSearch:
-					resDocs, KNNGetErr = llm.HybridSearch(searchPrefix, KNNQuery, llm.RagRowCount, llm.ScoreThreshold, nil)
-				case LexicalSearch:
-					resDocs, KNNGetErr = llm.performLexicalSearchOnly(searchPrefix, KNNQuery, llm.RagRowCount, llm.ScoreThreshold)
-				case SemanticSearch:
-					resDocs, KNNGetErr = llm.SemanticSearch(searchPrefix, KNNQuery, llm.RagRowCount, llm.ScoreThreshold)
-				default:
-					return result, errors.New("unknown search algorithm")
+				fallbackPrefixes, fallbackBoosts := []string{searchPrefix}, map[string]float64(nil)
+				if len(o.indexes) > 0 || len(o.prefixes) > 0 {
+					fallbackOptions := o
+					fallbackOptions.Language = language
+					fallbackPrefixes, fallbackBoosts = llm.retrievalPrefixes(&fallbackOptions)
 				}
-
-				if KNNGetErr != nil {
-					if !llm.AllowHallucinate && !o.AllowHallucinate {
-						return result, KNNGetErr
+				searches = append(searches, &languageSearch{language: language, prefixes: fallbackPrefixes, boosts: fallbackBoosts})
+			}
+			search := func(ls *languageSearch) {
+				ls.docs, ls.err = llm.retrieveFromPrefixes(retrievalCtx, searchAlgorithm, ls.prefixes, ls.boosts, llm.callIndexBoosts(&o), KNNQuery, o.rowCount(), o.indexCap)
+			}
+			concurrent := llm.ConcurrentFallbackSearch && len(searches) > 1
+			if concurrent {
+				// All the languages are searched at once, the best scoring one is used
+				var searchWait sync.WaitGroup
+				for _, ls := range searches {
+					searchWait.Add(1)
+					go func() {
+						defer searchWait.Done()
+						search(ls)
+					}()
+				}
+				searchWait.Wait()
+			}
+			var matched *languageSearch
+			var searchErr error
+			allFallbackPrefixes := []string{}
+			for idx, ls := range searches {
+				if !concurrent {
+					// The fallback languages are only searched while nothing was found
+					if matched != nil {
+						break
 					}
+					search(ls)
+				}
+				if idx > 0 {
+					allFallbackPrefixes = append(allFallbackPrefixes, ls.prefixes...)
 				}
+				if isIndexNotFound(ls.err) {
+					// A prefix without index has nothing to retrieve, the fallbacks are searched
+					if idx == 0 {
+						missingIndexErr = ls.err
+					}
+					ls.err = nil
+				}
+				if ls.err != nil {
+					if !concurrent && !llm.AllowHallucinate && !o.AllowHallucinate {
+						endSpan(retrievalSpan, ls.err)
+						return result, ls.err
+					}
+					if searchErr == nil {
+						searchErr = ls.err
+					}
+					continue
+				}
+				if len(ls.docs) > 0 && (matched == nil || bestRankingScore(ls.docs) > bestRankingScore(matched.docs)) {
+					matched = ls
+				}
+			}
+			if matched != nil {
+				hasRag = true
+				resDocs = matched.docs
+				result.MatchedLanguage = matched.language
+			} else if searchErr != nil {
+				KNNGetErr = searchErr
+				if !llm.AllowHallucinate && !o.AllowHallucinate {
+					endSpan(retrievalSpan, KNNGetErr)
+					return result, KNNGetErr
+				}
+			}
+			if result.DebugInfo != nil && len(allFallbackPrefixes) > 0 {
+				result.DebugInfo.FallbackRetrievalPrefix = strings.Join(allFallbackPrefixes, ",")
+			}
+		}
+		if len(resDocs) == 0 && searchAlgorithm != NoSearch && llm.useCrossLingual(o) {
+			translatedDocs, translation, translationTokens, translationErr := llm.searchTranslatedQuery(retrievalCtx, searchAlgorithm, Query, o)
+			result.TokenReport.TranslationTokens = translationTokens
+			if translationErr != nil {
+				// Without a translation the call answers as if nothing was found
+				logger.Warn("query translation failed", "error", translationErr)
+			} else if len(translatedDocs) > 0 {
+				resDocs = translatedDocs
+				result.MatchedLanguage = translation.Language
+				if result.DebugInfo != nil {
+					result.DebugInfo.TranslatedQuery = translation.Text
+				}
+			}
+		}
+		// Retrieved rows of a table are rendered as their whole table
+		resDocs = llm.expandTables(resDocs)
+		if llm.AfterRetrieve != nil && searchAlgorithm != NoSearch {
+			resDocs, KNNGetErr = llm.AfterRetrieve(retrievalCtx, KNNQuery, resDocs)
+			if KNNGetErr != nil {
+				endSpan(retrievalSpan, KNNGetErr)
+				return result, KNNGetErr
+			}
+		}
+		if len(resDocs) == 0 && searchAlgorithm != NoSearch && llm.useWebFallback(o) {
+			webDocs, webSources, webErr := llm.searchWeb(retrievalCtx, Query)
+			if webErr != nil {
+				// Without web results the call answers as if nothing was found
+				logger.Warn("web search fallback failed", "error", webErr)
+			} else if len(webDocs) > 0 {
+				resDocs, result.WebSources = webDocs, webSources
+				result.addAction("Web Search", o.ActionCallFunc)
+			}
+		}
+		if o.debug {
+			for _, doc := range resDocs {
+				logger.Debug("retrieved document", "score", doc.Score, "content", doc.PageContent)
+			}
+		}
+		result.Timings.Retrieval = time.Since(retrievalStart)
+		retrievalSpan.SetAttributes(attribute.Int("aillm.rag_docs", len(resDocs)))
+		endSpan(retrievalSpan, KNNGetErr)
+		if searchAlgorithm != NoSearch {
+			llm.metrics.observeRetrieval(searchAlgorithm, retrievalStart)
+		}
+		if o.imageRowCount > 0 {
+			// Retrieve related images and describe them in the context
+			ragImages, imageErr := llm.searchImages(retrievalCtx, o.getEmbeddingPrefix(), Query, o.imageRowCount, llm.ScoreThreshold)
+			if imageErr != nil && !llm.AllowHallucinate && !o.AllowHallucinate {
+				return result, imageErr
+			}
+			if len(ragImages) > 0 {
+				result.RagImages = ragImages
+				o.ExtraContext += "\n" + imageRagContext(ragImages)
 			}
 		}
 		result.addAction("Prompt Generation Start", o.ActionCallFunc)
@@ -470,36 +724,46 @@ func (llm *LLMContainer) AskLLM(Query string, options ...LLMCallOption) (LLMResu
 		languageCapabilityDetectionText := ``
 
 		if o.ForceLanguage && o.Language != "" {
-			languageCapabilityDetectionText = o.Language
+			languageCapabilityDetectionText = LanguageTag(o.Language).DisplayName()
+			answerLanguage = glossaryLanguage(o.Language)
 		} else {
 			languageCapabilityDetectionFunction = `detect language of "` + Query + `"`
 			languageCapabilityDetectionText = `detected language without mentioning it.`
 
 			if llm.LLMModelLanguageDetectionCapability {
 				LanguageDetectionTokens := TokenUsage{}
-				languageCapabilityDetectionFunction, languageCapabilityDetectionText, LanguageDetectionTokens = llm.setupResponseLanguage(Query, o.SessionID, o.LanguageChannel)
+				languageCapabilityDetectionFunction, languageCapabilityDetectionText, LanguageDetectionTokens = llm.setupResponseLanguage(ctx, Query, o.SessionID, o.LanguageChannel)
 				result.TokenReport.LanguageDetectionTokens = LanguageDetectionTokens
+				// The detected language is a name, e.g. "Persian"
+				answerLanguage = glossaryLanguage(languageCapabilityDetectionText)
 			} else {
 				if llm.AnswerLanguage != "" {
-					languageCapabilityDetectionText = llm.AnswerLanguage
+					languageCapabilityDetectionText = llm.AnswerLanguage.DisplayName()
+					answerLanguage = string(llm.AnswerLanguage)
 				}
 			}
 		}
+		if o.IncludeDate {
+			datePrompt = llm.datePrompt(answerLanguage)
+		}
 
 		// If no relevant documents found, handle response accordingly
 
 		if !hasRag && o.ExtraContext == "" {
 			if !llm.AllowHallucinate && !o.AllowHallucinate {
+				if missingIndexErr != nil {
+					return result, fmt.Errorf("%w: %w", ErrNoRagResults, missingIndexErr)
+				}
 				if llm.NoRagErrorMessage != "" {
 					ragText = languageCapabilityDetectionFunction + `You are ` + character + ` specialized in providing accurate and concise answers.
 your only answer to all of questions is the improved version of "` + llm.NotRelatedAnswer + `" in ` + languageCapabilityDetectionText + `.
-- Start the response with "@" and "@" should be the first character of the response.
+- Start the response with "` + refusalMarker + `", it must be the first text of the response.
 - Ignore all of the references and do not include them in the response.
 **Assistant:** `
 
 					msgs = append(msgs, llms.TextParts(llms.ChatMessageTypeSystem, ragText))
 				} else {
-					return result, errors.New("rag query has no results and hallucination is allowed but NoRagErrorMessage is empty")
+					return result, fmt.Errorf("%w, hallucination is disallowed and NoRagErrorMessage is empty", ErrNoRagResults)
 				}
 			} else {
 				// allow hallucinate - reload memory
@@ -516,7 +780,7 @@ your only answer to all of questions is the improved version of "` + llm.NotRela
 ### Instructions: 
 - Analyze the question carefully and reason step-by-step.
 - Then, provide a **clear answer `+brieflyText+`in %s.**.
-- If the question is unrelated to the provided context or cannot be answered based on the information above, **start the response with "@"** and reply politely in %s that you don't have any information about the question.
+- If the question is unrelated to the provided context or cannot be answered based on the information above, **start the response with "`+refusalMarker+`"** and reply politely in %s that you don't have any information about the question.
 - Do **not** reference the original text or mention language/translation details.
 %s
 
@@ -537,42 +801,23 @@ your only answer to all of questions is the improved version of "` + llm.NotRela
 				}
 			}
 		} else {
-			for idx, doc := range resDocs {
-				if idx > 0 {
-					ragText += "\n"
+			ragText = buildContextChunks(resDocs, o.RagReferences, o.CotextCleanup)
+			if llm.useMapReduce(o) && len(resDocs) > 1 {
+				mappedText, mapReduceTokens, mapReduceErr := llm.mapReduceContext(ctx, llmclient, Query, resDocs, o)
+				result.TokenReport.MapReduceTokens = mapReduceTokens
+				if mapReduceErr != nil {
+					// Without the extracted facts the call answers from all the chunks
+					logger.Warn("map-reduce failed", "error", mapReduceErr)
+				} else if mappedText != "" {
+					ragText = mappedText
+					result.addAction("Map Reduce", o.ActionCallFunc)
 				}
-				content := "Chunk " + strconv.Itoa(idx+1) + ":\n"
-
-				if o.RagReferences {
-					rawKey := doc.Metadata["rawkey"]
-
-					if rawKey != nil {
-						rawKeyObject := LLMEmbeddingContent{}
-						err := json.Unmarshal([]byte(rawKey.(string)), &rawKeyObject)
-						if err == nil {
-							content += `- Reference: {"id":"` + rawKeyObject.Id + `"` + "}\n"
-						}
-					}
-				}
-				content += doc.PageContent + "\n\n"
-				if o.CotextCleanup {
-					re := regexp.MustCompile(`<[^>]+>`)
-					content = re.ReplaceAllString(content, "")
-
-					// Replacing repeated spaces with a single space
-					reSpaces := regexp.MustCompile(`\s+`)
-					content = reSpaces.ReplaceAllString(content, " ")
-
-					// Removing empty lines
-					reNewlines := regexp.MustCompile(`\n+`)
-					content = reNewlines.ReplaceAllString(content, "\n")
-
-					// Removing extra spaces at the beginning and end
-					content = strings.TrimSpace(content)
-				}
-				ragText += content
 			}
 			ragText += "\n" + o.ExtraContext
+			sourcingPrompt := ""
+			if len(result.WebSources) > 0 {
+				sourcingPrompt = webSourcingPrompt
+			}
 			memStrPrompt := ""
 			if memoryStr != "" {
 				memStrPrompt = `### Previous Interactions:  
@@ -587,18 +832,19 @@ your only answer to all of questions is the improved version of "` + llm.NotRela
 ### Instructions:
 - Analyze the question carefully and reason step-by-step and think about the question and answer first.
 - Then, provide a **clear answer `+brieflyText+` in %s.**.%s
-- If the question is unrelated to the provided context or cannot be answered based on the information above, **start the response with "@"** and reply politely in %s that you don't have any information about the question.
+- If the question is unrelated to the provided context or cannot be answered based on the information above, **start the response with "`+refusalMarker+`"** and reply politely in %s that you don't have any information about the question.
 - Do **not** reference the original text or mention language/translation details.
 - Ignore chunk completely if it is not related to the question.
 - Do not include chunk number in the response.
 
 %s
 %s
-
+%s
 **User:** 
 %s
 **Assistant:** `,
-				character, ragText, memStrPrompt, languageCapabilityDetectionText, maxWordsPrompt, languageCapabilityDetectionText, datePrompt, ragReferencesPrompt, Query)
+				character, ragText, memStrPrompt, languageCapabilityDetectionText, maxWordsPrompt+sourcingPrompt, languageCapabilityDetectionText, datePrompt, ragReferencesPrompt,
+				glossaryInstructions(glossary, answerLanguage, Query+"\n"+ragText), Query)
 			ragArray = append(ragArray, llms.TextPart(ragText))
 			// fmt.Println(ragText)
 			curMessageContent.Parts = ragArray
@@ -611,7 +857,7 @@ your only answer to all of questions is the improved version of "` + llm.NotRela
 		memoryAddAllowed = hasRag || llm.AllowHallucinate
 	} else {
 		if o.ForceLanguage {
-			_, Language, _ := llm.setupResponseLanguage(Query, o.SessionID, o.LanguageChannel)
+			_, Language, _ := llm.setupResponseLanguage(ctx, Query, o.SessionID, o.LanguageChannel)
 			if Language != "" {
 				msgs = append(msgs, llms.TextParts(llms.ChatMessageTypeSystem, "Reply in "+Language))
 			}
@@ -620,34 +866,33 @@ your only answer to all of questions is the improved version of "` + llm.NotRela
 
 		msgs = append(msgs, llms.TextParts(llms.ChatMessageTypeHuman, o.ExactPrompt))
 	}
-	isFirstWord := true
+	if o.outputSchema != nil {
+		// The format instructions precede the question
+		msgs = append(msgs[:len(msgs)-1], llms.TextParts(llms.ChatMessageTypeSystem, outputSchemaPrompt(o.outputSchema)), msgs[len(msgs)-1])
+	}
+	refusal := refusalDetector{}
 	isFirstChunk := true
+	generationStart := time.Now()
 	// Generate content using the LLM and stream results via the provided callback function
 	refrencesStr := ""
 	startRefrences := false
 	failedToRespond := false
+	temperature, topP := llm.generationParameters(&o)
 	calloptions := []llms.CallOption{
-		llms.WithTemperature(llm.Temperature),
-		llms.WithTopP(llm.TopP),
+		llms.WithTemperature(temperature),
+		llms.WithTopP(topP),
 		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
 			totalTokens++
+			if o.debug {
+				logger.Debug("chunk received", "chunk", string(chunk))
+			}
 			if isFirstChunk {
 				isFirstChunk = false
+				result.Timings.TimeToFirstToken = time.Since(generationStart)
 				result.addAction("First Chunk Received", o.ActionCallFunc)
 			}
-			if isFirstWord && len(chunk) > 0 {
-				startsWithAt := strings.HasPrefix(string(chunk), "@")
-				if startsWithAt {
-
-					failedToRespond = true
-					// remove @ from the chunk
-					chunk = chunk[1:]
-				}
-				isFirstWord = false
-				// isFirstWord = isFirstWord && !strings.Contains(string(chunk), " ")
-				// if isFirstWord && startsWithAt {
-				// 	chunk = chunk[1:]
-				// }
+			if chunk = refusal.write(chunk); len(chunk) == 0 {
+				return nil
 			}
 			if /*o.RagReferences &&*/ strings.HasPrefix(string(chunk), "⧉") {
 				startRefrences = true
@@ -665,7 +910,36 @@ your only answer to all of questions is the improved version of "` + llm.NotRela
 			return o.StreamingFunc(ctx, chunk)
 		}),
 	}
+	if o.seed != nil {
+		calloptions = append(calloptions, llms.WithSeed(*o.seed))
+	}
+	if o.outputSchema["type"] == "object" {
+		// JSON mode of the providers only allows objects
+		calloptions = append(calloptions, llms.WithJSONMode())
+	}
+	if llm.BeforePrompt != nil {
+		msgs, err = llm.BeforePrompt(ctx, msgs)
+		if err != nil {
+			return result, err
+		}
+	}
+	if o.debug {
+		logger.Debug("prompt", "messages", promptLogValue(msgs))
+		systemPrompt := []string{}
+		for _, msg := range msgs {
+			if msg.Role != llms.ChatMessageTypeSystem {
+				continue
+			}
+			for _, part := range msg.Parts {
+				if text, ok := part.(llms.TextContent); ok {
+					systemPrompt = append(systemPrompt, text.Text)
+				}
+			}
+		}
+		result.DebugInfo.SystemPrompt = strings.Join(systemPrompt, "\n")
+	}
 	var response *llms.ContentResponse
+	generationCtx, generationSpan := llm.startSpan(ctx, "aillm.GenerateContent", attribute.Int("aillm.tools", len(o.Tools.Tools)))
 	if len(o.Tools.Tools) > 0 {
 		result.addAction("Calling tools", o.ActionCallFunc)
 
@@ -691,75 +965,101 @@ your only answer to all of questions is the improved version of "` + llm.NotRela
 
 		// Token usage calculation should be done here
 
-		callParams := []llms.CallOption{
-			llms.WithTools(o.Tools.Tools),
-			llms.WithStreamingFunc(o.StreamingFunc),
-		}
-
-		if o.customModel != "" {
-			callParams = append(callParams, llms.WithModel(o.customModel))
+		msgs, err = llm.runToolCalls(generationCtx, llmclient, o, messageHistory, msgs, &result)
+		if err != nil {
+			llm.metrics.observeProviderError("llm")
+			err = deadlineError(ctx, err)
+			endSpan(generationSpan, err)
+			return result, err
 		}
-
-		resp, err := llmclient.GenerateContent(ctx, messageHistory, callParams...)
+		// calloptions = append(calloptions, llms.WithTools(o.Tools.Tools))
+	} else {
+		result.addAction("Sending Request to LLM", o.ActionCallFunc)
+	}
+	// The answer is generated once, transient failures are retried by the client according to RetryPolicy
+	response, err = llmclient.GenerateContent(generationCtx,
+		msgs,
+		calloptions...,
+	)
+	if err != nil {
+		llm.metrics.observeProviderError("llm")
+		err = deadlineError(ctx, err)
+		endSpan(generationSpan, err)
+		return result, err
+	}
+	if validators := append(append([]AnswerValidator{}, llm.Guardrails.Validators...), o.validators...); len(validators) > 0 {
+		response, msgs, err = llm.applyGuardrails(generationCtx, llmclient, msgs, calloptions, response, validators, &result, o.ActionCallFunc, func() {
+			// The retried answer is streamed from its start
+			refusal = refusalDetector{}
+			refrencesStr, startRefrences = "", false
+		})
 		if err != nil {
+			endSpan(generationSpan, err)
 			return result, err
-
 		}
-		respchoice := resp.Choices[0]
-
-		assistantResponse := llms.TextParts(llms.ChatMessageTypeAI, respchoice.Content)
-		for _, tc := range respchoice.ToolCalls {
-			assistantResponse.Parts = append(assistantResponse.Parts, tc)
+	}
+	endSpan(generationSpan, nil)
+	result.Timings.Generation = time.Since(generationStart)
+	if held := refusal.flush(); len(held) > 0 && o.StreamingFunc != nil {
+		if err = o.StreamingFunc(ctx, held); err != nil {
+			return result, err
 		}
-		// messageHistory = append(messageHistory, assistantResponse)
-		msgs = append(msgs, assistantResponse)
-
-		for _, tc := range respchoice.ToolCalls {
-			if o.Tools.Handlers[tc.FunctionCall.Name] != nil {
-				fn := o.Tools.Handlers[tc.FunctionCall.Name]
-				var params interface{}
-				if err := json.Unmarshal([]byte(tc.FunctionCall.Arguments), &params); err != nil {
-					log.Fatal(err)
-				}
-				fnresult, handlererr := fn(params)
-				if handlererr != nil {
-					return result, handlererr
-				}
-				toolResponse := llms.MessageContent{
-					Role: llms.ChatMessageTypeTool,
-
-					Parts: []llms.ContentPart{
-						llms.ToolCallResponse{
-							ToolCallID: tc.ID,
-							Name:       tc.FunctionCall.Name,
-							Content:    fnresult,
-						},
-					},
-				}
-
-				msgs = append(msgs, toolResponse)
-			}
+	}
+	failedToRespond = refusal.refused
+	if response != nil && len(response.Choices) > 0 {
+		// Non streaming providers and markers the stream didn't start with are found in the answer
+		var refused bool
+		response.Choices[0].Content, refused = stripRefusalMarker(response.Choices[0].Content)
+		failedToRespond = failedToRespond || refused
+	}
+	if llm.AfterGenerate != nil {
+		response, err = llm.AfterGenerate(ctx, Query, response)
+		if err != nil {
+			return result, err
 		}
-		// calloptions = append(calloptions, llms.WithTools(o.Tools.Tools))
-
-		response, err = llmclient.GenerateContent(ctx,
-			msgs,
-			calloptions...,
-		)
+	}
+	if glossaryEnforced && !failedToRespond && response != nil && len(response.Choices) > 0 {
+		response.Choices[0].Content, result.GlossaryFixes = enforceGlossary(response.Choices[0].Content, glossary, answerLanguage)
+	}
+	if piiOutput != nil {
+		if err = piiOutput.flush(ctx); err != nil {
+			return result, err
+		}
+	}
+	if llm.PII.enabled() && llm.PII.OnOutput && response != nil && len(response.Choices) > 0 {
+		// The memory keeps the redacted answer
+		response.Choices[0].Content, result.PIIMatches, _, err = llm.redactPII(ctx, response.Choices[0].Content)
 		if err != nil {
 			return result, err
 		}
-
-	} else {
-		result.addAction("Sending Request to LLM", o.ActionCallFunc)
-		response, err = llmclient.GenerateContent(ctx,
-			msgs,
-			calloptions...,
-		)
-
+	}
+	if llm.Moderation.Moderator != nil && llm.Moderation.OnAnswer && response != nil && len(response.Choices) > 0 {
+		redact, err := llm.moderate(ctx, ModerationAnswer, response.Choices[0].Content, &result)
 		if err != nil {
 			return result, err
 		}
+		if redact {
+			response.Choices[0].Content = llm.Moderation.redactionText()
+			failedToRespond = true
+		}
+	}
+	groundednessCheck := llm.GroundednessCheck
+	if o.groundednessCheck != nil {
+		groundednessCheck = *o.groundednessCheck
+	}
+	if groundednessCheck && !failedToRespond && response != nil && len(response.Choices) > 0 {
+		answer := strings.Split(response.Choices[0].Content, "⧉")[0]
+		report, groundednessTokens, groundednessErr := llm.CheckGroundedness(ctx, answer, retrievedDocuments(resDocs))
+		result.TokenReport.GroundednessTokens = groundednessTokens
+		if groundednessErr != nil {
+			// The answer is still returned without a report
+			logger.Warn("groundedness check failed", "error", groundednessErr)
+		} else {
+			result.Groundedness = &report
+		}
+	}
+	if result.searchAlgorithm != NotDefinedSearch && result.searchAlgorithm != NoSearch {
+		result.Confidence = llm.answerConfidence(resDocs, failedToRespond, result.Groundedness)
 	}
 
 	result.addAction("Finished", o.ActionCallFunc)
@@ -783,7 +1083,7 @@ your only answer to all of questions is the improved version of "` + llm.NotRela
 				if exists {
 					memoryData = append(memoryData, queryData)
 				}
-				llm.MemoryManager.AddMemory(o.SessionID, memoryData)
+				llm.sessionMemory().AddMemory(o.SessionID, memoryData)
 
 			} else {
 				//persistent memory
@@ -813,17 +1113,35 @@ your only answer to all of questions is the improved version of "` + llm.NotRela
 	result = LLMResult{
 		Prompt:          msgs,
 		Response:        response,
-		RagDocs:         resDocs,
+		RagDocs:         retrievedDocuments(resDocs),
 		Memory:          memoryData[:],
 		Actions:         result.Actions,
 		MemorySummary:   MemorySummary,
 		TokenReport:     result.TokenReport,
 		FailedToRespond: failedToRespond,
+		RagImages:       result.RagImages,
+		Timings:         result.Timings,
+		DebugInfo:       result.DebugInfo,
+		RequestID:       result.RequestID,
+		PIIMatches:      result.PIIMatches,
+		Moderation:      result.Moderation,
+		Groundedness:    result.Groundedness,
+		Confidence:      result.Confidence,
+		WebSources:      result.WebSources,
+		GlossaryFixes:   result.GlossaryFixes,
+		MatchedLanguage: result.MatchedLanguage,
+		retrievalQuery:  result.retrievalQuery,
+		retrievalPrefix: result.retrievalPrefix,
+		searchAlgorithm: result.searchAlgorithm,
 	}
 	if o.RagReferences {
-		refrencesArray := llmReference{}
-		json.Unmarshal([]byte(refrencesStr), &refrencesArray)
-		result.LLMReferences = refrencesArray.References
+		result.Citations = verifyCitations(parseCitations(refrencesStr), resDocs)
+		result.LLMReferences = referenceIDs(result.Citations, llm.KeepUnverifiedReferences)
+		for _, citation := range result.Citations {
+			if !citation.Verified {
+				logger.Warn("fabricated reference in answer", "id", citation.ID, "reason", citation.Reason)
+			}
+		}
 	}
 	return result, err
 }
@@ -861,3 +1179,148 @@ func extractMemoryData(input string) MemoryData {
 
 	return memoryData
 }
+
+// retrievalPrefix returns the key prefix of the chunks searched by a call.
+//
+// Calls without index search all indexes, index searches without language use the FallbackLanguage.
+func (llm *LLMContainer) retrievalPrefix(o *LLMCallOptions) string {
+	KNNPrefix := "context:"
+	if o.getEmbeddingPrefix() != "" {
+		KNNPrefix += o.getEmbeddingPrefix() + ":"
+	}
+	if o.Index == "" {
+		o.searchAll = true
+	}
+	if o.searchAll {
+		// o.Prefix =
+		KNNPrefix = "all:"
+		if o.getEmbeddingPrefix() != "" {
+			KNNPrefix += o.getEmbeddingPrefix() + ":"
+		}
+
+	} else {
+		KNNPrefix += o.Index + ":"
+		if o.Language == "" {
+			if fallbackLanguages := llm.fallbackLanguages(); len(fallbackLanguages) > 0 {
+				o.Language = fallbackLanguages[0]
+			}
+		}
+
+	}
+	// Issue with forced language. Interference with vector search index!!!! Will be fixed in the future.
+	if o.Language != "" && !o.ForceLanguage {
+		KNNPrefix += o.Language + ":"
+	}
+	return KNNPrefix
+}
+
+// fallbackLanguages returns the languages searched in order when the language of a call finds nothing,
+// FallbackLanguages or else FallbackLanguage.
+func (llm *LLMContainer) fallbackLanguages() []string {
+	if len(llm.FallbackLanguages) > 0 {
+		return llm.FallbackLanguages
+	}
+	if llm.FallbackLanguage != "" {
+		return []string{llm.FallbackLanguage}
+	}
+	return nil
+}
+
+// languageSearch is the retrieval of a call in one of its languages, see ConcurrentFallbackSearch.
+type languageSearch struct {
+	language string
+	prefixes []string
+	boosts   map[string]float64
+	docs     []schema.Document
+	err      error
+}
+
+// bestRankingScore returns the ranking score of the best document, see rankingScore.
+func bestRankingScore(docs []schema.Document) float64 {
+	best := math.Inf(-1)
+	for _, doc := range docs {
+		best = math.Max(best, rankingScore(doc))
+	}
+	return best
+}
+
+// retrieveDocuments searches the documents of a prefix with the given search algorithm.
+//
+// The retrieval settings of the prefix, see SetRetrievalSettings, override the container settings, a rowCount
+// above 0 overrides the chunk count of both, see WithRagRowCount.
+func (llm *LLMContainer) retrieveDocuments(ctx context.Context, searchAlgorithm SearchAlgorithm, prefix, query string, rowCount int) ([]schema.Document, error) {
+	settings := llm.RetrievalSettings(prefix)
+	if rowCount > 0 {
+		settings.RagRowCount = rowCount
+	}
+	if llm.Retriever != nil {
+		return llm.retrieveWithRetriever(ctx, searchAlgorithm, prefix, query, settings)
+	}
+	switch searchAlgorithm {
+	case SimilaritySearch:
+		// Retrieve related documents using cosine similarity search
+		return llm.cosineSimilarity(ctx, prefix, query, settings.RagRowCount, settings.ScoreThreshold)
+	case KNearestNeighbors:
+		// Retrieve related documents using KNN search
+		return llm.findKNN(ctx, prefix, query, settings.RagRowCount, settings.ScoreThreshold)
+	case HybridSearch:
+		// Retrieve related documents using hybrid search (vector + lexical)
+		config := DefaultHybridSearchConfig()
+		config.VectorWeight, config.LexicalWeight = settings.VectorWeight, settings.LexicalWeight
+		return llm.hybridSearch(ctx, prefix, query, settings.RagRowCount, settings.ScoreThreshold, &config)
+	case LexicalSearch:
+		// Retrieve related documents using lexical search only
+		return llm.performLexicalSearchOnly(ctx, prefix, query, settings.RagRowCount, settings.ScoreThreshold)
+	case SemanticSearch:
+		// Retrieve related documents using enhanced semantic search
+		return llm.semanticSearch(ctx, prefix, query, settings.RagRowCount, settings.ScoreThreshold)
+	}
+	return nil, errors.New("unknown search algorithm")
+}
+
+// Patterns used to clean up retrieved chunks with WithCotextCleanup
+var (
+	htmlTagPattern  = regexp.MustCompile(`<[^>]+>`)
+	spacesPattern   = regexp.MustCompile(`\s+`)
+	newlinesPattern = regexp.MustCompile(`\n+`)
+)
+
+// buildContextChunks formats the retrieved documents as numbered chunks of the prompt context.
+//
+// Parameters:
+//   - docs: The retrieved documents.
+//   - ragReferences: Adds the reference id of every chunk.
+//   - cleanup: Removes HTML tags and extra whitespace from the chunks.
+//
+// Returns:
+//   - string: The formatted chunks.
+func buildContextChunks(docs []schema.Document, ragReferences, cleanup bool) string {
+	var ragText strings.Builder
+	for idx, doc := range docs {
+		if idx > 0 {
+			ragText.WriteString("\n")
+		}
+		content := "Chunk " + strconv.Itoa(idx+1) + ":\n"
+
+		if ragReferences {
+			if id := documentReferenceID(doc); id != "" {
+				content += `- Reference: {"id":"` + id + `"` + "}\n"
+			}
+		}
+		content += doc.PageContent + "\n\n"
+		if cleanup {
+			content = htmlTagPattern.ReplaceAllString(content, "")
+
+			// Replacing repeated spaces with a single space
+			content = spacesPattern.ReplaceAllString(content, " ")
+
+			// Removing empty lines
+			content = newlinesPattern.ReplaceAllString(content, "\n")
+
+			// Removing extra spaces at the beginning and end
+			content = strings.TrimSpace(content)
+		}
+		ragText.WriteString(content)
+	}
+	return ragText.String()
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DocumentLoader extracts the text of a source type, e.g. SharePoint documents or e-mail archives.
+//
+// Loaders are registered with RegisterDocumentLoader, EmbeddFile and EmbeddURL then dispatch to them
+// by URL scheme or MIME type before using the built-in PDF, HTML, text and Tika extraction.
+type DocumentLoader interface {
+	// Load returns the text of a source.
+	//
+	// source is the URL or file name passed to EmbeddURL or EmbeddFile when the loader matched its
+	// scheme, and the path of the local (or downloaded) file when it matched its MIME type.
+	// ctx is bounded by TranscribeConfig.MaxTimeout when it is set.
+	Load(ctx context.Context, source string, tc TranscribeConfig) (string, error)
+}
+
+// DocumentLoaderFunc adapts a function to the DocumentLoader interface.
+type DocumentLoaderFunc func(ctx context.Context, source string, tc TranscribeConfig) (string, error)
+
+// Load calls f.
+func (f DocumentLoaderFunc) Load(ctx context.Context, source string, tc TranscribeConfig) (string, error) {
+	return f(ctx, source, tc)
+}
+
+// DocumentLoaderSpec registers a DocumentLoader.
+//
+// Fields:
+//   - Name: Unique name of the loader, registering the same name again replaces it.
+//   - Schemes: URL schemes handled by the loader, e.g. "sharepoint" for "sharepoint://site/file.docx".
+//   - MimeTypes: MIME types handled by the loader, e.g. "application/vnd.ms-outlook", a type ending with "/"
+//     matches all its subtypes, e.g. "audio/".
+//   - Loader: The loader.
+type DocumentLoaderSpec struct {
+	Name      string
+	Schemes   []string
+	MimeTypes []string
+	Loader    DocumentLoader
+}
+
+// documentLoaders holds the registered loaders, shared by all containers like database/sql drivers.
+var documentLoaders = struct {
+	mu    sync.RWMutex
+	specs map[string]DocumentLoaderSpec
+}{specs: make(map[string]DocumentLoaderSpec)}
+
+// RegisterDocumentLoader adds a loader for new source types, usually from the init function of the
+// package providing it.
+//
+// Parameters:
+//   - spec: The loader and the schemes and MIME types it handles.
+//
+// Returns:
+//   - error: An error if the name or the loader is missing, or nothing is matched.
+//
+// Example Usage:
+//
+//	aillm.RegisterDocumentLoader(aillm.DocumentLoaderSpec{
+//		Name:    "sharepoint",
+//		Schemes: []string{"sharepoint"},
+//		Loader: aillm.DocumentLoaderFunc(func(ctx context.Context, source string, tc aillm.TranscribeConfig) (string, error) {
+//			return sharepointClient.DocumentText(ctx, source)
+//		}),
+//	})
+//	llm.EmbeddURL("Policies", "sharepoint://intranet/hr/policies.docx", aillm.TranscribeConfig{Language: "en"})
+func RegisterDocumentLoader(spec DocumentLoaderSpec) error {
+	if spec.Name == "" || spec.Loader == nil {
+		return errors.New("document loader name and loader are required")
+	}
+	if len(spec.Schemes) == 0 && len(spec.MimeTypes) == 0 {
+		return errors.New("document loader must handle at least one scheme or MIME type")
+	}
+	documentLoaders.mu.Lock()
+	defer documentLoaders.mu.Unlock()
+	documentLoaders.specs[spec.Name] = spec
+	return nil
+}
+
+// UnregisterDocumentLoader removes a registered loader.
+func UnregisterDocumentLoader(name string) {
+	documentLoaders.mu.Lock()
+	defer documentLoaders.mu.Unlock()
+	delete(documentLoaders.specs, name)
+}
+
+// DocumentLoaders returns the registered loaders sorted by name.
+func DocumentLoaders() []DocumentLoaderSpec {
+	documentLoaders.mu.RLock()
+	defer documentLoaders.mu.RUnlock()
+	specs := make([]DocumentLoaderSpec, 0, len(documentLoaders.specs))
+	for _, spec := range documentLoaders.specs {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+	return specs
+}
+
+// findDocumentLoader returns the first loader, by name, matching the scheme of a source or a MIME type.
+func findDocumentLoader(match func(spec DocumentLoaderSpec) bool) DocumentLoader {
+	for _, spec := range DocumentLoaders() {
+		if match(spec) {
+			return spec.Loader
+		}
+	}
+	return nil
+}
+
+// schemeLoader returns the loader registered for the URL scheme of source, if any.
+func schemeLoader(source string) DocumentLoader {
+	parsed, err := url.Parse(source)
+	if err != nil || parsed.Scheme == "" {
+		return nil
+	}
+	return findDocumentLoader(func(spec DocumentLoaderSpec) bool {
+		for _, scheme := range spec.Schemes {
+			if strings.EqualFold(scheme, parsed.Scheme) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// mimeTypeLoader returns the loader registered for a MIME type, parameters such as charset are ignored.
+func mimeTypeLoader(mimeType string) DocumentLoader {
+	mimeType, _, _ = strings.Cut(strings.ToLower(mimeType), ";")
+	mimeType = strings.TrimSpace(mimeType)
+	if mimeType == "" {
+		return nil
+	}
+	return findDocumentLoader(func(spec DocumentLoaderSpec) bool {
+		for _, handled := range spec.MimeTypes {
+			handled = strings.ToLower(handled)
+			if handled == mimeType || (strings.HasSuffix(handled, "/") && strings.HasPrefix(mimeType, handled)) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// load runs a loader with the timeout of the transcription configuration.
+func (Ts *Transcriber) load(loader DocumentLoader, source string, tc TranscribeConfig) (string, int, error) {
+	ctx := context.Background()
+	if tc.MaxTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tc.MaxTimeout)
+		defer cancel()
+	}
+	text, err := loader.Load(ctx, source, tc)
+	return text, 0, err
+}
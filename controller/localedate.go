@@ -0,0 +1,152 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLocale holds the names and the layout of the dates of a language.
+//
+// The layout placeholders are {weekday}, {day}, {month}, {year} and {time}.
+type dateLocale struct {
+	weekdays [7]string // Sunday first, as time.Weekday
+	months   [12]string
+	layout   string
+}
+
+// dateLocales are the date formats of the languages WithIncludeDate writes dates in, other languages use English.
+var dateLocales = map[string]dateLocale{
+	"en": {
+		weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		months:   [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		layout:   "{weekday}, {month} {day}, {year}, {time}",
+	},
+	"de": {
+		weekdays: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		months:   [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		layout:   "{weekday}, {day}. {month} {year}, {time} Uhr",
+	},
+	"fr": {
+		weekdays: [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		months:   [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		layout:   "{weekday} {day} {month} {year}, {time}",
+	},
+	"es": {
+		weekdays: [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		months:   [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		layout:   "{weekday}, {day} de {month} de {year}, {time}",
+	},
+	"pt": {
+		weekdays: [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		months:   [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		layout:   "{weekday}, {day} de {month} de {year}, {time}",
+	},
+	"it": {
+		weekdays: [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		months:   [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		layout:   "{weekday} {day} {month} {year}, {time}",
+	},
+	"nl": {
+		weekdays: [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		months:   [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		layout:   "{weekday} {day} {month} {year}, {time}",
+	},
+	"ru": {
+		weekdays: [7]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"},
+		months:   [12]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"},
+		layout:   "{weekday}, {day} {month} {year} г., {time}",
+	},
+	"tr": {
+		weekdays: [7]string{"Pazar", "Pazartesi", "Salı", "Çarşamba", "Perşembe", "Cuma", "Cumartesi"},
+		months:   [12]string{"Ocak", "Şubat", "Mart", "Nisan", "Mayıs", "Haziran", "Temmuz", "Ağustos", "Eylül", "Ekim", "Kasım", "Aralık"},
+		layout:   "{day} {month} {year} {weekday}, {time}",
+	},
+	"ar": {
+		weekdays: [7]string{"الأحد", "الاثنين", "الثلاثاء", "الأربعاء", "الخميس", "الجمعة", "السبت"},
+		months:   [12]string{"يناير", "فبراير", "مارس", "أبريل", "مايو", "يونيو", "يوليو", "أغسطس", "سبتمبر", "أكتوبر", "نوفمبر", "ديسمبر"},
+		layout:   "{weekday}، {day} {month} {year}، {time}",
+	},
+	"fa": {
+		// Persian dates use the Solar Hijri calendar
+		weekdays: [7]string{"یکشنبه", "دوشنبه", "سه‌شنبه", "چهارشنبه", "پنجشنبه", "جمعه", "شنبه"},
+		months:   [12]string{"فروردین", "اردیبهشت", "خرداد", "تیر", "مرداد", "شهریور", "مهر", "آبان", "آذر", "دی", "بهمن", "اسفند"},
+		layout:   "{weekday} {day} {month} {year}، ساعت {time}",
+	},
+	"ja": {
+		weekdays: [7]string{"日", "月", "火", "水", "木", "金", "土"},
+		months:   [12]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12"},
+		layout:   "{year}年{month}月{day}日({weekday}) {time}",
+	},
+	"zh": {
+		weekdays: [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+		months:   [12]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12"},
+		layout:   "{year}年{month}月{day}日 {weekday} {time}",
+	},
+}
+
+// gregorianToJalali converts a Gregorian date to the Solar Hijri calendar.
+func gregorianToJalali(gy, gm, gd int) (int, int, int) {
+	monthDays := [12]int{0, 31, 59, 90, 120, 151, 181, 212, 243, 273, 304, 334}
+	gy2 := gy
+	if gm > 2 {
+		gy2++
+	}
+	days := 355666 + 365*gy + (gy2+3)/4 - (gy2+99)/100 + (gy2+399)/400 + gd + monthDays[gm-1]
+	jy := -1595 + 33*(days/12053)
+	days %= 12053
+	jy += 4 * (days / 1461)
+	days %= 1461
+	if days > 365 {
+		jy += (days - 1) / 365
+		days = (days - 1) % 365
+	}
+	if days < 186 {
+		return jy, 1 + days/31, 1 + days%31
+	}
+	return jy, 7 + (days-186)/30, 1 + (days-186)%30
+}
+
+// localeDate writes a time in the language of the answer, followed by its ISO 8601 form and timezone so the model
+// can compute relative dates, e.g. "Donnerstag, 15. Oktober 2026, 14:05 Uhr (2026-10-15T14:05+02:00, Europe/Berlin)".
+func localeDate(now time.Time, language string) string {
+	locale, ok := dateLocales[baseLanguage(language)]
+	if !ok {
+		locale = dateLocales["en"]
+	}
+	year, month, day := now.Year(), int(now.Month()), now.Day()
+	if baseLanguage(language) == "fa" {
+		year, month, day = gregorianToJalali(year, month, day)
+	}
+	date := strings.NewReplacer(
+		"{weekday}", locale.weekdays[now.Weekday()],
+		"{day}", strconv.Itoa(day),
+		"{month}", locale.months[month-1],
+		"{year}", strconv.Itoa(year),
+		"{time}", now.Format("15:04"),
+	).Replace(locale.layout)
+	return date + " (" + now.Format("2006-01-02T15:04Z07:00") + ", " + now.Location().String() + ")"
+}
+
+// datePrompt returns the instruction giving the model the current date in the answer language and the Location of
+// the container.
+func (llm *LLMContainer) datePrompt(language string) string {
+	now := time.Now()
+	if llm.Location != nil {
+		now = now.In(llm.Location)
+	}
+	return "- It is " + localeDate(now, language) + ". Adjust your response based on the current date and time.\n"
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"log/slog"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// logger returns the configured structured logger or slog.Default().
+func (llm *LLMContainer) logger() *slog.Logger {
+	if llm.Logger != nil {
+		return llm.Logger
+	}
+	return slog.Default()
+}
+
+// logger returns the configured structured logger of the transcriber or slog.Default().
+func (Ts *Transcriber) logger() *slog.Logger {
+	if Ts.Logger != nil {
+		return Ts.Logger
+	}
+	return slog.Default()
+}
+
+// promptLogValue converts a prompt to a compact form for debug logging.
+func promptLogValue(msgs []llms.MessageContent) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(msgs))
+	for _, msg := range msgs {
+		texts := []string{}
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case llms.TextContent:
+				texts = append(texts, p.Text)
+			case llms.ToolCall:
+				texts = append(texts, "tool call: "+p.FunctionCall.Name+" "+p.FunctionCall.Arguments)
+			case llms.ToolCallResponse:
+				texts = append(texts, "tool response: "+p.Name+" "+p.Content)
+			default:
+				texts = append(texts, "[binary content]")
+			}
+		}
+		result = append(result, map[string]interface{}{"role": string(msg.Role), "parts": texts})
+	}
+	return result
+}
+
+// requestLogger returns the container logger with the request ID attached, if any.
+func (llm *LLMContainer) requestLogger(requestID string) *slog.Logger {
+	if requestID == "" {
+		return llm.logger()
+	}
+	return llm.logger().With("request_id", requestID)
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// MapReduceConfig answers from retrieved contexts larger than a token budget in two steps, instead of sending
+// every chunk in a single prompt.
+//
+// The chunks are split into groups that fit the budget and the model extracts the facts answering the query from
+// each group in parallel (map). The answer is then generated from the extracted facts (reduce).
+//
+// Fields:
+//   - ContextTokens: The token budget of the retrieved context, map-reduce is disabled when 0. Tokens are estimated
+//     from the length of the chunks.
+//   - Concurrency: The maximum number of parallel map calls (default 4).
+type MapReduceConfig struct {
+	ContextTokens int
+	Concurrency   int
+}
+
+func (mc MapReduceConfig) concurrency() int {
+	if mc.Concurrency > 0 {
+		return mc.Concurrency
+	}
+	return 4
+}
+
+// mapPrompt extracts the facts of a group of chunks that answer the query.
+const mapPrompt = `Extract from the chunks below the information that helps to answer the question.
+- Reply with a concise summary of the relevant facts, keep numbers, names and dates exact.
+- Keep the Reference lines of the chunks you use, if any, at the end of the summary.
+- If no chunk is relevant to the question, reply only with "NONE".
+
+### Chunks:
+{{chunks}}
+
+### Question:
+{{question}}`
+
+// WithMapReduce enables or disables map-reduce answering of a call, overriding the container default, which is
+// enabled when MapReduce.ContextTokens is set.
+//
+// Parameters:
+//   - enabled: True answers large retrievals with map-reduce, false sends every retrieved chunk in a single prompt.
+//
+// Returns:
+//   - LLMCallOption: An option that sets map-reduce answering.
+func (llm *LLMContainer) WithMapReduce(enabled bool) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.mapReduce = &enabled
+	}
+}
+
+// useMapReduce reports whether a call answers large retrievals with map-reduce.
+func (llm *LLMContainer) useMapReduce(o LLMCallOptions) bool {
+	if llm.MapReduce.ContextTokens <= 0 {
+		return false
+	}
+	return o.mapReduce == nil || *o.mapReduce
+}
+
+// mapChunkGroups splits chunks into consecutive groups whose estimated tokens fit a budget.
+//
+// A chunk larger than the budget forms a group of its own.
+func mapChunkGroups(docs []schema.Document, budget int, ragReferences, cleanup bool) [][]schema.Document {
+	groups := [][]schema.Document{}
+	current, tokens := []schema.Document{}, 0
+	for _, doc := range docs {
+		docTokens := estimateTokens(buildContextChunks([]schema.Document{doc}, ragReferences, cleanup))
+		if len(current) > 0 && tokens+docTokens > budget {
+			groups = append(groups, current)
+			current, tokens = []schema.Document{}, 0
+		}
+		current = append(current, doc)
+		tokens += docTokens
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
+
+// mapReduceContext extracts the facts answering a query from each group of chunks in parallel.
+//
+// Parameters:
+//   - ctx: The context of the call.
+//   - model: The model answering the call.
+//   - query: The query of the user.
+//   - docs: The retrieved chunks.
+//   - o: The options of the call.
+//
+// Returns:
+//   - string: The extracted facts used as context of the final answer, empty when the chunks fit the budget.
+//   - TokenUsage: Tokens used by the map calls.
+//   - error: An error if a map call fails.
+func (llm *LLMContainer) mapReduceContext(ctx context.Context, model llms.Model, query string, docs []schema.Document, o LLMCallOptions) (string, TokenUsage, error) {
+	usage := TokenUsage{}
+	groups := mapChunkGroups(docs, llm.MapReduce.ContextTokens, o.RagReferences, o.CotextCleanup)
+	if len(groups) < 2 {
+		return "", usage, nil
+	}
+	var outputTokens atomic.Int64
+	answers := make([]string, len(groups))
+	errs := make([]error, len(groups))
+	semaphore := make(chan struct{}, llm.MapReduce.concurrency())
+	var wait sync.WaitGroup
+	for i, group := range groups {
+		wait.Add(1)
+		go func(i int, group []schema.Document) {
+			defer wait.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			prompt := strings.NewReplacer("{{chunks}}", buildContextChunks(group, o.RagReferences, o.CotextCleanup),
+				"{{question}}", query).Replace(mapPrompt)
+			response, err := model.GenerateContent(ctx,
+				[]llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)},
+				llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+					outputTokens.Add(1)
+					return nil
+				}),
+				llms.WithTemperature(0))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if len(response.Choices) > 0 {
+				answers[i] = strings.TrimSpace(response.Choices[0].Content)
+			}
+		}(i, group)
+	}
+	wait.Wait()
+	usage.OutputTokens = int(outputTokens.Load())
+	for _, err := range errs {
+		if err != nil {
+			return "", usage, err
+		}
+	}
+
+	var mapped strings.Builder
+	first := 1
+	for i, answer := range answers {
+		last := first + len(groups[i]) - 1
+		if answer != "" && !strings.EqualFold(strings.Trim(answer, ` ."`), "NONE") {
+			mapped.WriteString("Facts from chunks " + strconv.Itoa(first) + "-" + strconv.Itoa(last) + ":\n" + answer + "\n\n")
+		}
+		first = last + 1
+	}
+	if mapped.Len() == 0 {
+		return "None of the chunks is relevant to the question.\n", usage, nil
+	}
+	return mapped.String(), usage, nil
+}
@@ -14,6 +14,7 @@
 package aillm
 
 import (
+	"sort"
 	"sync"
 	"time"
 )
@@ -46,6 +47,21 @@ type MemoryData struct {
 	Summary  string
 }
 
+// MemoryStore stores the recent questions and answers of the sessions, see LLMContainer.SessionMemory.
+//
+// MemoryManager is the default implementation, replace it to share sessions between servers or to
+// seed the history of a session in tests.
+//
+// Methods:
+//   - GetMemory(): Returns the memory of a session, false if the session has none.
+//   - AddMemory(): Replaces the history of a session.
+//   - DeleteMemory(): Removes the memory of a session.
+type MemoryStore interface {
+	GetMemory(sessionID string) (Memory, bool)
+	AddMemory(sessionID string, questions []MemoryData)
+	DeleteMemory(sessionID string)
+}
+
 // MemoryManager manages session memories with a time-to-live (TTL) mechanism.
 //
 // This struct is responsible for storing user sessions, providing thread-safe access
@@ -55,10 +71,15 @@ type MemoryData struct {
 //   - memoryMap: A map storing session ID as the key and Memory struct as the value.
 //   - mu: A mutex to ensure thread-safe operations on the memory map.
 //   - ttl: The time-to-live (TTL) duration after which sessions will be removed automatically.
+//   - stop: Closed by Close to stop the cleanup routine.
+//   - languages: The detected language of each session, removed when unused for the TTL.
 type MemoryManager struct {
 	memoryMap map[string]Memory // Stores session data with session ID as the key
 	mu        sync.Mutex        // Mutex to prevent concurrent access issues
 	ttl       time.Duration     // Session expiration time duration
+	stop      chan struct{}     // Stops the cleanup routine
+	stopOnce  sync.Once
+	languages map[string]sessionLanguage
 }
 
 // NewMemoryManager creates and initializes a new MemoryManager with a specified TTL (Time-To-Live).
@@ -75,6 +96,7 @@ func NewMemoryManager(ttlMinutes int) *MemoryManager {
 	m := &MemoryManager{
 		memoryMap: make(map[string]Memory),                 // Initialize memory map
 		ttl:       time.Duration(ttlMinutes) * time.Minute, // Set TTL duration
+		stop:      make(chan struct{}),
 	}
 	go m.cleanupExpiredSessions() // Start the cleanup routine in the background
 	return m
@@ -126,6 +148,28 @@ func (m *MemoryManager) DeleteMemory(sessionID string) {
 	delete(m.memoryMap, sessionID)
 }
 
+// ListSessions returns the IDs of the sessions currently held in memory, sorted.
+//
+// Returns:
+//   - []string: The session IDs.
+func (m *MemoryManager) ListSessions() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := make([]string, 0, len(m.memoryMap))
+	for sessionID := range m.memoryMap {
+		sessions = append(sessions, sessionID)
+	}
+	sort.Strings(sessions)
+	return sessions
+}
+
+// Close stops the background cleanup of expired sessions, the stored sessions stay readable.
+func (m *MemoryManager) Close() {
+	m.stopOnce.Do(func() {
+		close(m.stop)
+	})
+}
+
 // cleanupExpiredSessions periodically removes expired sessions from the memory map.
 //
 // This function runs in a background goroutine and executes every 10 minutes to check for expired sessions.
@@ -135,7 +179,12 @@ func (m *MemoryManager) cleanupExpiredSessions() {
 
 	ticker := time.NewTicker(10 * time.Minute) // Run cleanup every 10 minutes
 	defer ticker.Stop()
-	for range ticker.C {
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
 		m.mu.Lock()
 		for sessionID, mem := range m.memoryMap {
 			// Check if the session has expired based on the TTL
@@ -143,6 +192,22 @@ func (m *MemoryManager) cleanupExpiredSessions() {
 				delete(m.memoryMap, sessionID) // Remove expired session
 			}
 		}
+		for sessionID, language := range m.languages {
+			if time.Since(language.lastUsed) > m.ttl {
+				delete(m.languages, sessionID)
+			}
+		}
 		m.mu.Unlock()
 	}
 }
+
+// sessionMemory returns the session history store of the container, SessionMemory or else MemoryManager.
+func (llm *LLMContainer) sessionMemory() MemoryStore {
+	if llm.SessionMemory != nil {
+		return llm.SessionMemory
+	}
+	if llm.MemoryManager == nil {
+		return nil
+	}
+	return llm.MemoryManager
+}
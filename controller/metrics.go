@@ -0,0 +1,172 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// llmMetrics holds the Prometheus metrics of an LLMContainer.
+//
+// All methods are safe to call on a nil receiver, so metrics are only collected once Metrics()
+// or Init() created them.
+type llmMetrics struct {
+	requests         *prometheus.CounterVec
+	requestLatency   prometheus.Histogram
+	retrievalLatency *prometheus.HistogramVec
+	tokens           *prometheus.CounterVec
+	cacheRequests    *prometheus.CounterVec
+	providerErrors   *prometheus.CounterVec
+}
+
+// newLLMMetrics creates the metric set of a container.
+func newLLMMetrics() *llmMetrics {
+	return &llmMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "aillm",
+			Name:      "requests_total",
+			Help:      "Number of AskLLM requests by status.",
+		}, []string{"status"}),
+		requestLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "aillm",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of AskLLM requests.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+		}),
+		retrievalLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "aillm",
+			Name:      "retrieval_duration_seconds",
+			Help:      "Duration of document retrieval by search algorithm.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 10),
+		}, []string{"algorithm"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "aillm",
+			Name:      "tokens_total",
+			Help:      "Number of tokens used by stage and direction.",
+		}, []string{"stage", "direction"}),
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "aillm",
+			Name:      "cache_requests_total",
+			Help:      "Number of cache lookups by cache and result (hit or miss).",
+		}, []string{"cache", "result"}),
+		providerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "aillm",
+			Name:      "provider_errors_total",
+			Help:      "Number of errors returned by model providers.",
+		}, []string{"provider"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *llmMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.requestLatency.Describe(ch)
+	m.retrievalLatency.Describe(ch)
+	m.tokens.Describe(ch)
+	m.cacheRequests.Describe(ch)
+	m.providerErrors.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *llmMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.requestLatency.Collect(ch)
+	m.retrievalLatency.Collect(ch)
+	m.tokens.Collect(ch)
+	m.cacheRequests.Collect(ch)
+	m.providerErrors.Collect(ch)
+}
+
+// Metrics returns a Prometheus collector exposing request, token, retrieval latency, cache and
+// provider error metrics of the container.
+//
+// Register it with the registry of the host application:
+//
+//	prometheus.MustRegister(llm.Metrics())
+//
+// Returns:
+//   - prometheus.Collector: The collector of this container.
+func (llm *LLMContainer) Metrics() prometheus.Collector {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.metrics == nil {
+		llm.metrics = newLLMMetrics()
+	}
+	return llm.metrics
+}
+
+// observeRequest records a finished AskLLM request and its token usage.
+func (m *llmMetrics) observeRequest(start time.Time, report TokenReport, err error) {
+	if m == nil {
+		return
+	}
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.requests.WithLabelValues(status).Inc()
+	m.requestLatency.Observe(time.Since(start).Seconds())
+	m.addTokens("completion", report.CompletionTokens)
+	m.addTokens("chunking", report.TextChunkingTokens)
+	m.addTokens("language_detection", report.LanguageDetectionTokens)
+	m.addTokens("memory_summarization", report.MemorySummarizationTokens)
+	m.addTokens("security_check", report.SecurityCheckTokens)
+	m.addTokens("vision", report.VisionTokens)
+	m.addTokens("groundedness", report.GroundednessTokens)
+	m.addTokens("map_reduce", report.MapReduceTokens)
+	m.addTokens("translation", report.TranslationTokens)
+}
+
+// addTokens adds the token usage of a stage.
+func (m *llmMetrics) addTokens(stage string, usage TokenUsage) {
+	if m == nil {
+		return
+	}
+	if usage.InputTokens > 0 {
+		m.tokens.WithLabelValues(stage, "input").Add(float64(usage.InputTokens))
+	}
+	if usage.OutputTokens > 0 {
+		m.tokens.WithLabelValues(stage, "output").Add(float64(usage.OutputTokens))
+	}
+}
+
+// observeRetrieval records the duration of a document retrieval.
+func (m *llmMetrics) observeRetrieval(algorithm SearchAlgorithm, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.retrievalLatency.WithLabelValues(algorithm.String()).Observe(time.Since(start).Seconds())
+}
+
+// observeCache records a cache lookup.
+func (m *llmMetrics) observeCache(cache string, hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// observeProviderError records an error returned by a model provider (llm, embedding or vision).
+func (m *llmMetrics) observeProviderError(provider string) {
+	if m == nil {
+		return
+	}
+	m.providerErrors.WithLabelValues(provider).Inc()
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Moderation actions.
+const (
+	ModerationBlock    = "block"    // Fails the call with a *ModerationError
+	ModerationRedact   = "redact"   // Replaces the flagged text with ModerationConfig.RedactionText
+	ModerationAnnotate = "annotate" // Only reports the result in LLMResult.Moderation
+)
+
+// Moderation stages.
+const (
+	ModerationQuery  = "query"
+	ModerationAnswer = "answer"
+)
+
+// ErrModerationBlocked matches the *ModerationError of a call blocked by moderation, with errors.Is.
+var ErrModerationBlocked = errors.New("blocked by moderation")
+
+// Moderator classifies texts for harmful content, e.g. with the OpenAI moderation endpoint or a local classifier.
+type Moderator interface {
+	Moderate(ctx context.Context, text string) (ModerationResult, error)
+}
+
+// ModeratorFunc adapts a function, e.g. a local classifier, to the Moderator interface.
+type ModeratorFunc func(ctx context.Context, text string) (ModerationResult, error)
+
+// Moderate calls f.
+func (f ModeratorFunc) Moderate(ctx context.Context, text string) (ModerationResult, error) {
+	return f(ctx, text)
+}
+
+// ModerationResult is the classification of a text.
+//
+// Fields:
+//   - Flagged: True if the text violates a category.
+//   - Categories: The flagged categories, e.g. "harassment".
+//   - Scores: The score of every category, if the moderator provides them.
+type ModerationResult struct {
+	Flagged    bool               `json:"flagged"`
+	Categories []string           `json:"categories,omitempty"`
+	Scores     map[string]float64 `json:"scores,omitempty"`
+}
+
+// ModerationReport is the moderation of a stage of an AskLLM call, reported in LLMResult.Moderation.
+//
+// Fields:
+//   - Stage: ModerationQuery or ModerationAnswer.
+//   - Action: The action applied, empty if the text wasn't flagged.
+//   - Result: The moderator result.
+type ModerationReport struct {
+	Stage  string           `json:"stage"`
+	Action string           `json:"action,omitempty"`
+	Result ModerationResult `json:"result"`
+}
+
+// ModerationError is returned by AskLLM when moderation blocks a query or an answer.
+type ModerationError struct {
+	Stage  string
+	Result ModerationResult
+}
+
+func (e *ModerationError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Stage, ErrModerationBlocked, strings.Join(e.Result.Categories, ", "))
+}
+
+// Is reports whether target is ErrModerationBlocked.
+func (e *ModerationError) Is(target error) bool {
+	return target == ErrModerationBlocked
+}
+
+// ModerationConfig configures the moderation of queries and answers.
+//
+// Fields:
+//   - Moderator: The classifier, moderation is disabled when nil.
+//   - Action: ModerationBlock (default), ModerationRedact or ModerationAnnotate.
+//   - OnQuery: Moderates the user query before retrieval, a redacted query is answered with RedactionText
+//     without calling the LLM.
+//   - OnAnswer: Moderates the completed answer. Streamed chunks have already been sent, so streaming
+//     clients should use the final result.
+//   - RedactionText: The text replacing flagged content, default "This content was removed by moderation.".
+type ModerationConfig struct {
+	Moderator     Moderator
+	Action        string
+	OnQuery       bool
+	OnAnswer      bool
+	RedactionText string
+}
+
+// action returns the configured action, ModerationBlock by default.
+func (mc ModerationConfig) action() string {
+	if mc.Action == "" {
+		return ModerationBlock
+	}
+	return mc.Action
+}
+
+// redactionText returns the text replacing flagged content.
+func (mc ModerationConfig) redactionText() string {
+	if mc.RedactionText == "" {
+		return "This content was removed by moderation."
+	}
+	return mc.RedactionText
+}
+
+// moderate classifies the text of a stage and reports it in result.
+//
+// Returns:
+//   - bool: True if the text was flagged and the action is ModerationRedact.
+//   - error: A *ModerationError if the text is blocked, or the moderator error.
+func (llm *LLMContainer) moderate(ctx context.Context, stage, text string, result *LLMResult) (bool, error) {
+	moderation, err := llm.Moderation.Moderator.Moderate(ctx, text)
+	if err != nil {
+		return false, fmt.Errorf("moderation failed: %w", err)
+	}
+	report := ModerationReport{Stage: stage, Result: moderation}
+	if moderation.Flagged {
+		report.Action = llm.Moderation.action()
+	}
+	result.Moderation = append(result.Moderation, report)
+	if !moderation.Flagged {
+		return false, nil
+	}
+	llm.logger().Warn("content flagged by moderation", "stage", stage, "categories", moderation.Categories, "action", report.Action)
+	switch report.Action {
+	case ModerationBlock:
+		return false, &ModerationError{Stage: stage, Result: moderation}
+	case ModerationRedact:
+		return true, nil
+	}
+	return false, nil
+}
+
+// OpenAIModerator uses the OpenAI moderation endpoint, or a compatible one.
+//
+// Fields:
+//   - APIToken: The API key.
+//   - Apiurl: The API base URL, default "https://api.openai.com/v1".
+//   - Model: The moderation model, default "omni-moderation-latest".
+//   - Client: The HTTP client, defaults to the client shared by the providers.
+type OpenAIModerator struct {
+	APIToken string
+	Apiurl   string
+	Model    string
+	Client   *http.Client
+}
+
+// Moderate classifies a text with the moderation endpoint.
+func (om *OpenAIModerator) Moderate(ctx context.Context, text string) (ModerationResult, error) {
+	result := ModerationResult{}
+	baseURL := om.Apiurl
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	model := om.Model
+	if model == "" {
+		model = "omni-moderation-latest"
+	}
+	body, err := json.Marshal(map[string]string{"model": model, "input": text})
+	if err != nil {
+		return result, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/moderations", bytes.NewReader(body))
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+om.APIToken)
+	client := om.Client
+	if client == nil {
+		client = providerHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("moderation endpoint returned %s", resp.Status)
+	}
+	var response struct {
+		Results []struct {
+			Flagged        bool               `json:"flagged"`
+			Categories     map[string]bool    `json:"categories"`
+			CategoryScores map[string]float64 `json:"category_scores"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return result, err
+	}
+	if len(response.Results) == 0 {
+		return result, errors.New("moderation endpoint returned no results")
+	}
+	moderation := response.Results[0]
+	result.Flagged = moderation.Flagged
+	result.Scores = moderation.CategoryScores
+	for category, flagged := range moderation.Categories {
+		if flagged {
+			result.Categories = append(result.Categories, category)
+		}
+	}
+	sort.Strings(result.Categories)
+	return result, nil
+}
@@ -41,7 +41,11 @@ type OllamaController struct {
 //   - embeddings.Embedder: The initialized embedding model instance.
 //   - error: An error if the initialization fails.
 func (oc *OllamaController) NewEmbedder() (embeddings.Embedder, error) {
-	return embeddings.NewEmbedder(oc.LLMController)
+	embedder, err := embeddings.NewEmbedder(oc.LLMController)
+	if err != nil {
+		return nil, err
+	}
+	return embedder, nil
 }
 
 // NewLLMClient initializes and returns a new instance of the Ollama LLM client.
@@ -54,8 +58,11 @@ func (oc *OllamaController) NewEmbedder() (embeddings.Embedder, error) {
 //   - error: An error if the initialization fails.
 func (oc *OllamaController) NewLLMClient() (llms.Model, error) {
 	var err error
-	oc.LLMController, err = ollama.New(ollama.WithServerURL(oc.Config.Apiurl), ollama.WithModel(oc.Config.AiModel))
-	return oc.LLMController, err
+	oc.LLMController, err = ollama.New(ollama.WithServerURL(oc.Config.Apiurl), ollama.WithModel(oc.Config.AiModel), ollama.WithHTTPClient(providerHTTPClient))
+	if err != nil {
+		return nil, err
+	}
+	return oc.LLMController, nil
 }
 
 // initialized checks if the Ollama LLM client has been successfully initialized.
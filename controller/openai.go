@@ -41,7 +41,11 @@ type OpenAIController struct {
 //   - embeddings.Embedder: The initialized embedding model instance.
 //   - error: An error if the initialization fails.
 func (oc *OpenAIController) NewEmbedder() (embeddings.Embedder, error) {
-	return embeddings.NewEmbedder(oc.LLMController)
+	embedder, err := embeddings.NewEmbedder(oc.LLMController)
+	if err != nil {
+		return nil, err
+	}
+	return embedder, nil
 }
 
 // NewLLMClient initializes and returns a new instance of the OpenAI LLM client.
@@ -54,9 +58,12 @@ func (oc *OpenAIController) NewEmbedder() (embeddings.Embedder, error) {
 //   - error: An error if the initialization fails.
 func (oc *OpenAIController) NewLLMClient() (llms.Model, error) {
 	var err error
-	oc.LLMController, err = openai.New(openai.WithToken(oc.Config.APIToken), openai.WithBaseURL(oc.Config.Apiurl), openai.WithModel(oc.Config.AiModel), openai.WithEmbeddingModel(oc.Config.AiModel))
+	oc.LLMController, err = openai.New(openai.WithToken(oc.Config.APIToken), openai.WithBaseURL(oc.Config.Apiurl), openai.WithModel(oc.Config.AiModel), openai.WithEmbeddingModel(oc.Config.AiModel), openai.WithHTTPClient(providerHTTPClient))
 	//  openai.New(openai.WithToken(oc.Config.APIToken), openai.WithBaseURL(oc.Config.Apiurl), openai.WithModel(oc.Config.AiModel))
-	return oc.LLMController, err
+	if err != nil {
+		return nil, err
+	}
+	return oc.LLMController, nil
 }
 
 // initialized checks if the OpenAI LLM client has been successfully initialized.
@@ -0,0 +1,441 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"sigs.k8s.io/yaml"
+)
+
+// OpenAPIToolsConfig controls how an OpenAPI/Swagger document is converted into tools.
+//
+// Fields:
+//   - BaseURL: Overrides the server URL declared in the document.
+//   - Headers: Extra headers sent with every request (e.g., Authorization).
+//   - HTTPClient: The client used to execute operations, defaults to a client with a 30 second timeout.
+//   - Operations: Optional allow list of operation IDs (or generated tool names) to expose.
+//   - MaxResponseBytes: Maximum number of response bytes returned to the model, defaults to 64KB.
+type OpenAPIToolsConfig struct {
+	BaseURL          string
+	Headers          map[string]string
+	HTTPClient       *http.Client
+	Operations       []string
+	MaxResponseBytes int64
+}
+
+// openAPIParameter describes where an operation parameter has to be placed in the HTTP request.
+type openAPIParameter struct {
+	Name string
+	In   string
+}
+
+// openAPIOperation holds the information needed to execute a single API operation.
+type openAPIOperation struct {
+	Method     string
+	Path       string
+	Parameters []openAPIParameter
+	HasBody    bool
+}
+
+var openAPIToolNameCleaner = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// NewToolsFromOpenAPIFile reads an OpenAPI 3 or Swagger 2 document (JSON or YAML) from disk and
+// converts it into AillmTools.
+//
+// Parameters:
+//   - path: The path of the specification file.
+//   - config: Generation and execution settings.
+//
+// Returns:
+//   - AillmTools: One tool per operation with an HTTP execution handler.
+//   - error: An error if the file cannot be read or parsed.
+func NewToolsFromOpenAPIFile(path string, config OpenAPIToolsConfig) (AillmTools, error) {
+	spec, err := os.ReadFile(path)
+	if err != nil {
+		return AillmTools{}, fmt.Errorf("error reading file: %w", err)
+	}
+	return NewToolsFromOpenAPI(spec, config)
+}
+
+// NewToolsFromOpenAPI converts an OpenAPI 3 or Swagger 2 document (JSON or YAML) into AillmTools.
+//
+// Every operation becomes a tool. Path, query and header parameters become top level properties
+// of the tool schema and a JSON request body is exposed as the "body" property. Local "$ref"
+// references are resolved. The generated handlers execute the operation over HTTP and return
+// the response body to the model.
+//
+// Parameters:
+//   - spec: The specification document.
+//   - config: Generation and execution settings.
+//
+// Returns:
+//   - AillmTools: One tool per operation with an HTTP execution handler.
+//   - error: An error if the document is invalid or defines no operations.
+//
+// Example Usage:
+//
+//	tools, err := aillm.NewToolsFromOpenAPIFile("petstore.yaml", aillm.OpenAPIToolsConfig{})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	result, err := llm.AskLLM("Is pet 12 available?", llm.WithTools(tools))
+func NewToolsFromOpenAPI(spec []byte, config OpenAPIToolsConfig) (AillmTools, error) {
+	tools := AillmTools{
+		Handlers: make(map[string]func(interface{}) (string, error)),
+	}
+	jsonSpec, err := yaml.YAMLToJSON(spec)
+	if err != nil {
+		return tools, fmt.Errorf("error parsing OpenAPI document: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(jsonSpec, &doc); err != nil {
+		return tools, fmt.Errorf("error parsing OpenAPI document: %w", err)
+	}
+
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = openAPIServerURL(doc)
+	}
+	if baseURL == "" {
+		return tools, errors.New("OpenAPI document has no server url, set OpenAPIToolsConfig.BaseURL")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if config.MaxResponseBytes == 0 {
+		config.MaxResponseBytes = 64 * 1024
+	}
+	allowed := make(map[string]bool)
+	for _, name := range config.Operations {
+		allowed[name] = true
+	}
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	pathNames := make([]string, 0, len(paths))
+	for path := range paths {
+		pathNames = append(pathNames, path)
+	}
+	sort.Strings(pathNames)
+
+	methods := []string{"get", "post", "put", "patch", "delete", "head", "options"}
+	for _, path := range pathNames {
+		pathItem, ok := paths[path].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		commonParams, _ := pathItem["parameters"].([]interface{})
+		for _, method := range methods {
+			operation, ok := pathItem[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			operationID, _ := operation["operationId"].(string)
+			name := openAPIToolName(operationID, method, path)
+			if len(allowed) > 0 && !allowed[name] && !allowed[operationID] {
+				continue
+			}
+			if _, exists := tools.Handlers[name]; exists {
+				return tools, fmt.Errorf("duplicate tool name %s", name)
+			}
+
+			parameters, op := buildOpenAPIOperation(doc, method, path, commonParams, operation)
+			description, _ := operation["summary"].(string)
+			if detail, ok := operation["description"].(string); ok && detail != "" {
+				if description != "" {
+					description += "\n"
+				}
+				description += detail
+			}
+			if description == "" {
+				description = strings.ToUpper(method) + " " + path
+			}
+
+			tools.Tools = append(tools.Tools, llms.Tool{
+				Type: "function",
+				Function: &llms.FunctionDefinition{
+					Name:        name,
+					Description: description,
+					Parameters:  parameters,
+				},
+			})
+			tools.Handlers[name] = newOpenAPIHandler(baseURL, op, config)
+		}
+	}
+	if len(tools.Tools) == 0 {
+		return tools, errors.New("OpenAPI document defines no operations")
+	}
+	return tools, nil
+}
+
+// buildOpenAPIOperation creates the tool parameter schema and the execution details of an operation.
+func buildOpenAPIOperation(doc map[string]interface{}, method, path string, commonParams []interface{}, operation map[string]interface{}) (map[string]interface{}, openAPIOperation) {
+	op := openAPIOperation{
+		Method: strings.ToUpper(method),
+		Path:   path,
+	}
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	operationParams, _ := operation["parameters"].([]interface{})
+	for _, rawParam := range append(append([]interface{}{}, commonParams...), operationParams...) {
+		param, ok := resolveOpenAPIRef(doc, rawParam, nil).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if name == "" {
+			continue
+		}
+		var schema map[string]interface{}
+		if in == "body" {
+			// Swagger 2 body parameter
+			schema, _ = resolveOpenAPIRef(doc, param["schema"], nil).(map[string]interface{})
+			name = "body"
+			op.HasBody = true
+		} else {
+			if in != "path" && in != "query" && in != "header" {
+				continue
+			}
+			schema, _ = resolveOpenAPIRef(doc, param["schema"], nil).(map[string]interface{})
+			if schema == nil {
+				// Swagger 2 declares the type on the parameter itself
+				schema = map[string]interface{}{}
+				if paramType, ok := param["type"]; ok {
+					schema["type"] = paramType
+				}
+				if items, ok := param["items"]; ok {
+					schema["items"] = items
+				}
+				if enum, ok := param["enum"]; ok {
+					schema["enum"] = enum
+				}
+			}
+			op.Parameters = append(op.Parameters, openAPIParameter{Name: name, In: in})
+		}
+		if schema == nil {
+			schema = map[string]interface{}{}
+		}
+		if description, ok := param["description"].(string); ok && description != "" {
+			schema = copyJSONMap(schema)
+			schema["description"] = description
+		}
+		properties[name] = schema
+		if isRequired, _ := param["required"].(bool); isRequired || in == "path" {
+			required = append(required, name)
+		}
+	}
+
+	if requestBody, ok := resolveOpenAPIRef(doc, operation["requestBody"], nil).(map[string]interface{}); ok {
+		if content, ok := requestBody["content"].(map[string]interface{}); ok {
+			if media, ok := content["application/json"].(map[string]interface{}); ok {
+				schema, _ := resolveOpenAPIRef(doc, media["schema"], nil).(map[string]interface{})
+				if schema == nil {
+					schema = map[string]interface{}{}
+				}
+				properties["body"] = schema
+				op.HasBody = true
+				if isRequired, _ := requestBody["required"].(bool); isRequired {
+					required = append(required, "body")
+				}
+			}
+		}
+	}
+
+	parameters := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		parameters["required"] = required
+	}
+	return parameters, op
+}
+
+// newOpenAPIHandler returns a tool handler executing the operation over HTTP.
+func newOpenAPIHandler(baseURL string, op openAPIOperation, config OpenAPIToolsConfig) func(interface{}) (string, error) {
+	return func(input interface{}) (string, error) {
+		args, _ := input.(map[string]interface{})
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		path := op.Path
+		query := url.Values{}
+		header := http.Header{}
+		for _, param := range op.Parameters {
+			value, exists := args[param.Name]
+			if !exists || value == nil {
+				continue
+			}
+			switch param.In {
+			case "path":
+				path = strings.ReplaceAll(path, "{"+param.Name+"}", url.PathEscape(fmt.Sprintf("%v", value)))
+			case "query":
+				if list, ok := value.([]interface{}); ok {
+					for _, item := range list {
+						query.Add(param.Name, fmt.Sprintf("%v", item))
+					}
+				} else {
+					query.Set(param.Name, fmt.Sprintf("%v", value))
+				}
+			case "header":
+				header.Set(param.Name, fmt.Sprintf("%v", value))
+			}
+		}
+		if strings.Contains(path, "{") {
+			return "", fmt.Errorf("missing path parameters in %s", path)
+		}
+
+		requestURL := strings.TrimRight(baseURL, "/") + path
+		if len(query) > 0 {
+			requestURL += "?" + query.Encode()
+		}
+
+		var body io.Reader
+		if op.HasBody {
+			if payload, exists := args["body"]; exists {
+				data, err := json.Marshal(payload)
+				if err != nil {
+					return "", fmt.Errorf("error converting request to json: %w", err)
+				}
+				body = bytes.NewReader(data)
+				header.Set("Content-Type", "application/json")
+			}
+		}
+
+		req, err := http.NewRequest(op.Method, requestURL, body)
+		if err != nil {
+			return "", fmt.Errorf("error creating http request: %w", err)
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		for key, value := range config.Headers {
+			req.Header.Set(key, value)
+		}
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := config.HTTPClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("error sending request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		responseBody, err := io.ReadAll(io.LimitReader(resp.Body, config.MaxResponseBytes))
+		if err != nil {
+			return "", fmt.Errorf("error reading response: %w", err)
+		}
+		if resp.StatusCode >= 400 {
+			return "", fmt.Errorf("api error: status code %d\nresponse: %s", resp.StatusCode, string(responseBody))
+		}
+		return string(responseBody), nil
+	}
+}
+
+// openAPIServerURL extracts the base url from an OpenAPI 3 or Swagger 2 document.
+func openAPIServerURL(doc map[string]interface{}) string {
+	if servers, ok := doc["servers"].([]interface{}); ok && len(servers) > 0 {
+		if server, ok := servers[0].(map[string]interface{}); ok {
+			serverURL, _ := server["url"].(string)
+			return serverURL
+		}
+	}
+	host, _ := doc["host"].(string)
+	if host == "" {
+		return ""
+	}
+	scheme := "https"
+	if schemes, ok := doc["schemes"].([]interface{}); ok && len(schemes) > 0 {
+		scheme = fmt.Sprintf("%v", schemes[0])
+	}
+	basePath, _ := doc["basePath"].(string)
+	return scheme + "://" + host + basePath
+}
+
+// openAPIToolName generates a valid tool name for an operation.
+func openAPIToolName(operationID, method, path string) string {
+	name := operationID
+	if name == "" {
+		name = method + "_" + path
+	}
+	name = openAPIToolNameCleaner.ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// resolveOpenAPIRef replaces local "$ref" references ("#/components/...") with their targets. refs holds the
+// references followed to reach node, a reference to one of them (a recursive schema) or a chain of more than 16
+// references resolves to an empty schema.
+func resolveOpenAPIRef(doc map[string]interface{}, node interface{}, refs map[string]bool) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			if !strings.HasPrefix(ref, "#/") || refs[ref] || len(refs) >= 16 {
+				return map[string]interface{}{}
+			}
+			var target interface{} = doc
+			for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+				part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+				m, ok := target.(map[string]interface{})
+				if !ok {
+					return map[string]interface{}{}
+				}
+				target = m[part]
+			}
+			followed := make(map[string]bool, len(refs)+1)
+			for key := range refs {
+				followed[key] = true
+			}
+			followed[ref] = true
+			return resolveOpenAPIRef(doc, target, followed)
+		}
+		resolved := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			resolved[key] = resolveOpenAPIRef(doc, value, refs)
+		}
+		return resolved
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for idx, value := range v {
+			resolved[idx] = resolveOpenAPIRef(doc, value, refs)
+		}
+		return resolved
+	}
+	return node
+}
+
+// copyJSONMap returns a shallow copy of a JSON object.
+func copyJSONMap(m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		result[key] = value
+	}
+	return result
+}
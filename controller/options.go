@@ -44,19 +44,24 @@ func (llm *LLMContainer) WithActionCallFunc(ActionCallFunc func(action LLMAction
 
 // WithLanguage specifies the language to use for the query response.
 //
+// The code also selects the documents embedded in that language, it is used as given in the embedding prefix, so
+// "en-us" and "en-US" are different indexes. Calls with an invalid code fail, see ParseLanguage.
+//
 // Parameters:
-//   - Language: The language in which the LLM should generate responses.
+//   - Language: The BCP-47 code of the language in which the LLM should generate responses, e.g. "en" or "pt-BR".
 //
 // Returns:
 //   - LLMCallOption: An option that sets the query language.
-func (llm *LLMContainer) WithLanguage(Language string) LLMCallOption {
+func (llm *LLMContainer) WithLanguage(Language LanguageTag) LLMCallOption {
 	return func(o *LLMCallOptions) {
-		o.Language = Language
+		o.Language = string(Language)
 	}
 }
 
 // WithSessionID specifies the session ID for tracking user chat history and interactions.
 //
+// Session IDs starting with "tenant:" are reserved, calls using them fail with ErrInvalidOptions, see WithTenant.
+//
 // Parameters:
 //   - SessionID: A unique identifier for the user's session.
 //
@@ -70,6 +75,8 @@ func (llm *LLMContainer) WithSessionID(SessionID string) LLMCallOption {
 
 // WithEmbeddingPrefix specifies a prefix for identifying related embeddings.
 //
+// Prefixes starting with "tenant:" are reserved, calls using them fail with ErrInvalidOptions, see WithTenant.
+//
 // Parameters:
 //   - Prefix: A string prefix used to group or identify embeddings in the store.
 //
@@ -81,9 +88,19 @@ func (llm *LLMContainer) WithEmbeddingPrefix(Prefix string) LLMCallOption {
 		// 	Prefix = "default"
 		// }
 		o.Prefix = Prefix
+		o.aliases = llm.prefixAliasRegistry()
 	}
 }
 
+// WithEmbeddingIndex specifies the index of the embedded document.
+//
+// Indexes starting with "tenant:" are reserved, calls using them fail with ErrInvalidOptions, see WithTenant.
+//
+// Parameters:
+//   - Index: The index of the document inside the embedding prefix.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the embedding index.
 func (llm *LLMContainer) WithEmbeddingIndex(Index string) LLMCallOption {
 	return func(o *LLMCallOptions) {
 		// if Index == "" {
@@ -134,11 +151,28 @@ func (llm *LLMContainer) WithExactPrompt(ExactPrompt string) LLMCallOption {
 	}
 }
 
+// getEmbeddingPrefix returns the embedding prefix of a call: the prefix in the namespace of the tenant, or the
+// prefix its alias points to, see SetPrefixAlias.
 func (o *LLMCallOptions) getEmbeddingPrefix() string {
 	// if o.Prefix == "" {
 	// 	o.Prefix = "default"
 	// }
-	return o.Prefix
+	if o.physicalPrefix != "" {
+		return o.physicalPrefix
+	}
+	prefix := TenantPrefix(o.tenantID, o.Prefix)
+	if o.aliases != nil {
+		return o.aliases.resolve(prefix)
+	}
+	return prefix
+}
+
+// getSessionID returns the session ID in the namespace of the tenant.
+func (o *LLMCallOptions) getSessionID() string {
+	if o.tenantID == "" || o.SessionID == "" {
+		return o.SessionID
+	}
+	return "tenant:" + o.tenantID + ":" + o.SessionID
 }
 
 // WithEmbeddingPrefix specifies a prefix for identifying related embeddings.
@@ -273,6 +307,9 @@ func (llm *LLMContainer) WithLLMSpliter(UseLLMToSplitText bool) LLMCallOption {
 
 // WithIncludeDate include date in prompt
 //
+// The date is written in the answer language, e.g. with German month names or the Solar Hijri calendar for Persian,
+// and in the timezone of LLMContainer.Location so "today" and "tomorrow" match the day of the users.
+//
 // Parameters:
 //   - IncludeDate: A boolean value to update property
 //
@@ -315,18 +352,20 @@ func (llm *LLMContainer) WithTools(tools AillmTools) LLMCallOption {
 // WithSearchAlgorithm specifies the search algorithm to use for the query.
 //
 // Parameters:
-//   - SearchAlgorithm: The search algorithm to use for the query.
+//   - algorithm: The search algorithm to use for the query, e.g. HybridSearch.
 //
 // Returns:
 //   - LLMCallOption: An option that sets the search algorithm.
-func (llm *LLMContainer) WithSearchAlgorithm(SearchAlgorithm int) LLMCallOption {
+func (llm *LLMContainer) WithSearchAlgorithm(algorithm SearchAlgorithm) LLMCallOption {
 	return func(o *LLMCallOptions) {
-		o.SearchAlgorithm = SearchAlgorithm
+		o.SearchAlgorithm = algorithm
 	}
 }
 
 // WithIgnoreSecurityCheck ignores security check
 //
+// It takes precedence over WithSecurityCheck.
+//
 // Parameters:
 //   - IgnoreSecurityCheck: A boolean value to update property
 //
@@ -338,6 +377,23 @@ func (llm *LLMContainer) WithIgnoreSecurityCheck(ignoreSecurityCheck bool) LLMCa
 	}
 }
 
+// WithSecurityCheck enables or disables the content-safety and prompt-injection classifier for a call,
+// overriding the DisableSecurityCheck default of the container.
+//
+// The classifier runs on the user query before retrieval, a query violating the policies fails
+// with a *SecurityCheckError.
+//
+// Parameters:
+//   - enabled: True runs the classifier, false skips it.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the security check.
+func (llm *LLMContainer) WithSecurityCheck(enabled bool) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.securityCheck = &enabled
+	}
+}
+
 // WithHybridSearch enables hybrid search combining vector similarity and lexical search
 func (llm *LLMContainer) WithHybridSearch() LLMCallOption {
 	return func(o *LLMCallOptions) {
@@ -373,7 +429,51 @@ func (llm *LLMContainer) WithKNNSearch() LLMCallOption {
 	}
 }
 
+// WithRequestID sets a correlation ID for the request.
+//
+// The ID is added to the log records, the actions and the tool audit records of the request and is sent
+// to the OpenAI and Ollama endpoints in the X-Request-ID header, so a query can be followed across services.
+//
+// Parameters:
+//   - requestID: The correlation ID, e.g. the ID of the incoming HTTP request.
+func (llm *LLMContainer) WithRequestID(requestID string) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.requestID = requestID
+	}
+}
+
+// WithTenant runs the call for a registered tenant.
+//
+// Embeddings are stored and searched in the namespace of the tenant and the session memory is kept
+// per tenant, see Tenant.
+//
+// Parameters:
+//   - tenantID: The ID of a tenant registered with RegisterTenant or the Tenants field.
+func (llm *LLMContainer) WithTenant(tenantID string) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.tenantID = tenantID
+		o.aliases = llm.prefixAliasRegistry()
+	}
+}
+
+// withInternalCall marks the calls the container makes for itself, e.g. summaries, which don't belong to a tenant.
+func withInternalCall() LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.internal = true
+	}
+}
+
+// withDocumentID sets the Id of content entries embedded without one, used by the asynchronous ingestion jobs.
+func withDocumentID(documentID string) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.documentID = documentID
+	}
+}
+
 // WithDebug enables debug mode
+//
+// In debug mode the retrieved documents, the final prompt and every streamed chunk are logged
+// with the container Logger at debug level, and security check warnings are returned in LLMResult.
 func (llm *LLMContainer) WithDebug(debug bool) LLMCallOption {
 	return func(o *LLMCallOptions) {
 		o.debug = debug
@@ -418,3 +518,193 @@ func (llm *LLMContainer) WithAsyncMemorySummarization(asyncMemorySummarization b
 		o.asyncMemorySummarization = asyncMemorySummarization
 	}
 }
+
+// WithToolErrorRecovery sends tool handler errors back to the model instead of aborting the query.
+//
+// Parameters:
+//   - toolErrorRecovery: When true, the model receives the handler error as the tool response
+//     and may retry with corrected arguments or explain the failure to the user.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the tool error recovery mode.
+func (llm *LLMContainer) WithToolErrorRecovery(toolErrorRecovery bool) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.toolErrorRecovery = toolErrorRecovery
+	}
+}
+
+// WithImageRetrieval retrieves related images from the image index and adds their descriptions to the context.
+//
+// Parameters:
+//   - rowCount: Maximum number of images to retrieve, 0 disables image retrieval.
+//
+// Returns:
+//   - LLMCallOption: An option that enables image retrieval.
+func (llm *LLMContainer) WithImageRetrieval(rowCount int) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.imageRowCount = rowCount
+	}
+}
+
+// WithImageAttachment attaches an image to the query (e.g., "is this plant affected by the pest described in my documents?").
+//
+// The image is described by the VisionClient, the description is used for retrieval and merged into the RAG prompt.
+// The option can be used several times to attach several images.
+//
+// Parameters:
+//   - image: The image to attach, set Path for a local file, URL for a remote image or Data for raw bytes.
+//
+// Returns:
+//   - LLMCallOption: An option that attaches the image.
+func (llm *LLMContainer) WithImageAttachment(image ImageInput) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.imageAttachments = append(o.imageAttachments, image)
+	}
+}
+
+// WithGuardrails adds answer validators to a call, after the validators of the container Guardrails.
+//
+// An answer failing a validator is generated again with the validation error, up to Guardrails.MaxRetries
+// times, then AskLLM fails with a *GuardrailError. Retried answers are streamed again after a
+// "Guardrail validation failed" action, so streaming clients should discard the previous answer.
+//
+// Parameters:
+//   - validators: The validators, e.g. RegexValidator, LengthValidator or JSONSchemaValidator.
+//
+// Returns:
+//   - LLMCallOption: An option that adds the validators.
+func (llm *LLMContainer) WithGuardrails(validators ...AnswerValidator) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.validators = append(o.validators, validators...)
+	}
+}
+
+// WithOutputSchema asks the model to answer with JSON matching a schema, see AskLLMAs to decode the answer
+// into a Go type.
+//
+// The schema is added to the prompt and object schemas enable the JSON mode of the provider. Add a
+// JSONSchemaValidator with WithGuardrails to retry answers not matching it.
+//
+// Parameters:
+//   - schema: The JSON schema, any JSON serializable value.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the output schema.
+func (llm *LLMContainer) WithOutputSchema(schema any) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.outputSchema = schemaMap(schema)
+	}
+}
+
+// WithGroundednessCheck verifies the answer of a call against the retrieved documents, overriding the
+// GroundednessCheck default of the container.
+//
+// The LLM judges whether each sentence of the answer is supported by the documents, the score and the
+// unsupported sentences are returned in LLMResult.Groundedness. The check costs an additional model call.
+//
+// Parameters:
+//   - enabled: True runs the check, false skips it.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the groundedness check.
+func (llm *LLMContainer) WithGroundednessCheck(enabled bool) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.groundednessCheck = &enabled
+	}
+}
+
+// WithSeed sets the sampling seed of the answer generation.
+//
+// Retried attempts send the same seed, so providers supporting seeds reproduce the same answer
+// instead of generating a different one. See RetryPolicy.Deterministic to set a seed on every call.
+//
+// Parameters:
+//   - seed: The sampling seed.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the seed.
+func (llm *LLMContainer) WithSeed(seed int) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.seed = &seed
+	}
+}
+
+// WithTemperature sets the sampling temperature of the answer generation, overriding the Temperature of the container,
+// e.g. a higher temperature for a creative rewrite.
+//
+// Parameters:
+//   - temperature: The temperature, 0 or more.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the temperature.
+func (llm *LLMContainer) WithTemperature(temperature float64) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.temperature = &temperature
+	}
+}
+
+// WithTopP sets the nucleus sampling threshold of the answer generation, overriding the TopP of the container.
+//
+// Parameters:
+//   - topP: The threshold, between 0 and 1.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the top-p.
+func (llm *LLMContainer) WithTopP(topP float64) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.topP = &topP
+	}
+}
+
+// WithRagRowCount sets the number of chunks retrieved from each prefix, overriding the RagRowCount of the container
+// and the RetrievalSettings of the prefixes.
+//
+// Parameters:
+//   - rowCount: The number of chunks, more than 0.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the retrieved chunk count.
+func (llm *LLMContainer) WithRagRowCount(rowCount int) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.ragRowCount = &rowCount
+	}
+}
+
+// WithDeleteProgress reports the progress of CleanEmbeddings, which deletes the keys of a prefix in batches.
+//
+// Parameters:
+//   - progress: Called after each batch with the key prefix being purged and the number of its keys deleted so far.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the progress callback.
+//
+// Example Usage:
+//
+//	err := llm.CleanEmbeddings("yes", "archive", llm.WithDeleteProgress(func(pattern string, deleted int) {
+//		log.Printf("%s: %d keys deleted", pattern, deleted)
+//	}))
+func (llm *LLMContainer) WithDeleteProgress(progress func(pattern string, deleted int)) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.deleteProgress = progress
+	}
+}
+
+// generationParameters returns the temperature and top-p of a call, the container's unless overridden.
+func (llm *LLMContainer) generationParameters(o *LLMCallOptions) (temperature, topP float64) {
+	temperature, topP = llm.Temperature, llm.TopP
+	if o.temperature != nil {
+		temperature = *o.temperature
+	}
+	if o.topP != nil {
+		topP = *o.topP
+	}
+	return temperature, topP
+}
+
+// rowCount returns the retrieved chunk count of a call, 0 for the settings of the prefixes.
+func (o *LLMCallOptions) rowCount() int {
+	if o.ragRowCount == nil {
+		return 0
+	}
+	return *o.ragRowCount
+}
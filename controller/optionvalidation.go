@@ -0,0 +1,101 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidOptions is returned by AskLLM for call options that can't be used together, the error names the
+// options and how to fix the call.
+var ErrInvalidOptions = errors.New("invalid call options")
+
+// validateCallOptions rejects the option combinations AskLLM would otherwise silently ignore.
+//
+// Parameters:
+//   - o: The options of the call.
+//
+// Returns:
+//   - error: An error wrapping ErrInvalidOptions, nil if the options are consistent.
+func (llm *LLMContainer) validateCallOptions(o *LLMCallOptions) error {
+	if o.ExactPrompt != "" {
+		if ignored := exactPromptIgnoredOptions(o); len(ignored) > 0 {
+			return fmt.Errorf("%w: WithExactPrompt skips retrieval, so %s have no effect; remove them or ask without an exact prompt",
+				ErrInvalidOptions, strings.Join(ignored, ", "))
+		}
+	} else if o.ForceLanguage && o.Language == "" {
+		return fmt.Errorf("%w: WithForcedLanguage needs the language of the answer, add WithLanguage", ErrInvalidOptions)
+	}
+	if o.temperature != nil && *o.temperature < 0 {
+		return fmt.Errorf("%w: WithTemperature(%v) must not be negative", ErrInvalidOptions, *o.temperature)
+	}
+	if o.topP != nil && (*o.topP < 0 || *o.topP > 1) {
+		return fmt.Errorf("%w: WithTopP(%v) must be between 0 and 1", ErrInvalidOptions, *o.topP)
+	}
+	if o.ragRowCount != nil && *o.ragRowCount <= 0 {
+		return fmt.Errorf("%w: WithRagRowCount(%d) must be more than 0", ErrInvalidOptions, *o.ragRowCount)
+	}
+	if o.characterName != "" {
+		if _, ok := llm.CharacterPrompt(o.characterName); !ok {
+			return fmt.Errorf("%w: WithCharacterName(%q) selects no character, register it with RegisterCharacter", ErrInvalidOptions, o.characterName)
+		}
+	}
+	if o.ExactPrompt == "" && llm.Retriever == nil {
+		searchAlgorithm := o.SearchAlgorithm
+		if searchAlgorithm == NotDefinedSearch {
+			searchAlgorithm = llm.SearchAlgorithm
+		}
+		if searchAlgorithm == LexicalSearch || searchAlgorithm == HybridSearch {
+			if err := llm.checkLexicalSearch(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(o.Tools.Tools) > 0 {
+		if _, ok := llm.LLMClient.(*OllamaController); ok {
+			return fmt.Errorf("%w: WithTools needs an OpenAI LLM client, the Ollama client doesn't call tools", ErrInvalidOptions)
+		}
+	}
+	return nil
+}
+
+// exactPromptIgnoredOptions returns the retrieval options of a call, they aren't used with an exact prompt.
+func exactPromptIgnoredOptions(o *LLMCallOptions) []string {
+	ignored := []string{}
+	add := func(set bool, option string) {
+		if set {
+			ignored = append(ignored, option)
+		}
+	}
+	add(o.Prefix != "", "WithEmbeddingPrefix")
+	add(o.Index != "", "WithEmbeddingIndex")
+	add(o.searchAll, "SearchAll")
+	add(o.ExtraContext != "", "WithExtraContext")
+	add(o.SearchAlgorithm != 0, "WithSearchAlgorithm")
+	add(o.RagReferences, "WithRagReferences")
+	add(o.CotextCleanup, "WithCotextCleanup")
+	add(o.LimitGeneralEmbedding, "WithLimitGeneralEmbedding")
+	add(o.imageRowCount > 0, "WithImageRetrieval")
+	add(len(o.indexes) > 0, "WithIndexes")
+	add(len(o.prefixes) > 0, "WithPrefixes")
+	add(o.indexCap > 0, "WithIndexCap")
+	add(o.ragRowCount != nil, "WithRagRowCount")
+	add(o.indexBoosts != nil, "WithIndexBoosts")
+	add(o.mapReduce != nil && *o.mapReduce, "WithMapReduce")
+	add(o.crossLingual != nil && *o.crossLingual, "WithCrossLingual")
+	add(o.webFallback != nil && *o.webFallback, "WithWebFallback")
+	return ignored
+}
@@ -18,6 +18,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -75,13 +77,15 @@ func (pm *PersistentMemory) AddMemory(sessionID string, query MemoryData) (Token
 		Title: promotPart,
 	}
 
-	keys, _, _, _, err := pm.lLMContainer.embedText("Memory", "aillm", embeddingPrefix, "", promotPart, "", memoryembeddingContent, true, true, false)
+	keys, _, _, _, err := pm.lLMContainer.embedText(context.Background(), "Memory", "aillm", embeddingPrefix, "", promotPart, "", memoryembeddingContent, true, true, false)
 	//
-	//Updating redis TTL
-
-	for _, key := range keys {
-		pm.redisClient.Expire(context.TODO(), key, pm.MemoryTTL)
-	}
+	//Updating redis TTL, in a single round trip
+	pm.redisClient.Pipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Expire(context.TODO(), key, pm.MemoryTTL)
+		}
+		return nil
+	})
 	if err != nil {
 		return tokenUsage, err
 	}
@@ -102,12 +106,9 @@ func (pm *PersistentMemory) AddMemory(sessionID string, query MemoryData) (Token
 	if len(curUserMemory.Questions) >= 2 {
 		PrevConversation := ""
 		for _, question := range curUserMemory.Questions {
-			if question.Answer[0] == '@' {
-				question.Answer = question.Answer[1:]
-			}
 			PrevConversation += fmt.Sprintf("User: %v\nAssistant: %v\n\n", question.Question, question.Answer)
 		}
-		resp, err := pm.lLMContainer.AskLLM("", pm.lLMContainer.WithExactPrompt("You are a helpful assistant that summarizes conversations as short as possible with details for future use of LLM memory.\n"+PrevConversation), pm.lLMContainer.WithAllowHallucinate(true), pm.lLMContainer.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+		resp, err := pm.lLMContainer.AskLLM("", pm.lLMContainer.WithExactPrompt("You are a helpful assistant that summarizes conversations as short as possible with details for future use of LLM memory.\n"+PrevConversation), pm.lLMContainer.WithAllowHallucinate(true), withInternalCall(), pm.lLMContainer.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
 			tokenUsage.OutputTokens++
 			return nil
 		}))
@@ -187,13 +188,10 @@ func (pm *PersistentMemory) GetMemory(sessionID string, query string) (MemoryDat
 // Parameters:
 //   - sessionID: The unique identifier for the session to be deleted.
 func (pm *PersistentMemory) DeleteMemory(sessionID string) error {
-	// llm.userLanguage[o.SessionID]
 	if sessionID == "" {
 		return nil
 	}
-	if pm.lLMContainer.userLanguage != nil {
-		pm.lLMContainer.userLanguage[sessionID] = ""
-	}
+	pm.lLMContainer.forgetSessionLanguage(sessionID)
 	keyPrefix := "rawMemory:" + pm.MemoryPrefix + ":" + sessionID
 	redisCmd := pm.redisClient.Get(context.TODO(), keyPrefix)
 	redisCmdErr := redisCmd.Err()
@@ -203,15 +201,33 @@ func (pm *PersistentMemory) DeleteMemory(sessionID string) error {
 	curUserMemoryStr := redisCmd.Val()
 	curUserMemory := Memory{}
 	_ = json.Unmarshal([]byte(curUserMemoryStr), &curUserMemory)
+	keys := []string{keyPrefix}
 	for _, mem := range curUserMemory.Questions {
-		for _, key := range mem.Keys {
-			pm.redisClient.Del(context.TODO(), key)
-		}
+		keys = append(keys, mem.Keys...)
 	}
 	var err error
-	rawMemErr := pm.redisClient.Del(context.TODO(), keyPrefix).Err()
+	_, rawMemErr := unlinkKeys(context.TODO(), pm.redisClient, keys)
 	if rawMemErr != nil {
 		err = errors.New(rawMemErr.Error())
 	}
 	return err
 }
+
+// ListSessions returns the IDs of the sessions stored in Redis, sorted.
+//
+// Returns:
+//   - []string: The session IDs.
+//   - error: An error if scanning Redis fails.
+func (pm *PersistentMemory) ListSessions() ([]string, error) {
+	keyPrefix := "rawMemory:" + pm.MemoryPrefix + ":"
+	sessions := []string{}
+	iter := pm.redisClient.Scan(context.TODO(), 0, keyPrefix+"*", 100).Iterator()
+	for iter.Next(context.TODO()) {
+		sessions = append(sessions, strings.TrimPrefix(iter.Val(), keyPrefix))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(sessions)
+	return sessions, nil
+}
@@ -0,0 +1,372 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// PII handling modes.
+const (
+	PIIMask = "mask" // Replaces personal data with its type, e.g. "[EMAIL]"
+	PIIDrop = "drop" // Removes personal data from the text
+	PIIFlag = "flag" // Keeps the text and reports the personal data found
+)
+
+// Built-in PII types.
+const (
+	PIIEmail      = "EMAIL"
+	PIIPhone      = "PHONE"
+	PIICreditCard = "CREDIT_CARD"
+	PIIIBAN       = "IBAN"
+	PIIIPAddress  = "IP_ADDRESS"
+	PIIName       = "NAME"
+)
+
+// piiPatterns are the regular expressions of the built-in PII types, names are detected by the LLM.
+var piiPatterns = map[string]*regexp.Regexp{
+	PIIEmail:      regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	PIICreditCard: regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+	PIIIBAN:       regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`),
+	PIIPhone:      regexp.MustCompile(`(?:(?:\+\d{1,3}[ .\-]?)?\(\d{1,4}\)[ .\-]?|\+\d{1,3}[ .\-]?)\d{2,4}[ .\-]?\d{3,4}(?:[ .\-]?\d{2,4})?\b|\b\d{3}[ .\-]\d{3,4}[ .\-]\d{3,4}\b`),
+	PIIIPAddress:  regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+}
+
+// piiPatternOrder resolves overlapping matches, card numbers and IBANs before phone numbers.
+var piiPatternOrder = []string{PIIEmail, PIIIBAN, PIICreditCard, PIIIPAddress, PIIPhone}
+
+// piiValidators reject pattern matches of the built-in types that fail their checksum.
+var piiValidators = map[string]func(value string) bool{
+	PIICreditCard: luhnValid,
+}
+
+// luhnValid reports whether the digits of a card number pass the Luhn checksum.
+func luhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		if number[i] < '0' || number[i] > '9' {
+			continue
+		}
+		digit := int(number[i] - '0')
+		if double {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// PIIConfig configures the detection of personal data in embedded documents and answers.
+//
+// Fields:
+//   - Mode: PIIMask, PIIDrop or PIIFlag, an empty mode disables PII handling.
+//   - OnIngestion: Applies the mode to the title and text of documents before EmbeddText embeds them.
+//   - OnOutput: Applies the mode to the answers of AskLLM. Masked or dropped streams are sent word by word,
+//     holding back the numbers that may continue in the next chunk, or line by line with DetectNames.
+//   - Types: The built-in types to detect (EMAIL, PHONE, CREDIT_CARD, IBAN, IP_ADDRESS), empty detects all.
+//     Card numbers must pass the Luhn check, phone numbers need a country code, an area code in parentheses or
+//     separated digit groups.
+//   - Patterns: Additional types, e.g. national ID formats, by type name.
+//   - DetectNames: Also detects names of people with the LLM (NER), which costs a model call per text.
+type PIIConfig struct {
+	Mode        string                    `json:"mode"`
+	OnIngestion bool                      `json:"onIngestion"`
+	OnOutput    bool                      `json:"onOutput"`
+	Types       []string                  `json:"types"`
+	Patterns    map[string]*regexp.Regexp `json:"patterns"`
+	DetectNames bool                      `json:"detectNames"`
+}
+
+// PIIMatch is an occurrence of personal data in a text.
+//
+// Fields:
+//   - Type: The PII type, e.g. EMAIL.
+//   - Value: The matched text.
+//   - Start, End: The byte offsets of the match in the original text.
+type PIIMatch struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// enabled reports whether PII handling is configured.
+func (pc PIIConfig) enabled() bool {
+	return pc.Mode == PIIMask || pc.Mode == PIIDrop || pc.Mode == PIIFlag
+}
+
+// patterns returns the regular expressions to apply, in matching priority.
+func (pc PIIConfig) patterns() ([]string, map[string]*regexp.Regexp) {
+	types := []string{}
+	patterns := map[string]*regexp.Regexp{}
+	for _, piiType := range piiPatternOrder {
+		if len(pc.Types) > 0 && !containsFold(pc.Types, piiType) {
+			continue
+		}
+		types = append(types, piiType)
+		patterns[piiType] = piiPatterns[piiType]
+	}
+	custom := make([]string, 0, len(pc.Patterns))
+	for piiType := range pc.Patterns {
+		custom = append(custom, piiType)
+	}
+	sort.Strings(custom)
+	for _, piiType := range custom {
+		types = append(types, piiType)
+		patterns[piiType] = pc.Patterns[piiType]
+	}
+	return types, patterns
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectPII finds the personal data of a text with the configured patterns and, with DetectNames, the LLM.
+//
+// Parameters:
+//   - text: The text to scan.
+//
+// Returns:
+//   - []PIIMatch: The non overlapping matches, sorted by position.
+//   - TokenUsage: Tokens used to detect names.
+//   - error: An error if name detection fails, the pattern matches are still returned.
+func (llm *LLMContainer) DetectPII(text string) ([]PIIMatch, TokenUsage, error) {
+	return llm.detectPII(context.Background(), text)
+}
+
+// detectPII implements DetectPII, the name detection stops with ctx.
+func (llm *LLMContainer) detectPII(ctx context.Context, text string) ([]PIIMatch, TokenUsage, error) {
+	matches := []PIIMatch{}
+	taken := make([]bool, len(text))
+	add := func(piiType string, start, end int) {
+		for i := start; i < end; i++ {
+			if taken[i] {
+				return
+			}
+		}
+		for i := start; i < end; i++ {
+			taken[i] = true
+		}
+		matches = append(matches, PIIMatch{Type: piiType, Value: text[start:end], Start: start, End: end})
+	}
+	types, patterns := llm.PII.patterns()
+	for _, piiType := range types {
+		validate := piiValidators[piiType]
+		if _, custom := llm.PII.Patterns[piiType]; custom {
+			validate = nil
+		}
+		for _, loc := range patterns[piiType].FindAllStringIndex(text, -1) {
+			if validate == nil || validate(text[loc[0]:loc[1]]) {
+				add(piiType, loc[0], loc[1])
+			}
+		}
+	}
+
+	var usage TokenUsage
+	var err error
+	if llm.PII.DetectNames && strings.TrimSpace(text) != "" {
+		var names []string
+		names, usage, err = llm.detectNames(ctx, text)
+		for _, name := range names {
+			if strings.TrimSpace(name) == "" {
+				continue
+			}
+			for _, loc := range regexp.MustCompile(regexp.QuoteMeta(name)).FindAllStringIndex(text, -1) {
+				add(PIIName, loc[0], loc[1])
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+	return matches, usage, err
+}
+
+// RedactPII applies the configured mode to a text.
+//
+// Returns:
+//   - string: The masked or dropped text, the unchanged text in flag mode.
+//   - []PIIMatch: The personal data found.
+//   - TokenUsage: Tokens used to detect names.
+//   - error: An error if name detection fails.
+//
+// Example Usage:
+//
+//	llm.PII = aillm.PIIConfig{Mode: aillm.PIIMask}
+//	text, matches, _, _ := llm.RedactPII("Contact john@example.com")
+//	// text: "Contact [EMAIL]"
+func (llm *LLMContainer) RedactPII(text string) (string, []PIIMatch, TokenUsage, error) {
+	return llm.redactPII(context.Background(), text)
+}
+
+// redactPII implements RedactPII, the name detection stops with ctx.
+func (llm *LLMContainer) redactPII(ctx context.Context, text string) (string, []PIIMatch, TokenUsage, error) {
+	matches, usage, err := llm.detectPII(ctx, text)
+	if llm.PII.Mode == PIIFlag || len(matches) == 0 {
+		return text, matches, usage, err
+	}
+	var redacted strings.Builder
+	last := 0
+	for _, match := range matches {
+		redacted.WriteString(text[last:match.Start])
+		if llm.PII.Mode == PIIMask {
+			redacted.WriteString("[" + match.Type + "]")
+		}
+		last = match.End
+	}
+	redacted.WriteString(text[last:])
+	return redacted.String(), matches, usage, err
+}
+
+// detectNames asks the LLM for the names of people in a text.
+func (llm *LLMContainer) detectNames(ctx context.Context, text string) ([]string, TokenUsage, error) {
+	usage := TokenUsage{}
+	llmclient, err := llm.newLLMClient(llm.LLMClient, nil)
+	if err != nil {
+		return nil, usage, err
+	}
+	response, err := llmclient.GenerateContent(ctx,
+		[]llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeHuman, strings.Replace(piiNameDetectionPrompt, "{{text}}", text, 1)),
+		},
+		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			usage.OutputTokens++
+			return nil
+		}),
+		llms.WithTemperature(0))
+	if err != nil {
+		return nil, usage, err
+	}
+	if len(response.Choices) == 0 {
+		return nil, usage, errors.New("name detection returned no response")
+	}
+	content := response.Choices[0].Content
+	// Models sometimes wrap the array in a code block
+	start, end := strings.Index(content, "["), strings.LastIndex(content, "]")
+	if start < 0 || end < start {
+		return nil, usage, fmt.Errorf("name detection returned no name list: %q", content)
+	}
+	names := []string{}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &names); err != nil {
+		return nil, usage, fmt.Errorf("invalid name list: %w", err)
+	}
+	return names, usage, nil
+}
+
+// redactIngestedPII applies the ingestion mode to a document before it is embedded.
+func (llm *LLMContainer) redactIngestedPII(ctx context.Context, Index string, Contents *LLMEmbeddingContent) error {
+	if !llm.PII.enabled() || !llm.PII.OnIngestion {
+		return nil
+	}
+	title, titleMatches, _, err := llm.redactPII(ctx, Contents.Title)
+	if err != nil {
+		return err
+	}
+	text, textMatches, _, err := llm.redactPII(ctx, Contents.Text)
+	if err != nil {
+		return err
+	}
+	count := len(titleMatches) + len(textMatches)
+	for idx, table := range Contents.Tables {
+		// Tables are redacted as Markdown, a single call per table
+		markdown, tableMatches, _, err := llm.redactPII(ctx, table.Markdown())
+		if err != nil {
+			return err
+		}
+		if len(tableMatches) > 0 {
+			Contents.Tables[idx] = parseTableBlock(strings.Split(markdown, "\n"))
+			count += len(tableMatches)
+		}
+	}
+	if count > 0 {
+		llm.logger().Warn("personal data found in document", "index", Index, "id", Contents.Id, "matches", count, "mode", llm.PII.Mode)
+	}
+	Contents.Title = title
+	Contents.Text = text
+	return nil
+}
+
+// piiHeldWord matches the words that may continue in the next chunk as part of a phone, card or IBAN number.
+var piiHeldWord = regexp.MustCompile(`^[A-Z0-9+().\-/]*\d[A-Z0-9+().\-/]*$`)
+
+// piiStream redacts a streamed answer word by word, or line by line when names are detected.
+type piiStream struct {
+	llm    *LLMContainer
+	next   func(ctx context.Context, chunk []byte) error
+	buffer strings.Builder
+}
+
+// write buffers a chunk and sends the text that can be redacted without the next chunks.
+func (ps *piiStream) write(ctx context.Context, chunk []byte) error {
+	ps.buffer.Write(chunk)
+	buffered := ps.buffer.String()
+	end := ps.flushPoint(buffered)
+	if end <= 0 {
+		return nil
+	}
+	ps.buffer.Reset()
+	ps.buffer.WriteString(buffered[end:])
+	return ps.send(ctx, buffered[:end])
+}
+
+// flushPoint returns the length of the buffered text that can be sent: the complete lines and, without name
+// detection, the complete words before any trailing words that may be part of a number split between chunks.
+// Names are detected by the LLM, which needs whole lines and would be called for every word.
+func (ps *piiStream) flushPoint(buffered string) int {
+	lineEnd := strings.LastIndex(buffered, "\n") + 1
+	if ps.llm.PII.DetectNames {
+		return lineEnd
+	}
+	end := strings.LastIndexAny(buffered, " \t\n")
+	for end > 0 {
+		start := strings.LastIndexAny(buffered[:end], " \t\n")
+		if !piiHeldWord.MatchString(buffered[start+1 : end]) {
+			break
+		}
+		end = start
+	}
+	return max(end+1, lineEnd)
+}
+
+// flush sends the last buffered line.
+func (ps *piiStream) flush(ctx context.Context) error {
+	buffered := ps.buffer.String()
+	ps.buffer.Reset()
+	if buffered == "" {
+		return nil
+	}
+	return ps.send(ctx, buffered)
+}
+
+func (ps *piiStream) send(ctx context.Context, text string) error {
+	redacted, _, _, _ := ps.llm.redactPII(ctx, text)
+	return ps.next(ctx, []byte(redacted))
+}
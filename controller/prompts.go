@@ -52,3 +52,19 @@ Important: Asking for information like contact details, addresses, or other pers
 
 Now classify:
 <<< {{User query}} >>>`
+
+const piiNameDetectionPrompt = `List the full or partial names of people mentioned in the text between the triple chevrons <<< >>>.
+Don't list names of companies, products or places.
+Reply only with a JSON array of strings, exactly as the names appear in the text, or [] if there are none.
+
+<<<{{text}}>>>`
+
+const groundednessCheckPrompt = `You are a fact checking judge.
+Decide for each numbered sentence of the answer whether it is supported by the documents, i.e. whether the documents entail it.
+Greetings, questions and sentences without factual claims are supported.
+
+Documents:
+{{documents}}
+Answer sentences:
+{{sentences}}
+Reply only with a JSON array of the numbers of the sentences NOT supported by the documents, or [] if all are supported.`
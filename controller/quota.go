@@ -0,0 +1,178 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Quota limits the requests and tokens of a tenant or a session.
+//
+// Counters are kept in Redis, so the limits are shared by every instance using the same Redis server.
+//
+// Fields:
+//   - RequestsPerMinute: Maximum AskLLM calls per minute, 0 means unlimited.
+//   - RequestsPerDay: Maximum AskLLM calls per day (UTC), 0 means unlimited.
+//   - TokensPerDay: Maximum tokens per day (UTC) reported by TokenReport, 0 means unlimited.
+type Quota struct {
+	RequestsPerMinute int `json:"requestsPerMinute"`
+	RequestsPerDay    int `json:"requestsPerDay"`
+	TokensPerDay      int `json:"tokensPerDay"`
+}
+
+// ErrQuotaExceeded matches the *QuotaExceededError of a call rejected by a quota, with errors.Is.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaExceededError is returned by AskLLM when a tenant or session quota is reached.
+//
+// Fields:
+//   - Scope: "tenant" or "session".
+//   - ID: The tenant ID or the session ID.
+//   - Limit: The exceeded limit, "requestsPerMinute", "requestsPerDay" or "tokensPerDay".
+//   - RetryAfter: The time until the quota window is reset.
+type QuotaExceededError struct {
+	Scope      string
+	ID         string
+	Limit      string
+	RetryAfter time.Duration
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s %s exceeded its %s quota, retry after %s", e.Scope, e.ID, e.Limit, e.RetryAfter.Round(time.Second))
+}
+
+// Is reports whether target is ErrQuotaExceeded.
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// isZero reports whether the quota has no limits.
+func (q Quota) isZero() bool {
+	return q.RequestsPerMinute <= 0 && q.RequestsPerDay <= 0 && q.TokensPerDay <= 0
+}
+
+// quotaScope is a tenant or session quota applying to a call.
+type quotaScope struct {
+	scope string
+	id    string
+	quota Quota
+}
+
+// quotaScopes returns the quotas applying to a call, the session ID must already be namespaced by getSessionID.
+func (llm *LLMContainer) quotaScopes(o *LLMCallOptions) []quotaScope {
+	scopes := []quotaScope{}
+	if tenant, exists := llm.tenantRegistry().get(o.tenantID); exists && !tenant.Quota.isZero() {
+		scopes = append(scopes, quotaScope{scope: "tenant", id: tenant.ID, quota: tenant.Quota})
+	}
+	if o.SessionID != "" && !llm.SessionQuota.isZero() {
+		scopes = append(scopes, quotaScope{scope: "session", id: o.SessionID, quota: llm.SessionQuota})
+	}
+	return scopes
+}
+
+// key returns the Redis counter of a quota window.
+func (qs quotaScope) key(limit, window string) string {
+	return "quota:" + qs.scope + ":" + qs.id + ":" + limit + ":" + window
+}
+
+// checkQuota counts the request against the quotas of the call.
+//
+// Returns:
+//   - error: A *QuotaExceededError if a limit is reached, rejected requests aren't counted.
+func (llm *LLMContainer) checkQuota(ctx context.Context, o *LLMCallOptions) error {
+	scopes := llm.quotaScopes(o)
+	if len(scopes) == 0 || llm.RedisClient.redisClient == nil {
+		return nil
+	}
+	rdb := llm.RedisClient.redisClient
+	now := time.Now().UTC()
+	minute := strconv.FormatInt(now.Unix()/60, 10)
+	day := now.Format("20060102")
+	untilNextMinute := now.Truncate(time.Minute).Add(time.Minute).Sub(now)
+	untilNextDay := now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+
+	for _, qs := range scopes {
+		if qs.quota.TokensPerDay > 0 {
+			tokens, err := rdb.Get(ctx, qs.key("tokens", day)).Int()
+			if err != nil && err != redis.Nil {
+				return err
+			}
+			if tokens >= qs.quota.TokensPerDay {
+				return &QuotaExceededError{Scope: qs.scope, ID: qs.id, Limit: "tokensPerDay", RetryAfter: untilNextDay}
+			}
+		}
+	}
+
+	counted := []string{}
+	for _, qs := range scopes {
+		for _, limit := range []struct {
+			name       string
+			max        int
+			key        string
+			ttl        time.Duration
+			retryAfter time.Duration
+		}{
+			{"requestsPerMinute", qs.quota.RequestsPerMinute, qs.key("requests", minute), 2 * time.Minute, untilNextMinute},
+			{"requestsPerDay", qs.quota.RequestsPerDay, qs.key("requests", day), 48 * time.Hour, untilNextDay},
+		} {
+			if limit.max <= 0 {
+				continue
+			}
+			pipe := rdb.TxPipeline()
+			incr := pipe.Incr(ctx, limit.key)
+			pipe.Expire(ctx, limit.key, limit.ttl)
+			if _, err := pipe.Exec(ctx); err != nil {
+				return err
+			}
+			counted = append(counted, limit.key)
+			if incr.Val() > int64(limit.max) {
+				// Rejected requests don't use the quota
+				for _, key := range counted {
+					rdb.Decr(ctx, key)
+				}
+				return &QuotaExceededError{Scope: qs.scope, ID: qs.id, Limit: limit.name, RetryAfter: limit.retryAfter}
+			}
+		}
+	}
+	return nil
+}
+
+// addQuotaTokens adds the tokens used by a call to the daily token counters of its quotas.
+func (llm *LLMContainer) addQuotaTokens(o *LLMCallOptions, report TokenReport) {
+	sum := report.sum()
+	tokens := sum.InputTokens + sum.OutputTokens
+	if tokens == 0 || llm.RedisClient.redisClient == nil {
+		return
+	}
+	ctx := context.Background()
+	day := time.Now().UTC().Format("20060102")
+	for _, qs := range llm.quotaScopes(o) {
+		if qs.quota.TokensPerDay <= 0 {
+			continue
+		}
+		key := qs.key("tokens", day)
+		pipe := llm.RedisClient.redisClient.TxPipeline()
+		pipe.IncrBy(ctx, key, int64(tokens))
+		pipe.Expire(ctx, key, 48*time.Hour)
+		if _, err := pipe.Exec(ctx); err != nil {
+			llm.logger().Warn("error updating token quota", "scope", qs.scope, "id", qs.id, "error", err)
+		}
+	}
+}
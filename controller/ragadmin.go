@@ -0,0 +1,366 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// EmbeddedChunk is a chunk of an embedded document as stored in the vector store.
+//
+// Fields:
+//   - Key: The Redis key of the chunk.
+//   - Content: The embedded text, including the title and keywords lines added at embedding time.
+type EmbeddedChunk struct {
+	Key     string `json:"Key"`
+	Content string `json:"Content"`
+}
+
+// EmbeddedDocument is a content entry of an embedding object with its chunks.
+//
+// Fields:
+//   - EmbeddingPrefix: The prefix of the embedding object.
+//   - Index: The index of the embedding object.
+//   - Content: The stored content entry.
+//   - Chunks: The chunks embedded for the entry, general chunks are copies and are not listed.
+type EmbeddedDocument struct {
+	EmbeddingPrefix string              `json:"EmbeddingPrefix"`
+	Index           string              `json:"Index"`
+	Content         LLMEmbeddingContent `json:"Content"`
+	Chunks          []EmbeddedChunk     `json:"Chunks"`
+}
+
+// EmbeddedDocumentInfo describes a content entry without its text.
+type EmbeddedDocumentInfo struct {
+	Id         string    `json:"Id"`
+	Title      string    `json:"Title"`
+	Language   string    `json:"Language"`
+	Sources    string    `json:"Sources"`
+	ChunkCount int       `json:"ChunkCount"`
+	UpdatedAt  time.Time `json:"UpdatedAt"`
+}
+
+// EmbeddingObjectInfo describes an embedding object and counts its documents and chunks.
+//
+// Fields:
+//   - UpdatedAt: When the most recent document of the object was embedded.
+type EmbeddingObjectInfo struct {
+	EmbeddingPrefix string                 `json:"EmbeddingPrefix"`
+	Index           string                 `json:"Index"`
+	Documents       []EmbeddedDocumentInfo `json:"Documents"`
+	ChunkCount      int                    `json:"ChunkCount"`
+	UpdatedAt       time.Time              `json:"UpdatedAt"`
+}
+
+// EmbeddingPage is a page of the embedding objects of a prefix.
+//
+// Fields:
+//   - Total: The number of embedding objects of the prefix.
+//   - DocumentCount: The number of content entries of the prefix.
+//   - ChunkCount: The number of chunks of the prefix.
+//   - Offset, Limit: The requested page.
+//   - Rows: The embedding objects of the page, sorted by index.
+type EmbeddingPage struct {
+	Total         int                   `json:"Total"`
+	DocumentCount int                   `json:"DocumentCount"`
+	ChunkCount    int                   `json:"ChunkCount"`
+	Offset        int                   `json:"Offset"`
+	Limit         int                   `json:"Limit"`
+	Rows          []EmbeddingObjectInfo `json:"Rows"`
+}
+
+// Sort orders of QueryEmbeddings.
+const (
+	SortByIndex         = "index"
+	SortByUpdated       = "updated"
+	SortByUpdatedOldest = "updated_asc"
+)
+
+// EmbeddingQuery selects and orders the embedding objects listed by QueryEmbeddings.
+//
+// Fields:
+//   - Prefix: The embedding prefix, only objects stored with exactly this prefix are listed.
+//   - Language: Lists only the documents of a language, empty lists all languages.
+//   - Source: Lists only the documents whose sources contain this text, case-insensitive.
+//   - Sort: SortByIndex (default), SortByUpdated for the most recently embedded objects first, or SortByUpdatedOldest.
+//   - Offset: The number of objects to skip.
+//   - Limit: The maximum number of objects returned, 0 or less returns all.
+type EmbeddingQuery struct {
+	Prefix   string `json:"prefix,omitempty"`
+	Language string `json:"language,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Sort     string `json:"sort,omitempty"`
+	Offset   int    `json:"offset,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+// ListEmbeddingsByPrefix lists the embedding objects of a prefix page by page, with document and chunk counts.
+//
+// Unlike ListEmbeddings, only objects stored with exactly this prefix are returned and the order
+// is stable, so pages don't overlap. See QueryEmbeddings to filter and sort the objects.
+//
+// Parameters:
+//   - prefix: The embedding prefix or its alias, empty lists the objects stored without prefix.
+//   - offset: The number of objects to skip.
+//   - limit: The maximum number of objects returned, 0 or less returns all.
+//
+// Returns:
+//   - EmbeddingPage: The page and the totals of the prefix.
+//   - error: An error if Redis can't be read.
+func (llm *LLMContainer) ListEmbeddingsByPrefix(prefix string, offset, limit int) (EmbeddingPage, error) {
+	return llm.QueryEmbeddings(EmbeddingQuery{Prefix: prefix, Offset: offset, Limit: limit})
+}
+
+// QueryEmbeddings lists summaries of the embedding objects of a prefix, filtered by language and source and
+// sorted by index or embedding date, for admin UIs over large corpora. The texts of the documents aren't returned.
+//
+// Objects without a matching document are skipped, the totals of the page count the matching objects, documents
+// and chunks. Documents embedded before UpdatedAt was recorded sort as the oldest.
+//
+// Parameters:
+//   - query: The prefix, the filters, the sort order and the page.
+//
+// Returns:
+//   - EmbeddingPage: The page and the totals of the matching objects.
+//   - error: An error if Redis can't be read or the sort order is unknown.
+//
+// Example Usage:
+//
+//	page, err := llm.QueryEmbeddings(aillm.EmbeddingQuery{Prefix: "docs", Language: "en", Sort: aillm.SortByUpdated, Limit: 20})
+func (llm *LLMContainer) QueryEmbeddings(query EmbeddingQuery) (EmbeddingPage, error) {
+	page := EmbeddingPage{Offset: query.Offset, Limit: query.Limit, Rows: []EmbeddingObjectInfo{}}
+	switch query.Sort {
+	case "", SortByIndex, SortByUpdated, SortByUpdatedOldest:
+	default:
+		return page, fmt.Errorf("unknown sort order %q", query.Sort)
+	}
+	rdb := llm.RedisClient.redisClient
+	ctx := context.Background()
+	// Aliases list the objects of the prefix they point to
+	query.Prefix = llm.prefixAliasRegistry().resolve(query.Prefix)
+	keyPrefix := LLMEmbeddingObject{EmbeddingPrefix: query.Prefix}.getRawDocRedisId()
+
+	keys := []string{}
+	iter := rdb.Scan(ctx, 0, keyPrefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return page, err
+	}
+	sort.Strings(keys)
+
+	source := strings.ToLower(query.Source)
+	matching := []EmbeddingObjectInfo{}
+	for _, key := range keys {
+		var obj LLMEmbeddingObject
+		if err := obj.load(rdb, key); err != nil {
+			continue
+		}
+		// rawDocs:<prefix>:* also matches the objects of longer prefixes
+		if obj.EmbeddingPrefix != query.Prefix {
+			continue
+		}
+		for id, content := range obj.Contents {
+			if (query.Language != "" && content.Language != query.Language) ||
+				(source != "" && !strings.Contains(strings.ToLower(content.Sources), source)) {
+				delete(obj.Contents, id)
+			}
+		}
+		if len(obj.Contents) == 0 && (query.Language != "" || query.Source != "") {
+			continue
+		}
+		if err := llm.decryptObject(&obj); err != nil {
+			return page, err
+		}
+		info := newEmbeddingObjectInfo(obj)
+		matching = append(matching, info)
+		page.DocumentCount += len(info.Documents)
+		page.ChunkCount += info.ChunkCount
+	}
+	switch query.Sort {
+	case SortByUpdated:
+		sort.SliceStable(matching, func(i, j int) bool { return matching[i].UpdatedAt.After(matching[j].UpdatedAt) })
+	case SortByUpdatedOldest:
+		sort.SliceStable(matching, func(i, j int) bool { return matching[i].UpdatedAt.Before(matching[j].UpdatedAt) })
+	}
+	page.Total = len(matching)
+	if query.Offset < len(matching) {
+		matching = matching[max(query.Offset, 0):]
+		if query.Limit > 0 && len(matching) > query.Limit {
+			matching = matching[:query.Limit]
+		}
+		page.Rows = matching
+	}
+	return page, nil
+}
+
+// GetEmbedding returns the stored embedding object of an index.
+//
+// Parameters:
+//   - Index: The index of the embedding object.
+//   - options: WithEmbeddingPrefix selects the prefix.
+//
+// Returns:
+//   - LLMEmbeddingObject: The object with all its content entries.
+//   - error: ErrEmbeddingNotFound if the index has no embeddings.
+func (llm *LLMContainer) GetEmbedding(Index string, options ...LLMCallOption) (LLMEmbeddingObject, error) {
+	o := LLMCallOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+	llmo := LLMEmbeddingObject{
+		EmbeddingPrefix: o.getEmbeddingPrefix(),
+		Index:           Index,
+	}
+	if err := llm.checkTenant(&o); err != nil {
+		return llmo, err
+	}
+	if err := llmo.load(llm.RedisClient.redisClient, llmo.getRawDocRedisId()); err != nil {
+		return llmo, err
+	}
+	return llmo, llm.decryptObject(&llmo)
+}
+
+// GetEmbeddedDocument returns a content entry of an index with the text of its chunks.
+//
+// Parameters:
+//   - Index: The index of the embedding object.
+//   - Id: The Id of the content entry.
+//   - options: WithEmbeddingPrefix selects the prefix.
+//
+// Returns:
+//   - EmbeddedDocument: The entry and its chunks, in embedding order.
+//   - error: An error if the index or the entry doesn't exist.
+func (llm *LLMContainer) GetEmbeddedDocument(Index, Id string, options ...LLMCallOption) (EmbeddedDocument, error) {
+	obj, err := llm.GetEmbedding(Index, options...)
+	if err != nil {
+		return EmbeddedDocument{}, err
+	}
+	content, exists := obj.Contents[Id]
+	if !exists {
+		return EmbeddedDocument{}, fmt.Errorf("document %s not found in %s", Id, Index)
+	}
+	document := EmbeddedDocument{
+		EmbeddingPrefix: obj.EmbeddingPrefix,
+		Index:           obj.Index,
+		Content:         content,
+		Chunks:          []EmbeddedChunk{},
+	}
+	ctx := context.Background()
+	pipe := llm.RedisClient.redisClient.Pipeline()
+	cmds := make([]*redis.StringCmd, len(content.Keys))
+	for idx, key := range content.Keys {
+		cmds[idx] = pipe.HGet(ctx, key, "content")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return document, err
+	}
+	for idx, cmd := range cmds {
+		// Chunks removed from Redis are skipped
+		if cmd.Err() == nil {
+			chunk, err := llm.decryptText(cmd.Val())
+			if err != nil {
+				return document, err
+			}
+			document.Chunks = append(document.Chunks, EmbeddedChunk{Key: content.Keys[idx], Content: chunk})
+		}
+	}
+	return document, nil
+}
+
+// UpdateEmbeddingContent updates an existing content entry and embeds it again.
+//
+// Empty Title, Text, Language and Sources fields keep their stored values, so a CMS can update a
+// single field. EmbeddText creates new entries, this method fails if the entry doesn't exist.
+//
+// Parameters:
+//   - Index: The index of the embedding object.
+//   - Contents: The new values, Contents.Id selects the entry.
+//   - options: WithEmbeddingPrefix selects the prefix, other embedding options apply as in EmbeddText.
+//
+// Returns:
+//   - LLMEmbeddingObject: The updated embedding object.
+//   - error: An error if the entry doesn't exist or embedding fails.
+func (llm *LLMContainer) UpdateEmbeddingContent(Index string, Contents LLMEmbeddingContent, options ...LLMCallOption) (LLMEmbeddingObject, error) {
+	obj, err := llm.GetEmbedding(Index, options...)
+	if err != nil {
+		return obj, err
+	}
+	current, exists := obj.Contents[Contents.Id]
+	if Contents.Id == "" || !exists {
+		return obj, fmt.Errorf("document %s not found in %s", Contents.Id, Index)
+	}
+	if Contents.Title == "" {
+		Contents.Title = current.Title
+	}
+	if Contents.Text == "" {
+		Contents.Text = current.Text
+	}
+	if Contents.Language == "" {
+		Contents.Language = current.Language
+	}
+	if Contents.Sources == "" {
+		Contents.Sources = current.Sources
+	}
+	return llm.EmbeddText(Index, Contents, options...)
+}
+
+// ReembedDocument embeds the stored text of a content entry again, e.g. after changing the
+// embedding model or the chunking configuration.
+//
+// Parameters:
+//   - Index: The index of the embedding object.
+//   - Id: The Id of the content entry.
+//   - options: WithEmbeddingPrefix selects the prefix, other embedding options apply as in EmbeddText.
+//
+// Returns:
+//   - LLMEmbeddingObject: The updated embedding object.
+//   - error: An error if the entry doesn't exist or embedding fails.
+func (llm *LLMContainer) ReembedDocument(Index, Id string, options ...LLMCallOption) (LLMEmbeddingObject, error) {
+	return llm.UpdateEmbeddingContent(Index, LLMEmbeddingContent{Id: Id}, options...)
+}
+
+// newEmbeddingObjectInfo summarizes an embedding object, documents are sorted by Id.
+func newEmbeddingObjectInfo(obj LLMEmbeddingObject) EmbeddingObjectInfo {
+	info := EmbeddingObjectInfo{
+		EmbeddingPrefix: obj.EmbeddingPrefix,
+		Index:           obj.Index,
+		Documents:       []EmbeddedDocumentInfo{},
+	}
+	for _, content := range obj.Contents {
+		info.Documents = append(info.Documents, EmbeddedDocumentInfo{
+			Id:         content.Id,
+			Title:      content.Title,
+			Language:   content.Language,
+			Sources:    content.Sources,
+			ChunkCount: len(content.Keys),
+			UpdatedAt:  content.UpdatedAt,
+		})
+		info.ChunkCount += len(content.Keys)
+		if content.UpdatedAt.After(info.UpdatedAt) {
+			info.UpdatedAt = content.UpdatedAt
+		}
+	}
+	sort.Slice(info.Documents, func(i, j int) bool {
+		return info.Documents[i].Id < info.Documents[j].Id
+	})
+	return info
+}
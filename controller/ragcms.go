@@ -16,12 +16,15 @@ package aillm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
-	"github.com/tmc/langchaingo/schema"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // LLMEmbeddingContent represents a single piece of text content that is embedded and stored in the system.
@@ -32,18 +35,30 @@ import (
 // Fields:
 //   - Text: The raw text content that is embedded.
 //   - Title: A descriptive title for the embedded content.
-//   - Index:
+//   - Index: Set in the metadata of chunks only, the index the chunk was embedded with.
 //   - Source: The origin of the content, such as a file name, URL, or other identifier.
 //   - Keys: A slice of strings representing the Redis keys associated with this content.
+//   - Tables: The tables of the content, embedded apart from the text, see Table.
+//   - TableRef: Set in the metadata of table chunks only, the raw document and position of their table.
+//   - UpdatedAt: When the content was last embedded, zero for contents embedded by older versions.
 type LLMEmbeddingContent struct {
-	Text        string   `json:"Text" redis:"Text"`
-	Title       string   `json:"Title" redis:"Title"`
-	Language    string   `json:"Language" redis:"Language"`
-	Id          string   `json:"Id" redis:"Id"`
-	Keys        []string `json:"Keys" redis:"Keys"`
-	GeneralKeys []string `json:"GeneralKeys" redis:"GeneralKeys"`
-	Keywords    []string `json:"Keywords" redis:"Keywords"`
-	Sources     string   `json:"Sources" redis:"Sources"`
+	Text        string    `json:"Text" redis:"Text"`
+	Title       string    `json:"Title" redis:"Title"`
+	Language    string    `json:"Language" redis:"Language"`
+	Id          string    `json:"Id" redis:"Id"`
+	Index       string    `json:"Index,omitempty" redis:"Index"`
+	Keys        []string  `json:"Keys" redis:"Keys"`
+	GeneralKeys []string  `json:"GeneralKeys" redis:"GeneralKeys"`
+	Keywords    []string  `json:"Keywords" redis:"Keywords"`
+	Sources     string    `json:"Sources" redis:"Sources"`
+	Tables      []Table   `json:"Tables,omitempty" redis:"Tables"`
+	TableRef    string    `json:"TableRef,omitempty" redis:"TableRef"`
+	UpdatedAt   time.Time `json:"UpdatedAt" redis:"UpdatedAt"`
+}
+
+// chunkKeys returns the Redis keys of the chunks of the content, including the general chunks.
+func (content LLMEmbeddingContent) chunkKeys() []string {
+	return append(append([]string{}, content.Keys...), content.GeneralKeys...)
 }
 
 // LLMEmbeddingObject represents a collection of embedded text contents grouped under a specific object ID.
@@ -94,7 +109,12 @@ func (llm LLMContainer) EmbeddFile(Index, Title, fileName string, tc TranscribeC
 	var result LLMEmbeddingObject
 	// EmbeddingContents := make(map[string]LLMEmbeddingContent)
 	// Transcribe the file to extract text content
+	if tc.UseVisionOCR {
+		tc.visionOCR = llm.transcribeImage
+	}
+	_, transcribeSpan := llm.startSpan(context.Background(), "aillm.Transcribe", attribute.String("aillm.source", fileName))
 	fileContents, _, transcribeErr := llm.Transcriber.transcribeFile(fileName, "", tc)
+	endSpan(transcribeSpan, transcribeErr)
 	if transcribeErr != nil {
 		return result, transcribeErr
 	}
@@ -129,7 +149,12 @@ func (llm LLMContainer) EmbeddURL(Index, url string, tc TranscribeConfig, option
 
 	var result LLMEmbeddingObject
 	// Transcribe the content from the provided URL
+	if tc.UseVisionOCR {
+		tc.visionOCR = llm.transcribeImage
+	}
+	_, transcribeSpan := llm.startSpan(context.Background(), "aillm.Transcribe", attribute.String("aillm.source", url))
 	fileContents, _, transcribeErr := llm.Transcriber.TranscribeURL(url, tc)
+	endSpan(transcribeSpan, transcribeErr)
 	if transcribeErr != nil {
 		return result, transcribeErr
 	}
@@ -173,15 +198,30 @@ func (llm *LLMContainer) EmbeddText(Index string, Contents LLMEmbeddingContent,
 		EmbeddingPrefix: o.getEmbeddingPrefix(),
 		Index:           Index,
 	}
-	ctx := context.TODO()
+	if err := llm.checkTenant(&o); err != nil {
+		return result, err
+	}
+	ctx, span := llm.startSpan(context.Background(), "aillm.EmbeddText", attribute.String("aillm.index", Index))
 	_, err := llm.RedisClient.redisClient.Ping(ctx).Result()
 	if err != nil {
+		endSpan(span, err)
 		return result, err
 	}
 
 	// Load existing data from Redis if available
 	err = result.load(llm.RedisClient.redisClient, result.getRawDocRedisId())
-	if err != nil && err.Error() != "key not found" {
+	if err != nil && !errors.Is(err, ErrEmbeddingNotFound) {
+		endSpan(span, err)
+		return result, err
+	}
+	if err != nil {
+		// A new index counts against the limit of the tenant
+		if err := llm.checkTenantIndexLimit(&o); err != nil {
+			endSpan(span, err)
+			return result, err
+		}
+	} else if err := llm.decryptObject(&result); err != nil {
+		endSpan(span, err)
 		return result, err
 	}
 
@@ -189,6 +229,9 @@ func (llm *LLMContainer) EmbeddText(Index string, Contents LLMEmbeddingContent,
 	if result.Contents == nil {
 		result.Contents = make(map[string]LLMEmbeddingContent)
 	}
+	if Contents.Id == "" {
+		Contents.Id = o.documentID
+	}
 	if Contents.Id == "" {
 		Contents.Id = uuid.New().String()
 	}
@@ -199,18 +242,27 @@ func (llm *LLMContainer) EmbeddText(Index string, Contents LLMEmbeddingContent,
 	if Contents.Language == "" {
 		Contents.Language = o.Language
 	}
-	tempKeys, generalKeys, _, _, err := llm.embedText(o.getEmbeddingPrefix(), Contents.Language, Index, Contents.Title, llm.Transcriber.cleanupText(Contents.Text, o.CotextCleanup), Contents.Sources, Contents, o.LimitGeneralEmbedding, false, o.UseLLMToSplitText)
+	if err := checkLanguage(Contents.Language); err != nil {
+		endSpan(span, err)
+		return result, err
+	}
+	// Tables are embedded apart from the text, so their rows aren't flattened into text chunks
+	text, tables := extractTables(Contents.Text)
+	Contents.Text = text
+	Contents.Tables = append(append([]Table(nil), Contents.Tables...), tables...)
+	if err := llm.redactIngestedPII(ctx, Index, &Contents); err != nil {
+		endSpan(span, err)
+		return result, err
+	}
+	tempKeys, generalKeys, _, _, err := llm.embedText(ctx, o.getEmbeddingPrefix(), Contents.Language, Index, Contents.Title, llm.Transcriber.cleanupText(Contents.Text, o.CotextCleanup), Contents.Sources, Contents, o.LimitGeneralEmbedding, false, o.UseLLMToSplitText)
 	if err != nil {
+		llm.metrics.observeProviderError("embedding")
+		endSpan(span, err)
 		return result, err
 	}
 	curContents := result.Contents[Contents.Id]
 	// Cleanup previous keys
-	for _, key := range curContents.Keys {
-		llm.deleteRedisWildCard(llm.RedisClient.redisClient, key, false)
-	}
-	for _, key := range curContents.GeneralKeys {
-		llm.deleteRedisWildCard(llm.RedisClient.redisClient, key, false)
-	}
+	unlinkKeys(ctx, llm.RedisClient.redisClient, curContents.chunkKeys())
 
 	// updating with new keys
 	// tmpGeneralKeys := append(curContents.GeneralKeys, generalKeys...)
@@ -219,11 +271,16 @@ func (llm *LLMContainer) EmbeddText(Index string, Contents LLMEmbeddingContent,
 	curContents = Contents
 	curContents.GeneralKeys = generalKeys
 	curContents.Keys = tempKeys
+	if !o.keepUpdatedAt {
+		curContents.UpdatedAt = time.Now().UTC()
+	}
 
 	result.Contents[Contents.Id] = curContents
 
 	// Save the embedding data to Redis
 	redisErr := llm.saveEmbeddingDataToRedis(result)
+	span.SetAttributes(attribute.Int("aillm.chunks", len(tempKeys)))
+	endSpan(span, redisErr)
 	return result, redisErr
 }
 
@@ -234,7 +291,7 @@ func (llm *LLMContainer) EmbeddText(Index string, Contents LLMEmbeddingContent,
 //   - KeyID: The key used to retrieve the embedding object from Redis.
 //
 // Returns:
-//   - error: An error if the key is not found or data cannot be unmarshalled.
+//   - error: ErrEmbeddingNotFound if the key is not found, or an error if data cannot be unmarshalled.
 func (llmEO *LLMEmbeddingObject) load(client *redis.Client, KeyID string) error {
 
 	ctx := context.Background()
@@ -243,7 +300,7 @@ func (llmEO *LLMEmbeddingObject) load(client *redis.Client, KeyID string) error
 	data, err := client.Do(ctx, "JSON.GET", KeyID).Result()
 
 	if err == redis.Nil {
-		return fmt.Errorf("key not found")
+		return ErrEmbeddingNotFound
 	} else if err != nil {
 		return err
 	}
@@ -290,7 +347,7 @@ func (llmEO LLMEmbeddingObject) delete(rdb *redis.Client, KeyID string) error {
 	return nil
 }
 
-// Save stores an embedding object in Redis after deleting any existing entry.
+// Save stores an embedding object in Redis, replacing any existing entry.
 //
 // Parameters:
 //   - rdb: Redis client instance for database operations.
@@ -300,26 +357,23 @@ func (llmEO LLMEmbeddingObject) delete(rdb *redis.Client, KeyID string) error {
 //   - error: An error if the save operation fails.
 func (llmEO *LLMEmbeddingObject) save(rdb *redis.Client, KeyID string) error {
 	ctx := context.TODO()
-	// Check Redis connection before proceeding
-	_, err := rdb.Ping(ctx).Result()
-	if err != nil {
-		return err
-	}
-	err = createIndex(ctx, rdb, llmEO.EmbeddingPrefix)
-	if err != nil {
-		return err
-	}
-	// Delete any existing record before saving a new one
-	llmEO.delete(rdb, llmEO.getRawDocRedisId())
 	// Serialize the embedding object to JSON format
 	data, err := json.Marshal(llmEO)
 	if err != nil {
 		return err
 	}
-	// Store the serialized data in Redis with no expiration
-	err = saveKey(ctx, rdb, KeyID, data)
-	if err != nil {
-		return fmt.Errorf("error setting JSON in Redis: %v", err)
+	// The record is stored and the index checked in a single round trip, JSON.SET on the root path replaces
+	// the existing record
+	pipe := rdb.Pipeline()
+	indexInfo := pipe.Do(ctx, "FT.INFO", rawDocsIndexName(llmEO.EmbeddingPrefix))
+	saved := pipe.Do(ctx, "JSON.SET", KeyID, "$", string(data))
+	pipe.Exec(ctx)
+	if err := saved.Err(); err != nil {
+		return fmt.Errorf("error setting JSON in Redis: %w", err)
+	}
+	// A new index also indexes the records stored before it
+	if indexInfo.Err() != nil {
+		return createIndex(ctx, rdb, llmEO.EmbeddingPrefix)
 	}
 	return nil
 }
@@ -335,9 +389,21 @@ func (llmEO *LLMEmbeddingObject) save(rdb *redis.Client, KeyID string) error {
 // Returns:
 //   - map[string]interface{}: A map containing retrieved objects and total count.
 //   - error: An error if the operation fails.
+//
+// The objects are returned with their full texts, QueryEmbeddings lists summaries filtered by language and source.
 func (llm *LLMContainer) ListEmbeddings(KeyID string, offset, limit int) (map[string]interface{}, error) {
 	oe := LLMEmbeddingObject{}
-	return oe.list(llm.RedisClient.redisClient, KeyID, offset, limit)
+	response, err := oe.list(llm.RedisClient.redisClient, KeyID, offset, limit)
+	if err != nil {
+		return response, err
+	}
+	rows, _ := response["Rows"].([]LLMEmbeddingObject)
+	for idx := range rows {
+		if err := llm.decryptObject(&rows[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
 }
 func (llmEO LLMEmbeddingObject) list(rdb *redis.Client, KeyID string, offset, limit int) (map[string]interface{}, error) {
 	ctx := context.Background()
@@ -347,21 +413,16 @@ func (llmEO LLMEmbeddingObject) list(rdb *redis.Client, KeyID string, offset, li
 	if err != nil {
 		return nil, err
 	}
-	// Retrieve all matching keys with the given prefix
-	keys, err := rdb.Keys(ctx, KeyID+"*").Result()
+	// Retrieve all matching keys with the given prefix, SCAN doesn't block Redis as KEYS does
+	keys, err := scanKeys(ctx, rdb, KeyID+"*")
 	if err != nil {
 		return nil, err
 	}
+	sort.Strings(keys)
 
 	total := len(keys)
-	if offset > total {
-		offset = total
-	}
-
-	end := offset + limit
-	if end > total {
-		end = total
-	}
+	offset = max(0, min(offset, total))
+	end := max(offset, min(offset+limit, total))
 
 	// Load embedding objects within the requested range
 	var results []LLMEmbeddingObject
@@ -390,7 +451,11 @@ func (llmEO LLMEmbeddingObject) list(rdb *redis.Client, KeyID string, offset, li
 //   - error: An error if the save operation fails.
 func (llm *LLMContainer) saveEmbeddingDataToRedis(obj LLMEmbeddingObject) error {
 	// Store the embedding object in Redis using its generated key
-	return obj.save(llm.RedisClient.redisClient, obj.getRawDocRedisId())
+	sealed, err := llm.sealObject(obj)
+	if err != nil {
+		return err
+	}
+	return sealed.save(llm.RedisClient.redisClient, sealed.getRawDocRedisId())
 }
 
 // RemoveEmbedding deletes an embedding object and its associated keys from Redis.
@@ -411,27 +476,23 @@ func (llm *LLMContainer) RemoveEmbedding(Index string, options ...LLMCallOption)
 		EmbeddingPrefix: o.getEmbeddingPrefix(),
 		Index:           Index,
 	}
+	if err := llm.checkTenant(&o); err != nil {
+		return err
+	}
 
 	// Load the embedding object from Redis
 	err := llmo.load(llm.RedisClient.redisClient, llmo.getRawDocRedisId())
-	if err != nil && err.Error() != "key not found" {
+	if err != nil && !errors.Is(err, ErrEmbeddingNotFound) {
 		return err
 	}
 
 	// Delete all associated keys stored in Redis
+	keys := []string{}
 	for _, content := range llmo.Contents {
-		for _, key := range content.Keys {
-			_, err := llm.deleteRedisWildCard(llm.RedisClient.redisClient, key, false)
-			if err != nil {
-				return err
-			}
-		}
-		for _, key := range content.GeneralKeys {
-			_, err := llm.deleteRedisWildCard(llm.RedisClient.redisClient, key, false)
-			if err != nil {
-				return err
-			}
-		}
+		keys = append(keys, content.chunkKeys()...)
+	}
+	if _, err := unlinkKeys(context.Background(), llm.RedisClient.redisClient, keys); err != nil {
+		return err
 	}
 	//Remove indexes should be implemented
 
@@ -449,22 +510,15 @@ func (llm *LLMContainer) RemoveEmbeddingSubKey(Index, rawDocID string, options .
 		EmbeddingPrefix: o.getEmbeddingPrefix(),
 		Index:           Index,
 	}
+	if err := llm.checkTenant(&o); err != nil {
+		return err
+	}
 	// Load the embedding object from Redis
 	llmo.load(llm.RedisClient.redisClient, llmo.getRawDocRedisId())
 	keyToDelete := llmo.Contents[rawDocID]
 	// Delete all associated keys stored in Redis
-
-	for _, key := range keyToDelete.Keys {
-		_, err := llm.deleteRedisWildCard(llm.RedisClient.redisClient, key, false)
-		if err != nil {
-			return err
-		}
-	}
-	for _, key := range keyToDelete.GeneralKeys {
-		_, err := llm.deleteRedisWildCard(llm.RedisClient.redisClient, key, false)
-		if err != nil {
-			return err
-		}
+	if _, err := unlinkKeys(context.Background(), llm.RedisClient.redisClient, keyToDelete.chunkKeys()); err != nil {
+		return err
 	}
 	delete(llmo.Contents, rawDocID)
 	if len(llmo.Contents) == 0 {
@@ -480,13 +534,13 @@ func (llm *LLMContainer) RemoveEmbeddingSubKey(Index, rawDocID string, options .
 // GetRagIndexs retrieves the Redis index values for the given documents.
 //
 // Parameters:
-//   - docs: A slice of schema.Document objects containing the documents to search for.
+//   - docs: The retrieved documents to search for, e.g. LLMResult.RagDocs.
 //   - options: Additional options for the search operation.
 //
 // Returns:
 //   - []string: A slice of Redis index values.
 //   - error: An error if the operation fails.
-func (llm *LLMContainer) GetRagIndexs(docs []schema.Document, options ...LLMCallOption) ([]string, error) {
+func (llm *LLMContainer) GetRagIndexs(docs []RetrievedDocument, options ...LLMCallOption) ([]string, error) {
 	o := LLMCallOptions{}
 	for _, opt := range options {
 		opt(&o)
@@ -496,8 +550,8 @@ func (llm *LLMContainer) GetRagIndexs(docs []schema.Document, options ...LLMCall
 	}
 
 	indexName := "rawDocsIdx:"
-	if o.Prefix != "" {
-		indexName += o.Prefix
+	if o.getEmbeddingPrefix() != "" {
+		indexName += o.getEmbeddingPrefix()
 	}
 
 	rdb := llm.RedisClient.redisClient
@@ -505,14 +559,14 @@ func (llm *LLMContainer) GetRagIndexs(docs []schema.Document, options ...LLMCall
 
 	var escapedQueries []string
 	for _, value := range docs {
-		escapedValue := escapeRedisQuery(value.Metadata["id"].(string))
+		escapedValue := escapeRedisQuery(value.ChunkID)
 		query := fmt.Sprintf(`(@GeneralKeys:{%s}) | (@Keys:{%s})`, escapedValue, escapedValue)
 		escapedQueries = append(escapedQueries, query)
 	}
 	finalQuery := strings.Join(escapedQueries, " | ") // ترکیب همه کوئری‌ها با عملگر `OR`
 	results, err := rdb.Do(ctx, "FT.SEARCH", indexName, finalQuery, "RETURN", "1", "$.Index").Result()
 	if err != nil {
-		return nil, err
+		return nil, redisIndexError(err)
 	}
 	// Extract "index" values from the search results
 	var indexValues []string
@@ -557,7 +611,7 @@ func (llm *LLMContainer) GetRagIndexs(docs []schema.Document, options ...LLMCall
 					for _, indexItem := range idxContents {
 						indexItemData, ok := indexItem.(string)
 						if ok && strings.HasPrefix(indexItemData, "rawDocs:") {
-							finalIndex := strings.ReplaceAll(indexItemData, "rawDocs:"+o.Prefix+":", "")
+							finalIndex := strings.ReplaceAll(indexItemData, "rawDocs:"+o.getEmbeddingPrefix()+":", "")
 							indexValues = append(indexValues, finalIndex)
 							continue
 						}
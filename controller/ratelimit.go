@@ -0,0 +1,175 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+	"golang.org/x/time/rate"
+)
+
+// providerLimiter throttles requests and tokens sent to a single provider endpoint.
+//
+// Callers wait in a queue until the budget allows the request, so bursts of calls are spread
+// over time instead of failing with HTTP 429.
+type providerLimiter struct {
+	requests          *rate.Limiter
+	tokens            *rate.Limiter
+	requestsPerMinute int
+	tokensPerMinute   int
+}
+
+// providerLimiterRegistry keeps the limiters of the endpoints of a container, shared by all clients using the same
+// endpoint and model.
+type providerLimiterRegistry struct {
+	mu       sync.Mutex
+	limiters map[string]*providerLimiter
+}
+
+// providerLimiterRegistry returns the limiters of the container, creating them on first use.
+func (llm *LLMContainer) providerLimiterRegistry() *providerLimiterRegistry {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.providerLimiters == nil {
+		llm.providerLimiters = &providerLimiterRegistry{limiters: map[string]*providerLimiter{}}
+	}
+	return llm.providerLimiters
+}
+
+// getProviderLimiter returns the limiter of a configuration, or nil if no limits are configured.
+//
+// A limiter whose limits changed since it was created is replaced, so configuration changes apply to the next request.
+func (llm *LLMContainer) getProviderLimiter(config LLMConfig) *providerLimiter {
+	if config.RequestsPerMinute <= 0 && config.TokensPerMinute <= 0 {
+		return nil
+	}
+	registry := llm.providerLimiterRegistry()
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	key := config.Apiurl + "|" + config.AiModel
+	if limiter, ok := registry.limiters[key]; ok && limiter.requestsPerMinute == config.RequestsPerMinute && limiter.tokensPerMinute == config.TokensPerMinute {
+		return limiter
+	}
+	limiter := &providerLimiter{requestsPerMinute: config.RequestsPerMinute, tokensPerMinute: config.TokensPerMinute}
+	if config.RequestsPerMinute > 0 {
+		limiter.requests = rate.NewLimiter(rate.Every(time.Minute/time.Duration(config.RequestsPerMinute)), config.RequestsPerMinute)
+	}
+	if config.TokensPerMinute > 0 {
+		limiter.tokens = rate.NewLimiter(rate.Limit(float64(config.TokensPerMinute)/60), config.TokensPerMinute)
+	}
+	registry.limiters[key] = limiter
+	return limiter
+}
+
+// wait blocks until one request with the estimated number of tokens is allowed.
+func (pl *providerLimiter) wait(ctx context.Context, tokens int) error {
+	if pl == nil {
+		return nil
+	}
+	if pl.requests != nil {
+		if err := pl.requests.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if pl.tokens != nil && tokens > 0 {
+		// A single request larger than the budget waits for the whole budget
+		if tokens > pl.tokens.Burst() {
+			tokens = pl.tokens.Burst()
+		}
+		if err := pl.tokens.WaitN(ctx, tokens); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// estimateTokens roughly estimates the number of tokens of a text (about 4 characters per token).
+func estimateTokens(text string) int {
+	return len(text)/4 + 1
+}
+
+// rateLimitedModel applies the provider limits of the container to an llms.Model.
+type rateLimitedModel struct {
+	llms.Model
+	llm    *LLMContainer
+	config func() LLMConfig
+}
+
+// limitModel wraps a model with the limits of its client configuration, read on every request.
+func (llm *LLMContainer) limitModel(client LLMClient, model llms.Model) llms.Model {
+	if model == nil {
+		return model
+	}
+	return &rateLimitedModel{Model: model, llm: llm, config: client.GetConfig}
+}
+
+// GenerateContent waits for the rate limits before calling the wrapped model.
+func (m *rateLimitedModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	tokens := 0
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				tokens += estimateTokens(text.Text)
+			}
+		}
+	}
+	if err := m.llm.getProviderLimiter(m.config()).wait(ctx, tokens); err != nil {
+		return nil, err
+	}
+	return m.Model.GenerateContent(ctx, messages, options...)
+}
+
+// Call waits for the rate limits before calling the wrapped model.
+func (m *rateLimitedModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// rateLimitedEmbedder applies the provider limits of the container to an embeddings.Embedder.
+type rateLimitedEmbedder struct {
+	embedder embeddings.Embedder
+	llm      *LLMContainer
+	config   func() LLMConfig
+}
+
+// limitEmbedder wraps an embedder with the limits of its client configuration, read on every request.
+func (llm *LLMContainer) limitEmbedder(config func() LLMConfig, embedder embeddings.Embedder) embeddings.Embedder {
+	if embedder == nil {
+		return embedder
+	}
+	return &rateLimitedEmbedder{embedder: embedder, llm: llm, config: config}
+}
+
+// EmbedDocuments waits for the rate limits before embedding the texts.
+func (e *rateLimitedEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	tokens := 0
+	for _, text := range texts {
+		tokens += estimateTokens(text)
+	}
+	if err := e.llm.getProviderLimiter(e.config()).wait(ctx, tokens); err != nil {
+		return nil, err
+	}
+	return e.embedder.EmbedDocuments(ctx, texts)
+}
+
+// EmbedQuery waits for the rate limits before embedding the query.
+func (e *rateLimitedEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	if err := e.llm.getProviderLimiter(e.config()).wait(ctx, estimateTokens(text)); err != nil {
+		return nil, err
+	}
+	return e.embedder.EmbedQuery(ctx, text)
+}
@@ -20,24 +20,6 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-// saveKey saves a key in Redis.
-//
-// Parameters:
-//   - ctx: The context for the Redis operation.
-//   - rdb: The Redis client instance.
-//   - KeyID: The ID of the key to save.
-//   - data: The data to save.
-//
-// Returns:
-//   - error: An error if the operation fails.
-func saveKey(ctx context.Context, rdb *redis.Client, KeyID string, data []byte) error {
-	err := rdb.Do(ctx, "JSON.SET", KeyID, "$", string(data)).Err()
-	if err != nil {
-		return fmt.Errorf("error setting JSON in Redis: %v", err)
-	}
-	return nil
-}
-
 // deleteKey deletes a key in Redis.
 //
 // Parameters:
@@ -47,7 +29,7 @@ func saveKey(ctx context.Context, rdb *redis.Client, KeyID string, data []byte)
 func deleteKey(ctx context.Context, rdb *redis.Client, KeyID, indexName string) error {
 	_, err := rdb.Do(ctx, "JSON.DEL", KeyID, "$").Result()
 	if err != nil {
-		return fmt.Errorf("error deleting JSON in Redis: %v", err)
+		return fmt.Errorf("error deleting JSON in Redis: %w", err)
 	}
 	err = rdb.Do(ctx, "FT.DEL", "rawDocsIdx:"+indexName, KeyID).Err()
 	if err != nil {
@@ -57,6 +39,42 @@ func deleteKey(ctx context.Context, rdb *redis.Client, KeyID, indexName string)
 	return nil
 }
 
+// unlinkKeys deletes keys in batches sent in a single pipeline, one round trip instead of one per key.
+//
+// Parameters:
+//   - ctx: The context for the Redis operation.
+//   - rdb: The Redis client instance.
+//   - keys: The keys to delete, missing keys are ignored.
+//
+// Returns:
+//   - int: The number of keys deleted.
+//   - error: An error if the operation fails.
+func unlinkKeys(ctx context.Context, rdb *redis.Client, keys []string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	cmds := []*redis.IntCmd{}
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for start := 0; start < len(keys); start += deleteBatchSize {
+			cmds = append(cmds, pipe.Unlink(ctx, keys[start:min(start+deleteBatchSize, len(keys))]...))
+		}
+		return nil
+	})
+	deleted := 0
+	for _, cmd := range cmds {
+		deleted += int(cmd.Val())
+	}
+	return deleted, err
+}
+
+// rawDocsIndexName returns the name of the index of the embedding objects of a prefix.
+func rawDocsIndexName(prefix string) string {
+	if prefix == "" {
+		return "rawDocsIdx"
+	}
+	return "rawDocsIdx:" + prefix
+}
+
 // createIndex creates an index in Redis.
 //
 // Parameters:
@@ -67,10 +85,7 @@ func deleteKey(ctx context.Context, rdb *redis.Client, KeyID, indexName string)
 // Returns:
 //   - error: An error if the operation fails.
 func createIndex(ctx context.Context, rdb *redis.Client, prefix string) error {
-	indexName := "rawDocsIdx"
-	if prefix != "" {
-		indexName += ":" + prefix
-	}
+	indexName := rawDocsIndexName(prefix)
 	_, err := rdb.Do(ctx, "FT.INFO", indexName).Result()
 	if err != nil {
 		// If the index does not exist, create it
@@ -87,7 +102,7 @@ func createIndex(ctx context.Context, rdb *redis.Client, prefix string) error {
 		).Err()
 
 		if err != nil {
-			return fmt.Errorf("error creating index: %v", err)
+			return fmt.Errorf("error creating index: %w", err)
 		}
 	}
 	return nil
@@ -0,0 +1,75 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import "strings"
+
+// refusalMarker starts the answers the model can't give from the retrieved documents, it sets
+// LLMResult.FailedToRespond and is removed from the streamed chunks and the response.
+const refusalMarker = "[NO_ANSWER]"
+
+// refusalPadding is skipped before the marker, models sometimes format it or start with a new line.
+const refusalPadding = " \t\r\n*_`"
+
+// refusalDetector finds the refusal marker at the start of a streamed answer.
+//
+// The first chunks are held back while they can still be the start of the marker, so the marker
+// never reaches the stream even when the model splits it between chunks.
+type refusalDetector struct {
+	decided bool
+	refused bool
+	buffer  strings.Builder
+}
+
+// write returns the part of a chunk to stream, nothing while the start of the answer is undecided.
+func (rd *refusalDetector) write(chunk []byte) []byte {
+	if rd.decided {
+		return chunk
+	}
+	rd.buffer.Write(chunk)
+	buffered := rd.buffer.String()
+	trimmed := strings.TrimLeft(buffered, refusalPadding)
+	if strings.HasPrefix(trimmed, refusalMarker) {
+		rd.decided, rd.refused = true, true
+		rd.buffer.Reset()
+		return []byte(strings.TrimLeft(trimmed[len(refusalMarker):], refusalPadding))
+	}
+	if strings.HasPrefix(refusalMarker, trimmed) {
+		return nil
+	}
+	rd.decided = true
+	rd.buffer.Reset()
+	return []byte(buffered)
+}
+
+// flush returns the text held back when the answer ended before the detector could decide.
+func (rd *refusalDetector) flush() []byte {
+	rd.decided = true
+	held := rd.buffer.String()
+	rd.buffer.Reset()
+	return []byte(held)
+}
+
+// stripRefusalMarker removes the refusal marker from a complete answer.
+//
+// Returns:
+//   - string: The answer without the marker.
+//   - bool: True if the answer contained the marker.
+func stripRefusalMarker(answer string) (string, bool) {
+	if !strings.Contains(answer, refusalMarker) {
+		return answer, false
+	}
+	answer = strings.ReplaceAll(answer, refusalMarker, "")
+	return strings.TrimLeft(answer, refusalPadding), true
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// RequestIDHeader is the HTTP header carrying the request ID to model providers.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// contextWithRequestID returns a context carrying the request ID, an empty ID returns ctx unchanged.
+func contextWithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// requestIDFromContext returns the request ID of the context, if any.
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// requestIDTransport adds the request ID of the request context to outgoing provider requests.
+//
+// Without base, requests are sent with the transport set by SetProviderTransport.
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if requestID := requestIDFromContext(req.Context()); requestID != "" && req.Header.Get(RequestIDHeader) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	base := t.base
+	if base == nil {
+		base = providerTransport()
+	}
+	return base.RoundTrip(req)
+}
+
+// providerHTTPClient is the HTTP client of the OpenAI and Ollama controllers.
+var providerHTTPClient = &http.Client{Transport: requestIDTransport{}}
+
+// transportHolder wraps the provider transport for atomic replacement.
+type transportHolder struct {
+	transport http.RoundTripper
+}
+
+var customProviderTransport atomic.Pointer[transportHolder]
+
+// providerTransport returns the transport of provider requests.
+func providerTransport() http.RoundTripper {
+	if holder := customProviderTransport.Load(); holder != nil {
+		return holder.transport
+	}
+	return http.DefaultTransport
+}
+
+// SetProviderTransport replaces the transport of the requests sent to the OpenAI and Ollama
+// LLM, embedding and moderation endpoints, e.g. to record and replay them in tests (see package
+// cassette), to add a proxy or custom TLS settings. nil restores http.DefaultTransport.
+//
+// The transport applies to every container of the process, including the clients already created.
+//
+// Parameters:
+//   - transport: The transport, nil restores the default.
+//
+// Returns:
+//   - http.RoundTripper: The previous transport, so tests can restore it.
+func SetProviderTransport(transport http.RoundTripper) http.RoundTripper {
+	previous := providerTransport()
+	if transport == nil {
+		customProviderTransport.Store(nil)
+	} else {
+		customProviderTransport.Store(&transportHolder{transport: transport})
+	}
+	return previous
+}
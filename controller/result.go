@@ -0,0 +1,72 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"strings"
+)
+
+// Text returns the answer without the reference line added by WithRagReferences, "" if the model didn't answer.
+//
+// Example Usage:
+//
+//	result, err := llm.AskLLM("What is SemMapas?")
+//	if err == nil {
+//		fmt.Println(result.Text())
+//	}
+func (la *LLMResult) Text() string {
+	if la.Response == nil || len(la.Response.Choices) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(strings.Split(la.Response.Choices[0].Content, "⧉")[0])
+}
+
+// Usage returns the input and output tokens of every stage of the call, see TokenReport.
+func (la *LLMResult) Usage() TokenUsage {
+	return la.TokenReport.sum()
+}
+
+// TotalTokens returns the tokens of every stage of the call, input and output.
+func (la *LLMResult) TotalTokens() int {
+	usage := la.Usage()
+	return usage.InputTokens + usage.OutputTokens
+}
+
+// Sources returns the distinct sources of the retrieved documents in rank order, or the URLs of the web pages
+// when the web search fallback answered.
+func (la *LLMResult) Sources() []string {
+	sources := []string{}
+	seen := map[string]bool{}
+	add := func(source string) {
+		if source != "" && !seen[source] {
+			seen[source] = true
+			sources = append(sources, source)
+		}
+	}
+	for _, doc := range la.RagDocs {
+		add(doc.Source)
+	}
+	for _, page := range la.WebSources {
+		add(page.URL)
+	}
+	return sources
+}
+
+// TopDocument returns the best ranked retrieved document, false if nothing was retrieved.
+func (la *LLMResult) TopDocument() (RetrievedDocument, bool) {
+	if len(la.RagDocs) == 0 {
+		return RetrievedDocument{}, false
+	}
+	return la.RagDocs[0], true
+}
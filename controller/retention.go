@@ -0,0 +1,523 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PurgeReport counts the data deleted by PurgeUserData.
+//
+// Fields:
+//   - Keys: The Redis keys deleted: memory, memory vectors and quota counters.
+//   - UsageFields: The session fields removed from the usage accounting, the totals are kept.
+//   - AuditRecords: The audit records deleted.
+//   - ToolAuditRecords: The tool invocation records deleted from a RedisToolAuditSink.
+//   - Traces: The traces deleted from a TraceStore implementing TracePurger.
+type PurgeReport struct {
+	Keys             int `json:"keys"`
+	UsageFields      int `json:"usageFields"`
+	AuditRecords     int `json:"auditRecords"`
+	ToolAuditRecords int `json:"toolAuditRecords"`
+	Traces           int `json:"traces"`
+}
+
+// AuditPurger is implemented by audit sinks able to delete the records of a session, PurgeUserData calls it.
+type AuditPurger interface {
+	// PurgeAudit deletes the records of a session and returns how many were deleted.
+	PurgeAudit(ctx context.Context, tenantID, sessionID string) (int, error)
+}
+
+// TracePurger is implemented by trace stores able to delete traces, PurgeUserData and EnforceRetention call it.
+// RedisTraceStore and SQLTraceStore implement it.
+type TracePurger interface {
+	// PurgeTraces deletes the traces of a session and returns how many were deleted.
+	PurgeTraces(ctx context.Context, tenantID, sessionID string) (int, error)
+	// ExpireTraces deletes the traces older than a time and returns how many were deleted.
+	ExpireTraces(ctx context.Context, before time.Time) (int, error)
+}
+
+// escapeGlob escapes the special characters of a Redis MATCH pattern.
+func escapeGlob(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(value)
+}
+
+// scanKeys returns the keys matching a pattern.
+func scanKeys(ctx context.Context, rdb *redis.Client, pattern string) ([]string, error) {
+	keys := []string{}
+	iter := rdb.Scan(ctx, 0, pattern, 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// PurgeUserData deletes everything stored about a session, e.g. to honor a right-to-be-forgotten request.
+//
+// The Redis keys of the session are first found with SCAN and deleted in a single Redis transaction: the persistent
+// memory and its vectors, the quota counters, the session breakdown of the usage accounting and, with a
+// RedisAuditSink and a RedisToolAuditSink, the audit and tool invocation records of the session. The in-memory
+// session memory and the traces of a TraceStore implementing TracePurger are deleted afterwards, then the index of
+// the memory vectors is dropped and other audit sinks implementing AuditPurger, such as FileAuditSink, are purged.
+//
+// The steps aren't atomic together: a failing step leaves the data of the later ones, and data the session writes
+// while it is purged may be kept. Purging is idempotent, so PurgeUserData can be called again, e.g. once the session
+// is closed.
+//
+// Parameters:
+//   - sessionID: The session to delete.
+//   - options: WithTenant selects the tenant of the session.
+//
+// Returns:
+//   - PurgeReport: What was deleted.
+//   - error: An error if Redis or the audit sink fails.
+//
+// Example Usage:
+//
+//	report, err := llm.PurgeUserData("user-42", llm.WithTenant("acme"))
+func (llm *LLMContainer) PurgeUserData(sessionID string, options ...LLMCallOption) (PurgeReport, error) {
+	report := PurgeReport{}
+	if sessionID == "" {
+		return report, errors.New("session ID is required")
+	}
+	o := LLMCallOptions{SessionID: sessionID}
+	for _, opt := range options {
+		opt(&o)
+	}
+	o.SessionID = sessionID
+	if err := llm.checkTenant(&o); err != nil {
+		return report, err
+	}
+	namespaced := o.getSessionID()
+	rdb := llm.RedisClient.redisClient
+	ctx := context.Background()
+	memoryPrefix := llm.PersistentMemoryManager.MemoryPrefix
+	if memoryPrefix == "" {
+		memoryPrefix = "Memory"
+	}
+	memoryIndex := "Memory:" + memoryPrefix + ":" + namespaced + ":aillm_vector_idx"
+	auditStream := ""
+	if rdb != nil {
+		// The Redis transaction runs first, a failing one leaves the session intact
+		var err error
+		if auditStream, err = llm.purgeRedisSession(ctx, rdb, &report, o.tenantID, sessionID, namespaced, memoryPrefix, memoryIndex); err != nil {
+			return report, err
+		}
+	}
+	if memory := llm.sessionMemory(); memory != nil {
+		memory.DeleteMemory(namespaced)
+	}
+	llm.forgetSessionLanguage(namespaced)
+	if err := llm.purgeTraces(ctx, &report, o.tenantID, sessionID); err != nil {
+		return report, err
+	}
+	if rdb == nil {
+		return report, nil
+	}
+	// The index of the memory vectors is empty now
+	rdb.Do(ctx, "FT.DROPINDEX", memoryIndex)
+	if err := llm.purgeAuditSink(ctx, &report, o.tenantID, sessionID, auditStream); err != nil {
+		return report, err
+	}
+	llm.logger().Info("user data purged", "tenant", o.tenantID, "session", sessionID, "keys", report.Keys, "auditRecords", report.AuditRecords)
+	return report, nil
+}
+
+// purgeRedisSession deletes the Redis keys, usage fields and audit stream entries of a session in a single
+// transaction. It returns the stream of a RedisAuditSink, which is purged with the transaction.
+func (llm *LLMContainer) purgeRedisSession(ctx context.Context, rdb *redis.Client, report *PurgeReport, tenantID, sessionID, namespaced, memoryPrefix, memoryIndex string) (string, error) {
+	keys := []string{}
+	for _, pattern := range []string{
+		"rawMemory:" + escapeGlob(memoryPrefix+":"+namespaced),
+		"doc:" + escapeGlob(memoryIndex) + ":*",
+		"quota:session:" + escapeGlob(namespaced) + ":*",
+	} {
+		matched, err := scanKeys(ctx, rdb, pattern)
+		if err != nil {
+			return "", err
+		}
+		keys = append(keys, matched...)
+	}
+
+	// Sessions are stored without their tenant namespace in the usage accounting
+	usagePattern := "usage:[0-9]*"
+	if tenantID != "" {
+		usagePattern = "usage:" + escapeGlob(TenantPrefix(tenantID, "")) + ":*"
+	}
+	usageKeys, err := scanKeys(ctx, rdb, usagePattern)
+	if err != nil {
+		return "", err
+	}
+	usageFields := map[string][]string{}
+	for _, key := range usageKeys {
+		for _, counter := range []string{"requests", "input", "output"} {
+			usageFields[key] = append(usageFields[key], "session:"+sessionID+":"+counter)
+		}
+	}
+
+	auditStream, auditIDs := "", []string{}
+	if sink, ok := llm.AuditSink.(*RedisAuditSink); ok && sink.redisClient != nil {
+		auditStream = sink.Stream
+		if auditIDs, err = sink.sessionEntries(ctx, tenantID, sessionID); err != nil {
+			return "", err
+		}
+	}
+	// Tool audit records hold the namespaced session ID and the raw tool arguments
+	toolAuditStream, toolAuditIDs := "", []string{}
+	if sink, ok := llm.ToolAuditSink.(*RedisToolAuditSink); ok && sink.redisClient != nil {
+		toolAuditStream = sink.Stream
+		if toolAuditIDs, err = sink.sessionEntries(ctx, namespaced); err != nil {
+			return "", err
+		}
+	}
+
+	if len(keys) == 0 && len(usageFields) == 0 && len(auditIDs) == 0 && len(toolAuditIDs) == 0 {
+		return auditStream, nil
+	}
+	pipe := rdb.TxPipeline()
+	var deleted *redis.IntCmd
+	if len(keys) > 0 {
+		deleted = pipe.Del(ctx, keys...)
+	}
+	removed := []*redis.IntCmd{}
+	for key, fields := range usageFields {
+		removed = append(removed, pipe.HDel(ctx, key, fields...))
+	}
+	var auditDeleted, toolAuditDeleted *redis.IntCmd
+	if len(auditIDs) > 0 {
+		auditDeleted = pipe.XDel(ctx, auditStream, auditIDs...)
+	}
+	if len(toolAuditIDs) > 0 {
+		toolAuditDeleted = pipe.XDel(ctx, toolAuditStream, toolAuditIDs...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return "", err
+	}
+	if deleted != nil {
+		report.Keys = int(deleted.Val())
+	}
+	for _, cmd := range removed {
+		report.UsageFields += int(cmd.Val())
+	}
+	if auditDeleted != nil {
+		report.AuditRecords = int(auditDeleted.Val())
+	}
+	if toolAuditDeleted != nil {
+		report.ToolAuditRecords = int(toolAuditDeleted.Val())
+	}
+	return auditStream, nil
+}
+
+// purgeAuditSink purges the records of a session from an AuditPurger sink not purged in the Redis transaction.
+func (llm *LLMContainer) purgeAuditSink(ctx context.Context, report *PurgeReport, tenantID, sessionID, auditStream string) error {
+	purger, ok := llm.AuditSink.(AuditPurger)
+	if !ok || auditStream != "" {
+		return nil
+	}
+	count, err := purger.PurgeAudit(ctx, tenantID, sessionID)
+	report.AuditRecords += count
+	return err
+}
+
+// purgeTraces deletes the traces of a session from a TracePurger trace store.
+func (llm *LLMContainer) purgeTraces(ctx context.Context, report *PurgeReport, tenantID, sessionID string) error {
+	purger, ok := llm.TraceStore.(TracePurger)
+	if !ok {
+		return nil
+	}
+	count, err := purger.PurgeTraces(ctx, tenantID, sessionID)
+	report.Traces += count
+	return err
+}
+
+// forgetSessionLanguage removes the detected language of a session.
+func (llm *LLMContainer) forgetSessionLanguage(sessionID string) {
+	llm.languageMemory().DeleteSessionLanguage(sessionID)
+}
+
+// sessionEntries returns the IDs of the tool audit stream entries of a session, sessionID is namespaced by its tenant.
+func (rs *RedisToolAuditSink) sessionEntries(ctx context.Context, sessionID string) ([]string, error) {
+	ids := []string{}
+	start := "-"
+	for {
+		entries, err := rs.redisClient.XRangeN(ctx, rs.Stream, start, "+", 1000).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Values["sessionId"] == sessionID {
+				ids = append(ids, entry.ID)
+			}
+		}
+		if len(entries) < 1000 {
+			return ids, nil
+		}
+		start = "(" + entries[len(entries)-1].ID
+	}
+}
+
+// sessionEntries returns the IDs of the stream entries of a session.
+func (rs *RedisAuditSink) sessionEntries(ctx context.Context, tenantID, sessionID string) ([]string, error) {
+	ids := []string{}
+	start := "-"
+	for {
+		entries, err := rs.redisClient.XRangeN(ctx, rs.Stream, start, "+", 1000).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Values["sessionId"] == sessionID && entry.Values["tenantId"] == tenantID {
+				ids = append(ids, entry.ID)
+			}
+		}
+		if len(entries) < 1000 {
+			return ids, nil
+		}
+		start = "(" + entries[len(entries)-1].ID
+	}
+}
+
+// PurgeAudit deletes the stream entries of a session.
+func (rs *RedisAuditSink) PurgeAudit(ctx context.Context, tenantID, sessionID string) (int, error) {
+	if rs.redisClient == nil {
+		return 0, errors.New("redis audit sink is not initialized")
+	}
+	ids, err := rs.sessionEntries(ctx, tenantID, sessionID)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	deleted, err := rs.redisClient.XDel(ctx, rs.Stream, ids...).Result()
+	return int(deleted), err
+}
+
+// PurgeAudit rewrites the audit file without the records of a session.
+func (fs *FileAuditSink) PurgeAudit(ctx context.Context, tenantID, sessionID string) (int, error) {
+	return fs.rewrite(func(record AuditRecord) bool {
+		return record.SessionID == sessionID && record.TenantID == tenantID
+	})
+}
+
+// rewrite removes the records matching drop from the audit file.
+func (fs *FileAuditSink) rewrite(drop func(record AuditRecord) bool) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	content, err := os.ReadFile(fs.file.Name())
+	if err != nil {
+		return 0, err
+	}
+	var kept bytes.Buffer
+	dropped := 0
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		record := AuditRecord{}
+		if json.Unmarshal(scanner.Bytes(), &record) == nil && drop(record) {
+			dropped++
+			continue
+		}
+		kept.Write(scanner.Bytes())
+		kept.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil || dropped == 0 {
+		return 0, err
+	}
+	// The kept records are written to a temporary file renamed over the audit file, so a failure can't lose them
+	path := fs.file.Name()
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	renamed := false
+	defer func() {
+		if !renamed {
+			os.Remove(temp.Name())
+		}
+	}()
+	if _, err := temp.Write(kept.Bytes()); err != nil {
+		temp.Close()
+		return 0, err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return 0, err
+	}
+	if err := temp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return 0, err
+	}
+	renamed = true
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return dropped, err
+	}
+	fs.file.Close()
+	fs.file = file
+	return dropped, nil
+}
+
+// RetentionPolicy limits how long the container keeps personal data, enforced by EnforceRetention.
+//
+// Fields:
+//   - Interval: How often Init's background worker enforces the policy, default 1 hour.
+//   - MemoryMaxAge: Session memories and their vectors expire at most this long after their last update.
+//   - UsageMaxAge: Daily usage accounting older than this is deleted.
+//   - AuditMaxAge: Audit records older than this are deleted from a RedisAuditSink or a FileAuditSink.
+//   - TraceMaxAge: Traces older than this are deleted from a TraceStore implementing TracePurger.
+//
+// A zero max age keeps the data.
+type RetentionPolicy struct {
+	Interval     time.Duration `json:"interval"`
+	MemoryMaxAge time.Duration `json:"memoryMaxAge"`
+	UsageMaxAge  time.Duration `json:"usageMaxAge"`
+	AuditMaxAge  time.Duration `json:"auditMaxAge"`
+	TraceMaxAge  time.Duration `json:"traceMaxAge"`
+}
+
+// enabled reports whether a max age is set.
+func (rp RetentionPolicy) enabled() bool {
+	return rp.MemoryMaxAge > 0 || rp.UsageMaxAge > 0 || rp.AuditMaxAge > 0 || rp.TraceMaxAge > 0
+}
+
+// EnforceRetention applies the Retention policy once, Init runs it periodically when a max age is set.
+//
+// Returns:
+//   - error: An error if Redis or the audit sink fails.
+func (llm *LLMContainer) EnforceRetention(ctx context.Context) error {
+	policy := llm.Retention
+	if purger, ok := llm.TraceStore.(TracePurger); ok && policy.TraceMaxAge > 0 {
+		if _, err := purger.ExpireTraces(ctx, time.Now().Add(-policy.TraceMaxAge)); err != nil {
+			return err
+		}
+	}
+	rdb := llm.RedisClient.redisClient
+	if rdb == nil || !policy.enabled() {
+		return nil
+	}
+	if policy.MemoryMaxAge > 0 {
+		memoryPrefix := llm.PersistentMemoryManager.MemoryPrefix
+		if memoryPrefix == "" {
+			memoryPrefix = "Memory"
+		}
+		for _, pattern := range []string{"rawMemory:" + escapeGlob(memoryPrefix) + ":*", "doc:Memory:" + escapeGlob(memoryPrefix) + ":*"} {
+			keys, err := scanKeys(ctx, rdb, pattern)
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				// Keys without expiration, or expiring later, are capped
+				ttl, err := rdb.TTL(ctx, key).Result()
+				if err != nil {
+					return err
+				}
+				if ttl == -1 || ttl > policy.MemoryMaxAge {
+					rdb.Expire(ctx, key, policy.MemoryMaxAge)
+				}
+			}
+		}
+	}
+	if policy.UsageMaxAge > 0 {
+		cutoff := truncateDay(time.Now().Add(-policy.UsageMaxAge))
+		keys, err := scanKeys(ctx, rdb, "usage:*")
+		if err != nil {
+			return err
+		}
+		expired := []string{}
+		for _, key := range keys {
+			day, err := time.Parse(usageDayFormat, key[strings.LastIndex(key, ":")+1:])
+			if err == nil && day.Before(cutoff) {
+				expired = append(expired, key)
+			}
+		}
+		if len(expired) > 0 {
+			if err := rdb.Del(ctx, expired...).Err(); err != nil {
+				return err
+			}
+		}
+	}
+	if policy.AuditMaxAge > 0 {
+		cutoff := time.Now().Add(-policy.AuditMaxAge)
+		switch sink := llm.AuditSink.(type) {
+		case *RedisAuditSink:
+			if sink.redisClient != nil {
+				if err := sink.redisClient.XTrimMinID(ctx, sink.Stream, strconv.FormatInt(cutoff.UnixMilli(), 10)).Err(); err != nil {
+					return err
+				}
+			}
+		case *FileAuditSink:
+			if _, err := sink.rewrite(func(record AuditRecord) bool {
+				return record.TimeStamp.Before(cutoff)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// retentionWorker runs EnforceRetention periodically until it is stopped.
+type retentionWorker struct {
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// startRetentionWorker starts enforcing the Retention policy in the background, Shutdown stops it.
+func (llm *LLMContainer) startRetentionWorker() {
+	if !llm.Retention.enabled() {
+		return
+	}
+	interval := llm.Retention.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	llm.retention.close()
+	worker := &retentionWorker{stop: make(chan struct{})}
+	llm.retention = worker
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := llm.EnforceRetention(context.Background()); err != nil {
+				llm.logger().Warn("retention policy failed", "error", err)
+			}
+			select {
+			case <-worker.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// close stops the worker.
+func (rw *retentionWorker) close() {
+	if rw != nil {
+		rw.stopOnce.Do(func() {
+			close(rw.stop)
+		})
+	}
+}
@@ -0,0 +1,417 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RetrievalSettings overrides the retrieval settings of the container for the chunks of a retrieval prefix.
+//
+// Zero fields keep the container settings: ScoreThreshold, RagRowCount and the weights of DefaultHybridSearchConfig.
+//
+// Fields:
+//   - ScoreThreshold: The minimum score of vector search results.
+//   - RagRowCount: The number of chunks retrieved.
+//   - VectorWeight: The weight of the vector search in hybrid searches.
+//   - LexicalWeight: The weight of the lexical search in hybrid searches.
+type RetrievalSettings struct {
+	ScoreThreshold float32 `json:"scoreThreshold"`
+	RagRowCount    int     `json:"ragRowCount"`
+	VectorWeight   float64 `json:"vectorWeight"`
+	LexicalWeight  float64 `json:"lexicalWeight"`
+}
+
+// prefixSettings keeps the RetrievalSettings of each retrieval prefix.
+type prefixSettings struct {
+	mu       sync.RWMutex
+	settings map[string]RetrievalSettings
+}
+
+// prefixSettings returns the retrieval settings of the prefixes, creating it on first use.
+func (llm *LLMContainer) prefixSettings() *prefixSettings {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.prefixRetrieval == nil {
+		llm.prefixRetrieval = &prefixSettings{settings: map[string]RetrievalSettings{}}
+	}
+	return llm.prefixRetrieval
+}
+
+// SetRetrievalSettings overrides the retrieval settings of a retrieval prefix, e.g. with the suggestion of TuneRetrieval.
+//
+// Parameters:
+//   - prefix: The retrieval prefix, as recorded in Trace.RetrievalPrefix or LLMDebugInfo.RetrievalPrefix, e.g. "context:docs:en:".
+//   - settings: The settings, zero fields keep the container settings.
+func (llm *LLMContainer) SetRetrievalSettings(prefix string, settings RetrievalSettings) {
+	registry := llm.prefixSettings()
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.settings[prefix] = settings
+}
+
+// RetrievalSettings returns the effective retrieval settings of a retrieval prefix.
+//
+// Parameters:
+//   - prefix: The retrieval prefix.
+//
+// Returns:
+//   - RetrievalSettings: The overrides of the prefix completed with the container settings.
+func (llm *LLMContainer) RetrievalSettings(prefix string) RetrievalSettings {
+	registry := llm.prefixSettings()
+	registry.mu.RLock()
+	settings := registry.settings[prefix]
+	registry.mu.RUnlock()
+
+	if settings.ScoreThreshold == 0 {
+		settings.ScoreThreshold = llm.ScoreThreshold
+	}
+	if settings.RagRowCount == 0 {
+		settings.RagRowCount = llm.RagRowCount
+	}
+	if settings.VectorWeight == 0 && settings.LexicalWeight == 0 {
+		defaults := DefaultHybridSearchConfig()
+		settings.VectorWeight, settings.LexicalWeight = defaults.VectorWeight, defaults.LexicalWeight
+	}
+	return settings
+}
+
+// TuningBounds limits the settings suggested by TuneRetrieval.
+//
+// Zero fields use the defaults given below.
+//
+// Fields:
+//   - MinScoreThreshold, MaxScoreThreshold: Range of the score threshold (default 0 to 1).
+//   - MinRagRowCount, MaxRagRowCount: Range of the retrieved chunk count (default 1 to 20).
+//   - MinVectorWeight, MaxVectorWeight: Range of the hybrid vector weight (default 0.1 to 0.9).
+//   - MinFeedback: The rated answers needed before settings are suggested (default 20).
+//   - Since: Only uses the feedback submitted after this time, the zero time uses all feedback.
+//   - Apply: Applies the suggested settings with SetRetrievalSettings.
+type TuningBounds struct {
+	MinScoreThreshold float32
+	MaxScoreThreshold float32
+	MinRagRowCount    int
+	MaxRagRowCount    int
+	MinVectorWeight   float64
+	MaxVectorWeight   float64
+	MinFeedback       int
+	Since             time.Time
+	Apply             bool
+}
+
+func (tb *TuningBounds) setDefaults() {
+	if tb.MaxScoreThreshold == 0 {
+		tb.MaxScoreThreshold = 1
+	}
+	if tb.MinRagRowCount == 0 {
+		tb.MinRagRowCount = 1
+	}
+	if tb.MaxRagRowCount == 0 {
+		tb.MaxRagRowCount = 20
+	}
+	if tb.MinVectorWeight == 0 {
+		tb.MinVectorWeight = 0.1
+	}
+	if tb.MaxVectorWeight == 0 {
+		tb.MaxVectorWeight = 0.9
+	}
+	if tb.MinFeedback == 0 {
+		tb.MinFeedback = 20
+	}
+}
+
+// RetrievalTuning is the result of TuneRetrieval.
+//
+// Fields:
+//   - Prefix: The tuned retrieval prefix.
+//   - RatedAnswers: The number of rated answers of the prefix.
+//   - Current: The current settings of the prefix.
+//   - Suggested: The suggested settings, equal to Current when the feedback isn't conclusive.
+//   - Applied: True if the suggested settings were applied.
+//   - Reasons: Explains each suggested change, or why a setting was kept.
+type RetrievalTuning struct {
+	Prefix       string            `json:"prefix"`
+	RatedAnswers int               `json:"ratedAnswers"`
+	Current      RetrievalSettings `json:"current"`
+	Suggested    RetrievalSettings `json:"suggested"`
+	Applied      bool              `json:"applied"`
+	Reasons      []string          `json:"reasons"`
+}
+
+// ratedTrace is a trace with the mean rating of its feedback.
+type ratedTrace struct {
+	trace  Trace
+	rating float64
+}
+
+// TuneRetrieval suggests better retrieval settings for a retrieval prefix from the feedback on its answers,
+// and applies them within bounds when bounds.Apply is set.
+//
+// The suggestions are heuristics that need a TraceStore with feedback:
+//   - ScoreThreshold: The threshold that keeps the best chunks of well rated answers and drops the chunks behind badly
+//     rated ones, for similarity and KNN searches.
+//   - RagRowCount: The deepest rank of the cited chunks of well rated answers plus one, which needs WithRagReferences,
+//     or more chunks when badly rated answers failed to respond with every chunk used.
+//   - VectorWeight and LexicalWeight: Moved towards the search that found the chunks of well rated hybrid answers.
+//
+// Parameters:
+//   - prefix: The retrieval prefix, as recorded in Trace.RetrievalPrefix, e.g. "context:docs:en:".
+//   - bounds: The limits of the suggested settings and whether to apply them.
+//
+// Returns:
+//   - RetrievalTuning: The current and suggested settings with the reasons of the changes.
+//   - error: An error if the traces can't be read or no TraceStore is configured.
+//
+// Example Usage:
+//
+//	tuning, err := llm.TuneRetrieval("context:docs:en:", aillm.TuningBounds{MinScoreThreshold: 0.5, Apply: true})
+//	for _, reason := range tuning.Reasons {
+//		fmt.Println(reason)
+//	}
+func (llm *LLMContainer) TuneRetrieval(prefix string, bounds TuningBounds) (RetrievalTuning, error) {
+	bounds.setDefaults()
+	tuning := RetrievalTuning{
+		Prefix:  prefix,
+		Current: llm.RetrievalSettings(prefix),
+	}
+	tuning.Suggested = tuning.Current
+	rated, err := llm.ratedTraces(prefix, bounds.Since)
+	if err != nil {
+		return tuning, err
+	}
+	tuning.RatedAnswers = len(rated)
+	if len(rated) < bounds.MinFeedback {
+		tuning.Reasons = append(tuning.Reasons, fmt.Sprintf("only %d rated answers, %d are needed", len(rated), bounds.MinFeedback))
+		return tuning, nil
+	}
+
+	tuning.tuneScoreThreshold(rated, bounds)
+	tuning.tuneRagRowCount(rated, bounds)
+	tuning.tuneHybridWeights(rated, bounds)
+
+	if bounds.Apply && tuning.Suggested != tuning.Current {
+		llm.SetRetrievalSettings(prefix, tuning.Suggested)
+		tuning.Applied = true
+		llm.logger().Info("retrieval settings tuned", "prefix", prefix, "settings", tuning.Suggested)
+	}
+	return tuning, nil
+}
+
+// ratedTraces loads the rated traces of a retrieval prefix.
+func (llm *LLMContainer) ratedTraces(prefix string, since time.Time) ([]ratedTrace, error) {
+	feedback, err := llm.ListFeedback(since)
+	if err != nil {
+		return nil, err
+	}
+	sums, counts, order := map[string]float64{}, map[string]int{}, []string{}
+	for _, entry := range feedback {
+		if counts[entry.TraceID] == 0 {
+			order = append(order, entry.TraceID)
+		}
+		sums[entry.TraceID] += float64(entry.Rating)
+		counts[entry.TraceID]++
+	}
+	rated := []ratedTrace{}
+	for _, id := range order {
+		ctx, cancel := context.WithTimeout(context.Background(), llm.Timeouts.redisTimeout())
+		trace, err := llm.TraceStore.GetTrace(ctx, id)
+		cancel()
+		if err == ErrTraceNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if trace.RetrievalPrefix != prefix || trace.Error != "" {
+			continue
+		}
+		rating := sums[id] / float64(counts[id])
+		if rating != 0 {
+			rated = append(rated, ratedTrace{trace: trace, rating: rating})
+		}
+	}
+	return rated, nil
+}
+
+// tuneScoreThreshold picks the threshold keeping the most well rated answers and dropping the most badly rated ones.
+func (rt *RetrievalTuning) tuneScoreThreshold(rated []ratedTrace, bounds TuningBounds) {
+	type answer struct {
+		topScore float32
+		positive bool
+	}
+	answers := []answer{}
+	candidates := []float32{rt.Current.ScoreThreshold}
+	positives, negatives := 0, 0
+	for _, rated := range rated {
+		if rated.trace.SearchAlgorithm != "similarity" && rated.trace.SearchAlgorithm != "knn" {
+			continue
+		}
+		if len(rated.trace.Chunks) == 0 || rated.trace.FailedToRespond {
+			continue
+		}
+		// Vector searches report the cosine distance, the threshold applies to the similarity
+		top := float32(0)
+		for _, chunk := range rated.trace.Chunks {
+			top = float32(math.Max(float64(top), float64(1-chunk.Score)))
+		}
+		answers = append(answers, answer{topScore: top, positive: rated.rating > 0})
+		candidates = append(candidates, top)
+		if rated.rating > 0 {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	if positives == 0 || negatives == 0 {
+		rt.Reasons = append(rt.Reasons, "score threshold kept: needs well and badly rated answers of similarity or KNN searches")
+		return
+	}
+	// Each answer counts once: well rated answers should keep their best chunk, badly rated ones lose it
+	correct := func(threshold float32) int {
+		count := 0
+		for _, answer := range answers {
+			if (answer.topScore >= threshold) == answer.positive {
+				count++
+			}
+		}
+		return count
+	}
+	best, bestCorrect := rt.Current.ScoreThreshold, correct(rt.Current.ScoreThreshold)
+	for _, candidate := range candidates {
+		candidate = float32(math.Min(math.Max(float64(candidate), float64(bounds.MinScoreThreshold)), float64(bounds.MaxScoreThreshold)))
+		if c := correct(candidate); c > bestCorrect {
+			best, bestCorrect = candidate, c
+		}
+	}
+	if best == rt.Current.ScoreThreshold {
+		rt.Reasons = append(rt.Reasons, fmt.Sprintf("score threshold kept: %.3f separates %d of %d rated answers", best, bestCorrect, len(answers)))
+		return
+	}
+	rt.Suggested.ScoreThreshold = best
+	rt.Reasons = append(rt.Reasons, fmt.Sprintf("score threshold %.3f -> %.3f: separates %d of %d rated answers instead of %d",
+		rt.Current.ScoreThreshold, best, bestCorrect, len(answers), correct(rt.Current.ScoreThreshold)))
+}
+
+// tuneRagRowCount sizes the retrieval after the cited chunks of well rated answers.
+func (rt *RetrievalTuning) tuneRagRowCount(rated []ratedTrace, bounds TuningBounds) {
+	deepest := []int{}
+	exhausted, negatives := 0, 0
+	for _, rated := range rated {
+		if rated.rating < 0 {
+			negatives++
+			if rated.trace.FailedToRespond && len(rated.trace.Chunks) >= rt.Current.RagRowCount {
+				exhausted++
+			}
+			continue
+		}
+		rank := 0
+		for i, chunk := range rated.trace.Chunks {
+			if chunk.Cited {
+				rank = i + 1
+			}
+		}
+		if rank > 0 {
+			deepest = append(deepest, rank)
+		}
+	}
+	suggested := rt.Current.RagRowCount
+	reason := ""
+	switch {
+	case len(deepest) >= 5:
+		sort.Ints(deepest)
+		p90 := deepest[int(math.Ceil(0.9*float64(len(deepest))))-1]
+		suggested = p90 + 1
+		reason = fmt.Sprintf("well rated answers cite chunks up to rank %d (90th percentile)", p90)
+	case negatives > 0 && exhausted*2 > negatives:
+		suggested = rt.Current.RagRowCount + 2
+		reason = fmt.Sprintf("%d of %d badly rated answers failed to respond with all %d chunks", exhausted, negatives, rt.Current.RagRowCount)
+	default:
+		rt.Reasons = append(rt.Reasons, "chunk count kept: needs cited chunks of well rated answers (WithRagReferences)")
+		return
+	}
+	suggested = max(bounds.MinRagRowCount, min(bounds.MaxRagRowCount, suggested))
+	if suggested == rt.Current.RagRowCount {
+		rt.Reasons = append(rt.Reasons, fmt.Sprintf("chunk count kept: %s", reason))
+		return
+	}
+	rt.Suggested.RagRowCount = suggested
+	rt.Reasons = append(rt.Reasons, fmt.Sprintf("chunk count %d -> %d: %s", rt.Current.RagRowCount, suggested, reason))
+}
+
+// tuneHybridWeights moves the weights towards the search that found the chunks of well rated hybrid answers.
+func (rt *RetrievalTuning) tuneHybridWeights(rated []ratedTrace, bounds TuningBounds) {
+	// vectorShare is the share of the chunks of an answer found by the vector search, chunks found by both count half
+	vectorShare := func(trace Trace) (float64, bool) {
+		share, count := 0.0, 0
+		for _, chunk := range trace.Chunks {
+			switch chunk.SearchType {
+			case "vector":
+				share++
+			case "hybrid":
+				share += 0.5
+			case "lexical":
+			default:
+				continue
+			}
+			count++
+		}
+		if count == 0 {
+			return 0, false
+		}
+		return share / float64(count), true
+	}
+	positive, negative := []float64{}, []float64{}
+	for _, rated := range rated {
+		if rated.trace.SearchAlgorithm != "hybrid" {
+			continue
+		}
+		share, ok := vectorShare(rated.trace)
+		if !ok {
+			continue
+		}
+		if rated.rating > 0 {
+			positive = append(positive, share)
+		} else {
+			negative = append(negative, share)
+		}
+	}
+	if len(positive) == 0 || len(negative) == 0 {
+		rt.Reasons = append(rt.Reasons, "hybrid weights kept: needs well and badly rated answers of hybrid searches")
+		return
+	}
+	mean := func(values []float64) float64 {
+		sum := 0.0
+		for _, value := range values {
+			sum += value
+		}
+		return sum / float64(len(values))
+	}
+	current := rt.Current.VectorWeight / (rt.Current.VectorWeight + rt.Current.LexicalWeight)
+	// Half a step towards the search of the well rated answers keeps the tuning stable between runs
+	suggested := current + 0.5*(mean(positive)-mean(negative))
+	suggested = math.Max(bounds.MinVectorWeight, math.Min(bounds.MaxVectorWeight, suggested))
+	suggested = math.Round(suggested*100) / 100
+	if math.Abs(suggested-current) < 0.01 {
+		rt.Reasons = append(rt.Reasons, fmt.Sprintf("hybrid weights kept: vector weight %.2f", current))
+		return
+	}
+	rt.Suggested.VectorWeight, rt.Suggested.LexicalWeight = suggested, math.Round((1-suggested)*100)/100
+	rt.Reasons = append(rt.Reasons, fmt.Sprintf("vector weight %.2f -> %.2f: vector search found %.0f%% of the chunks of well rated answers and %.0f%% of badly rated ones",
+		current, suggested, 100*mean(positive), 100*mean(negative)))
+}
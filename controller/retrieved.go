@@ -0,0 +1,63 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"github.com/tmc/langchaingo/schema"
+)
+
+// RetrievedDocument is a chunk retrieved for a question, see LLMResult.RagDocs.
+//
+// Fields:
+//   - Content: The text of the chunk.
+//   - Score: The similarity score of the chunk, its meaning depends on the search algorithm.
+//   - Source: The sources of the embedded document, as given to EmbeddText.
+//   - ChunkID: The Redis key of the chunk.
+//   - DocumentID: The ID of the embedded document the chunk belongs to, empty for chunks without document metadata.
+//   - Metadata: The fields stored with the chunk, e.g. "search_type" or "hybrid_score".
+type RetrievedDocument struct {
+	Content    string         `json:"content"`
+	Score      float32        `json:"score"`
+	Source     string         `json:"source,omitempty"`
+	ChunkID    string         `json:"chunkId,omitempty"`
+	DocumentID string         `json:"documentId,omitempty"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+}
+
+// Document returns the chunk as a langchaingo document, e.g. for custom retrievers or rerankers.
+func (rd RetrievedDocument) Document() schema.Document {
+	return schema.Document{PageContent: rd.Content, Score: rd.Score, Metadata: rd.Metadata}
+}
+
+// newRetrievedDocument describes a chunk returned by the vector store.
+func newRetrievedDocument(doc schema.Document) RetrievedDocument {
+	retrieved := RetrievedDocument{
+		Content:    doc.PageContent,
+		Score:      doc.Score,
+		DocumentID: documentReferenceID(doc),
+		Metadata:   doc.Metadata,
+	}
+	retrieved.Source, _ = doc.Metadata["sources"].(string)
+	retrieved.ChunkID, _ = doc.Metadata["id"].(string)
+	return retrieved
+}
+
+// retrievedDocuments describes the chunks returned by the vector store.
+func retrievedDocuments(docs []schema.Document) []RetrievedDocument {
+	retrieved := make([]RetrievedDocument, 0, len(docs))
+	for _, doc := range docs {
+		retrieved = append(retrieved, newRetrievedDocument(doc))
+	}
+	return retrieved
+}
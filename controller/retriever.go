@@ -0,0 +1,83 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// RetrievalRequest is a search of the documents of a prefix, see Retriever.
+//
+// Fields:
+//   - Algorithm: The search algorithm of the call, e.g. HybridSearch.
+//   - Prefix: The searched prefix, e.g. "context:faq:en:".
+//   - Query: The retrieval text, the query with the session memory and attachments.
+//   - RowCount: The maximum number of documents.
+//   - ScoreThreshold: The minimum score of the documents.
+type RetrievalRequest struct {
+	Algorithm      SearchAlgorithm
+	Prefix         string
+	Query          string
+	RowCount       int
+	ScoreThreshold float32
+}
+
+// Retriever searches the documents of a prefix in place of the Redis vector store.
+//
+// Set LLMContainer.Retriever to use another vector database, or canned documents in tests, see the aillmtest package.
+// A container with a Retriever and without Redis host skips the Redis connection in Init.
+type Retriever interface {
+	Retrieve(ctx context.Context, request RetrievalRequest) ([]RetrievedDocument, error)
+}
+
+// RetrieverFunc adapts a function to the Retriever interface.
+type RetrieverFunc func(ctx context.Context, request RetrievalRequest) ([]RetrievedDocument, error)
+
+// Retrieve calls f.
+func (f RetrieverFunc) Retrieve(ctx context.Context, request RetrievalRequest) ([]RetrievedDocument, error) {
+	return f(ctx, request)
+}
+
+// retrieveWithRetriever searches a prefix with the Retriever of the container.
+func (llm *LLMContainer) retrieveWithRetriever(ctx context.Context, searchAlgorithm SearchAlgorithm, prefix, query string, settings RetrievalSettings) ([]schema.Document, error) {
+	ctx, cancel := llm.searchContext(ctx)
+	defer cancel()
+	retrieved, err := llm.Retriever.Retrieve(ctx, RetrievalRequest{
+		Algorithm:      searchAlgorithm,
+		Prefix:         prefix,
+		Query:          query,
+		RowCount:       settings.RagRowCount,
+		ScoreThreshold: settings.ScoreThreshold,
+	})
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]schema.Document, 0, len(retrieved))
+	for _, doc := range retrieved {
+		document := doc.Document()
+		if document.Metadata == nil {
+			document.Metadata = map[string]any{}
+		}
+		if doc.ChunkID != "" {
+			document.Metadata["id"] = doc.ChunkID
+		}
+		if doc.Source != "" {
+			document.Metadata["sources"] = doc.Source
+		}
+		docs = append(docs, document)
+	}
+	return docs, nil
+}
@@ -0,0 +1,291 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// RetryPolicy configures how failed LLM, embedding and vision calls are retried.
+//
+// Only transient failures are retried: HTTP 429 and 5xx responses, timeouts and dropped connections.
+// A streamed generation is never retried once the first chunk was delivered, to avoid duplicated output.
+// With Deterministic, every attempt of a generation sends the same sampling seed, so a retried request
+// asks for the same answer instead of a new one on providers supporting seeds. The seed is derived from the
+// messages, the same prompt is sent with the same seed every time, which keeps recorded cassettes replayable.
+//
+// Fields:
+//   - MaxAttempts: Maximum number of attempts including the first one, 1 disables retries (default 3).
+//   - InitialBackoff: Delay before the first retry (default 500ms).
+//   - MaxBackoff: Upper bound of the delay between attempts (default 10s).
+//   - Multiplier: Factor applied to the delay after every attempt (default 2).
+//   - Deterministic: Sends a fixed seed with generations without one, see WithSeed.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Deterministic  bool
+}
+
+// backoff returns the delay before the given retry (1 based), with ±20% jitter.
+func (rp RetryPolicy) backoff(retry int) time.Duration {
+	delay := rp.InitialBackoff
+	if delay <= 0 {
+		delay = 500 * time.Millisecond
+	}
+	maxDelay := rp.MaxBackoff
+	if maxDelay <= 0 {
+		maxDelay = 10 * time.Second
+	}
+	multiplier := rp.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	for i := 1; i < retry && delay < maxDelay; i++ {
+		delay = time.Duration(float64(delay) * multiplier)
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	jitter := 0.8 + rand.Float64()*0.4
+	return time.Duration(float64(delay) * jitter)
+}
+
+// statusCodePattern finds HTTP status codes in provider error messages.
+var statusCodePattern = regexp.MustCompile(`(?i)status(?: code)?:? ?(\d{3})`)
+
+// isRetryableError reports whether err is a transient provider failure.
+func isRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	if match := statusCodePattern.FindStringSubmatch(message); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return code == 429 || code >= 500
+	}
+	for _, transient := range []string{"too many requests", "rate limit", "timeout", "connection reset", "connection refused", "unexpected eof", "server overloaded", "temporarily unavailable"} {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry runs fn according to the retry policy.
+//
+// Parameters:
+//   - ctx: The request context, retries stop when it is done.
+//   - breaker: Optional circuit breaker of the endpoint, attempts fail with ErrCircuitOpen while it is open.
+//   - fn: The operation to run.
+//   - onRetry: Optional callback called before every retry with the retry number and the last error.
+//
+// Returns:
+//   - error: The error of the last attempt.
+func (llm *LLMContainer) withRetry(ctx context.Context, breaker *circuitBreaker, fn func() error, onRetry func(retry int, err error)) error {
+	attempts := llm.RetryPolicy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = breaker.call(fn)
+		if err == nil || attempt >= attempts || !isRetryableError(err) {
+			return err
+		}
+		if onRetry != nil {
+			onRetry(attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(llm.RetryPolicy.backoff(attempt)):
+		}
+	}
+}
+
+// retryingModel applies the container retry policy and circuit breaker to an llms.Model.
+type retryingModel struct {
+	llms.Model
+	llm      *LLMContainer
+	breaker  *circuitBreaker
+	failover *retryingModel
+	onRetry  func(retry int, err error)
+}
+
+// newLLMClient returns the cached model of the client wrapped with the retry policy and circuit breaker.
+//
+// When client is the LLMClient and a FailoverLLMClient is configured, requests that fail with a
+// transient error or an open circuit are sent to the failover provider.
+//
+// Parameters:
+//   - client: The LLM client (LLMClient or VisionClient).
+//   - onRetry: Optional callback called before every retry, e.g. to log an action.
+//
+// Returns:
+//   - llms.Model: The model.
+//   - error: An error if the client cannot be created.
+func (llm *LLMContainer) newLLMClient(client LLMClient, onRetry func(retry int, err error)) (llms.Model, error) {
+	if client == nil {
+		return nil, errors.New("missing llm client")
+	}
+	model, err := llm.getLLMModel(client)
+	if err != nil {
+		return nil, err
+	}
+	retrying := &retryingModel{Model: model, llm: llm, breaker: llm.getCircuitBreaker(client.GetConfig()), onRetry: onRetry}
+	if client == llm.LLMClient && llm.FailoverLLMClient != nil {
+		failoverModel, err := llm.getLLMModel(llm.FailoverLLMClient)
+		if err != nil {
+			llm.logger().Warn("failover llm client unavailable", "error", err)
+		} else {
+			retrying.failover = &retryingModel{Model: failoverModel, llm: llm, breaker: llm.getCircuitBreaker(llm.FailoverLLMClient.GetConfig()), onRetry: onRetry}
+		}
+	}
+	return retrying, nil
+}
+
+// deterministicSeed returns the sampling seed of a generation with RetryPolicy.Deterministic, a non-zero hash
+// of the messages.
+func deterministicSeed(messages []llms.MessageContent) int {
+	hash := fnv.New32a()
+	encoded, err := json.Marshal(messages)
+	if err != nil {
+		// Messages which can't be encoded are hashed as text
+		encoded = []byte(fmt.Sprint(messages))
+	}
+	hash.Write(encoded)
+	return int(hash.Sum32()%math.MaxInt32) + 1
+}
+
+// GenerateContent calls the wrapped model and retries transient failures.
+func (m *retryingModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	callOptions := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&callOptions)
+	}
+	if m.llm.RetryPolicy.Deterministic && callOptions.Seed == 0 {
+		// Every attempt, including the failover, asks for the same answer
+		options = append(options[:len(options):len(options)], llms.WithSeed(deterministicSeed(messages)))
+	}
+	streamed := false
+	if callOptions.StreamingFunc != nil {
+		streamingFunc := callOptions.StreamingFunc
+		options = append(options[:len(options):len(options)], llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			streamed = true
+			return streamingFunc(ctx, chunk)
+		}))
+	}
+	var response *llms.ContentResponse
+	var err error
+	retryErr := m.llm.withRetry(ctx, m.breaker, func() error {
+		attemptCtx, cancel := context.WithTimeout(ctx, m.llm.Timeouts.llmTimeout())
+		defer cancel()
+		response, err = m.Model.GenerateContent(attemptCtx, messages, options...)
+		if streamed {
+			// Part of the answer was already delivered, retrying would duplicate it
+			return nil
+		}
+		return err
+	}, m.onRetry)
+	if errors.Is(retryErr, ErrCircuitOpen) {
+		err = retryErr
+	}
+	if err != nil && !streamed && m.failover != nil && (errors.Is(err, ErrCircuitOpen) || isRetryableError(err)) {
+		m.llm.logger().Warn("sending request to failover llm client", "error", err)
+		return m.failover.GenerateContent(ctx, messages, options...)
+	}
+	return response, err
+}
+
+// Call calls the wrapped model and retries transient failures.
+func (m *retryingModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// retryingEmbedder applies the container retry policy and circuit breaker to an embeddings.Embedder.
+type retryingEmbedder struct {
+	embedder embeddings.Embedder
+	llm      *LLMContainer
+	breaker  *circuitBreaker
+}
+
+// newEmbedder creates the embedder of the container wrapped with the retry policy and circuit breaker.
+func (llm *LLMContainer) newEmbedder() (embeddings.Embedder, error) {
+	if llm.Embedder == nil {
+		return nil, ErrMissingEmbedder
+	}
+	embedder, err := llm.Embedder.NewEmbedder()
+	if err != nil {
+		return nil, err
+	}
+	retrying := &retryingEmbedder{embedder: embedder, llm: llm}
+	if configured, ok := llm.Embedder.(interface{ GetConfig() LLMConfig }); ok {
+		retrying.embedder = llm.limitEmbedder(configured.GetConfig, embedder)
+		retrying.breaker = llm.getCircuitBreaker(configured.GetConfig())
+	}
+	return retrying, nil
+}
+
+// EmbedDocuments embeds the texts and retries transient failures.
+func (e *retryingEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	err := e.llm.withRetry(ctx, e.breaker, func() error {
+		attemptCtx, cancel := context.WithTimeout(ctx, e.llm.Timeouts.embeddingTimeout())
+		defer cancel()
+		var err error
+		vectors, err = e.embedder.EmbedDocuments(attemptCtx, texts)
+		return err
+	}, e.logRetry)
+	return vectors, err
+}
+
+// EmbedQuery embeds the query and retries transient failures.
+func (e *retryingEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	var vector []float32
+	err := e.llm.withRetry(ctx, e.breaker, func() error {
+		attemptCtx, cancel := context.WithTimeout(ctx, e.llm.Timeouts.embeddingTimeout())
+		defer cancel()
+		var err error
+		vector, err = e.embedder.EmbedQuery(attemptCtx, text)
+		return err
+	}, e.logRetry)
+	return vector, err
+}
+
+func (e *retryingEmbedder) logRetry(retry int, err error) {
+	e.llm.logger().Warn("retrying embedding request", "retry", retry, "error", err)
+}
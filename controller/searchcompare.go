@@ -0,0 +1,159 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// SearchRun is the result of one search algorithm in a SearchComparison.
+//
+// Fields:
+//   - Algorithm: The search algorithm, e.g. HybridSearch.
+//   - Name: The name of the algorithm, e.g. "hybrid".
+//   - Documents: The retrieved documents, in rank order.
+//   - Duration: The time taken by the search, including the query embedding.
+//   - Error: The error of the search, other runs are still compared.
+type SearchRun struct {
+	Algorithm SearchAlgorithm
+	Name      string
+	Documents []schema.Document
+	Duration  time.Duration
+	Error     error
+}
+
+// SearchOverlap compares the documents retrieved by two algorithms.
+//
+// Fields:
+//   - A, B: The names of the compared algorithms.
+//   - Shared: The number of documents retrieved by both algorithms.
+//   - Jaccard: Shared divided by the number of distinct documents of both algorithms, between 0 and 1.
+type SearchOverlap struct {
+	A       string
+	B       string
+	Shared  int
+	Jaccard float64
+}
+
+// SearchComparison holds the results of the same query searched with several algorithms.
+//
+// Fields:
+//   - Query: The compared query.
+//   - Prefix: The key prefix of the searched chunks, comma separated when several indexes are searched.
+//   - Runs: The result of each algorithm, in the requested order.
+//   - Overlaps: The overlap of every pair of successful runs.
+//   - Common: The ids of the documents retrieved by every successful run.
+type SearchComparison struct {
+	Query    string
+	Prefix   string
+	Runs     []SearchRun
+	Overlaps []SearchOverlap
+	Common   []string
+}
+
+// CompareSearch runs the same query with several search algorithms and compares the retrieved documents,
+// which helps to choose the search algorithm of a dataset.
+//
+// Only the retrieval is run, the LLM isn't called. Documents are identified by their chunk id.
+//
+// Parameters:
+//   - query: The query to search.
+//   - algorithms: The search algorithms to compare, nil compares SimilaritySearch, KNearestNeighbors, HybridSearch and LexicalSearch.
+//   - options: The call options selecting the searched documents, e.g. WithEmbeddingIndex, WithLanguage or WithTenant.
+//
+// Returns:
+//   - SearchComparison: The documents of every algorithm and their overlap.
+//   - error: An error if the embedding model can't be initialized or the tenant is invalid.
+//
+// Example Usage:
+//
+//	comparison, err := llm.CompareSearch("machine learning", nil, llm.WithEmbeddingIndex("docs"))
+//	for _, run := range comparison.Runs {
+//		fmt.Println(run.Name, len(run.Documents), run.Duration)
+//	}
+func (llm *LLMContainer) CompareSearch(query string, algorithms []SearchAlgorithm, options ...LLMCallOption) (SearchComparison, error) {
+	comparison := SearchComparison{Query: query}
+	o := LLMCallOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+	if err := llm.checkTenant(&o); err != nil {
+		return comparison, err
+	}
+	if err := llm.ensureEmbedder(); err != nil {
+		return comparison, err
+	}
+	if algorithms == nil {
+		algorithms = []SearchAlgorithm{SimilaritySearch, KNearestNeighbors, HybridSearch, LexicalSearch}
+	}
+	prefixes, boosts := llm.retrievalPrefixes(&o)
+	comparison.Prefix = strings.Join(prefixes, ",")
+
+	ids := make([]map[string]bool, 0, len(algorithms))
+	names := make([]string, 0, len(algorithms))
+	var order []string
+	for _, algorithm := range algorithms {
+		run := SearchRun{Algorithm: algorithm, Name: algorithm.String()}
+		start := time.Now()
+		run.Documents, run.Error = llm.retrieveFromPrefixes(context.Background(), algorithm, prefixes, boosts, llm.callIndexBoosts(&o), query, o.rowCount(), o.indexCap)
+		run.Duration = time.Since(start)
+		comparison.Runs = append(comparison.Runs, run)
+		if run.Error != nil {
+			continue
+		}
+		found := make(map[string]bool, len(run.Documents))
+		for _, doc := range run.Documents {
+			id := llm.getDocumentID(doc)
+			if !found[id] && len(ids) == 0 {
+				order = append(order, id)
+			}
+			found[id] = true
+		}
+		ids = append(ids, found)
+		names = append(names, run.Name)
+	}
+
+	for i := range ids {
+		for j := i + 1; j < len(ids); j++ {
+			overlap := SearchOverlap{A: names[i], B: names[j]}
+			for id := range ids[i] {
+				if ids[j][id] {
+					overlap.Shared++
+				}
+			}
+			if union := len(ids[i]) + len(ids[j]) - overlap.Shared; union > 0 {
+				overlap.Jaccard = float64(overlap.Shared) / float64(union)
+			}
+			comparison.Overlaps = append(comparison.Overlaps, overlap)
+		}
+	}
+	// Common keeps the rank order of the first successful run
+	for _, id := range order {
+		common := true
+		for _, found := range ids[1:] {
+			if !found[id] {
+				common = false
+				break
+			}
+		}
+		if common {
+			comparison.Common = append(comparison.Common, id)
+		}
+	}
+	return comparison, nil
+}
@@ -2,13 +2,62 @@ package aillm
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"github.com/tmc/langchaingo/llms"
 )
 
+// ErrQueryNotSecure matches the *SecurityCheckError of a query blocked by the security check, with errors.Is.
+var ErrQueryNotSecure = errors.New("query is not secure")
+
+// SecurityCheckError is returned by AskLLM when the content-safety classifier blocks a query.
+//
+// Fields:
+//   - Reason: The explanation of the classifier, only set with WithDebug(true).
+type SecurityCheckError struct {
+	Reason string
+}
+
+func (e *SecurityCheckError) Error() string {
+	return ErrQueryNotSecure.Error()
+}
+
+// Is reports whether target is ErrQueryNotSecure.
+func (e *SecurityCheckError) Is(target error) bool {
+	return target == ErrQueryNotSecure
+}
+
+// securityCheckEnabled reports whether the classifier runs for a call: WithIgnoreSecurityCheck skips it,
+// WithSecurityCheck overrides the DisableSecurityCheck default and exact prompts are never checked.
+func (llm *LLMContainer) securityCheckEnabled(o *LLMCallOptions) bool {
+	if o.ignoreSecurityCheck || o.ExactPrompt != "" {
+		return false
+	}
+	if o.securityCheck != nil {
+		return *o.securityCheck
+	}
+	return !llm.DisableSecurityCheck
+}
+
+// IsQuerySafe classifies a query with the content-safety and prompt-injection prompt.
+//
+// Parameters:
+//   - Query: The user query.
+//   - debug: Asks the classifier to explain violations.
+//
+// Returns:
+//   - bool: True if the query doesn't violate the policies.
+//   - TokenUsage: Tokens used by the classifier.
+//   - string: The explanation of a violation in debug mode.
+//   - error: An error if the classifier can't be called, the query is then reported as safe.
 func (llm *LLMContainer) IsQuerySafe(Query string, debug bool) (bool, TokenUsage, string, error) {
-	llmclient, err := llm.LLMClient.NewLLMClient()
+	return llm.isQuerySafe(context.Background(), Query, debug)
+}
+
+// isQuerySafe implements IsQuerySafe, the classification stops with ctx.
+func (llm *LLMContainer) isQuerySafe(ctx context.Context, Query string, debug bool) (bool, TokenUsage, string, error) {
+	llmclient, err := llm.newLLMClient(llm.LLMClient, nil)
 	warning := ""
 	tokenReport := TokenUsage{}
 	if err != nil {
@@ -18,7 +67,7 @@ func (llm *LLMContainer) IsQuerySafe(Query string, debug bool) (bool, TokenUsage
 	if debug {
 		prompt = standAloneSecurityCheckPromptForDebugging
 	}
-	securityResponse, securityErr := llmclient.GenerateContent(context.TODO(),
+	securityResponse, securityErr := llmclient.GenerateContent(ctx,
 		[]llms.MessageContent{
 
 			llms.TextParts(llms.ChatMessageTypeHuman,
@@ -0,0 +1,164 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"time"
+	"unicode"
+)
+
+// sessionLanguage is the language detected for a session.
+type sessionLanguage struct {
+	language string
+	script   string // Script of the query the language was detected from, see dominantScript
+	lastUsed time.Time
+}
+
+// GetSessionLanguage returns the language detected for a session, "" if it is unknown or expired.
+//
+// Languages expire when the session doesn't use them for the TTL of the manager.
+func (m *MemoryManager) GetSessionLanguage(sessionID string) string {
+	language, _ := m.sessionLanguage(sessionID)
+	return language
+}
+
+// SetSessionLanguage sets the language of a session, e.g. from the profile of the user.
+func (m *MemoryManager) SetSessionLanguage(sessionID, language string) {
+	m.setSessionLanguage(sessionID, language, "")
+}
+
+// DeleteSessionLanguage removes the language of a session, it is detected again from the next query.
+func (m *MemoryManager) DeleteSessionLanguage(sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.languages, sessionID)
+}
+
+// sessionLanguage returns the language of a session and the script it was detected from, and refreshes its TTL.
+func (m *MemoryManager) sessionLanguage(sessionID string) (string, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	language, ok := m.languages[sessionID]
+	if !ok {
+		return "", ""
+	}
+	if m.ttl > 0 && time.Since(language.lastUsed) > m.ttl {
+		delete(m.languages, sessionID)
+		return "", ""
+	}
+	language.lastUsed = time.Now()
+	m.languages[sessionID] = language
+	return language.language, language.script
+}
+
+func (m *MemoryManager) setSessionLanguage(sessionID, language, script string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.languages == nil {
+		m.languages = make(map[string]sessionLanguage)
+	}
+	m.languages[sessionID] = sessionLanguage{language: language, script: script, lastUsed: time.Now()}
+}
+
+// languageMemory returns the memory manager keeping the session languages, creating it if Init was not called.
+func (llm *LLMContainer) languageMemory() *MemoryManager {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.MemoryManager == nil {
+		llm.MemoryManager = NewMemoryManager(300)
+	}
+	return llm.MemoryManager
+}
+
+// ResetSessionLanguage forgets the language detected for a session, the next query of the session detects it again.
+//
+// Parameters:
+//   - sessionID: The session of the user.
+//   - options: WithTenant resets the session of a tenant.
+//
+// Returns:
+//   - error: An error if the tenant is unknown.
+//
+// Example Usage:
+//
+//	// The user picked another language in the settings
+//	err := llm.ResetSessionLanguage("user-123")
+func (llm *LLMContainer) ResetSessionLanguage(sessionID string, options ...LLMCallOption) error {
+	o := LLMCallOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+	o.SessionID = sessionID
+	if err := llm.checkTenant(&o); err != nil {
+		return err
+	}
+	llm.forgetSessionLanguage(o.getSessionID())
+	return nil
+}
+
+// scriptTables are the scripts told apart by dominantScript.
+var scriptTables = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"Latin", unicode.Latin},
+	{"Cyrillic", unicode.Cyrillic},
+	{"Greek", unicode.Greek},
+	{"Arabic", unicode.Arabic},
+	{"Hebrew", unicode.Hebrew},
+	{"Han", unicode.Han},
+	{"Kana", unicode.Hiragana},
+	{"Kana", unicode.Katakana},
+	{"Hangul", unicode.Hangul},
+	{"Thai", unicode.Thai},
+	{"Devanagari", unicode.Devanagari},
+	{"Armenian", unicode.Armenian},
+	{"Georgian", unicode.Georgian},
+}
+
+// minScriptLetters is the number of letters a query needs to tell its script.
+const minScriptLetters = 4
+
+// dominantScript returns the script of most letters of a text, "" for texts too short to tell.
+//
+// Japanese mixes Han and Kana, any Kana makes a Han text Japanese.
+func dominantScript(text string) string {
+	counts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range scriptTables {
+			if unicode.Is(script.table, r) {
+				counts[script.name]++
+				break
+			}
+		}
+	}
+	if letters < minScriptLetters {
+		return ""
+	}
+	dominant := ""
+	for _, script := range scriptTables {
+		if counts[script.name] > counts[dominant] {
+			dominant = script.name
+		}
+	}
+	if dominant == "Han" && counts["Kana"] > 0 {
+		dominant = "Kana"
+	}
+	return dominant
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrShutdown is returned by calls made after Shutdown started.
+var ErrShutdown = errors.New("llm container is shutting down")
+
+// containerLifecycle tracks the in-flight calls of a container so Shutdown can wait for them.
+type containerLifecycle struct {
+	mu       sync.Mutex
+	closing  bool
+	inflight sync.WaitGroup
+}
+
+// begin registers a call, it returns false once the container is closing.
+func (l *containerLifecycle) begin() bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closing {
+		return false
+	}
+	l.inflight.Add(1)
+	return true
+}
+
+// end marks a call started with begin as finished.
+func (l *containerLifecycle) end() {
+	if l != nil {
+		l.inflight.Done()
+	}
+}
+
+// close rejects new calls and returns a channel closed when the in-flight calls are finished.
+func (l *containerLifecycle) close() <-chan struct{} {
+	done := make(chan struct{})
+	if l == nil {
+		close(done)
+		return done
+	}
+	l.mu.Lock()
+	l.closing = true
+	l.mu.Unlock()
+	go func() {
+		l.inflight.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// Shutdown stops the container gracefully, e.g. before a rolling deployment replaces the instance.
+//
+// New AskLLM calls and asynchronous ingestion jobs are rejected with ErrShutdown, the session memory
+// cleanup is stopped, then Shutdown waits for the in-flight AskLLM calls and the running ingestion
+// jobs before closing the Redis clients. The retention worker is stopped as well. Ingestion jobs still waiting for a worker fail.
+//
+// Parameters:
+//   - ctx: Bounds the wait, Redis is closed when ctx is done even if calls are still running.
+//
+// Returns:
+//   - error: ctx.Err() if the calls didn't finish in time, or an error closing Redis.
+//
+// Example Usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	if err := llm.Shutdown(ctx); err != nil {
+//		log.Println(err)
+//	}
+func (llm *LLMContainer) Shutdown(ctx context.Context) error {
+	if llm.MemoryManager != nil {
+		llm.MemoryManager.Close()
+	}
+	llm.retention.close()
+	calls := llm.lifecycle.close()
+	jobs := llm.ingestionManager().close()
+
+	var err error
+	for _, done := range []<-chan struct{}{calls, jobs} {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			llm.logger().Warn("shutdown timed out, closing with calls in flight", "error", err)
+			break
+		}
+	}
+	if llm.RedisClient.redisClient != nil {
+		if closeErr := llm.RedisClient.redisClient.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEWriter streams an AskLLM answer to an HTTP client as Server-Sent Events.
+//
+// It frames multi-line data correctly, sends heartbeat comments so proxies keep idle connections
+// open, and stops the generation when the client disconnects: StreamingFunc returns the request
+// context error, which cancels the streaming of the model.
+//
+// Fields:
+//   - ChunkEvent: The event name of streamed chunks, empty sends unnamed "message" events.
+type SSEWriter struct {
+	ChunkEvent string
+	w          http.ResponseWriter
+	flusher    http.Flusher
+	ctx        context.Context
+	mu         sync.Mutex
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewSSEWriter writes the Server-Sent Events headers and starts the heartbeat.
+//
+// Parameters:
+//   - w: The response writer, it must implement http.Flusher.
+//   - r: The request, its context signals client disconnects.
+//   - heartbeat: Interval of the heartbeat comments, 0 uses 15 seconds and a negative value disables them.
+//
+// Returns:
+//   - *SSEWriter: The writer, call Close when the answer is complete.
+//   - error: An error if the response writer doesn't support streaming.
+//
+// Example Usage:
+//
+//	sse, err := aillm.NewSSEWriter(w, r, 0)
+//	if err != nil {
+//		http.Error(w, err.Error(), http.StatusInternalServerError)
+//		return
+//	}
+//	defer sse.Close()
+//	result, err := llm.AskLLM(query, llm.WithStreamingFunc(sse.StreamingFunc))
+//	sse.WriteJSON("done", result.Response)
+func NewSSEWriter(w http.ResponseWriter, r *http.Request, heartbeat time.Duration) (*SSEWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming is not supported")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	sse := &SSEWriter{w: w, flusher: flusher, ctx: r.Context(), done: make(chan struct{})}
+	if heartbeat == 0 {
+		heartbeat = 15 * time.Second
+	}
+	if heartbeat > 0 {
+		go sse.heartbeat(heartbeat)
+	}
+	return sse, nil
+}
+
+// Context returns the request context, it is done when the client disconnects.
+func (sse *SSEWriter) Context() context.Context {
+	return sse.ctx
+}
+
+// WriteEvent sends an event, every line of data is sent as a separate data field.
+//
+// Parameters:
+//   - event: The event name, empty sends an unnamed "message" event.
+//   - data: The event data.
+//
+// Returns:
+//   - error: The request context error if the client disconnected.
+func (sse *SSEWriter) WriteEvent(event string, data []byte) error {
+	if err := sse.ctx.Err(); err != nil {
+		return err
+	}
+	var frame strings.Builder
+	if event != "" {
+		frame.WriteString("event: " + event + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		frame.WriteString("data: " + line + "\n")
+	}
+	frame.WriteString("\n")
+	return sse.write(frame.String())
+}
+
+// WriteJSON sends an event with v encoded as JSON.
+func (sse *SSEWriter) WriteJSON(event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error converting event to json: %w", err)
+	}
+	return sse.WriteEvent(event, data)
+}
+
+// StreamingFunc sends every chunk as a ChunkEvent event, use it with WithStreamingFunc.
+func (sse *SSEWriter) StreamingFunc(ctx context.Context, chunk []byte) error {
+	return sse.WriteEvent(sse.ChunkEvent, chunk)
+}
+
+// Close stops the heartbeat and further writes. It doesn't close the connection, returning from the handler does.
+func (sse *SSEWriter) Close() {
+	sse.closeOnce.Do(func() {
+		sse.mu.Lock()
+		defer sse.mu.Unlock()
+		close(sse.done)
+	})
+}
+
+func (sse *SSEWriter) write(frame string) error {
+	sse.mu.Lock()
+	defer sse.mu.Unlock()
+	select {
+	case <-sse.done:
+		return errors.New("sse writer is closed")
+	default:
+	}
+	if _, err := fmt.Fprint(sse.w, frame); err != nil {
+		return err
+	}
+	sse.flusher.Flush()
+	return nil
+}
+
+func (sse *SSEWriter) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sse.done:
+			return
+		case <-sse.ctx.Done():
+			return
+		case <-ticker.C:
+			// Comment lines are ignored by clients but keep the connection alive
+			if sse.write(": ping\n\n") != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// vectorStoreCache keeps the embedder and the redisvector stores of a container between calls.
+//
+// Every redisvector store opens its own Redis connection pool, so creating one per search or per
+// embedded text adds latency and connection churn.
+type vectorStoreCache struct {
+	mu       sync.Mutex
+	embedder embeddings.Embedder
+	stores   map[string]vectorstores.VectorStore
+}
+
+// vectorStores returns the store cache of the container, creating it if Init was not called.
+func (llm *LLMContainer) vectorStores() *vectorStoreCache {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.storeCache == nil {
+		llm.storeCache = &vectorStoreCache{}
+	}
+	return llm.storeCache
+}
+
+// getEmbedder returns the cached embedder of the container, creating it on first use.
+func (llm *LLMContainer) getEmbedder() (embeddings.Embedder, error) {
+	cache := llm.vectorStores()
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.embedder != nil {
+		return cache.embedder, nil
+	}
+	embedder, err := llm.newEmbedder()
+	if err != nil {
+		return nil, err
+	}
+	cache.embedder = embedder
+	return embedder, nil
+}
+
+// getVectorStore returns the cached vector store of an index, creating it on first use, see newVectorStore.
+//
+// Stores used to add documents are cached separately from stores used to search: a store learns the
+// index schema from the first added documents and then only returns those fields in searches.
+//
+// Parameters:
+//   - indexName: The name of the vector index.
+//   - forWrite: Whether the store is used to add documents.
+//
+// Returns:
+//   - vectorstores.VectorStore: The vector store.
+//   - embeddings.Embedder: The embedder of the store.
+//   - error: An error if the embedder or the store cannot be created.
+func (llm *LLMContainer) getVectorStore(indexName string, forWrite bool) (vectorstores.VectorStore, embeddings.Embedder, error) {
+	embedder, err := llm.getEmbedder()
+	if err != nil {
+		return nil, nil, err
+	}
+	key := "search|" + indexName
+	if forWrite {
+		key = "write|" + indexName
+	}
+	cache := llm.vectorStores()
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if store, ok := cache.stores[key]; ok {
+		return store, embedder, nil
+	}
+	if forWrite {
+		// Encrypted chunks are embedded from their plain text
+		embedder = decryptingEmbedder{Embedder: embedder, llm: llm}
+	}
+	store, err := llm.newVectorStore(indexName, embedder)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cache.stores == nil {
+		cache.stores = make(map[string]vectorstores.VectorStore)
+	}
+	cache.stores[key] = store
+	return store, embedder, nil
+}
+
+// forgetVectorStores drops the cached stores of dropped indexes, an index created again under the same name may
+// have another vector type.
+func (llm *LLMContainer) forgetVectorStores(indexNames ...string) {
+	cache := llm.vectorStores()
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for _, indexName := range indexNames {
+		delete(cache.stores, "search|"+indexName)
+		delete(cache.stores, "write|"+indexName)
+	}
+}
+
+// resetVectorStores drops the cached embedder and stores, e.g. after the configuration changed.
+func (llm *LLMContainer) resetVectorStores() {
+	cache := llm.vectorStores()
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.embedder = nil
+	cache.stores = nil
+}
+
+// llmModelCache keeps the models created by the LLM clients of a container between calls, so every
+// request reuses the HTTP client of the provider instead of creating a new one.
+type llmModelCache struct {
+	mu     sync.Mutex
+	models map[string]llms.Model
+}
+
+// llmModels returns the model cache of the container, creating it if Init was not called.
+func (llm *LLMContainer) llmModels() *llmModelCache {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.modelCache == nil {
+		llm.modelCache = &llmModelCache{}
+	}
+	return llm.modelCache
+}
+
+// getLLMModel returns the cached model of a client, creating it on first use.
+//
+// Models are cached by client type, endpoint, model and token, so a changed configuration creates a new model.
+func (llm *LLMContainer) getLLMModel(client LLMClient) (llms.Model, error) {
+	cache := llm.llmModels()
+	config := client.GetConfig()
+	key := fmt.Sprintf("%T|%s|%s|%s", client, config.Apiurl, config.AiModel, config.APIToken)
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if model, ok := cache.models[key]; ok {
+		return model, nil
+	}
+	clientInitMu.Lock()
+	model, err := client.NewLLMClient()
+	clientInitMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	model = llm.limitModel(client, model)
+	if cache.models == nil {
+		cache.models = make(map[string]llms.Model)
+	}
+	cache.models[key] = model
+	return model, nil
+}
+
+// Reconnect drops the cached LLM models, embedder and vector stores and recreates the LLM model.
+//
+// Clients are created once and reused by all requests; call Reconnect after changing the configuration
+// of LLMClient, VisionClient, FailoverLLMClient or Embedder in place.
+//
+// Returns:
+//   - error: An error if the LLM client cannot be created with the new configuration.
+func (llm *LLMContainer) Reconnect() error {
+	cache := llm.llmModels()
+	cache.mu.Lock()
+	cache.models = nil
+	cache.mu.Unlock()
+	llm.resetVectorStores()
+	if llm.LLMClient == nil {
+		return nil
+	}
+	_, err := llm.getLLMModel(llm.LLMClient)
+	return err
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package aillm
+
+import (
+	"context"
+	"iter"
+)
+
+// Chunk is an element of the answer streamed by AskLLMStream.
+//
+// Fields:
+//   - Text: A part of the answer, empty in the last chunk.
+//   - Done: True in the last chunk, the answer is complete.
+//   - Result: The result of the call, set in the last chunk only.
+type Chunk struct {
+	Text   string
+	Done   bool
+	Result *LLMResult
+}
+
+// AskLLMStream asks a question and returns the answer as an iterator of chunks, an alternative to WithStreamingFunc
+// for Go 1.23 range-over-func loops.
+//
+// The last chunk is Done and holds the LLMResult, a failed call yields its error instead. Breaking out of the loop
+// or canceling ctx stops the call. A WithStreamingFunc option given to the call is replaced by the iterator.
+//
+// Parameters:
+//   - ctx: The context of the call.
+//   - query: The question.
+//   - opts: The options of the call, as for AskLLM.
+//
+// Returns:
+//   - iter.Seq2[Chunk, error]: The chunks of the answer, to range over once.
+//
+// Example Usage:
+//
+//	for chunk, err := range llm.AskLLMStream(ctx, "What is SemMapas?", llm.WithSessionID(sessionID)) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Print(chunk.Text)
+//	}
+func (llm *LLMContainer) AskLLMStream(ctx context.Context, query string, opts ...LLMCallOption) iter.Seq2[Chunk, error] {
+	return func(yield func(Chunk, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		chunks := make(chan string)
+		done := make(chan struct{})
+		var result LLMResult
+		var err error
+		streaming := llm.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+			select {
+			case chunks <- string(chunk):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		go func() {
+			defer close(done)
+			result, err = llm.askWithContext(ctx, query, append(opts[:len(opts):len(opts)], streaming)...)
+		}()
+		for {
+			select {
+			case text := <-chunks:
+				if !yield(Chunk{Text: text}, nil) {
+					// The call stops at its next chunk
+					cancel()
+					<-done
+					return
+				}
+			case <-done:
+				if err != nil {
+					yield(Chunk{}, err)
+					return
+				}
+				yield(Chunk{Done: true, Result: &result}, nil)
+				return
+			}
+		}
+	}
+}
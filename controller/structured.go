@@ -0,0 +1,189 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// AskLLMAs asks a question and decodes the JSON answer into a value of type T.
+//
+// The JSON schema of T is given to the model with WithOutputSchema, answers that aren't valid JSON or
+// don't match the schema are generated again with the error, up to Guardrails.MaxRetries times. The
+// schema follows the json tags of the struct fields, fields without omitempty are required.
+//
+// Parameters:
+//   - llm: The container.
+//   - query: The question.
+//   - opts: The options of the call, as for AskLLM.
+//
+// Returns:
+//   - T: The decoded answer.
+//   - LLMResult: The result of the call.
+//   - error: The error of AskLLM, a *GuardrailError if the answer never matched the schema, or an error if the
+//     model couldn't answer from the documents.
+//
+// Example Usage:
+//
+//	type Product struct {
+//		Name     string   `json:"name"`
+//		Price    float64  `json:"price"`
+//		Features []string `json:"features,omitempty"`
+//	}
+//	product, _, err := aillm.AskLLMAs[Product](llm, "Describe the premium plan", llm.WithEmbeddingPrefix("products"))
+func AskLLMAs[T any](llm *LLMContainer, query string, opts ...LLMCallOption) (T, LLMResult, error) {
+	var value T
+	schema := jsonSchemaOf(reflect.TypeOf(value))
+	decode := AnswerValidatorFunc(func(answer string) error {
+		var decoded T
+		if err := json.Unmarshal([]byte(trimCodeBlock(answer)), &decoded); err != nil {
+			return fmt.Errorf("the answer must be valid JSON: %v", err)
+		}
+		return nil
+	})
+	opts = append(opts, llm.WithOutputSchema(schema), llm.WithGuardrails(JSONSchemaValidator(schema), decode))
+	result, err := llm.AskLLM(query, opts...)
+	if err != nil {
+		return value, result, err
+	}
+	if result.FailedToRespond {
+		return value, result, errors.New("structured output: the model couldn't answer from the documents")
+	}
+	if err = json.Unmarshal([]byte(trimCodeBlock(result.Text())), &value); err != nil {
+		return value, result, fmt.Errorf("structured output: %w", err)
+	}
+	return value, result, nil
+}
+
+// schemaMap converts a JSON schema to a map, nil if it can't be serialized.
+func schemaMap(schema any) map[string]any {
+	if schema, ok := schema.(map[string]any); ok {
+		return schema
+	}
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return nil
+	}
+	var converted map[string]any
+	if json.Unmarshal(raw, &converted) != nil {
+		return nil
+	}
+	return converted
+}
+
+// outputSchemaPrompt instructs the model to answer with JSON matching a schema.
+func outputSchemaPrompt(schema map[string]any) string {
+	raw, _ := json.Marshal(schema)
+	return "Reply only with JSON matching this JSON schema, without explanations or code blocks:\n" + string(raw)
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// jsonSchemaOf describes the JSON encoding of a Go type with the subset of JSON schema validated by
+// JSONSchemaValidator. Types without a fixed encoding, e.g. interfaces, have an empty schema.
+func jsonSchemaOf(t reflect.Type) map[string]any {
+	return jsonSchemaOfType(t, map[reflect.Type]bool{})
+}
+
+func jsonSchemaOfType(t reflect.Type, visiting map[reflect.Type]bool) map[string]any {
+	if t == nil {
+		return map[string]any{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]any{}
+	case reflect.PointerTo(t).Implements(textMarshalerType):
+		return map[string]any{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Byte slices are encoded as base64 strings
+			return map[string]any{"type": "string"}
+		}
+		return map[string]any{"type": "array", "items": jsonSchemaOfType(t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchemaOfType(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			// Recursive types are described once
+			return map[string]any{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		properties := map[string]any{}
+		required := []string{}
+		addStructFields(t, properties, &required, visiting)
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	return map[string]any{}
+}
+
+// addStructFields adds the JSON fields of a struct to a schema, including the fields of embedded structs.
+func addStructFields(t reflect.Type, properties map[string]any, required *[]string, visiting map[reflect.Type]bool) {
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, flags, _ := strings.Cut(tag, ",")
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			addStructFields(fieldType, properties, required, visiting)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = jsonSchemaOfType(field.Type, visiting)
+		if !strings.Contains(","+flags+",", ",omitempty,") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/tmc/langchaingo/schema"
+)
+
+const (
+	tableStartMarker = "[Table Start]"
+	tableEndMarker   = "[Table End]"
+	// maxRenderedTableLength is the longest table rendered whole in the prompt, longer tables keep their retrieved rows
+	maxRenderedTableLength = 20000
+)
+
+// Table is a table extracted from a document, stored with its structure so it can be rendered back as Markdown.
+//
+// Tables found in the text of EmbeddText, between "[Table Start]" and "[Table End]" lines as written by the
+// transcriber, are moved to LLMEmbeddingContent.Tables. Each table is embedded in chunks of rows that repeat the
+// headers, and a retrieved chunk is replaced by the whole table in the prompt, so questions over all rows, e.g. the
+// sum of a column, see every row.
+//
+// Fields:
+//   - Caption: The caption of the table, if any.
+//   - Headers: The column names.
+//   - Rows: The cells of each row.
+type Table struct {
+	Caption string     `json:"caption,omitempty"`
+	Headers []string   `json:"headers"`
+	Rows    [][]string `json:"rows"`
+}
+
+// markdownCell escapes a cell of a Markdown table.
+func markdownCell(cell string) string {
+	cell = strings.Join(strings.Fields(cell), " ")
+	return strings.ReplaceAll(cell, "|", `\|`)
+}
+
+// markdownRow renders the cells of a row padded to the number of columns.
+func markdownRow(cells []string, columns int) string {
+	var row strings.Builder
+	row.WriteString("|")
+	for i := 0; i < columns; i++ {
+		cell := ""
+		if i < len(cells) {
+			cell = markdownCell(cells[i])
+		}
+		row.WriteString(" " + cell + " |")
+	}
+	return row.String() + "\n"
+}
+
+// Markdown renders the table as a Markdown table preceded by its caption.
+func (t Table) Markdown() string {
+	columns := len(t.Headers)
+	for _, row := range t.Rows {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return t.Caption
+	}
+	var markdown strings.Builder
+	if t.Caption != "" {
+		markdown.WriteString(t.Caption + "\n\n")
+	}
+	markdown.WriteString(markdownRow(t.Headers, columns))
+	markdown.WriteString(strings.Repeat("| --- ", columns) + "|\n")
+	for _, row := range t.Rows {
+		markdown.WriteString(markdownRow(row, columns))
+	}
+	return markdown.String()
+}
+
+// split divides the table into tables of consecutive rows whose Markdown fits a length, each with the caption and headers.
+//
+// A row longer than the length forms a table of its own.
+func (t Table) split(length int) []Table {
+	if length <= 0 || len(t.Markdown()) <= length {
+		return []Table{t}
+	}
+	parts := []Table{}
+	part := Table{Caption: t.Caption, Headers: t.Headers}
+	for _, row := range t.Rows {
+		candidate := part
+		candidate.Rows = append(append([][]string(nil), part.Rows...), row)
+		if len(part.Rows) > 0 && len(candidate.Markdown()) > length {
+			parts = append(parts, part)
+			candidate = Table{Caption: t.Caption, Headers: t.Headers, Rows: [][]string{row}}
+		}
+		part = candidate
+	}
+	if len(part.Rows) > 0 {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// htmlTable extracts a table element, the header cells of its first row are the headers.
+func htmlTable(table *goquery.Selection) Table {
+	result := Table{Caption: strings.TrimSpace(table.Find("caption").First().Text())}
+	table.Find("tr").Each(func(i int, row *goquery.Selection) {
+		cells := []string{}
+		headerRow := true
+		row.Find("th, td").Each(func(j int, cell *goquery.Selection) {
+			cells = append(cells, strings.TrimSpace(cell.Text()))
+			if goquery.NodeName(cell) == "td" {
+				headerRow = false
+			}
+		})
+		switch {
+		case len(cells) == 0:
+		case headerRow && len(result.Headers) == 0 && len(result.Rows) == 0:
+			result.Headers = cells
+		default:
+			result.Rows = append(result.Rows, cells)
+		}
+	})
+	if len(result.Headers) == 0 && len(result.Rows) > 1 {
+		// Tables without header cells, e.g. from Tika, use their first row as headers
+		result.Headers, result.Rows = result.Rows[0], result.Rows[1:]
+	}
+	return result
+}
+
+// markdownSeparator matches the separator line of a Markdown table.
+var markdownSeparator = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+
+// splitMarkdownRow splits a table line into its cells, escaped pipes are kept in the cells.
+func splitMarkdownRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	cells := []string{}
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// parseTableBlock reads the lines between table markers, lines before the first row are the caption.
+func parseTableBlock(lines []string) Table {
+	table := Table{}
+	caption := []string{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case !strings.Contains(line, "|"):
+			if table.Headers == nil && len(table.Rows) == 0 {
+				caption = append(caption, line)
+			}
+		case markdownSeparator.MatchString(line):
+		case table.Headers == nil:
+			table.Headers = splitMarkdownRow(line)
+		default:
+			table.Rows = append(table.Rows, splitMarkdownRow(line))
+		}
+	}
+	table.Caption = strings.Join(caption, " ")
+	return table
+}
+
+// extractTables moves the tables between table markers out of a text.
+//
+// Returns:
+//   - string: The text without its tables.
+//   - []Table: The tables in order of appearance.
+func extractTables(text string) (string, []Table) {
+	if !strings.Contains(text, tableStartMarker) {
+		return text, nil
+	}
+	tables := []Table{}
+	var rest strings.Builder
+	var block []string
+	inTable := false
+	for _, line := range strings.Split(text, "\n") {
+		switch trimmed := strings.TrimSpace(line); {
+		case trimmed == tableStartMarker:
+			inTable, block = true, nil
+		case trimmed == tableEndMarker && inTable:
+			inTable = false
+			if table := parseTableBlock(block); len(table.Headers) > 0 || len(table.Rows) > 0 {
+				tables = append(tables, table)
+			}
+		case inTable:
+			block = append(block, line)
+		default:
+			rest.WriteString(line + "\n")
+		}
+	}
+	if inTable {
+		// An unterminated table stays in the text
+		rest.WriteString(tableStartMarker + "\n" + strings.Join(block, "\n") + "\n")
+	}
+	return strings.TrimSuffix(rest.String(), "\n"), tables
+}
+
+// sealTables returns encrypted copies of tables.
+func (s *sealer) sealTables(tables []Table) ([]Table, error) {
+	if s == nil || tables == nil {
+		return tables, nil
+	}
+	sealed := make([]Table, len(tables))
+	for idx, table := range tables {
+		var err error
+		if sealed[idx].Caption, err = s.seal(table.Caption); err != nil {
+			return nil, err
+		}
+		if sealed[idx].Headers, err = s.sealCells(table.Headers); err != nil {
+			return nil, err
+		}
+		sealed[idx].Rows = make([][]string, len(table.Rows))
+		for rowIdx, row := range table.Rows {
+			if sealed[idx].Rows[rowIdx], err = s.sealCells(row); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sealed, nil
+}
+
+func (s *sealer) sealCells(cells []string) ([]string, error) {
+	sealed := make([]string, len(cells))
+	for idx, cell := range cells {
+		var err error
+		if sealed[idx], err = s.seal(cell); err != nil {
+			return nil, err
+		}
+	}
+	return sealed, nil
+}
+
+// decryptTables decrypts tables in place.
+func (llm *LLMContainer) decryptTables(tables []Table) error {
+	var err error
+	for idx := range tables {
+		if tables[idx].Caption, err = llm.decryptText(tables[idx].Caption); err != nil {
+			return err
+		}
+		for _, cells := range append([][]string{tables[idx].Headers}, tables[idx].Rows...) {
+			for cellIdx, cell := range cells {
+				if cells[cellIdx], err = llm.decryptText(cell); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// tableDocuments splits tables into chunks of rows, each chunk keeps the index of its table.
+func tableDocuments(tables []Table, chunkSize int) ([]schema.Document, []int) {
+	docs := []schema.Document{}
+	tableOf := []int{}
+	for idx, table := range tables {
+		for _, part := range table.split(chunkSize) {
+			docs = append(docs, schema.Document{PageContent: part.Markdown()})
+			tableOf = append(tableOf, idx)
+		}
+	}
+	return docs, tableOf
+}
+
+// tableRef refers to a table of the content stored in a raw document, it is kept in the metadata of table chunks.
+func tableRef(rawDocKey string, table int) string {
+	return rawDocKey + "#" + strconv.Itoa(table)
+}
+
+// chunkTable returns the table of a retrieved table chunk. Raw documents referenced by TableRef are loaded once and
+// cached in objects, chunks embedded by older versions hold their table in their metadata.
+func (llm *LLMContainer) chunkTable(content LLMEmbeddingContent, objects map[string]*LLMEmbeddingObject) (Table, bool) {
+	if len(content.Tables) == 1 {
+		return content.Tables[0], true
+	}
+	sep := strings.LastIndex(content.TableRef, "#")
+	if sep < 0 {
+		return Table{}, false
+	}
+	table, err := strconv.Atoi(content.TableRef[sep+1:])
+	if err != nil {
+		return Table{}, false
+	}
+	key := content.TableRef[:sep]
+	obj, loaded := objects[key]
+	if !loaded {
+		obj = &LLMEmbeddingObject{}
+		rdb := llm.RedisClient.redisClient
+		if rdb == nil || obj.load(rdb, key) != nil || llm.decryptObject(obj) != nil {
+			obj = nil
+		}
+		objects[key] = obj
+	}
+	if obj == nil {
+		return Table{}, false
+	}
+	stored, ok := obj.Contents[content.Id]
+	if !ok || table < 0 || table >= len(stored.Tables) {
+		return Table{}, false
+	}
+	return stored.Tables[table], true
+}
+
+// expandTables replaces retrieved table chunks with their whole table rendered as Markdown, keeping the first
+// chunk of each table only. Tables longer than maxRenderedTableLength, or whose raw document can't be read, keep
+// their retrieved rows.
+func (llm *LLMContainer) expandTables(docs []schema.Document) []schema.Document {
+	expanded := make([]schema.Document, 0, len(docs))
+	seen := map[string]bool{}
+	objects := map[string]*LLMEmbeddingObject{}
+	for _, doc := range docs {
+		rawKey, _ := doc.Metadata["rawkey"].(string)
+		content := LLMEmbeddingContent{}
+		if !strings.Contains(rawKey, `"Table`) || json.Unmarshal([]byte(rawKey), &content) != nil {
+			expanded = append(expanded, doc)
+			continue
+		}
+		table, ok := llm.chunkTable(content, objects)
+		if !ok {
+			expanded = append(expanded, doc)
+			continue
+		}
+		markdown := table.Markdown()
+		if len(markdown) > maxRenderedTableLength {
+			expanded = append(expanded, doc)
+			continue
+		}
+		key := content.Id + "\n" + markdown
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if content.Title != "" {
+			markdown = "Title: " + content.Title + "\n" + markdown
+		}
+		doc.PageContent = markdown
+		expanded = append(expanded, doc)
+	}
+	return expanded
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope used when the host application passes a TracerProvider.
+const tracerName = "github.com/RezaArani/aillm"
+
+// noopTracer is used when no tracer is configured, so instrumentation costs nothing.
+var noopTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// startSpan starts a span with the configured tracer.
+//
+// Parameters:
+//   - ctx: The parent context.
+//   - name: The span name, e.g. "aillm.AskLLM".
+//   - attrs: Optional span attributes.
+//
+// Returns:
+//   - context.Context: The context carrying the new span.
+//   - trace.Span: The span, which must be finished with endSpan.
+func (llm *LLMContainer) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	tracer := llm.Tracer
+	if tracer == nil {
+		tracer = noopTracer
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err on the span, if any, and finishes it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// redisTracingHook creates a span for every Redis command and pipeline.
+type redisTracingHook struct {
+	tracer trace.Tracer
+}
+
+func (h redisTracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h redisTracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := h.tracer.Start(ctx, "redis."+cmd.Name(),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", cmd.Name()),
+			))
+		err := next(ctx, cmd)
+		if err == redis.Nil {
+			// A missing key is a regular result, not an error
+			span.End()
+			return err
+		}
+		endSpan(span, err)
+		return err
+	}
+}
+
+func (h redisTracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := h.tracer.Start(ctx, "redis.pipeline",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.Int("db.redis.num_cmd", len(cmds)),
+			))
+		err := next(ctx, cmds)
+		endSpan(span, err)
+		return err
+	}
+}
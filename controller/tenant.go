@@ -0,0 +1,190 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrTenantRequired is returned when RequireTenant is set and a call has no WithTenant option.
+var ErrTenantRequired = errors.New("a tenant is required, use WithTenant")
+
+// tenantIDPattern restricts tenant IDs to characters that can't escape their key namespace.
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Tenant is a customer served by a shared container.
+//
+// Calls made WithTenant store and search their embeddings under the "tenant:<ID>" prefix, which is
+// prepended to the prefix given with WithEmbeddingPrefix, and keep their session memory under
+// "tenant:<ID>:<SessionID>", so tenants can't read each other's documents or conversations. Embedding prefixes,
+// indexes and session IDs in the "tenant" namespace are rejected, tenant data is only reachable WithTenant.
+//
+// Fields:
+//   - ID: The tenant ID, letters, digits, "-" and "_" only.
+//   - MaxIndexes: Maximum number of indexes the tenant can embed, 0 means unlimited.
+//   - Quota: Request and token limits of the tenant, shared by all its sessions.
+type Tenant struct {
+	ID         string `json:"id"`
+	MaxIndexes int    `json:"maxIndexes"`
+	Quota      Quota  `json:"quota"`
+}
+
+// tenantRegistry holds the registered tenants of a container.
+type tenantRegistry struct {
+	mu      sync.RWMutex
+	tenants map[string]Tenant
+}
+
+func (r *tenantRegistry) add(tenant Tenant) error {
+	if !tenantIDPattern.MatchString(tenant.ID) {
+		return fmt.Errorf("invalid tenant id %q", tenant.ID)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tenants[tenant.ID] = tenant
+	return nil
+}
+
+func (r *tenantRegistry) get(tenantID string) (Tenant, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tenant, exists := r.tenants[tenantID]
+	return tenant, exists
+}
+
+// tenantRegistry returns the tenants of the container.
+func (llm *LLMContainer) tenantRegistry() *tenantRegistry {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if llm.tenants == nil {
+		llm.tenants = &tenantRegistry{tenants: make(map[string]Tenant)}
+	}
+	return llm.tenants
+}
+
+// RegisterTenant adds or updates a tenant.
+//
+// Returns:
+//   - error: An error if the tenant ID is invalid.
+func (llm *LLMContainer) RegisterTenant(tenant Tenant) error {
+	return llm.tenantRegistry().add(tenant)
+}
+
+// RemoveTenant unregisters a tenant, further calls for it fail. Its data is kept.
+func (llm *LLMContainer) RemoveTenant(tenantID string) {
+	registry := llm.tenantRegistry()
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	delete(registry.tenants, tenantID)
+}
+
+// GetTenant returns a registered tenant.
+func (llm *LLMContainer) GetTenant(tenantID string) (Tenant, bool) {
+	return llm.tenantRegistry().get(tenantID)
+}
+
+// ListTenants returns the registered tenants sorted by ID.
+func (llm *LLMContainer) ListTenants() []Tenant {
+	registry := llm.tenantRegistry()
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	tenants := make([]Tenant, 0, len(registry.tenants))
+	for _, tenant := range registry.tenants {
+		tenants = append(tenants, tenant)
+	}
+	sort.Slice(tenants, func(i, j int) bool {
+		return tenants[i].ID < tenants[j].ID
+	})
+	return tenants
+}
+
+// TenantPrefix returns the embedding prefix of a tenant, for the functions taking a raw prefix
+// such as CosineSimilarity, SearchImages or ListEmbeddingsByPrefix.
+//
+// Parameters:
+//   - tenantID: The tenant ID.
+//   - prefix: The prefix inside the tenant namespace, may be empty.
+func TenantPrefix(tenantID, prefix string) string {
+	if tenantID == "" {
+		return prefix
+	}
+	tenantPrefix := "tenant:" + tenantID
+	if prefix != "" {
+		tenantPrefix += ":" + prefix
+	}
+	return tenantPrefix
+}
+
+// isTenantKey reports whether a prefix, index or session ID is in the "tenant" namespace.
+func isTenantKey(key string) bool {
+	return key == "tenant" || strings.HasPrefix(key, "tenant:")
+}
+
+// checkTenant validates the tenant of a call.
+func (llm *LLMContainer) checkTenant(o *LLMCallOptions) error {
+	if !o.internal {
+		// Keys of untenanted calls could otherwise address the keys of a tenant
+		for _, prefix := range append([]string{o.Prefix}, o.prefixes...) {
+			if isTenantKey(prefix) {
+				return fmt.Errorf("%w: the embedding prefix %q is reserved for tenants, use WithTenant", ErrInvalidOptions, prefix)
+			}
+		}
+		for _, index := range append([]string{o.Index}, o.indexes...) {
+			if isTenantKey(index) {
+				return fmt.Errorf("%w: the embedding index %q is reserved for tenants, use WithTenant", ErrInvalidOptions, index)
+			}
+		}
+		if isTenantKey(o.SessionID) {
+			return fmt.Errorf("%w: the session ID %q is reserved for tenants, use WithTenant", ErrInvalidOptions, o.SessionID)
+		}
+	}
+	if o.tenantID == "" {
+		if llm.RequireTenant && !o.internal {
+			return ErrTenantRequired
+		}
+		return nil
+	}
+	if _, exists := llm.tenantRegistry().get(o.tenantID); !exists {
+		return fmt.Errorf("unknown tenant %q", o.tenantID)
+	}
+	return nil
+}
+
+// checkTenantIndexLimit fails if embedding a new index would exceed the MaxIndexes of the tenant.
+func (llm *LLMContainer) checkTenantIndexLimit(o *LLMCallOptions) error {
+	tenant, exists := llm.tenantRegistry().get(o.tenantID)
+	if !exists || tenant.MaxIndexes <= 0 {
+		return nil
+	}
+	ctx := context.Background()
+	keyPrefix := LLMEmbeddingObject{EmbeddingPrefix: TenantPrefix(tenant.ID, "")}.getRawDocRedisId()
+	count := 0
+	iter := llm.RedisClient.redisClient.Scan(ctx, 0, keyPrefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		count++
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if count >= tenant.MaxIndexes {
+		return fmt.Errorf("tenant %s reached its limit of %d indexes", tenant.ID, tenant.MaxIndexes)
+	}
+	return nil
+}
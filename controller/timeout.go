@@ -0,0 +1,108 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Timeouts bounds the calls of a container to its upstream services, so a stuck service can't hang callers.
+//
+// Fields:
+//   - Request: Deadline of a whole AskLLM call, 0 means no deadline. WithTimeout overrides it per call.
+//   - LLM: Timeout of a single LLM generation attempt (default 5m), timed out attempts are retried.
+//   - Embedding: Timeout of a single embedding request (default 1m), timed out requests are retried.
+//   - Tika: Document extraction timeout used when TranscribeConfig.MaxTimeout isn't set (default 1m).
+//   - Redis: Timeout of Redis reads, writes and vector searches (default 10s).
+type Timeouts struct {
+	Request   time.Duration
+	LLM       time.Duration
+	Embedding time.Duration
+	Tika      time.Duration
+	Redis     time.Duration
+}
+
+func (t Timeouts) llmTimeout() time.Duration {
+	if t.LLM > 0 {
+		return t.LLM
+	}
+	return 5 * time.Minute
+}
+
+func (t Timeouts) embeddingTimeout() time.Duration {
+	if t.Embedding > 0 {
+		return t.Embedding
+	}
+	return time.Minute
+}
+
+func (t Timeouts) tikaTimeout() time.Duration {
+	if t.Tika > 0 {
+		return t.Tika
+	}
+	return time.Minute
+}
+
+func (t Timeouts) redisTimeout() time.Duration {
+	if t.Redis > 0 {
+		return t.Redis
+	}
+	return 10 * time.Second
+}
+
+// WithTimeout sets the deadline of the whole call, overriding Timeouts.Request.
+//
+// When the deadline is reached the generation is stopped and the call returns an error wrapping
+// context.DeadlineExceeded.
+//
+// Parameters:
+//   - timeout: The maximum duration of the call, 0 keeps the container default.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the deadline.
+func (llm *LLMContainer) WithTimeout(timeout time.Duration) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.timeout = timeout
+	}
+}
+
+// requestContext applies the deadline of a call to ctx.
+func (llm *LLMContainer) requestContext(ctx context.Context, o *LLMCallOptions) (context.Context, context.CancelFunc) {
+	timeout := o.timeout
+	if timeout <= 0 {
+		timeout = llm.Timeouts.Request
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// searchContext returns the context of a vector search, which embeds the query and searches Redis. The search is
+// bounded by the deadline of the request too and stops when the request is canceled.
+func (llm *LLMContainer) searchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, llm.Timeouts.embeddingTimeout()+llm.Timeouts.redisTimeout())
+}
+
+// deadlineError wraps the error of a call stopped by its deadline, so callers can match context.DeadlineExceeded
+// even when the provider reports it as a plain error.
+func deadlineError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		return fmt.Errorf("%w: %v", ctxErr, err)
+	}
+	return err
+}
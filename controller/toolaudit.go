@@ -0,0 +1,118 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ToolAuditRecord represents a single tool invocation performed on behalf of the model.
+//
+// Fields:
+//   - SessionID: The session in which the tool was called.
+//   - Tool: The name of the invoked tool.
+//   - ToolCallID: The identifier assigned to the call by the model.
+//   - Arguments: The raw JSON arguments generated by the model.
+//   - ResultHash: SHA-256 hash of the handler result (the result itself is not stored).
+//   - Duration: Time spent inside the handler.
+//   - Error: The handler error message, empty on success.
+//   - TimeStamp: The time the invocation started.
+//   - RequestID: The correlation ID set with WithRequestID.
+type ToolAuditRecord struct {
+	SessionID  string        `json:"sessionId"`
+	Tool       string        `json:"tool"`
+	ToolCallID string        `json:"toolCallId"`
+	Arguments  string        `json:"arguments"`
+	ResultHash string        `json:"resultHash"`
+	Duration   time.Duration `json:"duration"`
+	Error      string        `json:"error,omitempty"`
+	TimeStamp  time.Time     `json:"timestamp"`
+	RequestID  string        `json:"requestId,omitempty"`
+}
+
+// ToolAuditSink defines a destination for tool invocation records.
+//
+// Implementations must be safe for concurrent use.
+type ToolAuditSink interface {
+	// WriteToolAudit persists a single tool invocation record.
+	WriteToolAudit(ctx context.Context, record ToolAuditRecord) error
+}
+
+// RedisToolAuditSink stores tool invocation records in a Redis stream.
+//
+// Fields:
+//   - Stream: The Redis stream key records are appended to.
+//   - MaxLen: Approximate maximum stream length, 0 keeps every record.
+type RedisToolAuditSink struct {
+	Stream      string
+	MaxLen      int64
+	redisClient *redis.Client
+}
+
+// NewRedisToolAuditSink creates a tool audit sink backed by the container's Redis connection.
+//
+// Init must be called before the sink is created.
+//
+// Parameters:
+//   - stream: The Redis stream key, defaults to "aillm:toolaudit".
+//
+// Returns:
+//   - *RedisToolAuditSink: The Redis backed sink.
+func (llm *LLMContainer) NewRedisToolAuditSink(stream string) *RedisToolAuditSink {
+	if stream == "" {
+		stream = "aillm:toolaudit"
+	}
+	return &RedisToolAuditSink{
+		Stream:      stream,
+		redisClient: llm.RedisClient.redisClient,
+	}
+}
+
+// WriteToolAudit appends the record to the Redis stream.
+func (rs *RedisToolAuditSink) WriteToolAudit(ctx context.Context, record ToolAuditRecord) error {
+	if rs.redisClient == nil {
+		return fmt.Errorf("redis tool audit sink is not initialized")
+	}
+	args := &redis.XAddArgs{
+		Stream: rs.Stream,
+		Values: map[string]interface{}{
+			"sessionId":  record.SessionID,
+			"requestId":  record.RequestID,
+			"tool":       record.Tool,
+			"toolCallId": record.ToolCallID,
+			"arguments":  record.Arguments,
+			"resultHash": record.ResultHash,
+			"durationMs": record.Duration.Milliseconds(),
+			"error":      record.Error,
+			"timestamp":  record.TimeStamp.Format(time.RFC3339Nano),
+		},
+	}
+	if rs.MaxLen > 0 {
+		args.MaxLen = rs.MaxLen
+		args.Approx = true
+	}
+	return rs.redisClient.XAdd(ctx, args).Err()
+}
+
+// sha256Hex returns the hex encoded SHA-256 hash of a tool result or an answer.
+func sha256Hex(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// invokeTool runs the handler registered for a tool call requested by the model.
+//
+// The arguments are validated against the tool's declared JSON schema before the handler runs,
+// so handlers never receive malformed input. The call is recorded in the configured
+// ToolAuditSink regardless of its outcome.
+//
+// Parameters:
+//   - o: The options of the current AskLLM call.
+//   - tc: The tool call generated by the model.
+//
+// Returns:
+//   - string: The handler result.
+//   - error: A *ToolArgumentError if the arguments are invalid, or the handler error.
+func (llm *LLMContainer) invokeTool(o LLMCallOptions, tc llms.ToolCall) (string, error) {
+	fn := o.Tools.Handlers[tc.FunctionCall.Name]
+	start := time.Now()
+	var fnresult string
+	params, err := validateToolArguments(tc.FunctionCall.Name, tc.FunctionCall.Arguments, o.Tools.findToolParameters(tc.FunctionCall.Name))
+	if err == nil {
+		fnresult, err = fn(params)
+	}
+	llm.auditToolCall(o, tc, fnresult, start, err)
+	return fnresult, err
+}
+
+// auditToolCall sends a tool invocation record to the configured ToolAuditSink.
+//
+// Failures of the sink never interrupt the query; they are reported as warnings.
+func (llm *LLMContainer) auditToolCall(o LLMCallOptions, tc llms.ToolCall, fnresult string, start time.Time, handlerErr error) {
+	if llm.ToolAuditSink == nil {
+		return
+	}
+	record := ToolAuditRecord{
+		SessionID:  o.SessionID,
+		Tool:       tc.FunctionCall.Name,
+		ToolCallID: tc.ID,
+		Arguments:  tc.FunctionCall.Arguments,
+		ResultHash: sha256Hex(fnresult),
+		Duration:   time.Since(start),
+		TimeStamp:  start,
+		RequestID:  o.requestID,
+	}
+	if handlerErr != nil {
+		record.Error = handlerErr.Error()
+	}
+	if err := llm.ToolAuditSink.WriteToolAudit(context.TODO(), record); err != nil && llm.ShowWarnings {
+		llm.requestLogger(o.requestID).Warn("unable to write tool audit record", "tool", tc.FunctionCall.Name, "error", err)
+	}
+}
+
+// maxToolRecoveryRounds limits how many times the model may retry tool calls after a handler error.
+const maxToolRecoveryRounds = 3
+
+// runToolCalls lets the model request tool calls and appends the calls and their responses to msgs.
+//
+// Calls with arguments that do not match the tool schema are always rejected back to the model.
+// When tool error recovery is enabled, handler errors are sent back the same way and the model
+// is given another round to correct its arguments. Otherwise the first handler error aborts the query.
+//
+// Parameters:
+//   - ctx: The request context.
+//   - llmclient: The initialized LLM client.
+//   - o: The options of the current AskLLM call.
+//   - messageHistory: The conversation sent to the model for tool selection.
+//   - msgs: The prompt that will be used for the final answer.
+//   - result: The result used for action logging.
+//
+// Returns:
+//   - []llms.MessageContent: msgs extended with the tool calls and tool responses.
+//   - error: An error if generation fails or a handler fails without recovery.
+func (llm *LLMContainer) runToolCalls(ctx context.Context, llmclient llms.Model, o LLMCallOptions, messageHistory, msgs []llms.MessageContent, result *LLMResult) ([]llms.MessageContent, error) {
+	callParams := []llms.CallOption{
+		llms.WithTools(o.Tools.Tools),
+		llms.WithStreamingFunc(o.StreamingFunc),
+	}
+
+	if o.customModel != "" {
+		callParams = append(callParams, llms.WithModel(o.customModel))
+	}
+
+	for round := 0; ; round++ {
+		resp, err := llmclient.GenerateContent(ctx, messageHistory, callParams...)
+		if err != nil {
+			return msgs, err
+		}
+		respchoice := resp.Choices[0]
+
+		assistantResponse := llms.TextParts(llms.ChatMessageTypeAI, respchoice.Content)
+		for _, tc := range respchoice.ToolCalls {
+			assistantResponse.Parts = append(assistantResponse.Parts, tc)
+		}
+		msgs = append(msgs, assistantResponse)
+		messageHistory = append(messageHistory, assistantResponse)
+
+		recovered := false
+		for _, tc := range respchoice.ToolCalls {
+			if o.Tools.Handlers[tc.FunctionCall.Name] == nil {
+				continue
+			}
+			fnresult, handlererr := llm.invokeTool(o, tc)
+			if handlererr != nil {
+				var argumentErr *ToolArgumentError
+				if !o.toolErrorRecovery && !errors.As(handlererr, &argumentErr) {
+					return msgs, handlererr
+				}
+				result.addAction("Tool Error: "+tc.FunctionCall.Name, o.ActionCallFunc)
+				fnresult = "Error: " + handlererr.Error()
+				recovered = true
+			}
+			toolResponse := llms.MessageContent{
+				Role: llms.ChatMessageTypeTool,
+
+				Parts: []llms.ContentPart{
+					llms.ToolCallResponse{
+						ToolCallID: tc.ID,
+						Name:       tc.FunctionCall.Name,
+						Content:    fnresult,
+					},
+				},
+			}
+
+			msgs = append(msgs, toolResponse)
+			messageHistory = append(messageHistory, toolResponse)
+		}
+		if !recovered || round >= maxToolRecoveryRounds {
+			return msgs, nil
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ToolArgumentError is returned when the arguments generated by the model do not match
+// the JSON schema declared for the tool.
+//
+// These errors are always sent back to the model so it can correct the call.
+type ToolArgumentError struct {
+	Tool   string
+	Reason string
+}
+
+func (e *ToolArgumentError) Error() string {
+	return fmt.Sprintf("invalid arguments for tool %s: %s", e.Tool, e.Reason)
+}
+
+// findToolParameters returns the declared parameter schema of a tool as a generic JSON map.
+//
+// Returns nil if the tool is unknown or declares no parameters.
+func (tools AillmTools) findToolParameters(name string) map[string]interface{} {
+	for _, tool := range tools.Tools {
+		if tool.Function == nil || tool.Function.Name != name || tool.Function.Parameters == nil {
+			continue
+		}
+		// Parameters can be any JSON serializable value, normalize it to a map
+		raw, err := json.Marshal(tool.Function.Parameters)
+		if err != nil {
+			return nil
+		}
+		var schema map[string]interface{}
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			return nil
+		}
+		return schema
+	}
+	return nil
+}
+
+// validateToolArguments decodes raw tool arguments and validates them against the tool schema.
+//
+// Parameters:
+//   - tool: The tool name used in error messages.
+//   - arguments: The raw JSON arguments generated by the model.
+//   - schema: The declared JSON schema, validation is skipped when nil.
+//
+// Returns:
+//   - interface{}: The decoded arguments.
+//   - error: A *ToolArgumentError if decoding or validation fails.
+func validateToolArguments(tool, arguments string, schema map[string]interface{}) (interface{}, error) {
+	var params interface{}
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	if err := json.Unmarshal([]byte(arguments), &params); err != nil {
+		return nil, &ToolArgumentError{Tool: tool, Reason: "arguments are not valid JSON: " + err.Error()}
+	}
+	if schema != nil {
+		if err := validateJSONSchema(schema, params, "$"); err != nil {
+			return nil, &ToolArgumentError{Tool: tool, Reason: err.Error()}
+		}
+	}
+	return params, nil
+}
+
+// validateJSONSchema validates a decoded JSON value against the subset of JSON schema used for
+// tool definitions: type, properties, required, additionalProperties, items and enum.
+func validateJSONSchema(schema map[string]interface{}, value interface{}, path string) error {
+	if schemaType, ok := schema["type"]; ok {
+		if !matchesSchemaType(schemaType, value) {
+			return fmt.Errorf("%s must be of type %v", path, schemaType)
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s must be one of %v", path, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				key := fmt.Sprintf("%v", name)
+				if _, exists := v[key]; !exists {
+					return fmt.Errorf("%s.%s is required", path, key)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			propertySchema, declared := properties[key].(map[string]interface{})
+			if !declared {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s.%s is not allowed", path, key)
+				}
+				continue
+			}
+			if err := validateJSONSchema(propertySchema, v[key], path+"."+key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for idx, item := range v {
+				if err := validateJSONSchema(items, item, fmt.Sprintf("%s[%d]", path, idx)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matchesSchemaType reports whether value matches a JSON schema "type" which may be a string or a list.
+func matchesSchemaType(schemaType interface{}, value interface{}) bool {
+	switch t := schemaType.(type) {
+	case string:
+		return matchesSingleSchemaType(t, value)
+	case []interface{}:
+		for _, item := range t {
+			if name, ok := item.(string); ok && matchesSingleSchemaType(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesSingleSchemaType(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
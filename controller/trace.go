@@ -0,0 +1,458 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrTraceNotFound is returned by GetTrace when no trace is stored for an ID.
+var ErrTraceNotFound = errors.New("trace not found")
+
+// TraceChunk is a chunk retrieved for a traced call.
+//
+// Fields:
+//   - ID: The key of the chunk.
+//   - DocumentID: The Id of the embedded content the chunk belongs to.
+//   - Score: The retrieval score of the chunk, the cosine distance for similarity and KNN searches (lower is closer).
+//   - SearchType: The search that found the chunk in a hybrid search: "vector", "lexical" or "hybrid".
+//   - Cited: True if the answer cited the document of the chunk, only known with WithRagReferences.
+type TraceChunk struct {
+	ID         string  `json:"id"`
+	DocumentID string  `json:"documentId,omitempty"`
+	Score      float32 `json:"score"`
+	SearchType string  `json:"searchType,omitempty"`
+	Cited      bool    `json:"cited,omitempty"`
+}
+
+// Trace records how an AskLLM call produced its answer, for postmortem debugging of bad answers.
+//
+// Unlike AuditRecord, a trace keeps the answer itself.
+//
+// Fields:
+//   - ID: The trace ID, generated for every call.
+//   - RequestID: The request ID of the call, set with WithRequestID or generated. Callers choose it, so it may be
+//     shared by several traces.
+//   - TenantID: The tenant set with WithTenant.
+//   - SessionID: The session of the call.
+//   - Query: The user query.
+//   - RetrievalQuery: The text used for retrieval, after memory, attachments and the BeforeRetrieve hook.
+//   - RetrievalPrefix: The key prefix of the searched chunks, the prefix tuned by TuneRetrieval, comma separated when several indexes are searched.
+//   - SearchAlgorithm: The readable name of the search algorithm.
+//   - Chunks: The retrieved chunks with their scores, in retrieval order.
+//   - PromptHash: SHA-256 hash of the messages sent to the model.
+//   - Answer: The final answer.
+//   - FailedToRespond: True if the model couldn't answer from the documents.
+//   - Confidence: The estimated confidence of the answer.
+//   - TokenReport: The tokens used by the call.
+//   - Timings: Latency breakdown of the call.
+//   - Error: The error of the call, empty on success.
+//   - TimeStamp: The time the call started.
+type Trace struct {
+	ID              string       `json:"id"`
+	RequestID       string       `json:"requestId,omitempty"`
+	TenantID        string       `json:"tenantId,omitempty"`
+	SessionID       string       `json:"sessionId"`
+	Query           string       `json:"query"`
+	RetrievalQuery  string       `json:"retrievalQuery,omitempty"`
+	RetrievalPrefix string       `json:"retrievalPrefix,omitempty"`
+	SearchAlgorithm string       `json:"searchAlgorithm,omitempty"`
+	Chunks          []TraceChunk `json:"chunks"`
+	PromptHash      string       `json:"promptHash,omitempty"`
+	Answer          string       `json:"answer"`
+	FailedToRespond bool         `json:"failedToRespond"`
+	Confidence      *Confidence  `json:"confidence,omitempty"`
+	TokenReport     TokenReport  `json:"tokenReport"`
+	Timings         LLMTimings   `json:"timings"`
+	Error           string       `json:"error,omitempty"`
+	TimeStamp       time.Time    `json:"timestamp"`
+}
+
+// TraceStore persists the traces of AskLLM calls and the feedback of users on their answers.
+//
+// Implementations must be safe for concurrent use.
+type TraceStore interface {
+	// SaveTrace persists a trace.
+	SaveTrace(ctx context.Context, trace Trace) error
+	// GetTrace loads a trace, it returns ErrTraceNotFound for unknown IDs.
+	GetTrace(ctx context.Context, id string) (Trace, error)
+	// SaveFeedback persists the feedback on a traced answer.
+	SaveFeedback(ctx context.Context, feedback Feedback) error
+	// ListFeedback returns the feedback submitted since a time, oldest first.
+	ListFeedback(ctx context.Context, since time.Time) ([]Feedback, error)
+}
+
+// RedisTraceStore stores traces as JSON strings in Redis.
+//
+// Fields:
+//   - Prefix: The key prefix of the traces.
+//   - TTL: How long traces are kept, 0 keeps them forever.
+type RedisTraceStore struct {
+	Prefix string
+	TTL    time.Duration
+	client *RedisClient
+}
+
+// NewRedisTraceStore creates a trace store backed by the container's Redis connection.
+//
+// The store uses the connection opened by Init, so it can be created before Init is called.
+//
+// Parameters:
+//   - ttl: How long traces are kept, 0 keeps them forever.
+//
+// Returns:
+//   - *RedisTraceStore: The Redis backed store, keys are prefixed with "aillm:trace:".
+func (llm *LLMContainer) NewRedisTraceStore(ttl time.Duration) *RedisTraceStore {
+	return &RedisTraceStore{
+		Prefix: "aillm:trace:",
+		TTL:    ttl,
+		client: &llm.RedisClient,
+	}
+}
+
+func (rs *RedisTraceStore) redisClient() (*redis.Client, error) {
+	if rs.client == nil || rs.client.redisClient == nil {
+		return nil, fmt.Errorf("redis trace store is not initialized")
+	}
+	return rs.client.redisClient, nil
+}
+
+// SaveTrace stores the trace under its ID.
+func (rs *RedisTraceStore) SaveTrace(ctx context.Context, trace Trace) error {
+	rdb, err := rs.redisClient()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(trace)
+	if err != nil {
+		return err
+	}
+	sessionKey := rs.sessionKey(trace.TenantID, trace.SessionID)
+	pipe := rdb.TxPipeline()
+	pipe.Set(ctx, rs.Prefix+trace.ID, data, rs.TTL)
+	pipe.SAdd(ctx, sessionKey, trace.ID)
+	pipe.ZAdd(ctx, rs.timeKey(), redis.Z{Score: float64(trace.TimeStamp.UnixMilli()), Member: trace.ID})
+	if rs.TTL > 0 {
+		pipe.Expire(ctx, sessionKey, rs.TTL)
+		// Traces expired by their TTL leave the time index
+		pipe.ZRemRangeByScore(ctx, rs.timeKey(), "-inf", "("+strconv.FormatInt(time.Now().Add(-rs.TTL).UnixMilli(), 10))
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// sessionKey returns the key of the set of the trace IDs of a session, see PurgeTraces.
+func (rs *RedisTraceStore) sessionKey(tenantID, sessionID string) string {
+	return rs.Prefix + "session:" + TenantPrefix(tenantID, sessionID)
+}
+
+// timeKey returns the key of the trace IDs sorted by time, see ExpireTraces.
+func (rs *RedisTraceStore) timeKey() string {
+	return rs.Prefix + "byTime"
+}
+
+// PurgeTraces deletes the traces of a session.
+func (rs *RedisTraceStore) PurgeTraces(ctx context.Context, tenantID, sessionID string) (int, error) {
+	rdb, err := rs.redisClient()
+	if err != nil {
+		return 0, err
+	}
+	sessionKey := rs.sessionKey(tenantID, sessionID)
+	ids, err := rdb.SMembers(ctx, sessionKey).Result()
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	keys, members := make([]string, len(ids)), make([]any, len(ids))
+	for idx, id := range ids {
+		keys[idx], members[idx] = rs.Prefix+id, id
+	}
+	pipe := rdb.TxPipeline()
+	deleted := pipe.Del(ctx, keys...)
+	pipe.ZRem(ctx, rs.timeKey(), members...)
+	pipe.Del(ctx, sessionKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(deleted.Val()), nil
+}
+
+// ExpireTraces deletes the traces older than a time.
+func (rs *RedisTraceStore) ExpireTraces(ctx context.Context, before time.Time) (int, error) {
+	rdb, err := rs.redisClient()
+	if err != nil {
+		return 0, err
+	}
+	maxScore := "(" + strconv.FormatInt(before.UnixMilli(), 10)
+	ids, err := rdb.ZRangeByScore(ctx, rs.timeKey(), &redis.ZRangeBy{Min: "-inf", Max: maxScore}).Result()
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	keys := make([]string, len(ids))
+	for idx, id := range ids {
+		keys[idx] = rs.Prefix + id
+	}
+	deleted, err := unlinkKeys(ctx, rdb, keys)
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, rdb.ZRemRangeByScore(ctx, rs.timeKey(), "-inf", maxScore).Err()
+}
+
+// GetTrace loads the trace of an ID.
+func (rs *RedisTraceStore) GetTrace(ctx context.Context, id string) (Trace, error) {
+	trace := Trace{}
+	rdb, err := rs.redisClient()
+	if err != nil {
+		return trace, err
+	}
+	data, err := rdb.Get(ctx, rs.Prefix+id).Bytes()
+	if err == redis.Nil {
+		return trace, ErrTraceNotFound
+	}
+	if err != nil {
+		return trace, err
+	}
+	return trace, json.Unmarshal(data, &trace)
+}
+
+// sqlIdentifierPattern restricts table names, they can't be passed as query parameters.
+var sqlIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// SQLTraceStore stores traces as JSON in a SQL table, e.g. for long term analysis next to other application data.
+//
+// The driver is chosen by the caller when opening DB. CreateTable creates the table with portable column types.
+//
+// Fields:
+//   - DB: The database.
+//   - Table: The table name, defaults to "aillm_traces".
+//   - NumberedPlaceholders: Uses $1 style placeholders (PostgreSQL) instead of ?.
+type SQLTraceStore struct {
+	DB                   *sql.DB
+	Table                string
+	NumberedPlaceholders bool
+}
+
+func (ss *SQLTraceStore) table() (string, error) {
+	if ss.Table == "" {
+		return "aillm_traces", nil
+	}
+	if !sqlIdentifierPattern.MatchString(ss.Table) {
+		return "", fmt.Errorf("invalid trace table name %q", ss.Table)
+	}
+	return ss.Table, nil
+}
+
+func (ss *SQLTraceStore) placeholder(n int) string {
+	if ss.NumberedPlaceholders {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// CreateTable creates the trace table if it doesn't exist.
+func (ss *SQLTraceStore) CreateTable(ctx context.Context) error {
+	table, err := ss.table()
+	if err != nil {
+		return err
+	}
+	_, err = ss.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+table+
+		" (id VARCHAR(128) PRIMARY KEY, session_id VARCHAR(255), created_at TIMESTAMP, trace TEXT)")
+	return err
+}
+
+// SaveTrace inserts the trace.
+func (ss *SQLTraceStore) SaveTrace(ctx context.Context, trace Trace) error {
+	table, err := ss.table()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(trace)
+	if err != nil {
+		return err
+	}
+	_, err = ss.DB.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (id, session_id, created_at, trace) VALUES (%s, %s, %s, %s)",
+		table, ss.placeholder(1), ss.placeholder(2), ss.placeholder(3), ss.placeholder(4)),
+		trace.ID, trace.SessionID, trace.TimeStamp.UTC(), string(data))
+	return err
+}
+
+// GetTrace loads the trace of an ID.
+func (ss *SQLTraceStore) GetTrace(ctx context.Context, id string) (Trace, error) {
+	trace := Trace{}
+	table, err := ss.table()
+	if err != nil {
+		return trace, err
+	}
+	var data string
+	err = ss.DB.QueryRowContext(ctx, "SELECT trace FROM "+table+" WHERE id = "+ss.placeholder(1), id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return trace, ErrTraceNotFound
+	}
+	if err != nil {
+		return trace, err
+	}
+	return trace, json.Unmarshal([]byte(data), &trace)
+}
+
+// PurgeTraces deletes the traces of a session.
+func (ss *SQLTraceStore) PurgeTraces(ctx context.Context, tenantID, sessionID string) (int, error) {
+	table, err := ss.table()
+	if err != nil {
+		return 0, err
+	}
+	rows, err := ss.DB.QueryContext(ctx, "SELECT id, trace FROM "+table+" WHERE session_id = "+ss.placeholder(1), sessionID)
+	if err != nil {
+		return 0, err
+	}
+	// The table has no tenant column, the tenant is read from the traces
+	ids := []string{}
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		trace := Trace{}
+		if json.Unmarshal([]byte(data), &trace) == nil && trace.TenantID == tenantID {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, id := range ids {
+		result, err := ss.DB.ExecContext(ctx, "DELETE FROM "+table+" WHERE id = "+ss.placeholder(1), id)
+		if err != nil {
+			return deleted, err
+		}
+		count, _ := result.RowsAffected()
+		deleted += int(count)
+	}
+	return deleted, nil
+}
+
+// ExpireTraces deletes the traces older than a time.
+func (ss *SQLTraceStore) ExpireTraces(ctx context.Context, before time.Time) (int, error) {
+	table, err := ss.table()
+	if err != nil {
+		return 0, err
+	}
+	result, err := ss.DB.ExecContext(ctx, "DELETE FROM "+table+" WHERE created_at < "+ss.placeholder(1), before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	return int(count), err
+}
+
+// GetTrace returns the trace of an AskLLM call persisted in the TraceStore.
+//
+// Parameters:
+//   - id: The TraceID of the LLMResult.
+//
+// Returns:
+//   - Trace: The trace of the call.
+//   - error: ErrTraceNotFound for unknown IDs, or an error if no TraceStore is configured.
+//
+// Example Usage:
+//
+//	result, _ := llm.AskLLM("What is the warranty period?")
+//	trace, err := llm.GetTrace(result.TraceID)
+//	for _, chunk := range trace.Chunks {
+//		fmt.Println(chunk.ID, chunk.Score)
+//	}
+func (llm *LLMContainer) GetTrace(id string) (Trace, error) {
+	if llm.TraceStore == nil {
+		return Trace{}, fmt.Errorf("trace store is not configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), llm.Timeouts.redisTimeout())
+	defer cancel()
+	return llm.TraceStore.GetTrace(ctx, id)
+}
+
+// saveTrace persists the trace of an AskLLM call to the TraceStore and sets the TraceID of the result.
+//
+// Failures of the store never fail the query; they are reported as warnings.
+func (llm *LLMContainer) saveTrace(Query string, start time.Time, result *LLMResult, queryErr error, options []LLMCallOption) {
+	if llm.TraceStore == nil {
+		return
+	}
+	o := LLMCallOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+	// The request ID comes from the caller, it can't identify the trace
+	trace := Trace{
+		ID:              uuid.New().String(),
+		RequestID:       result.RequestID,
+		TenantID:        o.tenantID,
+		SessionID:       o.SessionID,
+		Query:           Query,
+		RetrievalQuery:  result.retrievalQuery,
+		RetrievalPrefix: result.retrievalPrefix,
+		Chunks:          []TraceChunk{},
+		FailedToRespond: result.FailedToRespond,
+		Confidence:      result.Confidence,
+		TokenReport:     result.TokenReport,
+		Timings:         result.Timings,
+		TimeStamp:       start,
+	}
+	if result.searchAlgorithm != NotDefinedSearch {
+		trace.SearchAlgorithm = result.searchAlgorithm.String()
+	}
+	cited := map[string]bool{}
+	for _, citation := range result.Citations {
+		cited[citation.ID] = citation.Verified
+	}
+	for _, doc := range result.RagDocs {
+		chunk := TraceChunk{
+			ID:         llm.getDocumentID(doc.Document()),
+			DocumentID: doc.DocumentID,
+			Score:      doc.Score,
+		}
+		chunk.SearchType, _ = doc.Metadata["search_type"].(string)
+		chunk.Cited = chunk.DocumentID != "" && cited[chunk.DocumentID]
+		trace.Chunks = append(trace.Chunks, chunk)
+	}
+	if len(result.Prompt) > 0 {
+		if prompt, err := json.Marshal(result.Prompt); err == nil {
+			trace.PromptHash = sha256Hex(string(prompt))
+		}
+	}
+	if result.Response != nil && len(result.Response.Choices) > 0 {
+		trace.Answer = result.Response.Choices[0].Content
+	}
+	if queryErr != nil {
+		trace.Error = queryErr.Error()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), llm.Timeouts.redisTimeout())
+	defer cancel()
+	if err := llm.TraceStore.SaveTrace(ctx, trace); err != nil {
+		llm.requestLogger(result.RequestID).Warn("unable to save trace", "error", err)
+		return
+	}
+	result.TraceID = trace.ID
+}
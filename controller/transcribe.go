@@ -19,12 +19,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -45,12 +47,18 @@ import (
 //   - initialized: A boolean indicating if the transcriber has been initialized successfully.
 //   - TempFolder: The folder where temporary files will be stored during processing (Downloading / Transcribing).
 //   - folderSep: The file path separator used for compatibility across operating systems.
+//   - PDFRenderer: The pdftoppm (poppler-utils) executable used to render PDF pages for vision OCR.
+//   - Logger: Structured logger used for progress and error messages.
+//   - Timeout: Processing timeout of documents without TranscribeConfig.MaxTimeout, set from LLMContainer.Timeouts by Init.
 type Transcriber struct {
-	MaxPageLimit uint   // Maximum number of pages allowed for processing
-	TikaURL      string // URL of the Apache Tika service for text extraction
-	initialized  bool   // Indicates if the transcriber is initialized
-	TempFolder   string // Path to the temporary folder for storing transcribed files
-	folderSep    string // File separator ("/" for Linux, "\" for Windows)
+	MaxPageLimit uint          // Maximum number of pages allowed for processing
+	TikaURL      string        // URL of the Apache Tika service for text extraction
+	initialized  bool          // Indicates if the transcriber is initialized
+	TempFolder   string        // Path to the temporary folder for storing transcribed files
+	folderSep    string        // File separator ("/" for Linux, "\" for Windows)
+	PDFRenderer  string        // Path of the pdftoppm executable, default "pdftoppm"
+	Logger       *slog.Logger  // Logger, defaults to the LLMContainer logger
+	Timeout      time.Duration // Default processing timeout (default 1m)
 }
 
 // TranscribeConfig provides configuration settings for document transcription.
@@ -64,6 +72,9 @@ type Transcriber struct {
 //   - OCROnly: A flag to indicate whether to perform only Optical Character Recognition (OCR).
 //   - ExtractInlineImages: A flag to extract text from inline images within the document.
 //   - MaxTimeout: The maximum allowed duration for document processing.
+//   - UseVisionOCR: Render PDF pages to images and transcribe them with the VisionClient instead of Tika.
+//   - VisionOCRDPI: Resolution used to render PDF pages for vision OCR (default 150).
+//   - ExtractTables: Requests HTML from Tika, so the tables of office documents and spreadsheets are kept as tables, see Table.
 
 type TranscribeConfig struct {
 	TikaLanguage        string        //PDF ONLY, OCR language code (refer to Tesseract OCR languages) can be found @ https://github.com/tesseract-ocr/tessdata/
@@ -71,6 +82,11 @@ type TranscribeConfig struct {
 	OCROnly             bool          // Perform OCR only, ignoring non-image text
 	ExtractInlineImages bool          // Enable extraction of text from inline images
 	MaxTimeout          time.Duration // Maximum processing time before timeout
+	UseVisionOCR        bool          // PDF ONLY, transcribe rendered pages with the vision model (useful for scanned documents)
+	VisionOCRDPI        int           // Rendering resolution for vision OCR
+	ExtractTables       bool          // Keep the tables of Tika documents as tables instead of flattened text
+
+	visionOCR func(imageData []byte) (string, error) // Set by the LLMContainer when UseVisionOCR is enabled
 }
 
 // init initializes the Transcriber instance by setting default values and preparing the environment.
@@ -94,7 +110,7 @@ func (Ts *Transcriber) init() error {
 		if Ts.TempFolder == "" {
 			exePath, err := os.Executable()
 			if err != nil {
-				fmt.Println("error fetching application folder:", err)
+				Ts.logger().Error("error fetching application folder", "error", err)
 				return err
 			}
 			Ts.TempFolder = filepath.Dir(exePath) + Ts.folderSep + "tmp"
@@ -107,7 +123,8 @@ func (Ts *Transcriber) init() error {
 // transcribeURL downloads and processes content from a given URL.
 //
 // This function downloads the content from the URL, detects the MIME type, and extracts text
-// based on file type (PDF, HTML, etc.).
+// based on file type (PDF, HTML, etc.). Registered DocumentLoaders handle their URL schemes
+// without downloading and their MIME types before the built-in types.
 //
 // Parameters:
 //   - inputURL: The URL of the document to be transcribed.
@@ -119,11 +136,17 @@ func (Ts *Transcriber) init() error {
 //   - error: An error if the transcription fails.
 func (Ts *Transcriber) TranscribeURL(inputURL string, tc TranscribeConfig) (string, int, error) {
 	Ts.init()
-	log.Println("Downloading " + inputURL + "...")
+	if loader := schemeLoader(inputURL); loader != nil {
+		return Ts.load(loader, inputURL, tc)
+	}
+	Ts.logger().Info("downloading", "url", inputURL)
 	fileContents, mimeType, fileName, _, fetchErr := Ts.downloadPage(inputURL)
 	if fetchErr != nil {
 		return "", 0, fetchErr
 	}
+	if loader := mimeTypeLoader(mimeType); loader != nil {
+		return Ts.load(loader, fileName, tc)
+	}
 	switch {
 	case strings.Contains(mimeType, "application/pdf"):
 		return Ts.getPDFContents(tc, fileName)
@@ -138,7 +161,8 @@ func (Ts *Transcriber) TranscribeURL(inputURL string, tc TranscribeConfig) (stri
 // transcribeFile processes a local file and extracts text based on its MIME type.
 //
 // The function detects the MIME type if not provided, and processes the file accordingly
-// (e.g., using OCR for PDFs or parsing plain text files).
+// (e.g., using OCR for PDFs or parsing plain text files). Registered DocumentLoaders are used
+// first for their schemes and MIME types.
 //
 // Parameters:
 //   - fileName: The path to the file to be transcribed.
@@ -151,6 +175,9 @@ func (Ts *Transcriber) TranscribeURL(inputURL string, tc TranscribeConfig) (stri
 //   - error: An error if the transcription fails.
 func (Ts *Transcriber) transcribeFile(fileName, mimeType string, tc TranscribeConfig) (string, int, error) {
 	Ts.init()
+	if loader := schemeLoader(fileName); loader != nil {
+		return Ts.load(loader, fileName, tc)
+	}
 	if mimeType == "" {
 		detectedMimeType, mimedetectionErr := mimetype.DetectFile(fileName)
 		if mimedetectionErr != nil {
@@ -160,6 +187,9 @@ func (Ts *Transcriber) transcribeFile(fileName, mimeType string, tc TranscribeCo
 			mimeType = detectedMimeType.String()
 		}
 	}
+	if loader := mimeTypeLoader(mimeType); loader != nil {
+		return Ts.load(loader, fileName, tc)
+	}
 	switch {
 	case strings.Contains(mimeType, "application/pdf"):
 		return Ts.getPDFContents(tc, fileName)
@@ -265,6 +295,9 @@ func (Ts *Transcriber) getContentsFromTika(tc TranscribeConfig, inputPath string
 	pageCount := -1
 
 	header := http.Header{"Accept": []string{"text/plain"}}
+	if tc.ExtractTables {
+		header.Set("Accept", "text/html")
+	}
 	//
 	if tc.Language != "" {
 		header.Add("X-Tika-OCRLanguage", tc.TikaLanguage)
@@ -276,24 +309,34 @@ func (Ts *Transcriber) getContentsFromTika(tc TranscribeConfig, inputPath string
 		header.Add("X-Tika-PDFextractInlineImages", "true")
 	}
 
-	timeout := int64(tc.MaxTimeout) / 1000000
-	if timeout == 0 {
-		tc.MaxTimeout = 1 * time.Minute
-		timeout = int64(tc.MaxTimeout) / 1000000
+	if tc.MaxTimeout == 0 {
+		tc.MaxTimeout = Ts.defaultTimeout()
 	}
-	header.Add("X-Tika-Timeout-Millis", fmt.Sprintf("%d", timeout))
+	header.Add("X-Tika-Timeout-Millis", fmt.Sprintf("%d", tc.MaxTimeout.Milliseconds()))
 
-	ioReadCloser, err := client.ParseReaderWithHeader(context.Background(), f, header)
+	// Tika stops processing at the timeout, the extra time covers the upload and the reply of a stuck server
+	ctx, cancel := context.WithTimeout(context.Background(), tc.MaxTimeout+5*time.Second)
+	defer cancel()
+	ioReadCloser, err := client.ParseReaderWithHeader(ctx, f, header)
 	if err != nil {
 		return "", pageCount, err
 	}
 	buf := new(strings.Builder)
 	io.Copy(buf, ioReadCloser)
 	result := buf.String()
-	result = Ts.cleanupText(result, false)
+	// The HTML of Tika is reduced to text and Markdown tables
+	result = Ts.cleanupText(result, tc.ExtractTables)
 	return result, pageCount, nil
 }
 
+// defaultTimeout returns the processing timeout of documents without TranscribeConfig.MaxTimeout.
+func (Ts *Transcriber) defaultTimeout() time.Duration {
+	if Ts.Timeout > 0 {
+		return Ts.Timeout
+	}
+	return time.Minute
+}
+
 // getPDFContents extracts text content from a PDF file.
 //
 // This function first checks the number of pages in the PDF and then processes it using
@@ -323,11 +366,83 @@ func (Ts *Transcriber) getPDFContents(tc TranscribeConfig, inputPath string) (st
 		return "", pageCount, errors.New("PDF file has more than " + fmt.Sprintf("%d", Ts.MaxPageLimit) + " pages")
 	}
 
+	if tc.UseVisionOCR {
+		result, err = Ts.getPDFContentsWithVision(tc, inputPath)
+		return result, pageCount, err
+	}
+
 	result, pageCount, err = Ts.getContentsFromTika(tc, inputPath)
 	return result, pageCount, err
 
 }
 
+// getPDFContentsWithVision renders every page of a PDF file to an image and transcribes it
+// with the vision model.
+//
+// Pages are rendered with pdftoppm (poppler-utils), which must be installed on the host.
+//
+// Parameters:
+//   - tc: Transcription configuration settings, including the vision transcription function.
+//   - inputPath: The file path of the PDF document to be processed.
+//
+// Returns:
+//   - string: The transcribed text of all pages in order.
+//   - error: An error if rendering or transcription fails.
+func (Ts *Transcriber) getPDFContentsWithVision(tc TranscribeConfig, inputPath string) (string, error) {
+	if tc.visionOCR == nil {
+		return "", errors.New("vision OCR requires a vision client")
+	}
+	renderer := Ts.PDFRenderer
+	if renderer == "" {
+		renderer = "pdftoppm"
+	}
+	dpi := tc.VisionOCRDPI
+	if dpi == 0 {
+		dpi = 150
+	}
+	timeout := tc.MaxTimeout
+	if timeout == 0 {
+		timeout = Ts.defaultTimeout()
+	}
+
+	if err := os.MkdirAll(Ts.TempFolder, os.ModePerm); err != nil {
+		return "", errors.New("error creating temp folder")
+	}
+	pagesFolder, err := os.MkdirTemp(Ts.TempFolder, "visionocr")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(pagesFolder)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, renderer, "-r", fmt.Sprintf("%d", dpi), "-png", inputPath, pagesFolder+Ts.folderSep+"page")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("error rendering PDF pages: %v %s", err, strings.TrimSpace(string(output)))
+	}
+
+	// pdftoppm pads page numbers to the same width, so lexical order is page order
+	pages, err := filepath.Glob(pagesFolder + Ts.folderSep + "page-*.png")
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(pages)
+
+	var output strings.Builder
+	for _, page := range pages {
+		pageContents, err := os.ReadFile(page)
+		if err != nil {
+			return "", err
+		}
+		text, err := tc.visionOCR(pageContents)
+		if err != nil {
+			return "", err
+		}
+		output.WriteString(text + "\n")
+	}
+	return Ts.cleanupText(output.String(), false), nil
+}
+
 /*** Tools ***/
 
 // cleanupText removes unnecessary whitespace, special characters, and formatting inconsistencies
@@ -422,38 +537,25 @@ func (Ts Transcriber) extractHTMLContent(htmlBytes []byte) string {
 		output.WriteString(strings.TrimSpace(title) + "\n")
 	}
 
-	// Extract text from headings and paragraphs
-	doc.Find("h1, h2, h3, h4, h5, h6, p").Each(func(i int, s *goquery.Selection) {
+	// Extract text from headings and paragraphs, paragraphs of table cells are part of their table
+	doc.Find("h1, h2, h3, h4, h5, h6, p").FilterFunction(func(i int, s *goquery.Selection) bool {
+		return s.Closest("table").Length() == 0
+	}).Each(func(i int, s *goquery.Selection) {
 		text := strings.TrimSpace(s.Text())
 		if text != "" {
 			output.WriteString(text + "\n")
 		}
 	})
 
-	// Extract tables
+	// Extract tables as Markdown, EmbeddText stores them as structured tables
 	doc.Find("table").Each(func(i int, table *goquery.Selection) {
-		output.WriteString("[Table Start]\n")
-
-		// Extract headers
-		var headers []string
-		table.Find("th").Each(func(j int, header *goquery.Selection) {
-			headers = append(headers, strings.TrimSpace(header.Text()))
-		})
-		output.WriteString(strings.Join(headers, " | ") + " | \n")
-
-		// Extract rows
-		table.Find("tr").Each(func(j int, row *goquery.Selection) {
-			var rowData []string
-			row.Find("td").Each(func(k int, cell *goquery.Selection) {
-				rowData = append(rowData, strings.TrimSpace(cell.Text()))
-			})
-
-			if len(rowData) > 0 {
-				output.WriteString(strings.Join(rowData, " | ") + " | \n")
-			}
-		})
-
-		output.WriteString("[Table End]\n")
+		if table.ParentsFiltered("table").Length() > 0 {
+			// Nested tables are part of the cells of their parent
+			return
+		}
+		output.WriteString(tableStartMarker + "\n")
+		output.WriteString(htmlTable(table).Markdown())
+		output.WriteString(tableEndMarker + "\n")
 	})
 
 	return output.String()
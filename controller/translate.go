@@ -0,0 +1,237 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	translationChunkSize   = 4000 // Maximum bytes translated by a single call
+	translationConcurrency = 4    // Maximum parallel translation calls of a text
+)
+
+// translatePrompt translates a chunk of text.
+const translatePrompt = `Translate the text below into {{language}}.
+- Keep the formatting, Markdown, numbers, URLs, code and placeholders unchanged.
+- Reply with the translation only, without notes or quotes.
+{{glossary}}
+### Text:
+{{text}}`
+
+// Translation is the result of Translate.
+//
+// Fields:
+//   - Text: The translated text.
+//   - Language: The target language.
+//   - Chunks: The number of chunks the text was translated in.
+//   - TokenUsage: Tokens used by the translation.
+type Translation struct {
+	Text       string     `json:"text"`
+	Language   string     `json:"language"`
+	Chunks     int        `json:"chunks"`
+	TokenUsage TokenUsage `json:"tokenUsage"`
+}
+
+// WithGlossary sets the approved translations of terms, e.g. product names that must not be translated.
+//
+// Parameters:
+//   - glossary: The approved translation of each term, a term mapped to itself is kept as is.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the glossary.
+func (llm *LLMContainer) WithGlossary(glossary map[string]string) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.glossary = glossary
+	}
+}
+
+// glossaryPrompt lists the glossary terms found in a text, sorted so prompts are stable.
+func glossaryPrompt(glossary map[string]string, text string) string {
+	lowerText := strings.ToLower(text)
+	terms := []string{}
+	for term := range glossary {
+		if term != "" && strings.Contains(lowerText, strings.ToLower(term)) {
+			terms = append(terms, term)
+		}
+	}
+	if len(terms) == 0 {
+		return ""
+	}
+	sort.Strings(terms)
+	var prompt strings.Builder
+	prompt.WriteString("- Always translate these terms as given:\n")
+	for _, term := range terms {
+		prompt.WriteString("  - " + term + " → " + glossary[term] + "\n")
+	}
+	return prompt.String()
+}
+
+// splitForTranslation splits a text into chunks of at most limit bytes, at line ends, sentence ends or, for
+// longer sentences, between characters. The chunks joined are the text.
+func splitForTranslation(text string, limit int) []string {
+	segments := []string{}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if len(line) <= limit {
+			segments = append(segments, line)
+			continue
+		}
+		last := 0
+		for _, loc := range sentenceEnd.FindAllStringIndex(line, -1) {
+			segments = append(segments, line[last:loc[1]])
+			last = loc[1]
+		}
+		segments = append(segments, line[last:])
+	}
+
+	chunks := []string{}
+	var chunk strings.Builder
+	add := func(segment string) {
+		if chunk.Len() > 0 && chunk.Len()+len(segment) > limit {
+			chunks = append(chunks, chunk.String())
+			chunk.Reset()
+		}
+		chunk.WriteString(segment)
+	}
+	for _, segment := range segments {
+		for len(segment) > limit {
+			// Cut sentences longer than the limit at a character boundary
+			cut := limit
+			for cut > 0 && !utf8.RuneStart(segment[cut]) {
+				cut--
+			}
+			add(segment[:cut])
+			segment = segment[cut:]
+		}
+		if segment != "" {
+			add(segment)
+		}
+	}
+	if chunk.Len() > 0 {
+		chunks = append(chunks, chunk.String())
+	}
+	return chunks
+}
+
+// Translate translates a text with the LLMClient, long texts are translated in chunks in parallel.
+//
+// Parameters:
+//   - text: The text to translate.
+//   - targetLanguage: The language to translate to, e.g. "German".
+//   - options: WithGlossary, WithCustomModel, WithSeed and WithTimeout apply to the translation, and the glossary of
+//     WithEmbeddingPrefix, see SetGlossary.
+//
+// Returns:
+//   - Translation: The translated text.
+//   - error: An error if a chunk can't be translated.
+//
+// Example Usage:
+//
+//	translation, err := llm.Translate(article, "Portuguese", llm.WithGlossary(map[string]string{"aillm": "aillm"}))
+//	fmt.Println(translation.Text)
+func (llm *LLMContainer) Translate(text, targetLanguage string, options ...LLMCallOption) (Translation, error) {
+	result := Translation{Language: targetLanguage}
+	if !llm.lifecycle.begin() {
+		return result, ErrShutdown
+	}
+	defer llm.lifecycle.end()
+	if targetLanguage == "" {
+		return result, errors.New("missing target language")
+	}
+	if strings.TrimSpace(text) == "" {
+		result.Text = text
+		return result, nil
+	}
+	o := LLMCallOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+	ctx, cancel := llm.requestContext(contextWithRequestID(context.Background(), o.requestID), &o)
+	defer cancel()
+	return llm.translate(ctx, text, targetLanguage, o)
+}
+
+// translate translates a text in chunks, see Translate.
+func (llm *LLMContainer) translate(ctx context.Context, text, targetLanguage string, o LLMCallOptions) (Translation, error) {
+	result := Translation{Language: targetLanguage}
+	model, err := llm.newLLMClient(llm.LLMClient, nil)
+	if err != nil {
+		return result, err
+	}
+
+	glossary := llm.translationGlossary(&o, targetLanguage)
+	chunks := splitForTranslation(text, translationChunkSize)
+	translated := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	var outputTokens atomic.Int64
+	semaphore := make(chan struct{}, translationConcurrency)
+	var wait sync.WaitGroup
+	for i, chunk := range chunks {
+		// Whitespace around a chunk is kept as is, the model trims it
+		trimmed := strings.TrimSpace(chunk)
+		if trimmed == "" {
+			translated[i] = chunk
+			continue
+		}
+		wait.Add(1)
+		go func(i int, chunk, trimmed string) {
+			defer wait.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			prompt := strings.NewReplacer("{{language}}", targetLanguage, "{{glossary}}", glossaryPrompt(glossary, trimmed),
+				"{{text}}", trimmed).Replace(translatePrompt)
+			callOptions := []llms.CallOption{
+				llms.WithTemperature(0),
+				llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+					outputTokens.Add(1)
+					return nil
+				}),
+			}
+			if o.customModel != "" {
+				callOptions = append(callOptions, llms.WithModel(o.customModel))
+			}
+			if o.seed != nil {
+				callOptions = append(callOptions, llms.WithSeed(*o.seed))
+			}
+			response, err := model.GenerateContent(ctx, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}, callOptions...)
+			if err != nil {
+				errs[i] = deadlineError(ctx, err)
+				return
+			}
+			if len(response.Choices) == 0 {
+				errs[i] = errors.New("empty translation response")
+				return
+			}
+			leading := chunk[:len(chunk)-len(strings.TrimLeft(chunk, " \t\r\n"))]
+			trailing := chunk[len(strings.TrimRight(chunk, " \t\r\n")):]
+			translated[i] = leading + strings.TrimSpace(response.Choices[0].Content) + trailing
+		}(i, chunk, trimmed)
+	}
+	wait.Wait()
+	result.TokenUsage.OutputTokens = int(outputTokens.Load())
+	result.Chunks = len(chunks)
+	if err := errors.Join(errs...); err != nil {
+		return result, err
+	}
+	result.Text = strings.Join(translated, "")
+	return result, nil
+}
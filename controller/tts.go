@@ -0,0 +1,245 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TTSClient defines an interface for text-to-speech engines.
+//
+// Methods:
+//   - Synthesize(): Converts text to audio and returns the encoded audio (e.g., mp3 or wav).
+type TTSClient interface {
+	Synthesize(ctx context.Context, text string) ([]byte, error)
+}
+
+// OpenAITTSController converts text to speech using an OpenAI compatible /audio/speech endpoint.
+//
+// Fields:
+//   - Config: API endpoint (e.g., "https://api.openai.com/v1"), model name (e.g., "tts-1") and token.
+//   - Voice: The voice to use, default "alloy".
+//   - ResponseFormat: The audio format (mp3, opus, aac, flac, wav or pcm), default "mp3".
+//   - Speed: Speech speed between 0.25 and 4.0, 0 uses the API default.
+//   - HTTPClient: Optional HTTP client, defaults to a client with a 60 second timeout.
+type OpenAITTSController struct {
+	Config         LLMConfig
+	Voice          string
+	ResponseFormat string
+	Speed          float64
+	HTTPClient     *http.Client
+}
+
+// Synthesize implements TTSClient.
+func (tc *OpenAITTSController) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	request := map[string]interface{}{
+		"model":           tc.Config.AiModel,
+		"input":           text,
+		"voice":           tc.Voice,
+		"response_format": tc.ResponseFormat,
+	}
+	if tc.Voice == "" {
+		request["voice"] = "alloy"
+	}
+	if tc.ResponseFormat == "" {
+		request["response_format"] = "mp3"
+	}
+	if tc.Speed > 0 {
+		request["speed"] = tc.Speed
+	}
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error converting request to json: %w", err)
+	}
+	url := strings.TrimSuffix(tc.Config.Apiurl, "/") + "/audio/speech"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if tc.Config.APIToken != "" {
+		req.Header.Set("Authorization", "Bearer "+tc.Config.APIToken)
+	}
+	client := tc.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("api error: status code %d\nresponse: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// CommandTTSController converts text to speech using a local engine executable such as piper or espeak-ng.
+//
+// The text is written to the standard input of the command and the audio is read from its standard output,
+// e.g. Command: "piper", Args: []string{"--model", "en_US-lessac-medium.onnx", "--output_file", "-"}
+// or Command: "espeak-ng", Args: []string{"--stdin", "--stdout"}.
+//
+// Fields:
+//   - Command: The executable to run.
+//   - Args: The command arguments.
+type CommandTTSController struct {
+	Command string
+	Args    []string
+}
+
+// Synthesize implements TTSClient.
+func (tc *CommandTTSController) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	if tc.Command == "" {
+		return nil, errors.New("missing tts command")
+	}
+	cmd := exec.CommandContext(ctx, tc.Command, tc.Args...)
+	cmd.Stdin = strings.NewReader(text)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("error running tts command: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// SpeakResponse converts the answer of an AskLLM call to audio using the configured TTSClient.
+//
+// Parameters:
+//   - result: The result returned by AskLLM.
+//
+// Returns:
+//   - []byte: The encoded audio of the answer.
+//   - error: An error if no TTS client is configured, the answer is empty or synthesis fails.
+//
+// Example Usage:
+//
+//	result, _ := llm.AskLLM("What is the capital of France?")
+//	audio, err := llm.SpeakResponse(result)
+func (llm *LLMContainer) SpeakResponse(result LLMResult) ([]byte, error) {
+	if llm.TTSClient == nil {
+		return nil, errors.New("missing tts client")
+	}
+	if result.Response == nil || len(result.Response.Choices) == 0 || strings.TrimSpace(result.Response.Choices[0].Content) == "" {
+		return nil, errors.New("response is empty")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	return llm.TTSClient.Synthesize(ctx, result.Response.Choices[0].Content)
+}
+
+// SpeechStreamer converts a streamed answer to audio sentence by sentence, so a voice assistant can
+// start speaking before the whole answer is generated.
+//
+// Use StreamingFunc with WithStreamingFunc and call Flush after AskLLM returns to speak the last sentence.
+type SpeechStreamer struct {
+	client  TTSClient
+	onAudio func(audio []byte) error
+	buffer  strings.Builder
+	mu      sync.Mutex
+}
+
+// NewSpeechStreamer creates a SpeechStreamer using the configured TTSClient.
+//
+// Parameters:
+//   - onAudio: Called with the audio of each sentence, in order.
+//
+// Returns:
+//   - *SpeechStreamer: The streamer.
+//   - error: An error if no TTS client is configured.
+//
+// Example Usage:
+//
+//	speaker, _ := llm.NewSpeechStreamer(func(audio []byte) error { return player.Play(audio) })
+//	result, err := llm.AskLLM(query, llm.WithStreamingFunc(speaker.StreamingFunc))
+//	speaker.Flush(context.Background())
+func (llm *LLMContainer) NewSpeechStreamer(onAudio func(audio []byte) error) (*SpeechStreamer, error) {
+	if llm.TTSClient == nil {
+		return nil, errors.New("missing tts client")
+	}
+	return &SpeechStreamer{client: llm.TTSClient, onAudio: onAudio}, nil
+}
+
+// StreamingFunc collects streamed chunks and synthesizes every completed sentence.
+func (ss *SpeechStreamer) StreamingFunc(ctx context.Context, chunk []byte) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.buffer.Write(chunk)
+	text := ss.buffer.String()
+	end := lastSentenceEnd(text)
+	if end < 0 {
+		return nil
+	}
+	ss.buffer.Reset()
+	ss.buffer.WriteString(text[end:])
+	return ss.speak(ctx, text[:end])
+}
+
+// Flush synthesizes the remaining text that does not end with a sentence terminator.
+func (ss *SpeechStreamer) Flush(ctx context.Context) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	text := ss.buffer.String()
+	ss.buffer.Reset()
+	return ss.speak(ctx, text)
+}
+
+func (ss *SpeechStreamer) speak(ctx context.Context, text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	audio, err := ss.client.Synthesize(ctx, text)
+	if err != nil {
+		return err
+	}
+	if ss.onAudio == nil {
+		return nil
+	}
+	return ss.onAudio(audio)
+}
+
+// lastSentenceEnd returns the byte offset after the last sentence terminator followed by whitespace,
+// or -1 if the text has no complete sentence yet.
+func lastSentenceEnd(text string) int {
+	end := -1
+	runes := []rune(text)
+	offset := 0
+	for idx, r := range runes {
+		offset += len(string(r))
+		switch r {
+		case '.', '!', '?', '؟', '。', '！', '？', '\n':
+			if r == '\n' || r == '。' || r == '！' || r == '？' || (idx+1 < len(runes) && (runes[idx+1] == ' ' || runes[idx+1] == '\n')) {
+				end = offset
+			}
+		}
+	}
+	return end
+}
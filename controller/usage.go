@@ -0,0 +1,244 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// usageDayFormat is the date format of the daily usage keys (UTC).
+const usageDayFormat = "20060102"
+
+// UsageTotals counts the calls and tokens of a group of AskLLM calls.
+//
+// Tokens are the sum of all stages of TokenReport.
+type UsageTotals struct {
+	Requests     int `json:"requests"`
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+}
+
+// DailyUsage is the usage of a single day (UTC), with its breakdown by model and session.
+type DailyUsage struct {
+	Day      time.Time              `json:"day"`
+	Totals   UsageTotals            `json:"totals"`
+	Models   map[string]UsageTotals `json:"models"`
+	Sessions map[string]UsageTotals `json:"sessions"`
+}
+
+// UsageReport is the usage of a tenant in a period, returned by GetUsage.
+//
+// Fields:
+//   - Tenant: The tenant ID, empty for calls made without WithTenant.
+//   - From, To: The first and last day of the period.
+//   - Totals: The usage of the whole period.
+//   - Models, Sessions: The usage of the period by model and by session ID.
+//   - Days: The usage of each day of the period having calls.
+type UsageReport struct {
+	Tenant   string                 `json:"tenant"`
+	From     time.Time              `json:"from"`
+	To       time.Time              `json:"to"`
+	Totals   UsageTotals            `json:"totals"`
+	Models   map[string]UsageTotals `json:"models"`
+	Sessions map[string]UsageTotals `json:"sessions"`
+	Days     []DailyUsage           `json:"days"`
+}
+
+// UsagePeriod is a range of days, both ends included.
+type UsagePeriod struct {
+	From time.Time
+	To   time.Time
+}
+
+// UsageDay returns the period of the day of t.
+func UsageDay(t time.Time) UsagePeriod {
+	return UsagePeriod{From: t, To: t}
+}
+
+// UsageMonth returns the period of a calendar month.
+func UsageMonth(year int, month time.Month) UsagePeriod {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return UsagePeriod{From: first, To: first.AddDate(0, 1, -1)}
+}
+
+// add adds the totals of other to t.
+func (t *UsageTotals) add(other UsageTotals) {
+	t.Requests += other.Requests
+	t.InputTokens += other.InputTokens
+	t.OutputTokens += other.OutputTokens
+}
+
+// sum returns the input and output tokens of all stages.
+func (tr TokenReport) sum() TokenUsage {
+	sum := TokenUsage{}
+	for _, usage := range []TokenUsage{tr.CompletionTokens, tr.TextChunkingTokens, tr.LanguageDetectionTokens,
+		tr.MemorySummarizationTokens, tr.SecurityCheckTokens, tr.VisionTokens, tr.GroundednessTokens, tr.MapReduceTokens,
+		tr.TranslationTokens} {
+		sum.InputTokens += usage.InputTokens
+		sum.OutputTokens += usage.OutputTokens
+	}
+	return sum
+}
+
+// usageKey returns the Redis hash holding the usage of a tenant in a day.
+func usageKey(tenantID string, day time.Time) string {
+	return "usage:" + TenantPrefix(tenantID, day.UTC().Format(usageDayFormat))
+}
+
+// recordUsage adds the token usage of an AskLLM call to the daily usage of its tenant.
+//
+// The hash fields are "<dimension>:<name>:<counter>", so totals, models and sessions of a day are
+// updated with a single HINCRBY pipeline.
+func (llm *LLMContainer) recordUsage(o *LLMCallOptions, report TokenReport) {
+	if llm.RedisClient.redisClient == nil {
+		return
+	}
+	sum := report.sum()
+	model := ""
+	if llm.LLMClient != nil {
+		model = llm.LLMClient.GetConfig().AiModel
+	}
+	// Sessions are reported without their tenant namespace
+	sessionID := o.SessionID
+	if o.tenantID != "" {
+		sessionID = strings.TrimPrefix(sessionID, TenantPrefix(o.tenantID, "")+":")
+	}
+
+	ctx := context.Background()
+	key := usageKey(o.tenantID, time.Now())
+	pipe := llm.RedisClient.redisClient.TxPipeline()
+	for _, group := range []string{"total:", "model:" + model + ":", "session:" + sessionID + ":"} {
+		pipe.HIncrBy(ctx, key, group+"requests", 1)
+		pipe.HIncrBy(ctx, key, group+"input", int64(sum.InputTokens))
+		pipe.HIncrBy(ctx, key, group+"output", int64(sum.OutputTokens))
+	}
+	if llm.UsageRetention > 0 {
+		pipe.Expire(ctx, key, llm.UsageRetention)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		llm.logger().Warn("error recording usage", "tenant", o.tenantID, "error", err)
+	}
+}
+
+// GetUsage returns the requests and tokens of a tenant in a period, by day, model and session.
+//
+// Usage is recorded in Redis by every AskLLM call, days are in UTC.
+//
+// Parameters:
+//   - tenantID: The tenant ID, empty for calls made without WithTenant.
+//   - period: The days to report, see UsageDay and UsageMonth.
+//
+// Returns:
+//   - UsageReport: The usage of the period.
+//   - error: An error if the period is invalid or Redis can't be read.
+//
+// Example Usage:
+//
+//	report, err := llm.GetUsage("acme", aillm.UsageMonth(2025, time.March))
+//	fmt.Println(report.Totals.InputTokens, report.Totals.OutputTokens)
+func (llm *LLMContainer) GetUsage(tenantID string, period UsagePeriod) (UsageReport, error) {
+	from := truncateDay(period.From)
+	to := truncateDay(period.To)
+	report := UsageReport{
+		Tenant:   tenantID,
+		From:     from,
+		To:       to,
+		Models:   map[string]UsageTotals{},
+		Sessions: map[string]UsageTotals{},
+		Days:     []DailyUsage{},
+	}
+	if to.Before(from) {
+		return report, errors.New("usage period ends before it starts")
+	}
+	ctx := context.Background()
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		fields, err := llm.RedisClient.redisClient.HGetAll(ctx, usageKey(tenantID, day)).Result()
+		if err != nil {
+			return report, err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		daily := parseDailyUsage(day, fields)
+		report.Totals.add(daily.Totals)
+		for model, totals := range daily.Models {
+			sum := report.Models[model]
+			sum.add(totals)
+			report.Models[model] = sum
+		}
+		for session, totals := range daily.Sessions {
+			sum := report.Sessions[session]
+			sum.add(totals)
+			report.Sessions[session] = sum
+		}
+		report.Days = append(report.Days, daily)
+	}
+	return report, nil
+}
+
+// parseDailyUsage reads the fields of a daily usage hash.
+func parseDailyUsage(day time.Time, fields map[string]string) DailyUsage {
+	daily := DailyUsage{Day: day, Models: map[string]UsageTotals{}, Sessions: map[string]UsageTotals{}}
+	for field, value := range fields {
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		dimension, rest, _ := strings.Cut(field, ":")
+		// Model names and session IDs may contain ":", the counter is the last part
+		separator := strings.LastIndex(rest, ":")
+		name, counter := "", rest
+		if separator >= 0 {
+			name, counter = rest[:separator], rest[separator+1:]
+		}
+		var totals UsageTotals
+		switch dimension {
+		case "total":
+			totals = daily.Totals
+		case "model":
+			totals = daily.Models[name]
+		case "session":
+			totals = daily.Sessions[name]
+		default:
+			continue
+		}
+		switch counter {
+		case "requests":
+			totals.Requests = count
+		case "input":
+			totals.InputTokens = count
+		case "output":
+			totals.OutputTokens = count
+		}
+		switch dimension {
+		case "total":
+			daily.Totals = totals
+		case "model":
+			daily.Models[name] = totals
+		case "session":
+			daily.Sessions[name] = totals
+		}
+	}
+	return daily
+}
+
+// truncateDay returns the start of the UTC day of t.
+func truncateDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
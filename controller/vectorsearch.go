@@ -24,7 +24,6 @@ import (
 
 	"github.com/tmc/langchaingo/schema"
 	"github.com/tmc/langchaingo/vectorstores"
-	"github.com/tmc/langchaingo/vectorstores/redisvector"
 )
 
 // HybridSearchResult represents a result from hybrid search with combined scores
@@ -75,43 +74,34 @@ func DefaultHybridSearchConfig() HybridSearchConfig {
 //   - interface{}: The search results containing the most similar documents.
 //   - error: An error if the search fails or the embedding model is missing.
 func (llm *LLMContainer) CosineSimilarity(prefix, Query string, rowCount int, ScoreThreshold float32) ([]schema.Document, error) {
+	return llm.cosineSimilarity(context.Background(), prefix, Query, rowCount, ScoreThreshold)
+}
+
+// cosineSimilarity is CosineSimilarity bounded by the context of the request.
+func (llm *LLMContainer) cosineSimilarity(ctx context.Context, prefix, Query string, rowCount int, ScoreThreshold float32) ([]schema.Document, error) {
 	var result []schema.Document
 	if llm.Embedder == nil {
-		return nil, errors.New("missing embedding model")
+		return nil, ErrMissingEmbedder
 	} else {
-		if !llm.Embedder.initialized() {
-			llm.InitEmbedding()
-		}
-	}
-
-	// Get the embedder from the client
-	embedder, err := llm.Embedder.NewEmbedder()
-	if err != nil {
-		return result, err
+		llm.ensureEmbedder()
 	}
 
-	// Setup Redis vector store
-	redisVector := redisvector.WithIndexName(prefix+"aillm_vector_idx", true)
-	embedderVector := redisvector.WithEmbedder(embedder)
-
-	redisHostURL, redisConnectionErr := llm.getRedisHost()
-	if redisConnectionErr != nil {
-		return result, redisConnectionErr
-	}
-	store, err := redisvector.New(context.TODO(), redisvector.WithConnectionURL(redisHostURL), redisVector, embedderVector)
+	// Get the cached Redis vector store and embedder
+	store, embedder, err := llm.getVectorStore(prefix+"aillm_vector_idx", false)
 	if err != nil {
 		return result, err
 	}
-	ctx := context.Background()
+	ctx, cancel := llm.searchContext(ctx)
+	defer cancel()
 	optionsVector := []vectorstores.Option{
 		vectorstores.WithScoreThreshold(ScoreThreshold),
 		vectorstores.WithEmbedder(embedder),
 	}
 	results, err := store.SimilaritySearch(ctx, Query, rowCount, optionsVector...)
-	if err != nil && !strings.Contains(err.Error(), "no such index") {
-		return result, fmt.Errorf("search error: %v", err)
+	if err != nil {
+		return result, fmt.Errorf("search error: %w", redisIndexError(err))
 	}
-	return results, nil
+	return llm.decryptDocuments(results)
 }
 
 // FindKNN performs a K-Nearest Neighbors (KNN) search on the stored vector embeddings.
@@ -129,22 +119,15 @@ func (llm *LLMContainer) CosineSimilarity(prefix, Query string, rowCount int, Sc
 //   - []schema.Document: The retrieved relevant documents.
 //   - error: An error if the search fails or the embedding model is missing.
 func (llm *LLMContainer) FindKNN(prefix, searchQuery string, rowCount int, ScoreThreshold float32) ([]schema.Document, error) {
+	return llm.findKNN(context.Background(), prefix, searchQuery, rowCount, ScoreThreshold)
+}
+
+// findKNN is FindKNN bounded by the context of the request.
+func (llm *LLMContainer) findKNN(ctx context.Context, prefix, searchQuery string, rowCount int, ScoreThreshold float32) ([]schema.Document, error) {
 	var result []schema.Document
 
 	// llm.CosineSimilarity(prefix, searchQuery,rowCount,ScoreThreshold)
-	embedder, err := llm.Embedder.NewEmbedder()
-	if err != nil {
-		return result, err
-	}
-
-	redisVector := redisvector.WithIndexName(prefix+"aillm_vector_idx", true)
-	embedderVector := redisvector.WithEmbedder(embedder)
-	redisHostURL, redisConnectionErr := llm.getRedisHost()
-	if redisConnectionErr != nil {
-		return result, redisConnectionErr
-	}
-
-	store, err := redisvector.New(context.TODO(), redisvector.WithConnectionURL(redisHostURL), redisVector, embedderVector)
+	store, embedder, err := llm.getVectorStore(prefix+"aillm_vector_idx", false)
 	if err != nil {
 		return result, err
 	}
@@ -156,11 +139,13 @@ func (llm *LLMContainer) FindKNN(prefix, searchQuery string, rowCount int, Score
 
 	retriever := vectorstores.ToRetriever(store, rowCount, optionsVector...)
 
-	resDocs, err := retriever.GetRelevantDocuments(context.Background(), searchQuery)
+	ctx, cancel := llm.searchContext(ctx)
+	defer cancel()
+	resDocs, err := retriever.GetRelevantDocuments(ctx, searchQuery)
 	if err != nil {
-		return result, err
+		return result, redisIndexError(err)
 	}
-	return resDocs, nil
+	return llm.decryptDocuments(resDocs)
 }
 
 // HybridSearch performs a hybrid search combining vector similarity and lexical search for improved accuracy.
@@ -180,6 +165,11 @@ func (llm *LLMContainer) FindKNN(prefix, searchQuery string, rowCount int, Score
 //   - []schema.Document: The retrieved relevant documents with hybrid scores.
 //   - error: An error if the search fails or required components are missing.
 func (llm *LLMContainer) HybridSearch(prefix, searchQuery string, rowCount int, ScoreThreshold float32, config *HybridSearchConfig) ([]schema.Document, error) {
+	return llm.hybridSearch(context.Background(), prefix, searchQuery, rowCount, ScoreThreshold, config)
+}
+
+// hybridSearch is HybridSearch bounded by the context of the request.
+func (llm *LLMContainer) hybridSearch(ctx context.Context, prefix, searchQuery string, rowCount int, ScoreThreshold float32, config *HybridSearchConfig) ([]schema.Document, error) {
 	if config == nil {
 		defaultConfig := DefaultHybridSearchConfig()
 		config = &defaultConfig
@@ -200,15 +190,15 @@ func (llm *LLMContainer) HybridSearch(prefix, searchQuery string, rowCount int,
 	}
 
 	// Perform vector similarity search
-	vectorResults, err := llm.performVectorSearch(prefix, searchQuery, config.MaxResults, config.MinVectorScore)
+	vectorResults, err := llm.performVectorSearch(ctx, prefix, searchQuery, config.MaxResults, config.MinVectorScore)
 	if err != nil {
-		return nil, fmt.Errorf("vector search failed: %v", err)
+		return nil, fmt.Errorf("vector search failed: %w", err)
 	}
 
 	// Perform lexical search
-	lexicalResults, err := llm.performLexicalSearch(prefix, searchQuery, config.MaxResults, config.MinLexicalScore)
+	lexicalResults, err := llm.performLexicalSearch(ctx, prefix, searchQuery, config.MaxResults, config.MinLexicalScore)
 	if err != nil {
-		return nil, fmt.Errorf("lexical search failed: %v", err)
+		return nil, fmt.Errorf("lexical search failed: %w", err)
 	}
 
 	// Combine results using hybrid scoring
@@ -240,48 +230,33 @@ func (llm *LLMContainer) HybridSearch(prefix, searchQuery string, rowCount int,
 		finalResults = append(finalResults, doc)
 	}
 
-	return finalResults, nil
+	return llm.decryptDocuments(finalResults)
 }
 
 // performVectorSearch executes vector similarity search
-func (llm *LLMContainer) performVectorSearch(prefix, searchQuery string, maxResults int, minScore float32) ([]HybridSearchResult, error) {
+func (llm *LLMContainer) performVectorSearch(ctx context.Context, prefix, searchQuery string, maxResults int, minScore float32) ([]HybridSearchResult, error) {
 	if llm.Embedder == nil {
-		return nil, errors.New("missing embedding model")
-	}
-
-	if !llm.Embedder.initialized() {
-		llm.InitEmbedding()
-	}
-
-	// Get the embedder from the client
-	embedder, err := llm.Embedder.NewEmbedder()
-	if err != nil {
-		return nil, err
+		return nil, ErrMissingEmbedder
 	}
 
-	// Setup Redis vector store
-	redisVector := redisvector.WithIndexName(prefix+"aillm_vector_idx", true)
-	embedderVector := redisvector.WithEmbedder(embedder)
-
-	redisHostURL, redisConnectionErr := llm.getRedisHost()
-	if redisConnectionErr != nil {
-		return nil, redisConnectionErr
-	}
+	llm.ensureEmbedder()
 
-	store, err := redisvector.New(context.TODO(), redisvector.WithConnectionURL(redisHostURL), redisVector, embedderVector)
+	// Get the cached Redis vector store and embedder
+	store, embedder, err := llm.getVectorStore(prefix+"aillm_vector_idx", false)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx := context.Background()
+	ctx, cancel := llm.searchContext(ctx)
+	defer cancel()
 	optionsVector := []vectorstores.Option{
 		vectorstores.WithScoreThreshold(minScore),
 		vectorstores.WithEmbedder(embedder),
 	}
 
 	results, err := store.SimilaritySearch(ctx, searchQuery, maxResults, optionsVector...)
-	if err != nil && !strings.Contains(err.Error(), "no such index") {
-		return nil, fmt.Errorf("vector search error: %v", err)
+	if err != nil {
+		return nil, fmt.Errorf("vector search error: %w", redisIndexError(err))
 	}
 
 	var hybridResults []HybridSearchResult
@@ -299,9 +274,11 @@ func (llm *LLMContainer) performVectorSearch(prefix, searchQuery string, maxResu
 }
 
 // performLexicalSearch executes lexical/keyword search using Redis FT.SEARCH
-func (llm *LLMContainer) performLexicalSearch(prefix, searchQuery string, maxResults int, minScore float32) ([]HybridSearchResult, error) {
+func (llm *LLMContainer) performLexicalSearch(ctx context.Context, prefix, searchQuery string, maxResults int, minScore float32) ([]HybridSearchResult, error) {
+	if err := llm.checkLexicalSearch(); err != nil {
+		return nil, err
+	}
 	rdb := llm.RedisClient.redisClient
-	ctx := context.Background()
 
 	// Create a text index name for lexical search
 	textIndexName := prefix + "aillm_text_idx"
@@ -309,11 +286,13 @@ func (llm *LLMContainer) performLexicalSearch(prefix, searchQuery string, maxRes
 	// Ensure text index exists
 	err := llm.createTextIndex(textIndexName, prefix)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create text index: %v", err)
+		return nil, fmt.Errorf("failed to create text index: %w", err)
 	}
 
 	// Perform FT.SEARCH query for lexical search
 	// Search in both content and title fields
+	language := prefixLanguage(prefix)
+	redisearchLanguage := lexicalLanguage(language)
 	keywords := []string{}
 	lines := strings.Split(searchQuery, "\n")
 	for _, line := range lines {
@@ -321,7 +300,7 @@ func (llm *LLMContainer) performLexicalSearch(prefix, searchQuery string, maxRes
 		re := regexp.MustCompile(`[ ,\.]+`)
 		words := re.Split(line, -1)
 		for _, word := range words {
-			if len(word) > 2 {
+			if len(word) > 2 && !isLexicalStopword(language, word) {
 				keywords = append(keywords, llm.escapeRedisSearchQuery(word))
 			}
 		}
@@ -333,6 +312,10 @@ func (llm *LLMContainer) performLexicalSearch(prefix, searchQuery string, maxRes
 		if i > 0 {
 			finalSearchQuery += " | "
 		}
+		if redisearchLanguage != "" {
+			// Plain terms are stemmed, so inflected forms of the keyword match too
+			finalSearchQuery += fmt.Sprintf("(@content:%s) | ", keyword)
+		}
 		finalSearchQuery += fmt.Sprintf("(@content:*%s*)", keyword)
 	}
 
@@ -357,14 +340,16 @@ func (llm *LLMContainer) performLexicalSearch(prefix, searchQuery string, maxRes
 	// 	searchQuery = fmt.Sprintf("(@content:*%s*)", llm.escapeRedisSearchQuery(searchQuery))
 	// }
 
-	searchResults, err := rdb.Do(ctx,
-		"FT.SEARCH", textIndexName,
-		finalSearchQuery,
+	searchArgs := []any{"FT.SEARCH", textIndexName, finalSearchQuery}
+	if redisearchLanguage != "" {
+		searchArgs = append(searchArgs, "LANGUAGE", redisearchLanguage)
+	}
+	searchResults, err := rdb.Do(ctx, append(searchArgs,
 		"LIMIT", 0, maxResults,
-		"WITHSCORES").Result()
+		"WITHSCORES")...).Result()
 
 	if err != nil {
-		return nil, fmt.Errorf("lexical search error: %v", err)
+		return nil, fmt.Errorf("lexical search error: %w", redisIndexError(err))
 	}
 
 	// Parse Redis FT.SEARCH results
@@ -372,6 +357,9 @@ func (llm *LLMContainer) performLexicalSearch(prefix, searchQuery string, maxRes
 }
 
 // createTextIndex creates a text index for lexical search if it doesn't exist
+//
+// The index stems and filters the stopwords of the language of the prefix, chunks with a language field use the
+// stemmer of their language. Indexes created before keep their analyzers until they are dropped.
 func (llm *LLMContainer) createTextIndex(indexName, prefix string) error {
 	rdb := llm.RedisClient.redisClient
 	ctx := context.Background()
@@ -383,12 +371,11 @@ func (llm *LLMContainer) createTextIndex(indexName, prefix string) error {
 	}
 
 	// Create text index for lexical search
-	_, err = rdb.Do(ctx,
-		"FT.CREATE", indexName,
-		"ON", "HASH",
-		"PREFIX", "1", "doc:"+prefix,
+	args := []any{"FT.CREATE", indexName, "ON", "HASH", "PREFIX", "1", "doc:" + prefix}
+	args = append(args, textIndexOptions(prefix)...)
+	_, err = rdb.Do(ctx, append(args,
 		"SCHEMA",
-		"content", "TEXT").Result()
+		"content", "TEXT")...).Result()
 
 	return err
 }
@@ -654,11 +641,11 @@ func hash(s string) uint32 {
 // Returns:
 //   - []schema.Document: The retrieved relevant documents.
 //   - error: An error if the search fails.
-func (llm *LLMContainer) performLexicalSearchOnly(prefix, searchQuery string, rowCount int, ScoreThreshold float32) ([]schema.Document, error) {
+func (llm *LLMContainer) performLexicalSearchOnly(ctx context.Context, prefix, searchQuery string, rowCount int, ScoreThreshold float32) ([]schema.Document, error) {
 	// Perform lexical search
-	hybridResults, err := llm.performLexicalSearch(prefix, searchQuery, rowCount, ScoreThreshold)
+	hybridResults, err := llm.performLexicalSearch(ctx, prefix, searchQuery, rowCount, ScoreThreshold)
 	if err != nil {
-		return nil, fmt.Errorf("lexical search failed: %v", err)
+		return nil, fmt.Errorf("lexical search failed: %w", err)
 	}
 
 	// Sort by lexical score (descending)
@@ -685,7 +672,7 @@ func (llm *LLMContainer) performLexicalSearchOnly(prefix, searchQuery string, ro
 		finalResults = append(finalResults, doc)
 	}
 
-	return finalResults, nil
+	return llm.decryptDocuments(finalResults)
 }
 
 // SemanticSearch performs enhanced semantic search using the best available algorithm
@@ -703,9 +690,14 @@ func (llm *LLMContainer) performLexicalSearchOnly(prefix, searchQuery string, ro
 //   - []schema.Document: The retrieved relevant documents.
 //   - error: An error if the search fails.
 func (llm *LLMContainer) SemanticSearch(prefix, searchQuery string, rowCount int, ScoreThreshold float32) ([]schema.Document, error) {
+	return llm.semanticSearch(context.Background(), prefix, searchQuery, rowCount, ScoreThreshold)
+}
+
+// semanticSearch is SemanticSearch bounded by the context of the request.
+func (llm *LLMContainer) semanticSearch(ctx context.Context, prefix, searchQuery string, rowCount int, ScoreThreshold float32) ([]schema.Document, error) {
 	// Use hybrid search for better accuracy
 	config := DefaultHybridSearchConfig()
 	config.MaxResults = rowCount * 2 // Get more results for better fusion
 
-	return llm.HybridSearch(prefix, searchQuery, rowCount, ScoreThreshold, &config)
+	return llm.hybridSearch(ctx, prefix, searchQuery, rowCount, ScoreThreshold, &config)
 }
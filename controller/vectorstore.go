@@ -0,0 +1,566 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"github.com/tmc/langchaingo/vectorstores/redisvector"
+)
+
+// VectorType selects how the chunk vectors are stored in Redis, see EmbeddingConfig.
+//
+// Smaller types reduce the memory of large corpora at a small cost in search precision. The type is set when a
+// vector index is created, existing indexes keep their type until their documents are embedded again in a new
+// prefix, e.g. with ReembedAll, or after CleanEmbeddings. Queries and chunks of an existing index are encoded with
+// the type of the index, read from FT.INFO.
+type VectorType string
+
+const (
+	// VectorFloat32 stores 4 bytes per dimension, the default.
+	VectorFloat32 VectorType = "FLOAT32"
+	// VectorFloat16 stores 2 bytes per dimension, it needs RediSearch 2.10 or later.
+	VectorFloat16 VectorType = "FLOAT16"
+	// VectorInt8 stores 1 byte per dimension, each vector scaled to its largest component. It needs Redis 8 and
+	// keeps cosine similarities, the distance of the container's indexes.
+	VectorInt8 VectorType = "INT8"
+)
+
+// HNSWConfig sets the parameters of HNSW vector indexes, approximate indexes that search large corpora faster
+// than the default FLAT indexes. Zero values keep the defaults of Redis.
+//
+// Fields:
+//   - M: The maximum number of neighbors of a node, Redis uses 16.
+//   - EfConstruction: The number of candidates considered while building the graph, Redis uses 200.
+//   - EfRuntime: The number of candidates considered while searching, Redis uses 10.
+type HNSWConfig struct {
+	M              int `json:"m"`
+	EfConstruction int `json:"efConstruction"`
+	EfRuntime      int `json:"efRuntime"`
+}
+
+// validateVectors reports unknown vector types and invalid HNSW parameters.
+func (ec EmbeddingConfig) validateVectors() error {
+	switch ec.VectorType {
+	case "", VectorFloat32, VectorFloat16, VectorInt8:
+	default:
+		return fmt.Errorf("unknown vector type %q, use FLOAT32, FLOAT16 or INT8", ec.VectorType)
+	}
+	if ec.HNSW != nil && (ec.HNSW.M < 0 || ec.HNSW.EfConstruction < 0 || ec.HNSW.EfRuntime < 0) {
+		return errors.New("HNSW parameters can't be negative")
+	}
+	return nil
+}
+
+// defaultVectorStorage reports whether the vectors are stored as FLOAT32 in FLAT indexes, the layout of the
+// langchaingo redisvector store.
+func (ec EmbeddingConfig) defaultVectorStorage() bool {
+	return (ec.VectorType == "" || ec.VectorType == VectorFloat32) && ec.HNSW == nil
+}
+
+// newVectorStore creates the vector store of an index: the langchaingo redisvector store for FLOAT32 vectors in
+// FLAT indexes, else a redisVectorStore. Existing indexes keep their vector type and dimension, new ones are
+// created as configured in EmbeddingConfig.
+func (llm *LLMContainer) newVectorStore(indexName string, embedder embeddings.Embedder) (vectorstores.VectorStore, error) {
+	rdb := llm.RedisClient.redisClient
+	if rdb != nil {
+		index, exists, err := readVectorIndex(context.Background(), rdb, indexName)
+		if err != nil {
+			return nil, err
+		}
+		if exists && (index.vectorType != VectorFloat32 || index.algorithm != "FLAT") {
+			return &redisVectorStore{rdb: rdb, indexName: indexName, embedder: embedder, config: llm.EmbeddingConfig,
+				vectorType: index.vectorType, dimension: index.dimension, indexReady: true}, nil
+		}
+		if !exists && !llm.EmbeddingConfig.defaultVectorStorage() {
+			return &redisVectorStore{rdb: rdb, indexName: indexName, embedder: embedder, config: llm.EmbeddingConfig,
+				vectorType: llm.EmbeddingConfig.VectorType}, nil
+		}
+	} else if !llm.EmbeddingConfig.defaultVectorStorage() {
+		return nil, ErrNoRedis
+	}
+	redisHostURL, err := llm.getRedisHost()
+	if err != nil {
+		return nil, err
+	}
+	return redisvector.New(context.TODO(), redisvector.WithConnectionURL(redisHostURL), redisvector.WithIndexName(indexName, true), redisvector.WithEmbedder(embedder))
+}
+
+// redisVectorStore is a vector store keeping the layout of the langchaingo redisvector store, chunks are hashes
+// at doc:<index>:<id> with content and content_vector fields, with quantized vectors and HNSW indexes.
+type redisVectorStore struct {
+	rdb       *redis.Client
+	indexName string
+	embedder  embeddings.Embedder
+	config    EmbeddingConfig
+
+	mu         sync.Mutex
+	indexReady bool
+	vectorType VectorType // The type of the index, the configured one until it exists
+	dimension  int        // The dimension of the index, 0 until it exists
+}
+
+// AddDocuments embeds the documents and stores them with their metadata, the index is created from the
+// metadata of the first document when missing. The "keys" or "ids" metadata names the key of a document.
+func (s *redisVectorStore) AddDocuments(ctx context.Context, docs []schema.Document, _ ...vectorstores.Option) ([]string, error) {
+	if len(docs) == 0 {
+		return []string{}, nil
+	}
+	texts := make([]string, len(docs))
+	for idx, doc := range docs {
+		texts[idx] = doc.PageContent
+	}
+	vectors, err := s.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(docs) || len(vectors[0]) == 0 {
+		return nil, errors.New("embedding vector error")
+	}
+	vectorType, err := s.ensureIndex(ctx, docs[0].Metadata, len(vectors[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(docs))
+	pipe := s.rdb.Pipeline()
+	for idx, doc := range docs {
+		keys[idx] = "doc:" + s.indexName + ":" + documentID(doc.Metadata)
+		fields := []any{"content", doc.PageContent, vectorField, encodeVector(vectors[idx], vectorType)}
+		for name, value := range doc.Metadata {
+			if name != "content" && name != vectorField {
+				fields = append(fields, name, fmt.Sprintf("%v", value))
+			}
+		}
+		pipe.HSet(ctx, keys[idx], fields...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// documentID returns the ID of a document from its "ids" or "keys" metadata, else a new UUID.
+func documentID(metadata map[string]any) string {
+	if id, ok := metadata["ids"]; ok {
+		return fmt.Sprintf("%v", id)
+	}
+	if key, ok := metadata["keys"]; ok {
+		return fmt.Sprintf("%v", key)
+	}
+	return uuid.New().String()
+}
+
+// ensureIndex creates the vector index when it doesn't exist, with a field per metadata entry, and returns the
+// vector type of the index. Vectors of another dimension than the index fail, Redis wouldn't index them.
+func (s *redisVectorStore) ensureIndex(ctx context.Context, metadata map[string]any, dimension int) (VectorType, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exists, err := s.loadIndex(ctx)
+	if err != nil {
+		return s.vectorType, err
+	}
+	if exists {
+		return s.vectorType, s.checkDimension(dimension)
+	}
+	args := []any{"FT.CREATE", s.indexName, "ON", "HASH", "PREFIX", "1", "doc:" + s.indexName, "SCHEMA", "content", "TEXT"}
+	names := make([]string, 0, len(metadata))
+	for name := range metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := metadata[name]
+		if name == "content" || name == vectorField || value == nil {
+			continue
+		}
+		switch reflect.TypeOf(value).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			args = append(args, name, "NUMERIC")
+		case reflect.Slice:
+			args = append(args, name, "TAG", "SEPARATOR", ",")
+		default:
+			args = append(args, name, "TEXT")
+		}
+	}
+	args = append(args, s.vectorFieldSchema(dimension)...)
+	if err := s.rdb.Do(ctx, args...).Err(); err != nil {
+		if !isIndexExists(err) {
+			return s.vectorType, fmt.Errorf("error creating vector index: %w", err)
+		}
+		// Created concurrently, maybe with another type
+		if _, err := s.loadIndex(ctx); err != nil {
+			return s.vectorType, err
+		}
+		return s.vectorType, s.checkDimension(dimension)
+	}
+	s.indexReady, s.dimension = true, dimension
+	return s.vectorType, nil
+}
+
+// loadIndex reads the vector type and dimension of the index once it exists, the caller holds mu.
+func (s *redisVectorStore) loadIndex(ctx context.Context) (bool, error) {
+	if s.indexReady {
+		return true, nil
+	}
+	index, exists, err := readVectorIndex(ctx, s.rdb, s.indexName)
+	if err != nil || !exists {
+		return false, err
+	}
+	s.indexReady, s.vectorType, s.dimension = true, index.vectorType, index.dimension
+	return true, nil
+}
+
+// checkDimension fails for vectors of another dimension than the index.
+func (s *redisVectorStore) checkDimension(dimension int) error {
+	if s.dimension > 0 && dimension != s.dimension {
+		return fmt.Errorf("the embedding model returns %d dimensions but the index %s has %d, embed the documents again in a new prefix, e.g. with ReembedAll",
+			dimension, s.indexName, s.dimension)
+	}
+	return nil
+}
+
+// vectorIndex is the vector field of an existing index.
+type vectorIndex struct {
+	vectorType VectorType
+	algorithm  string
+	dimension  int
+}
+
+// readVectorIndex reads the vector field of an index with FT.INFO, false if the index doesn't exist. Indexes
+// reporting no data type store FLOAT32 vectors.
+func readVectorIndex(ctx context.Context, rdb *redis.Client, indexName string) (vectorIndex, bool, error) {
+	index := vectorIndex{vectorType: VectorFloat32, algorithm: "FLAT"}
+	result, err := rdb.Do(ctx, "FT.INFO", indexName).Result()
+	if isIndexNotFound(err) {
+		return index, false, nil
+	}
+	if err != nil {
+		return index, false, err
+	}
+	for _, attribute := range ftInfoAttributes(result) {
+		if !strings.EqualFold(attribute["type"], "VECTOR") {
+			continue
+		}
+		if dataType := strings.ToUpper(attribute["data_type"]); dataType != "" {
+			index.vectorType = VectorType(dataType)
+		}
+		if algorithm := strings.ToUpper(attribute["algorithm"]); algorithm != "" {
+			index.algorithm = algorithm
+		}
+		index.dimension, _ = strconv.Atoi(attribute["dim"])
+		break
+	}
+	return index, true, nil
+}
+
+// ftInfoAttributes returns the field attributes of an FT.INFO reply, RESP3 or RESP2, with lower case names.
+func ftInfoAttributes(result any) []map[string]string {
+	var attributes any
+	switch reply := result.(type) {
+	case map[any]any:
+		attributes = reply["attributes"]
+	case []any:
+		for idx := 0; idx+1 < len(reply); idx += 2 {
+			if name, _ := reply[idx].(string); name == "attributes" {
+				attributes = reply[idx+1]
+			}
+		}
+	}
+	items, _ := attributes.([]any)
+	fields := []map[string]string{}
+	for _, item := range items {
+		attribute := map[string]string{}
+		switch values := item.(type) {
+		case map[any]any:
+			for name, value := range values {
+				attribute[strings.ToLower(fmt.Sprint(name))] = fmt.Sprint(value)
+			}
+		case []any:
+			for idx := 0; idx+1 < len(values); idx += 2 {
+				attribute[strings.ToLower(fmt.Sprint(values[idx]))] = fmt.Sprint(values[idx+1])
+			}
+		}
+		fields = append(fields, attribute)
+	}
+	return fields
+}
+
+// vectorFieldSchema returns the FT.CREATE arguments of the vector field.
+func (s *redisVectorStore) vectorFieldSchema(dimension int) []any {
+	vectorType := s.config.VectorType
+	if vectorType == "" {
+		vectorType = VectorFloat32
+	}
+	attributes := []any{"TYPE", string(vectorType), "DIM", dimension, "DISTANCE_METRIC", "COSINE"}
+	algorithm := "FLAT"
+	if hnsw := s.config.HNSW; hnsw != nil {
+		algorithm = "HNSW"
+		for _, parameter := range []struct {
+			name  string
+			value int
+		}{{"M", hnsw.M}, {"EF_CONSTRUCTION", hnsw.EfConstruction}, {"EF_RUNTIME", hnsw.EfRuntime}} {
+			if parameter.value > 0 {
+				attributes = append(attributes, parameter.name, parameter.value)
+			}
+		}
+	}
+	return append([]any{vectorField, "VECTOR", algorithm, len(attributes)}, attributes...)
+}
+
+// isIndexExists reports the error of FT.CREATE for an index created concurrently.
+func isIndexExists(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "index already exists")
+}
+
+// SimilaritySearch returns the documents nearest to a query, with the distance as score as the langchaingo
+// redisvector store does. A score threshold between 0 and 1 searches the vectors within 1-threshold.
+func (s *redisVectorStore) SimilaritySearch(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error) {
+	opts := vectorstores.Options{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if opts.ScoreThreshold < 0 || opts.ScoreThreshold > 1 {
+		return nil, errors.New("score threshold must be between 0 and 1")
+	}
+	filter := "*"
+	if opts.Filters != nil {
+		filters, ok := opts.Filters.(string)
+		if !ok {
+			return nil, errors.New("invalid filters")
+		}
+		if filters != "" {
+			filter = filters
+		}
+	}
+	embedder := s.embedder
+	if opts.Embedder != nil {
+		embedder = opts.Embedder
+	}
+	vector, err := embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	_, err = s.loadIndex(ctx)
+	if err == nil {
+		err = s.checkDimension(len(vector))
+	}
+	vectorType := s.vectorType
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if numDocuments <= 0 {
+		numDocuments = 1
+	}
+	args := []any{"FT.SEARCH", s.indexName}
+	params := []any{"vector", encodeVector(vector, vectorType)}
+	if opts.ScoreThreshold > 0 && opts.ScoreThreshold < 1 {
+		rangeQuery := fmt.Sprintf("@%s:[VECTOR_RANGE $distance_threshold $vector]=>{$yield_distance_as: distance}", vectorField)
+		if filter != "*" {
+			rangeQuery = "(" + filter + ") " + rangeQuery
+		}
+		args = append(args, rangeQuery)
+		params = append(params, "distance_threshold", strconv.FormatFloat(float64(1-opts.ScoreThreshold), 'f', -1, 32))
+	} else {
+		args = append(args, fmt.Sprintf("(%s)=>[KNN %d @%s $vector AS distance]", filter, numDocuments, vectorField))
+	}
+	args = append(args, "SORTBY", "distance", "ASC", "DIALECT", 2, "LIMIT", 0, numDocuments, "PARAMS", len(params))
+	result, err := s.rdb.Do(ctx, append(args, params...)...).Result()
+	if err != nil {
+		return nil, err
+	}
+	return vectorSearchDocuments(result), nil
+}
+
+// vectorSearchDocuments converts the FT.SEARCH reply of a vector search, RESP3 or RESP2, into documents.
+func vectorSearchDocuments(result any) []schema.Document {
+	docs := []schema.Document{}
+	add := func(key string, fields map[string]string) {
+		doc := schema.Document{Metadata: map[string]any{}}
+		for name, value := range fields {
+			switch name {
+			case "content":
+				doc.PageContent = value
+			case "distance":
+				score, _ := strconv.ParseFloat(value, 32)
+				doc.Score = float32(score)
+			case vectorField:
+			default:
+				doc.Metadata[name] = value
+			}
+		}
+		if _, ok := doc.Metadata["id"]; !ok {
+			doc.Metadata["id"] = key
+		}
+		docs = append(docs, doc)
+	}
+	switch reply := result.(type) {
+	case map[any]any:
+		results, _ := reply["results"].([]any)
+		for _, item := range results {
+			entry, ok := item.(map[any]any)
+			if !ok {
+				continue
+			}
+			key, _ := entry["id"].(string)
+			attributes, _ := entry["extra_attributes"].(map[any]any)
+			fields := map[string]string{}
+			for name, value := range attributes {
+				nameStr, _ := name.(string)
+				valueStr, _ := value.(string)
+				fields[nameStr] = valueStr
+			}
+			add(key, fields)
+		}
+	case []any:
+		// total, key, [field, value, ...], key, ...
+		for idx := 1; idx+1 < len(reply); idx += 2 {
+			key, _ := reply[idx].(string)
+			values, _ := reply[idx+1].([]any)
+			fields := map[string]string{}
+			for field := 0; field+1 < len(values); field += 2 {
+				name, _ := values[field].(string)
+				value, _ := values[field+1].(string)
+				fields[name] = value
+			}
+			add(key, fields)
+		}
+	}
+	return docs
+}
+
+// encodeVector encodes a vector as stored in a vector index of a type, little-endian.
+func encodeVector(vector []float32, vectorType VectorType) []byte {
+	switch vectorType {
+	case VectorFloat16:
+		raw := make([]byte, len(vector)*2)
+		for idx, value := range vector {
+			binary.LittleEndian.PutUint16(raw[idx*2:], float16Bits(value))
+		}
+		return raw
+	case VectorInt8:
+		var maxAbs float64
+		for _, value := range vector {
+			maxAbs = math.Max(maxAbs, math.Abs(float64(value)))
+		}
+		raw := make([]byte, len(vector))
+		if maxAbs == 0 {
+			return raw
+		}
+		for idx, value := range vector {
+			raw[idx] = byte(int8(math.Round(float64(value) / maxAbs * 127)))
+		}
+		return raw
+	}
+	raw := make([]byte, len(vector)*4)
+	for idx, value := range vector {
+		binary.LittleEndian.PutUint32(raw[idx*4:], math.Float32bits(value))
+	}
+	return raw
+}
+
+// decodeVector decodes a vector stored in a vector index of a type, nil if it is invalid. INT8 vectors are
+// returned scaled to [-1, 1], their original scale is not stored.
+func decodeVector(raw string, vectorType VectorType) []float32 {
+	size := 4
+	switch vectorType {
+	case VectorFloat16:
+		size = 2
+	case VectorInt8:
+		size = 1
+	}
+	if raw == "" || len(raw)%size != 0 {
+		return nil
+	}
+	vector := make([]float32, len(raw)/size)
+	for idx := range vector {
+		switch vectorType {
+		case VectorFloat16:
+			vector[idx] = float16Value(binary.LittleEndian.Uint16([]byte(raw[idx*2 : idx*2+2])))
+		case VectorInt8:
+			vector[idx] = float32(int8(raw[idx])) / 127
+		default:
+			vector[idx] = math.Float32frombits(binary.LittleEndian.Uint32([]byte(raw[idx*4 : idx*4+4])))
+		}
+	}
+	return vector
+}
+
+// float16Bits converts a float32 to the bits of the nearest IEEE 754 half precision value.
+func float16Bits(value float32) uint16 {
+	bits := math.Float32bits(value)
+	sign := uint16(bits>>16) & 0x8000
+	exponent := int32(bits>>23&0xff) - 127 + 15
+	mantissa := bits & 0x7fffff
+	switch {
+	case bits&0x7fffffff > 0x7f800000:
+		return sign | 0x7e00 // NaN
+	case bits&0x7fffffff == 0x7f800000 || exponent >= 31:
+		return sign | 0x7c00 // Infinity and overflows
+	case exponent <= 0:
+		if exponent < -10 {
+			return sign
+		}
+		// Subnormal half values
+		mantissa |= 0x800000
+		shift := uint32(14 - exponent)
+		half := uint16(mantissa >> shift)
+		remainder, halfway := mantissa&(1<<shift-1), uint32(1)<<(shift-1)
+		if remainder > halfway || (remainder == halfway && half&1 == 1) {
+			half++
+		}
+		return sign | half
+	}
+	half := sign | uint16(exponent)<<10 | uint16(mantissa>>13)
+	// Rounding to nearest even may carry into the exponent, which is still the nearest value
+	if remainder := mantissa & 0x1fff; remainder > 0x1000 || (remainder == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return half
+}
+
+// float16Value converts the bits of an IEEE 754 half precision value to a float32.
+func float16Value(half uint16) float32 {
+	sign := uint32(half&0x8000) << 16
+	exponent := uint32(half>>10) & 0x1f
+	mantissa := uint32(half & 0x3ff)
+	switch exponent {
+	case 0:
+		value := float32(mantissa) / (1 << 24)
+		if sign != 0 {
+			return -value
+		}
+		return value
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mantissa<<13)
+	}
+	return math.Float32frombits(sign | (exponent+127-15)<<23 | mantissa<<13)
+}
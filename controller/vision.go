@@ -17,17 +17,22 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gabriel-vasile/mimetype"
+	"github.com/tmc/langchaingo/llms"
 )
 
-// Structures needed for the request
+// ChatCompletionRequest is the OpenAI chat completion request DescribeImage used to send.
+//
+// Deprecated: DescribeImage sends images through the VisionClient, the type is no longer used and will be removed.
 type ChatCompletionRequest struct {
 	Model            string            `json:"model"`
 	Messages         []Message         `json:"messages"`
@@ -39,19 +44,28 @@ type ChatCompletionRequest struct {
 	ResponseFormat   map[string]string `json:"response_format"`
 }
 
+// Message is a message of a ChatCompletionRequest.
+//
+// Deprecated: DescribeImage sends images through the VisionClient, the type is no longer used and will be removed.
 type Message struct {
 	Role    string      `json:"role"`
 	Content interface{} `json:"content"`
 }
 
 // Structures needed for the response
+//
+// TokenReport carries the vision token usage in the same form AskLLM reports it (VisionTokens),
+// so image description costs can be accounted together with the other LLM calls.
+// Cached is true when the response was served from the vision cache, its TokenReport is then empty.
 type ChatCompletionResponse struct {
-	ID      string   `json:"id"`
-	Object  string   `json:"object"`
-	Created int64    `json:"created"`
-	Model   string   `json:"model"`
-	Choices []Choice `json:"choices"`
-	Usage   Usage    `json:"usage"`
+	ID          string      `json:"id"`
+	Object      string      `json:"object"`
+	Created     int64       `json:"created"`
+	Model       string      `json:"model"`
+	Choices     []Choice    `json:"choices"`
+	Usage       Usage       `json:"usage"`
+	TokenReport TokenReport `json:"-"`
+	Cached      bool        `json:"-"`
 }
 
 type Choice struct {
@@ -73,17 +87,18 @@ type Usage struct {
 
 // DescribeImage sends an image along with a text query to an AI vision model for description.
 //
-// This function constructs a JSON payload containing the provided image (Base64-encoded) and query,
-// then sends an HTTP POST request to the AI model endpoint. The API response is processed to extract
-// the textual description of the image.
+// The request is sent through the configured `VisionClient` as a multimodal message, so it uses the
+// same langchaingo client, options and token accounting as the other LLM calls. OpenAI compatible
+// clients receive the image as an image URL part and Ollama clients (llava, bakllava, llama3.2-vision, ...)
+// receive it as binary data, so image description also works fully offline.
 //
 // Parameters:
-//   - encodedImage: A string containing the Base64-encoded image data.
+//   - encodedImage: A Base64 data URL ("data:image/jpeg;base64,...") or a remote image URL.
 //   - query: A text prompt to know how to deal with the image (e.g., "Describe this image.").
 //   - options: Variadic LLMCallOption parameters for additional configuration (if applicable).
 //
 // Returns:
-//   - string: The textual description generated by the AI model.
+//   - ChatCompletionResponse: The textual description generated by the AI model and its token usage.
 //   - error: An error if the request fails or the response is invalid.
 //
 // Example Usage:
@@ -95,12 +110,34 @@ type Usage struct {
 //	fmt.Println("Image Description:", description)
 //
 // Notes:
-//   - The function extracts the AI model and API details from `llm.VisionClient.GetConfig()`.
-//   - It expects a valid API token to be set in `llm.VisionClient.GetConfig().APIToken`.
-//   - The function handles HTTP request creation, response validation, and JSON parsing.
-//   - If the API response structure changes, parsing logic might require adjustments.
-
+//   - The AI model and API details are taken from `llm.VisionClient.GetConfig()`.
+//   - `WithMaxTokens` (default 2048) and `WithCustomModel` are honored.
+//   - When `llm.VisionCacheTTL` is set, results are cached in Redis by image hash, query and model.
+//   - `WithStreamingFunc` streams the description chunk by chunk while it is generated; the complete
+//     description is still returned in the response.
 func (llm *LLMContainer) DescribeImage(encodedImage, query string, options ...LLMCallOption) (ChatCompletionResponse, error) {
+	return llm.DescribeMultipleImages([]string{encodedImage}, query, options...)
+}
+
+// DescribeMultipleImages sends several images along with a single text query to an AI vision model,
+// e.g. to compare them or spot differences.
+//
+// All images are sent in one multimodal message, in the given order, followed by the query.
+// The vision model must support multiple images per request (e.g., GPT-4o, Claude, Qwen-VL).
+//
+// Parameters:
+//   - encodedImages: Base64 data URLs or remote image URLs.
+//   - query: A text prompt referring to the images (e.g., "What are the differences between these images?").
+//   - options: Variadic LLMCallOption parameters, see DescribeImage.
+//
+// Returns:
+//   - ChatCompletionResponse: The textual response generated by the AI model and its token usage.
+//   - error: An error if the request fails or the response is invalid.
+//
+// Example Usage:
+//
+//	response, err := llm.DescribeMultipleImages([]string{before, after}, "What changed between the first and the second image?")
+func (llm *LLMContainer) DescribeMultipleImages(encodedImages []string, query string, options ...LLMCallOption) (ChatCompletionResponse, error) {
 	o := LLMCallOptions{}
 	for _, opt := range options {
 		opt(&o)
@@ -109,83 +146,174 @@ func (llm *LLMContainer) DescribeImage(encodedImage, query string, options ...LL
 	if o.MaxTokens == 0 {
 		o.MaxTokens = 2048
 	}
-	config := llm.VisionClient.GetConfig()
-	apiKey := config.APIToken
-	url := config.Apiurl + "chat/completions"
 	response := ChatCompletionResponse{}
-	request := ChatCompletionRequest{
-		Model: config.AiModel,
-		Messages: []Message{
-			{
-				Role: "user",
-				Content: []map[string]interface{}{
-					{
-						"type": "image_url",
-						"image_url": map[string]string{
-							"url": encodedImage,
-						},
-					},
-					{
-						"type": "text",
-						"text": query,
-					},
-				},
-			},
-		},
-		Temperature:      llm.Temperature,
-		MaxTokens:        o.MaxTokens,
-		TopP:             llm.TopP,
-		FrequencyPenalty: 0.0,
-		PresencePenalty:  0.0,
-		ResponseFormat:   map[string]string{"type": "text"},
+	if llm.VisionClient == nil {
+		return response, errors.New("missing vision client")
 	}
-
-	// Convert request to JSON
-	requestBody, err := json.Marshal(request)
+	visionClient, err := llm.newLLMClient(llm.VisionClient, func(retry int, retryErr error) {
+		llm.requestLogger(o.requestID).Warn("retrying vision request", "retry", retry, "error", retryErr)
+	})
 	if err != nil {
-		return response, fmt.Errorf("error converting request to json: %v", err)
+		return response, err
+	}
+	if len(encodedImages) == 0 {
+		return response, errors.New("no images provided")
+	}
+	model := llm.VisionClient.GetConfig().AiModel
+	if o.customModel != "" {
+		model = o.customModel
 	}
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(contextWithRequestID(context.Background(), o.requestID), llm.visionTimeout())
 	defer cancel()
 
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return response, fmt.Errorf("error creating http request: %v", err)
+	cacheKey := ""
+	if llm.VisionCacheTTL > 0 {
+		cacheKey = visionCacheKey(model, query, o.MaxTokens, encodedImages)
+		cached, found := llm.getCachedVisionResponse(ctx, cacheKey)
+		llm.metrics.observeCache("vision", found)
+		if found {
+			if o.StreamingFunc != nil && len(cached.Choices) > 0 {
+				if err := o.StreamingFunc(ctx, []byte(cached.Choices[0].Message.Content)); err != nil {
+					return cached, err
+				}
+			}
+			return cached, nil
+		}
 	}
 
-	// Set HTTP headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+	parts := make([]llms.ContentPart, 0, len(encodedImages)+1)
+	for _, encodedImage := range encodedImages {
+		imagePart, err := llm.visionImagePart(ctx, encodedImage)
+		if err != nil {
+			return response, err
+		}
+		parts = append(parts, imagePart)
+	}
+	parts = append(parts, llms.TextPart(query))
 
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return response, fmt.Errorf("error sending request: %v", err)
+	msgs := []llms.MessageContent{
+		{
+			Role:  llms.ChatMessageTypeHuman,
+			Parts: parts,
+		},
+	}
+	temperature, topP := llm.generationParameters(&o)
+	callOptions := []llms.CallOption{
+		llms.WithTemperature(temperature),
+		llms.WithTopP(topP),
+		llms.WithMaxTokens(o.MaxTokens),
+	}
+	if o.customModel != "" {
+		callOptions = append(callOptions, llms.WithModel(o.customModel))
+	}
+	if o.StreamingFunc != nil {
+		callOptions = append(callOptions, llms.WithStreamingFunc(o.StreamingFunc))
 	}
-	defer resp.Body.Close()
 
-	// Read response
-	body, err := io.ReadAll(resp.Body)
+	resp, err := visionClient.GenerateContent(ctx, msgs, callOptions...)
 	if err != nil {
-		return response, fmt.Errorf("error reading response: %v", err)
+		llm.metrics.observeProviderError("vision")
+		return response, err
+	}
+	response = newChatCompletionResponse(model, resp)
+	if cacheKey != "" {
+		llm.cacheVisionResponse(ctx, cacheKey, response)
 	}
+	return response, nil
+}
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return response, fmt.Errorf("api error: status code %d\nresponse: %s", resp.StatusCode, string(body))
+// visionImagePart converts an encoded image into the content part expected by the vision client.
+//
+// Ollama only accepts raw image bytes, so remote images are downloaded first. Every other client
+// receives an image URL part.
+func (llm *LLMContainer) visionImagePart(ctx context.Context, encodedImage string) (llms.ContentPart, error) {
+	if _, isOllama := llm.VisionClient.(*OllamaController); isOllama {
+		if strings.HasPrefix(encodedImage, "http://") || strings.HasPrefix(encodedImage, "https://") {
+			imageData, err := llm.fetchImage(ctx, encodedImage)
+			if err != nil {
+				return nil, err
+			}
+			encodedImage, err = encodeImage(imageData, "")
+			if err != nil {
+				return nil, err
+			}
+		}
+		mimeType, data, err := decodeDataURL(encodedImage)
+		if err != nil {
+			return nil, err
+		}
+		return llms.BinaryPart(mimeType, data), nil
 	}
+	return llms.ImageURLPart(encodedImage), nil
+}
 
-	// Convert JSON response to Go structure
-	if err := json.Unmarshal(body, &response); err != nil {
-		return response, fmt.Errorf("error parsing JSON response: %v", err)
+// decodeDataURL extracts the MIME type and the data from a Base64 data URL.
+func decodeDataURL(dataURL string) (string, []byte, error) {
+	if !strings.HasPrefix(dataURL, "data:") {
+		return "", nil, errors.New("image must be a base64 data url")
 	}
+	header, payload, found := strings.Cut(strings.TrimPrefix(dataURL, "data:"), ",")
+	if !found || !strings.HasSuffix(header, ";base64") {
+		return "", nil, errors.New("image must be a base64 data url")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("error decoding image: %w", err)
+	}
+	return strings.TrimSuffix(header, ";base64"), data, nil
+}
 
-	// Display complete response
-	return response, nil
+// newChatCompletionResponse converts a langchaingo response into a ChatCompletionResponse.
+func newChatCompletionResponse(model string, resp *llms.ContentResponse) ChatCompletionResponse {
+	response := ChatCompletionResponse{
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+	}
+	if resp == nil {
+		return response
+	}
+	for idx, choice := range resp.Choices {
+		response.Choices = append(response.Choices, Choice{
+			Index: idx,
+			Message: ReplyMessage{
+				Role:    "assistant",
+				Content: choice.Content,
+			},
+			FinishReason: choice.StopReason,
+		})
+		if idx == 0 {
+			response.Usage = Usage{
+				PromptTokens:     generationInfoInt(choice.GenerationInfo, "PromptTokens"),
+				CompletionTokens: generationInfoInt(choice.GenerationInfo, "CompletionTokens"),
+				TotalTokens:      generationInfoInt(choice.GenerationInfo, "TotalTokens"),
+			}
+			response.TokenReport.VisionTokens = TokenUsage{
+				InputTokens:  response.Usage.PromptTokens,
+				OutputTokens: response.Usage.CompletionTokens,
+			}
+		}
+	}
+	return response
+}
+
+// generationInfoInt reads an integer value from a langchaingo GenerationInfo map.
+//
+// Providers store token counts with different numeric types, this function normalizes them.
+func generationInfoInt(info map[string]any, key string) int {
+	switch v := info[key].(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
 }
 
 // DescribeImageFromFile reads an image file, encodes it to Base64, and sends it along with a text query
@@ -212,23 +340,323 @@ func (llm *LLMContainer) DescribeImage(encodedImage, query string, options ...LL
 //	fmt.Println("Image Description:", description)
 //
 // Notes:
-//   - The function reads the image as a binary file using `os.ReadFile()`.
+//   - Options such as `WithStreamingFunc` are passed through to `DescribeImage()`.
+//   - The MIME type is detected from the file content and must be an image type.
 //   - It converts the image to a Base64-encoded string before calling `DescribeImage()`.
 //   - Requires a valid API token and properly configured `llm.VisionClient` settings.
-//   - If the image file is too large, encoding may consume significant memory.
+//   - Images larger than `llm.MaxImageSize` (20MB by default) are rejected.
 func (llm *LLMContainer) DescribeImageFromFile(imagePath, query string, options ...LLMCallOption) (ChatCompletionResponse, error) {
 	// reading image file
-	imageData, err := os.ReadFile(imagePath)
+	imageFile, err := os.Open(imagePath)
 	if err != nil {
-		return ChatCompletionResponse{}, fmt.Errorf("error reading file: %v", err)
+		return ChatCompletionResponse{}, fmt.Errorf("error reading file: %w", err)
 	}
+	defer imageFile.Close()
 
-	detectedMimeType, mimedetectionErr := mimetype.DetectFile(imagePath)
-	// checking for errors
-	if mimedetectionErr != nil {
-		return ChatCompletionResponse{}, fmt.Errorf("error detecting mime type: %v", mimedetectionErr)
+	imageData, err := llm.readImage(imageFile)
+	if err != nil {
+		return ChatCompletionResponse{}, err
 	}
 	// converting image to base64 string
-	encodedImage := fmt.Sprintf("data:"+detectedMimeType.String()+";base64,%s", base64.StdEncoding.EncodeToString(imageData))
+	encodedImage, err := encodeImage(imageData, "")
+	if err != nil {
+		return ChatCompletionResponse{}, err
+	}
 	return llm.DescribeImage(encodedImage, query, options...)
 }
+
+// defaultMaxImageSize is the image size limit used when LLMContainer.MaxImageSize is not set.
+const defaultMaxImageSize int64 = 20 * 1024 * 1024
+
+// DescribeImageFromReader reads an image from an io.Reader and sends it along with a text query
+// to an AI vision model for description.
+//
+// The image is rejected if it is larger than `llm.MaxImageSize` (20MB by default) or if it is not
+// an image. When mimeType is empty, the MIME type is detected from the image content.
+//
+// Parameters:
+//   - r: The reader providing the image data.
+//   - mimeType: The MIME type of the image, leave empty for detection.
+//   - query: A text query to describe the image (e.g., "Describe this image.").
+//   - options: Variadic LLMCallOption parameters for additional configuration (if applicable).
+//
+// Returns:
+//   - ChatCompletionResponse: The textual description generated by the AI model.
+//   - error: An error if the image cannot be read, is too large, is not an image or the AI request fails.
+func (llm *LLMContainer) DescribeImageFromReader(r io.Reader, mimeType, query string, options ...LLMCallOption) (ChatCompletionResponse, error) {
+	imageData, err := llm.readImage(r)
+	if err != nil {
+		return ChatCompletionResponse{}, err
+	}
+	encodedImage, err := encodeImage(imageData, mimeType)
+	if err != nil {
+		return ChatCompletionResponse{}, err
+	}
+	return llm.DescribeImage(encodedImage, query, options...)
+}
+
+// DescribeImageFromURL downloads an image and sends it along with a text query to an AI vision
+// model for description.
+//
+// The download is limited to `llm.MaxImageSize` bytes and the MIME type is detected from the
+// downloaded content, so the caller doesn't need to download and encode the image.
+//
+// Parameters:
+//   - imageURL: The URL of the image.
+//   - query: A text query to describe the image (e.g., "Describe this image.").
+//   - options: Variadic LLMCallOption parameters for additional configuration (if applicable).
+//
+// Returns:
+//   - ChatCompletionResponse: The textual description generated by the AI model.
+//   - error: An error if the download fails, the image is too large or the AI request fails.
+func (llm *LLMContainer) DescribeImageFromURL(imageURL, query string, options ...LLMCallOption) (ChatCompletionResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	imageData, err := llm.fetchImage(ctx, imageURL)
+	if err != nil {
+		return ChatCompletionResponse{}, err
+	}
+	encodedImage, err := encodeImage(imageData, "")
+	if err != nil {
+		return ChatCompletionResponse{}, err
+	}
+	return llm.DescribeImage(encodedImage, query, options...)
+}
+
+// fetchImage downloads a remote image while enforcing the configured size limit.
+func (llm *LLMContainer) fetchImage(ctx context.Context, imageURL string) ([]byte, error) {
+	return fetchImageData(ctx, imageURL, llm.maxImageSize())
+}
+
+// fetchImageData downloads a remote image of at most limit bytes.
+func fetchImageData(ctx context.Context, imageURL string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating http request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading image: status code %d", resp.StatusCode)
+	}
+	if resp.ContentLength > limit {
+		return nil, fmt.Errorf("image is larger than %d bytes", limit)
+	}
+	return readImageData(resp.Body, limit)
+}
+
+// visionTimeout returns the configured timeout of a single vision request.
+func (llm *LLMContainer) visionTimeout() time.Duration {
+	if llm.VisionTimeout > 0 {
+		return llm.VisionTimeout
+	}
+	return 60 * time.Second
+}
+
+// readImage reads image data while enforcing the configured size limit.
+func (llm *LLMContainer) readImage(r io.Reader) ([]byte, error) {
+	return readImageData(r, llm.maxImageSize())
+}
+
+// readImageData reads image data of at most limit bytes.
+func readImageData(r io.Reader, limit int64) ([]byte, error) {
+	imageData, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading image: %w", err)
+	}
+	if int64(len(imageData)) > limit {
+		return nil, fmt.Errorf("image is larger than %d bytes", limit)
+	}
+	return imageData, nil
+}
+
+// maxImageSize returns the configured image size limit.
+func (llm *LLMContainer) maxImageSize() int64 {
+	if llm.MaxImageSize > 0 {
+		return llm.MaxImageSize
+	}
+	return defaultMaxImageSize
+}
+
+// encodeImage converts image data to a Base64 data URL, detecting the MIME type when it is not provided.
+func encodeImage(imageData []byte, mimeType string) (string, error) {
+	if len(imageData) == 0 {
+		return "", errors.New("image is empty")
+	}
+	if mimeType == "" {
+		mimeType = mimetype.Detect(imageData).String()
+	}
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("unsupported image type: %s", mimeType)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(imageData), nil
+}
+
+// ImageInput describes a single image given by path, URL or raw bytes (batch descriptions, attachments, embeddings).
+//
+// Exactly one of Path, URL or Data should be set.
+//
+// Fields:
+//   - ID: Optional caller defined identifier returned with the result.
+//   - Path: Local file path of the image.
+//   - URL: Remote URL of the image.
+//   - Data: Raw image bytes.
+//   - MimeType: MIME type of Data, detected from the content when empty.
+type ImageInput struct {
+	ID       string
+	Path     string
+	URL      string
+	Data     []byte
+	MimeType string
+}
+
+// ImageDescriptionResult holds the outcome of describing a single image of a batch.
+//
+// Fields:
+//   - ID: The ID of the corresponding ImageInput.
+//   - Index: The position of the image in the input slice.
+//   - Response: The description generated by the AI model.
+//   - Err: An error if the image could not be described.
+type ImageDescriptionResult struct {
+	ID       string
+	Index    int
+	Response ChatCompletionResponse
+	Err      error
+}
+
+// defaultVisionWorkers is the number of concurrent vision requests used when LLMContainer.VisionWorkers is not set.
+const defaultVisionWorkers = 4
+
+// DescribeImages describes many images concurrently with the same query.
+//
+// A pool of `llm.VisionWorkers` workers (4 by default) processes the images. A failing image does not
+// stop the batch; its error is reported in the corresponding result.
+//
+// Parameters:
+//   - images: The images to describe.
+//   - query: A text query to describe the images (e.g., "Describe this image.").
+//   - options: Variadic LLMCallOption parameters applied to every request.
+//
+// Returns:
+//   - []ImageDescriptionResult: One result per image, in the same order as images.
+//
+// Example Usage:
+//
+//	results := llm.DescribeImages([]aillm.ImageInput{{Path: "a.jpg"}, {URL: "https://example.com/b.png"}}, "Describe this image.")
+//	for _, res := range results {
+//	    if res.Err != nil {
+//	        log.Println(res.Index, res.Err)
+//	        continue
+//	    }
+//	    fmt.Println(res.Response.Choices[0].Message.Content)
+//	}
+func (llm *LLMContainer) DescribeImages(images []ImageInput, query string, options ...LLMCallOption) []ImageDescriptionResult {
+	results := make([]ImageDescriptionResult, len(images))
+	workers := llm.VisionWorkers
+	if workers <= 0 {
+		workers = defaultVisionWorkers
+	}
+	if workers > len(images) {
+		workers = len(images)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				response, err := llm.describeImageInput(images[idx], query, options...)
+				results[idx] = ImageDescriptionResult{
+					ID:       images[idx].ID,
+					Index:    idx,
+					Response: response,
+					Err:      err,
+				}
+			}
+		}()
+	}
+	for idx := range images {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// describeImageInput dispatches a single ImageInput to the matching Describe function.
+func (llm *LLMContainer) describeImageInput(image ImageInput, query string, options ...LLMCallOption) (ChatCompletionResponse, error) {
+	switch {
+	case image.Path != "":
+		return llm.DescribeImageFromFile(image.Path, query, options...)
+	case image.URL != "":
+		return llm.DescribeImageFromURL(image.URL, query, options...)
+	case len(image.Data) > 0:
+		return llm.DescribeImageFromReader(bytes.NewReader(image.Data), image.MimeType, query, options...)
+	}
+	return ChatCompletionResponse{}, errors.New("image input has no path, url or data")
+}
+
+// visionOCRPrompt instructs the vision model to transcribe a rendered document page.
+const visionOCRPrompt = "Transcribe all text visible in this document page exactly as written, preserving reading order. Format tables as rows with cells separated by \" | \". Return only the transcribed text."
+
+// transcribeImage transcribes the text of a rendered document page using the VisionClient.
+//
+// Parameters:
+//   - imageData: Raw image bytes of the page.
+//
+// Returns:
+//   - string: The transcribed text.
+//   - error: An error if the vision request fails.
+func (llm *LLMContainer) transcribeImage(imageData []byte) (string, error) {
+	encodedImage, err := encodeImage(imageData, "")
+	if err != nil {
+		return "", err
+	}
+	response, err := llm.DescribeImage(encodedImage, visionOCRPrompt)
+	if err != nil {
+		return "", err
+	}
+	if len(response.Choices) == 0 {
+		return "", errors.New("vision model returned no transcription")
+	}
+	return response.Choices[0].Message.Content, nil
+}
+
+// describeImageAttachments describes the images attached to an AskLLM query.
+//
+// The description focuses on the details relevant to the question, so it can be used both as
+// additional retrieval text and as context in the prompt.
+//
+// Parameters:
+//   - images: The attached images.
+//   - query: The user question.
+//
+// Returns:
+//   - string: The descriptions of all images.
+//   - TokenUsage: The vision tokens used.
+//   - error: An error if any image cannot be described.
+func (llm *LLMContainer) describeImageAttachments(images []ImageInput, query string) (string, TokenUsage, error) {
+	tokens := TokenUsage{}
+	prompt := "Describe this image in detail, focusing on everything relevant to the following question. Do not answer the question.\nQuestion: " + query
+	descriptions := make([]string, 0, len(images))
+	for idx, image := range images {
+		response, err := llm.describeImageInput(image, prompt)
+		if err != nil {
+			return "", tokens, err
+		}
+		tokens.InputTokens += response.TokenReport.VisionTokens.InputTokens
+		tokens.OutputTokens += response.TokenReport.VisionTokens.OutputTokens
+		if len(response.Choices) == 0 {
+			return "", tokens, errors.New("vision model returned no description")
+		}
+		descriptions = append(descriptions, fmt.Sprintf("Image %d: %s", idx+1, response.Choices[0].Message.Content))
+	}
+	return strings.Join(descriptions, "\n"), tokens, nil
+}
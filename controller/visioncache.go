@@ -0,0 +1,77 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// visionCachePrefix is the Redis key prefix of cached image descriptions.
+const visionCachePrefix = "visioncache:"
+
+// visionCacheKey builds the cache key of a vision request.
+//
+// Images are hashed individually so the key stays small for large Base64 payloads; the query,
+// model and token limit are part of the key because they change the description.
+func visionCacheKey(model, query string, maxTokens int, encodedImages []string) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%d", model, query, maxTokens)
+	for _, encodedImage := range encodedImages {
+		imageHash := sha256.Sum256([]byte(encodedImage))
+		hash.Write(imageHash[:])
+	}
+	return visionCachePrefix + hex.EncodeToString(hash.Sum(nil))
+}
+
+// getCachedVisionResponse returns a cached vision response.
+//
+// Cache failures are never fatal, they are reported as warnings and treated as a miss.
+func (llm *LLMContainer) getCachedVisionResponse(ctx context.Context, key string) (ChatCompletionResponse, bool) {
+	response := ChatCompletionResponse{}
+	rdb := llm.RedisClient.redisClient
+	if rdb == nil {
+		return response, false
+	}
+	cached, err := rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		return response, false
+	}
+	if err := json.Unmarshal(cached, &response); err != nil {
+		if llm.ShowWarnings {
+			llm.logger().Warn("invalid vision cache entry", "key", key, "error", err)
+		}
+		return response, false
+	}
+	response.Cached = true
+	return response, true
+}
+
+// cacheVisionResponse stores a vision response for llm.VisionCacheTTL.
+func (llm *LLMContainer) cacheVisionResponse(ctx context.Context, key string, response ChatCompletionResponse) {
+	rdb := llm.RedisClient.redisClient
+	if rdb == nil {
+		return
+	}
+	data, err := json.Marshal(response)
+	if err == nil {
+		err = rdb.Set(ctx, key, data, llm.VisionCacheTTL).Err()
+	}
+	if err != nil && llm.ShowWarnings {
+		llm.logger().Warn("unable to cache vision response", "key", key, "error", err)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package aillm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// WebSearchResult is a single result of a web search.
+//
+// Fields:
+//   - Title: The title of the page.
+//   - URL: The address of the page.
+//   - Snippet: The text of the page matching the query.
+type WebSearchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+// WebSearcher defines a web search engine used by the web search fallback.
+//
+// Implementations must be safe for concurrent use.
+type WebSearcher interface {
+	// SearchWeb returns up to maxResults results of a query.
+	SearchWeb(ctx context.Context, query string, maxResults int) ([]WebSearchResult, error)
+}
+
+// SearXNGSearcher searches the web with a SearXNG instance, its JSON format must be enabled.
+//
+// Fields:
+//   - URL: The address of the instance, e.g. "http://localhost:8888".
+//   - Client: The HTTP client, defaults to a client with a 10 second timeout.
+type SearXNGSearcher struct {
+	URL    string
+	Client *http.Client
+}
+
+// SearchWeb queries the /search endpoint of the instance.
+func (ss *SearXNGSearcher) SearchWeb(ctx context.Context, query string, maxResults int) ([]WebSearchResult, error) {
+	endpoint := strings.TrimRight(ss.URL, "/") + "/search?format=json&q=" + url.QueryEscape(query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := ss.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("web search returned %s", resp.Status)
+	}
+	var body struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid web search response: %w", err)
+	}
+	results := []WebSearchResult{}
+	for _, result := range body.Results {
+		if len(results) == maxResults {
+			break
+		}
+		results = append(results, WebSearchResult{Title: result.Title, URL: result.URL, Snippet: result.Content})
+	}
+	return results, nil
+}
+
+// WebFallbackConfig answers from a web search when retrieval finds no document and hallucination is disallowed.
+//
+// The results are embedded in memory only, they are never stored in Redis. The answer states that it comes from the web
+// and the used pages are returned in LLMResult.WebSources.
+//
+// Fields:
+//   - Searcher: The web search engine, the fallback is disabled when nil.
+//   - MaxResults: The number of search results requested (default 8).
+//   - ChunkCount: The number of results closest to the query added to the prompt (default 3).
+//   - MinSimilarity: The minimum cosine similarity of a result to the query (default 0.3).
+type WebFallbackConfig struct {
+	Searcher      WebSearcher
+	MaxResults    int
+	ChunkCount    int
+	MinSimilarity float64
+}
+
+func (wc WebFallbackConfig) maxResults() int {
+	if wc.MaxResults > 0 {
+		return wc.MaxResults
+	}
+	return 8
+}
+
+func (wc WebFallbackConfig) chunkCount() int {
+	if wc.ChunkCount > 0 {
+		return wc.ChunkCount
+	}
+	return 3
+}
+
+func (wc WebFallbackConfig) minSimilarity() float64 {
+	if wc.MinSimilarity > 0 {
+		return wc.MinSimilarity
+	}
+	return 0.3
+}
+
+// webSourcingPrompt asks the model to tell the user that the answer comes from the web.
+const webSourcingPrompt = `
+- The knowledge above comes from a web search, not from the documents of the user. Start the answer by saying it was found on the web and end it with the URLs of the used pages.`
+
+// WithWebFallback enables or disables the web search fallback of a call, overriding the container default, which
+// is enabled when WebFallback.Searcher is set.
+//
+// Parameters:
+//   - enabled: True searches the web when retrieval finds no document, false never does.
+//
+// Returns:
+//   - LLMCallOption: An option that sets the web search fallback.
+func (llm *LLMContainer) WithWebFallback(enabled bool) LLMCallOption {
+	return func(o *LLMCallOptions) {
+		o.webFallback = &enabled
+	}
+}
+
+// useWebFallback reports whether a call without retrieved documents searches the web.
+func (llm *LLMContainer) useWebFallback(o LLMCallOptions) bool {
+	if llm.WebFallback.Searcher == nil || llm.AllowHallucinate || o.AllowHallucinate || o.ExtraContext != "" {
+		return false
+	}
+	return o.webFallback == nil || *o.webFallback
+}
+
+// searchWeb searches the web for a query and keeps the results closest to it.
+//
+// The results are ranked with the embedding model in memory.
+//
+// Returns:
+//   - []schema.Document: The kept results as documents with "url", "title" and "search_type" metadata.
+//   - []WebSearchResult: The kept results.
+//   - error: An error if the search or the embedding fails.
+func (llm *LLMContainer) searchWeb(ctx context.Context, query string) ([]schema.Document, []WebSearchResult, error) {
+	results, err := llm.WebFallback.Searcher.SearchWeb(ctx, query, llm.WebFallback.maxResults())
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil, nil
+	}
+	if err := llm.ensureEmbedder(); err != nil {
+		return nil, nil, err
+	}
+	embedder, err := llm.getEmbedder()
+	if err != nil {
+		return nil, nil, err
+	}
+	texts := make([]string, len(results))
+	for i, result := range results {
+		texts[i] = result.Title + "\n" + result.Snippet
+	}
+	vectors, err := embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, nil, err
+	}
+	queryVector, err := embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(vectors) != len(results) {
+		return nil, nil, errors.New("web search results and embeddings don't match")
+	}
+
+	type rankedResult struct {
+		result     WebSearchResult
+		text       string
+		similarity float64
+	}
+	ranked := []rankedResult{}
+	for i, result := range results {
+		similarity := cosineSimilarity(queryVector, vectors[i])
+		if similarity >= llm.WebFallback.minSimilarity() {
+			ranked = append(ranked, rankedResult{result: result, text: texts[i], similarity: similarity})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].similarity > ranked[j].similarity
+	})
+	if len(ranked) > llm.WebFallback.chunkCount() {
+		ranked = ranked[:llm.WebFallback.chunkCount()]
+	}
+	docs := make([]schema.Document, 0, len(ranked))
+	sources := make([]WebSearchResult, 0, len(ranked))
+	for _, rank := range ranked {
+		docs = append(docs, schema.Document{
+			PageContent: rank.text + "\nSource: " + rank.result.URL,
+			// Scores are cosine distances, as reported by the vector store
+			Score: float32(1 - rank.similarity),
+			Metadata: map[string]interface{}{
+				"url":         rank.result.URL,
+				"title":       rank.result.Title,
+				"search_type": "web",
+			},
+		})
+		sources = append(sources, rank.result)
+	}
+	return docs, sources, nil
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors.
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		if i >= len(b) {
+			break
+		}
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,379 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eval measures the retrieval and answer quality of an LLMContainer on a set of test
+// questions, e.g. to compare score thresholds, chunk sizes or search algorithms.
+//
+// Metrics:
+//   - Recall@k: The share of the expected sources found in the first k retrieved chunks.
+//   - MRR: The mean reciprocal rank of the first retrieved chunk of an expected source.
+//   - Answer similarity: The cosine similarity of the embeddings of the answer and the expected answer.
+//   - Faithfulness: The groundedness score of the answer against the retrieved chunks.
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	aillm "github.com/RezaArani/aillm/controller"
+	"github.com/tmc/langchaingo/embeddings"
+	"sigs.k8s.io/yaml"
+)
+
+// Case is a test question of a dataset.
+//
+// Fields:
+//   - Question: The question asked to the container.
+//   - ExpectedSource, ExpectedSources: The documents the answer comes from, matched against the ID, the title
+//     or the sources of the retrieved chunks. Retrieval metrics are skipped for cases without expected sources.
+//   - ExpectedAnswer: The reference answer, answer similarity is skipped when it is empty.
+//   - Index: Optional embedding index searched for this question.
+type Case struct {
+	Question        string   `json:"question"`
+	ExpectedSource  string   `json:"expectedSource,omitempty"`
+	ExpectedSources []string `json:"expectedSources,omitempty"`
+	ExpectedAnswer  string   `json:"expectedAnswer,omitempty"`
+	Index           string   `json:"index,omitempty"`
+}
+
+// sources returns the expected sources of the case.
+func (c Case) sources() []string {
+	sources := append([]string{}, c.ExpectedSources...)
+	if c.ExpectedSource != "" {
+		sources = append(sources, c.ExpectedSource)
+	}
+	return sources
+}
+
+// Dataset is a set of test questions.
+//
+// Fields:
+//   - Name: Optional name reported with the results.
+//   - Prefix: Optional embedding prefix searched by every question.
+//   - Cases: The test questions.
+type Dataset struct {
+	Name   string `json:"name,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	Cases  []Case `json:"cases"`
+}
+
+// LoadDataset reads a dataset from a YAML or JSON file.
+//
+// Example file:
+//
+//	name: support
+//	cases:
+//	  - question: How long is the warranty?
+//	    expectedSource: warranty-policy
+//	    expectedAnswer: The warranty lasts two years.
+func LoadDataset(path string) (Dataset, error) {
+	dataset := Dataset{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return dataset, err
+	}
+	if err := yaml.Unmarshal(data, &dataset); err != nil {
+		return dataset, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(dataset.Cases) == 0 {
+		return dataset, fmt.Errorf("%s: the dataset has no cases", path)
+	}
+	return dataset, nil
+}
+
+// Runner evaluates an initialized container.
+//
+// Fields:
+//   - LLM: The container to evaluate.
+//   - K: The number of retrieved chunks considered by Recall@k, defaults to LLM.RagRowCount. AskLLM
+//     retrieves RagRowCount chunks, so larger values don't retrieve more.
+//   - SkipFaithfulness: Skips the groundedness check, which costs an additional model call per question.
+//   - Options: Options added to every AskLLM call, e.g. a search algorithm.
+type Runner struct {
+	LLM              *aillm.LLMContainer
+	K                int
+	SkipFaithfulness bool
+	Options          []aillm.LLMCallOption
+}
+
+// CaseResult holds the metrics of a single question.
+//
+// Fields:
+//   - Question: The question.
+//   - RetrievedSources: The sources of the retrieved chunks, in retrieval order.
+//   - Recall: The share of the expected sources in the first K chunks, nil without expected sources.
+//   - ReciprocalRank: 1/rank of the first chunk of an expected source, 0 if none was retrieved.
+//   - Answer: The generated answer.
+//   - AnswerSimilarity: Cosine similarity with the expected answer, nil without expected answer.
+//   - Faithfulness: The groundedness score of the answer, nil when skipped.
+//   - Error: The error of the question, the question is then excluded from the averages.
+type CaseResult struct {
+	Question         string   `json:"question"`
+	RetrievedSources []string `json:"retrievedSources"`
+	Recall           *float64 `json:"recall,omitempty"`
+	ReciprocalRank   *float64 `json:"reciprocalRank,omitempty"`
+	Answer           string   `json:"answer"`
+	AnswerSimilarity *float64 `json:"answerSimilarity,omitempty"`
+	Faithfulness     *float64 `json:"faithfulness,omitempty"`
+	Error            string   `json:"error,omitempty"`
+}
+
+// Report holds the averaged metrics of a dataset.
+//
+// Averages only include the questions the metric applies to.
+type Report struct {
+	Dataset          string       `json:"dataset,omitempty"`
+	K                int          `json:"k"`
+	RecallAtK        float64      `json:"recallAtK"`
+	MRR              float64      `json:"mrr"`
+	AnswerSimilarity float64      `json:"answerSimilarity"`
+	Faithfulness     float64      `json:"faithfulness"`
+	Errors           int          `json:"errors"`
+	Cases            []CaseResult `json:"cases"`
+}
+
+// Run asks every question of the dataset and computes the metrics.
+//
+// Failed questions are reported in their CaseResult and don't stop the run.
+//
+// Parameters:
+//   - ctx: Stops the run between questions when it is done.
+//   - dataset: The test questions.
+//
+// Returns:
+//   - Report: The metrics of every question and their averages.
+//   - error: An error if the runner has no container or ctx is done.
+//
+// Example Usage:
+//
+//	dataset, _ := eval.LoadDataset("questions.yaml")
+//	report, err := (&eval.Runner{LLM: &llm}).Run(context.Background(), dataset)
+//	report.WriteText(os.Stdout)
+func (r *Runner) Run(ctx context.Context, dataset Dataset) (Report, error) {
+	report := Report{Dataset: dataset.Name, K: r.K, Cases: []CaseResult{}}
+	if r.LLM == nil {
+		return report, errors.New("eval: the runner has no container")
+	}
+	if report.K <= 0 {
+		report.K = r.LLM.RagRowCount
+	}
+	var embedder embeddings.Embedder
+	recall, mrr, similarity, faithfulness := average{}, average{}, average{}, average{}
+	for _, testCase := range dataset.Cases {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		options := append([]aillm.LLMCallOption{}, r.Options...)
+		if dataset.Prefix != "" {
+			options = append(options, r.LLM.WithEmbeddingPrefix(dataset.Prefix))
+		}
+		if testCase.Index != "" {
+			options = append(options, r.LLM.WithEmbeddingIndex(testCase.Index))
+		}
+		caseResult := CaseResult{Question: testCase.Question, RetrievedSources: []string{}}
+		result, err := r.LLM.AskLLM(testCase.Question, options...)
+		if err != nil {
+			caseResult.Error = err.Error()
+			report.Errors++
+			report.Cases = append(report.Cases, caseResult)
+			continue
+		}
+		if result.Response != nil && len(result.Response.Choices) > 0 {
+			caseResult.Answer = result.Response.Choices[0].Content
+		}
+		for _, doc := range result.RagDocs {
+			caseResult.RetrievedSources = append(caseResult.RetrievedSources, documentSource(doc))
+		}
+
+		if expected := testCase.sources(); len(expected) > 0 {
+			caseRecall, reciprocalRank := retrievalMetrics(result.RagDocs, expected, report.K)
+			caseResult.Recall, caseResult.ReciprocalRank = &caseRecall, &reciprocalRank
+			recall.add(caseRecall)
+			mrr.add(reciprocalRank)
+		}
+		if testCase.ExpectedAnswer != "" && caseResult.Answer != "" {
+			if embedder == nil {
+				// The embedder is initialized by the first AskLLM call
+				if embedder, err = r.LLM.Embedder.NewEmbedder(); err != nil {
+					return report, err
+				}
+			}
+			score, err := answerSimilarity(ctx, embedder, caseResult.Answer, testCase.ExpectedAnswer)
+			if err != nil {
+				caseResult.Error = err.Error()
+			} else {
+				caseResult.AnswerSimilarity = &score
+				similarity.add(score)
+			}
+		}
+		if !r.SkipFaithfulness && caseResult.Answer != "" && len(result.RagDocs) > 0 {
+			groundedness, _, err := r.LLM.CheckGroundedness(ctx, caseResult.Answer, result.RagDocs)
+			if err != nil {
+				caseResult.Error = err.Error()
+			} else {
+				caseResult.Faithfulness = &groundedness.Score
+				faithfulness.add(groundedness.Score)
+			}
+		}
+		report.Cases = append(report.Cases, caseResult)
+	}
+	report.RecallAtK, report.MRR = recall.value(), mrr.value()
+	report.AnswerSimilarity, report.Faithfulness = similarity.value(), faithfulness.value()
+	return report, nil
+}
+
+// average accumulates a mean.
+type average struct {
+	sum   float64
+	count int
+}
+
+func (a *average) add(value float64) {
+	a.sum += value
+	a.count++
+}
+
+func (a average) value() float64 {
+	if a.count == 0 {
+		return 0
+	}
+	return a.sum / float64(a.count)
+}
+
+// embeddedContent returns the document metadata stored with a chunk.
+func embeddedContent(doc aillm.RetrievedDocument) aillm.LLMEmbeddingContent {
+	content := aillm.LLMEmbeddingContent{}
+	if rawKey, ok := doc.Metadata["rawkey"].(string); ok {
+		json.Unmarshal([]byte(rawKey), &content)
+	}
+	return content
+}
+
+// documentSource returns the ID of the document of a chunk, or its title or sources without ID.
+func documentSource(doc aillm.RetrievedDocument) string {
+	content := embeddedContent(doc)
+	switch {
+	case content.Id != "":
+		return content.Id
+	case content.Title != "":
+		return content.Title
+	}
+	return content.Sources
+}
+
+// matchesSource reports whether a chunk belongs to an expected source.
+func matchesSource(doc aillm.RetrievedDocument, source string) bool {
+	content := embeddedContent(doc)
+	source = strings.TrimSpace(source)
+	return source != "" && (strings.EqualFold(content.Id, source) || strings.EqualFold(content.Title, source) ||
+		strings.Contains(strings.ToLower(content.Sources), strings.ToLower(source)))
+}
+
+// retrievalMetrics computes the recall of the expected sources in the first k chunks and the reciprocal
+// rank of the first chunk of an expected source.
+func retrievalMetrics(docs []aillm.RetrievedDocument, expected []string, k int) (float64, float64) {
+	found := map[string]bool{}
+	reciprocalRank := 0.0
+	for rank, doc := range docs {
+		for _, source := range expected {
+			if !matchesSource(doc, source) {
+				continue
+			}
+			if reciprocalRank == 0 {
+				reciprocalRank = 1 / float64(rank+1)
+			}
+			if rank < k {
+				found[strings.ToLower(source)] = true
+			}
+		}
+	}
+	distinct := map[string]bool{}
+	for _, source := range expected {
+		distinct[strings.ToLower(source)] = true
+	}
+	return float64(len(found)) / float64(len(distinct)), reciprocalRank
+}
+
+// answerSimilarity returns the cosine similarity of the embeddings of two answers.
+func answerSimilarity(ctx context.Context, embedder embeddings.Embedder, answer, expected string) (float64, error) {
+	vectors, err := embedder.EmbedDocuments(ctx, []string{answer, expected})
+	if err != nil {
+		return 0, err
+	}
+	if len(vectors) != 2 {
+		return 0, errors.New("eval: unexpected number of embeddings")
+	}
+	var dot, normA, normB float64
+	for i := range vectors[0] {
+		if i >= len(vectors[1]) {
+			break
+		}
+		a, b := float64(vectors[0][i]), float64(vectors[1][i])
+		dot += a * b
+		normA += a * a
+		normB += b * b
+	}
+	if normA == 0 || normB == 0 {
+		return 0, nil
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB)), nil
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteText writes the averages and a table of the questions.
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if r.Dataset != "" {
+		fmt.Fprintf(tw, "Dataset:\t%s\n", r.Dataset)
+	}
+	fmt.Fprintf(tw, "Recall@%d:\t%.3f\n", r.K, r.RecallAtK)
+	fmt.Fprintf(tw, "MRR:\t%.3f\n", r.MRR)
+	fmt.Fprintf(tw, "Answer similarity:\t%.3f\n", r.AnswerSimilarity)
+	fmt.Fprintf(tw, "Faithfulness:\t%.3f\n", r.Faithfulness)
+	fmt.Fprintf(tw, "Errors:\t%d/%d\n\n", r.Errors, len(r.Cases))
+	fmt.Fprintln(tw, "Question\tRecall\tRR\tSimilarity\tFaithfulness\tError")
+	for _, c := range r.Cases {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", shorten(c.Question, 60), metric(c.Recall), metric(c.ReciprocalRank),
+			metric(c.AnswerSimilarity), metric(c.Faithfulness), c.Error)
+	}
+	return tw.Flush()
+}
+
+func metric(value *float64) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.3f", *value)
+}
+
+func shorten(text string, length int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= length {
+		return string(runes)
+	}
+	return string(runes[:length-1]) + "…"
+}
@@ -79,7 +79,7 @@ func askKLLM(llm aillm.LLMContainer,  query string) {
 	log.Println("Reference Documents: ", len(resDocs))
 
 	for idx, doc := range resDocs {
-		srcDocs := fmt.Sprintf("\t%v. Score: %v,\tSource: %s+...", idx+1, doc.Score, doc.PageContent[:50])
+		srcDocs := fmt.Sprintf("\t%v. Score: %v,\tSource: %s+...", idx+1, doc.Score, doc.Content[:50])
 		log.Println(srcDocs)
 	}
 
@@ -147,7 +147,7 @@ func testSearchAlgorithm(llm aillm.LLMContainer, query string, searchOption aill
 
 	log.Printf("📊 Found %d relevant documents", len(result.RagDocs))
 	for i, doc := range result.RagDocs {
-		log.Printf("   %d. Score: %.3f - %s", i+1, doc.Score, getDocumentTitle(doc))
+		log.Printf("   %d. Score: %.3f - %s", i+1, doc.Score, getDocumentTitle(doc.Document()))
 
 		// Show search type if available
 		if searchType, ok := doc.Metadata["search_type"]; ok {
@@ -170,29 +170,29 @@ func testSearchAlgorithm(llm aillm.LLMContainer, query string, searchOption aill
 func testQueryComparison(llm aillm.LLMContainer, query string) {
 	log.Printf("Query: '%s'", query)
 
-	// Test with different search algorithms
-	algorithms := []struct {
-		name   string
-		option aillm.LLMCallOption
-	}{
-		{"Vector", llm.WithSimilaritySearch()},
-		{"Lexical", llm.WithLexicalSearch()},
-		{"Hybrid", llm.WithHybridSearch()},
+	// Compare the documents retrieved by different search algorithms
+	comparison, err := llm.CompareSearch(query, []aillm.SearchAlgorithm{aillm.SimilaritySearch, aillm.LexicalSearch, aillm.HybridSearch}, llm.WithEmbeddingIndex("HybridSearchDemo"))
+	if err != nil {
+		log.Printf("   ❌ Error: %v", err)
+		return
 	}
 
-	for _, alg := range algorithms {
-		result, err := llm.AskLLM(query, alg.option)
-		if err != nil {
-			log.Printf("   %s: ❌ Error: %v", alg.name, err)
+	for _, run := range comparison.Runs {
+		if run.Error != nil {
+			log.Printf("   %s: ❌ Error: %v", run.Name, run.Error)
 			continue
 		}
 
-		log.Printf("   %s: Found %d docs", alg.name, len(result.RagDocs))
-		if len(result.RagDocs) > 0 {
+		log.Printf("   %s: Found %d docs in %v", run.Name, len(run.Documents), run.Duration)
+		if len(run.Documents) > 0 {
 			log.Printf("        Top result: %s (Score: %.3f)",
-				getDocumentTitle(result.RagDocs[0]), result.RagDocs[0].Score)
+				getDocumentTitle(run.Documents[0]), run.Documents[0].Score)
 		}
 	}
+	for _, overlap := range comparison.Overlaps {
+		log.Printf("   %s/%s: %d shared docs (Jaccard %.2f)", overlap.A, overlap.B, overlap.Shared, overlap.Jaccard)
+	}
+	log.Printf("   Found by all: %d docs", len(comparison.Common))
 }
 
 func getDocumentTitle(doc schema.Document) string {
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"log"
+
+	aillm "github.com/RezaArani/aillm/controller"
+	"github.com/RezaArani/aillm/server"
+)
+
+func main() {
+	llmclient := &aillm.OllamaController{
+		Config: aillm.LLMConfig{
+			Apiurl:  "http://127.0.0.1:11434",
+			AiModel: "llama3.1",
+		},
+	}
+
+	// Create an LLM instance with OllamaClient
+	llm := aillm.LLMContainer{
+		Embedder:  llmclient,
+		LLMClient: llmclient,
+		RedisClient: aillm.RedisClient{
+			Host: "localhost:6379",
+		},
+	}
+	if err := llm.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	// curl -X POST localhost:8080/embed -d '{"index":"SemMapas","language":"en","text":"SemMapas is a tourism platform..."}'
+	// curl -X POST localhost:8080/ask -d '{"query":"What is SemMapas?","sessionId":"User1"}'
+	// curl -N -X POST localhost:8080/ask -d '{"query":"What is SemMapas?","stream":true}'
+	log.Println("Listening on :8080")
+	log.Fatal(server.New(&llm).ListenAndServe(":8080"))
+}
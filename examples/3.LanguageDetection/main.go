@@ -49,7 +49,7 @@ func main() {
 
 func askKLLM(llm aillm.LLMContainer, Language, query string) {
 	log.Println("LLM Reply to " + query + ":")
-	queryResult, err := llm.AskLLM(query, llm.WithLanguage(Language), llm.WithStreamingFunc(print))
+	queryResult, err := llm.AskLLM(query, llm.WithLanguage(aillm.LanguageTag(Language)), llm.WithStreamingFunc(print))
 	response := queryResult.Response
 	resDocs := queryResult.RagDocs
 	if err != nil {
@@ -61,7 +61,7 @@ func askKLLM(llm aillm.LLMContainer, Language, query string) {
 	log.Println("Reference Documents: ", len(resDocs))
 
 	for idx, doc := range resDocs {
-		srcDocs := fmt.Sprintf("\t%v. Score: %v,\tSource: %s+...", idx+1, doc.Score, doc.PageContent[:50])
+		srcDocs := fmt.Sprintf("\t%v. Score: %v,\tSource: %s+...", idx+1, doc.Score, doc.Content[:50])
 		log.Println(srcDocs)
 	}
 
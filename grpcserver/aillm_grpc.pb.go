@@ -0,0 +1,318 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: aillm.proto
+
+package grpcserver
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	Aillm_Ask_FullMethodName             = "/aillm.v1.Aillm/Ask"
+	Aillm_AskStream_FullMethodName       = "/aillm.v1.Aillm/AskStream"
+	Aillm_Embed_FullMethodName           = "/aillm.v1.Aillm/Embed"
+	Aillm_RemoveEmbedding_FullMethodName = "/aillm.v1.Aillm/RemoveEmbedding"
+	Aillm_ListEmbeddings_FullMethodName  = "/aillm.v1.Aillm/ListEmbeddings"
+)
+
+// AillmClient is the client API for Aillm service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Aillm exposes an LLMContainer as a gRPC service.
+type AillmClient interface {
+	// Ask answers a query with the RAG pipeline.
+	Ask(ctx context.Context, in *AskRequest, opts ...grpc.CallOption) (*AskResponse, error)
+	// AskStream answers a query and streams the answer chunk by chunk, the last message holds the result.
+	AskStream(ctx context.Context, in *AskRequest, opts ...grpc.CallOption) (Aillm_AskStreamClient, error)
+	// Embed embeds a text into an index.
+	Embed(ctx context.Context, in *EmbedRequest, opts ...grpc.CallOption) (*EmbedResponse, error)
+	// RemoveEmbedding removes the embeddings of an index.
+	RemoveEmbedding(ctx context.Context, in *RemoveEmbeddingRequest, opts ...grpc.CallOption) (*RemoveEmbeddingResponse, error)
+	// ListEmbeddings lists the embedded documents with pagination.
+	ListEmbeddings(ctx context.Context, in *ListEmbeddingsRequest, opts ...grpc.CallOption) (*ListEmbeddingsResponse, error)
+}
+
+type aillmClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAillmClient(cc grpc.ClientConnInterface) AillmClient {
+	return &aillmClient{cc}
+}
+
+func (c *aillmClient) Ask(ctx context.Context, in *AskRequest, opts ...grpc.CallOption) (*AskResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AskResponse)
+	err := c.cc.Invoke(ctx, Aillm_Ask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aillmClient) AskStream(ctx context.Context, in *AskRequest, opts ...grpc.CallOption) (Aillm_AskStreamClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Aillm_ServiceDesc.Streams[0], Aillm_AskStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aillmAskStreamClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Aillm_AskStreamClient interface {
+	Recv() (*AskStreamResponse, error)
+	grpc.ClientStream
+}
+
+type aillmAskStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *aillmAskStreamClient) Recv() (*AskStreamResponse, error) {
+	m := new(AskStreamResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *aillmClient) Embed(ctx context.Context, in *EmbedRequest, opts ...grpc.CallOption) (*EmbedResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EmbedResponse)
+	err := c.cc.Invoke(ctx, Aillm_Embed_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aillmClient) RemoveEmbedding(ctx context.Context, in *RemoveEmbeddingRequest, opts ...grpc.CallOption) (*RemoveEmbeddingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveEmbeddingResponse)
+	err := c.cc.Invoke(ctx, Aillm_RemoveEmbedding_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aillmClient) ListEmbeddings(ctx context.Context, in *ListEmbeddingsRequest, opts ...grpc.CallOption) (*ListEmbeddingsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListEmbeddingsResponse)
+	err := c.cc.Invoke(ctx, Aillm_ListEmbeddings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AillmServer is the server API for Aillm service.
+// All implementations must embed UnimplementedAillmServer
+// for forward compatibility
+//
+// Aillm exposes an LLMContainer as a gRPC service.
+type AillmServer interface {
+	// Ask answers a query with the RAG pipeline.
+	Ask(context.Context, *AskRequest) (*AskResponse, error)
+	// AskStream answers a query and streams the answer chunk by chunk, the last message holds the result.
+	AskStream(*AskRequest, Aillm_AskStreamServer) error
+	// Embed embeds a text into an index.
+	Embed(context.Context, *EmbedRequest) (*EmbedResponse, error)
+	// RemoveEmbedding removes the embeddings of an index.
+	RemoveEmbedding(context.Context, *RemoveEmbeddingRequest) (*RemoveEmbeddingResponse, error)
+	// ListEmbeddings lists the embedded documents with pagination.
+	ListEmbeddings(context.Context, *ListEmbeddingsRequest) (*ListEmbeddingsResponse, error)
+	mustEmbedUnimplementedAillmServer()
+}
+
+// UnimplementedAillmServer must be embedded to have forward compatible implementations.
+type UnimplementedAillmServer struct {
+}
+
+func (UnimplementedAillmServer) Ask(context.Context, *AskRequest) (*AskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ask not implemented")
+}
+func (UnimplementedAillmServer) AskStream(*AskRequest, Aillm_AskStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method AskStream not implemented")
+}
+func (UnimplementedAillmServer) Embed(context.Context, *EmbedRequest) (*EmbedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Embed not implemented")
+}
+func (UnimplementedAillmServer) RemoveEmbedding(context.Context, *RemoveEmbeddingRequest) (*RemoveEmbeddingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveEmbedding not implemented")
+}
+func (UnimplementedAillmServer) ListEmbeddings(context.Context, *ListEmbeddingsRequest) (*ListEmbeddingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEmbeddings not implemented")
+}
+func (UnimplementedAillmServer) mustEmbedUnimplementedAillmServer() {}
+
+// UnsafeAillmServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AillmServer will
+// result in compilation errors.
+type UnsafeAillmServer interface {
+	mustEmbedUnimplementedAillmServer()
+}
+
+func RegisterAillmServer(s grpc.ServiceRegistrar, srv AillmServer) {
+	s.RegisterService(&Aillm_ServiceDesc, srv)
+}
+
+func _Aillm_Ask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AillmServer).Ask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Aillm_Ask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AillmServer).Ask(ctx, req.(*AskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aillm_AskStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AskRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AillmServer).AskStream(m, &aillmAskStreamServer{ServerStream: stream})
+}
+
+type Aillm_AskStreamServer interface {
+	Send(*AskStreamResponse) error
+	grpc.ServerStream
+}
+
+type aillmAskStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *aillmAskStreamServer) Send(m *AskStreamResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Aillm_Embed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmbedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AillmServer).Embed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Aillm_Embed_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AillmServer).Embed(ctx, req.(*EmbedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aillm_RemoveEmbedding_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveEmbeddingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AillmServer).RemoveEmbedding(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Aillm_RemoveEmbedding_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AillmServer).RemoveEmbedding(ctx, req.(*RemoveEmbeddingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aillm_ListEmbeddings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEmbeddingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AillmServer).ListEmbeddings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Aillm_ListEmbeddings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AillmServer).ListEmbeddings(ctx, req.(*ListEmbeddingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Aillm_ServiceDesc is the grpc.ServiceDesc for Aillm service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Aillm_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "aillm.v1.Aillm",
+	HandlerType: (*AillmServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ask",
+			Handler:    _Aillm_Ask_Handler,
+		},
+		{
+			MethodName: "Embed",
+			Handler:    _Aillm_Embed_Handler,
+		},
+		{
+			MethodName: "RemoveEmbedding",
+			Handler:    _Aillm_RemoveEmbedding_Handler,
+		},
+		{
+			MethodName: "ListEmbeddings",
+			Handler:    _Aillm_ListEmbeddings_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "AskStream",
+			Handler:       _Aillm_AskStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "aillm.proto",
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grpcserver exposes an initialized LLMContainer as the gRPC service defined in aillm.proto.
+//
+// Clients in other languages can be generated from aillm.proto with protoc.
+//
+// Example Usage:
+//
+//	llm.Init()
+//	lis, _ := net.Listen("tcp", ":9090")
+//	s := grpc.NewServer()
+//	grpcserver.Register(s, &llm)
+//	s.Serve(lis)
+package grpcserver
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative aillm.proto
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	aillm "github.com/RezaArani/aillm/controller"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Service implements AillmServer with an LLMContainer.
+type Service struct {
+	UnimplementedAillmServer
+	LLM *aillm.LLMContainer
+}
+
+// NewService creates the service of an initialized container.
+func NewService(llm *aillm.LLMContainer) *Service {
+	return &Service{LLM: llm}
+}
+
+// Register registers the service of an initialized container on a gRPC server.
+func Register(s grpc.ServiceRegistrar, llm *aillm.LLMContainer) {
+	RegisterAillmServer(s, NewService(llm))
+}
+
+// Ask implements AillmServer.
+func (s *Service) Ask(ctx context.Context, req *AskRequest) (*AskResponse, error) {
+	if req.GetQuery() == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	result, err := s.LLM.AskLLMContext(ctx, req.GetQuery(), s.askOptions(req)...)
+	if err != nil {
+		return nil, askStatus(ctx, err)
+	}
+	return newAskResponse(result), nil
+}
+
+// AskStream implements AillmServer.
+func (s *Service) AskStream(req *AskRequest, stream Aillm_AskStreamServer) error {
+	if req.GetQuery() == "" {
+		return status.Error(codes.InvalidArgument, "query is required")
+	}
+	options := append(s.askOptions(req), s.LLM.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+		if err := stream.Context().Err(); err != nil {
+			// The client cancelled the call
+			return err
+		}
+		return stream.Send(&AskStreamResponse{Event: &AskStreamResponse_Chunk{Chunk: string(chunk)}})
+	}))
+	result, err := s.LLM.AskLLMContext(stream.Context(), req.GetQuery(), options...)
+	if err != nil {
+		return askStatus(stream.Context(), err)
+	}
+	return stream.Send(&AskStreamResponse{Event: &AskStreamResponse_Done{Done: newAskResponse(result)}})
+}
+
+// Embed implements AillmServer.
+func (s *Service) Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse, error) {
+	if req.GetIndex() == "" || req.GetText() == "" {
+		return nil, status.Error(codes.InvalidArgument, "index and text are required")
+	}
+	result, err := s.LLM.EmbeddText(req.GetIndex(), aillm.LLMEmbeddingContent{
+		Id:       req.GetId(),
+		Title:    req.GetTitle(),
+		Text:     req.GetText(),
+		Language: req.GetLanguage(),
+		Sources:  req.GetSources(),
+	}, s.LLM.WithEmbeddingPrefix(req.GetPrefix()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &EmbedResponse{Object: newEmbeddingObject(result)}, nil
+}
+
+// RemoveEmbedding implements AillmServer.
+func (s *Service) RemoveEmbedding(ctx context.Context, req *RemoveEmbeddingRequest) (*RemoveEmbeddingResponse, error) {
+	if req.GetIndex() == "" {
+		return nil, status.Error(codes.InvalidArgument, "index is required")
+	}
+	if err := s.LLM.RemoveEmbedding(req.GetIndex(), s.LLM.WithEmbeddingPrefix(req.GetPrefix())); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &RemoveEmbeddingResponse{}, nil
+}
+
+// ListEmbeddings implements AillmServer.
+func (s *Service) ListEmbeddings(ctx context.Context, req *ListEmbeddingsRequest) (*ListEmbeddingsResponse, error) {
+	if req.GetOffset() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+	limit := int(req.GetLimit())
+	if limit <= 0 {
+		limit = 20
+	}
+	list, err := s.LLM.ListEmbeddings(req.GetKeyId(), int(req.GetOffset()), limit)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	response := &ListEmbeddingsResponse{}
+	if total, ok := list["Total"].(int); ok {
+		response.Total = int32(total)
+	}
+	if rows, ok := list["Rows"].([]aillm.LLMEmbeddingObject); ok {
+		for _, row := range rows {
+			response.Rows = append(response.Rows, newEmbeddingObject(row))
+		}
+	}
+	return response, nil
+}
+
+func (s *Service) askOptions(req *AskRequest) []aillm.LLMCallOption {
+	llm := s.LLM
+	return []aillm.LLMCallOption{
+		llm.WithSessionID(req.GetSessionId()),
+		llm.WithLanguage(aillm.LanguageTag(req.GetLanguage())),
+		llm.WithEmbeddingPrefix(req.GetPrefix()),
+		llm.WithEmbeddingIndex(req.GetIndex()),
+		llm.WithRequestID(req.GetRequestId()),
+	}
+}
+
+// askStatus converts an AskLLM error to its gRPC status, as the HTTP server maps errors to status codes: quota errors
+// are ResourceExhausted, calls rejected by Shutdown or failing to reach Redis are Unavailable, searches of prefixes
+// without index are NotFound and invalid, blocked or too large inputs are InvalidArgument.
+func askStatus(ctx context.Context, err error) error {
+	code := codes.Internal
+	var quotaErr *aillm.QuotaExceededError
+	switch {
+	case ctx.Err() != nil:
+		// The client cancelled the call or its deadline passed
+		code = status.FromContextError(ctx.Err()).Code()
+	case errors.As(err, &quotaErr):
+		code = codes.ResourceExhausted
+	case errors.Is(err, aillm.ErrShutdown) || errors.Is(err, aillm.ErrNoRedis):
+		code = codes.Unavailable
+	case errors.Is(err, aillm.ErrIndexNotFound):
+		code = codes.NotFound
+	case errors.Is(err, aillm.ErrInputTooLarge) || errors.Is(err, aillm.ErrQueryNotSecure) ||
+		errors.Is(err, aillm.ErrModerationBlocked) || errors.Is(err, aillm.ErrInvalidOptions):
+		code = codes.InvalidArgument
+	}
+	return status.Error(code, err.Error())
+}
+
+// newAskResponse converts an AskLLM result to the response message.
+func newAskResponse(result aillm.LLMResult) *AskResponse {
+	response := &AskResponse{
+		FailedToRespond: result.FailedToRespond,
+		References:      result.LLMReferences,
+		RequestId:       result.RequestID,
+		CompletionTokens: &TokenUsage{
+			InputTokens:  int32(result.TokenReport.CompletionTokens.InputTokens),
+			OutputTokens: int32(result.TokenReport.CompletionTokens.OutputTokens),
+		},
+	}
+	if result.Response != nil && len(result.Response.Choices) > 0 {
+		response.Answer = result.Response.Choices[0].Content
+	}
+	return response
+}
+
+// newEmbeddingObject converts an embedding object to its message, contents are sorted by id.
+func newEmbeddingObject(object aillm.LLMEmbeddingObject) *EmbeddingObject {
+	message := &EmbeddingObject{Prefix: object.EmbeddingPrefix, Index: object.Index}
+	for _, content := range object.Contents {
+		message.Contents = append(message.Contents, &EmbeddingContent{
+			Id:          content.Id,
+			Title:       content.Title,
+			Language:    content.Language,
+			Sources:     content.Sources,
+			Keys:        content.Keys,
+			GeneralKeys: content.GeneralKeys,
+			Keywords:    content.Keywords,
+		})
+	}
+	sort.Slice(message.Contents, func(i, j int) bool {
+		return message.Contents[i].Id < message.Contents[j].Id
+	})
+	return message
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	aillm "github.com/RezaArani/aillm/controller"
+)
+
+// Content management endpoints, every endpoint accepts the "prefix" query parameter:
+//   - GET /embeddings?offset=0&limit=20: Pages the embedding objects of the prefix with counts, the "language",
+//     "source" and "sort" (updated, updated_asc) parameters filter and order them, see LLMContainer.QueryEmbeddings.
+//   - GET /embeddings/{index}: The embedding object of an index.
+//   - GET /stats/embeddings: The document, chunk, language and memory stats of the prefix.
+//   - GET /embeddings/{index}/documents/{id}: A document with its chunks.
+//   - PUT /embeddings/{index}/documents/{id}: Updates a document, empty fields keep their values.
+//   - DELETE /embeddings/{index}/documents/{id}: Removes a document.
+//   - POST /embeddings/{index}/documents/{id}/reembed: Embeds the stored text of a document again.
+
+// UpdateDocumentRequest is the body of PUT /embeddings/{index}/documents/{id}.
+type UpdateDocumentRequest struct {
+	Title    string `json:"title,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Language string `json:"language,omitempty"`
+	Sources  string `json:"sources,omitempty"`
+}
+
+func (s *Server) handleListEmbeddings(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil {
+		limit = 20
+	}
+	switch query.Get("sort") {
+	case "", aillm.SortByIndex, aillm.SortByUpdated, aillm.SortByUpdatedOldest:
+	default:
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("unknown sort order %q", query.Get("sort")))
+		return
+	}
+	page, err := s.LLM.QueryEmbeddings(aillm.EmbeddingQuery{
+		Prefix:   aillm.TenantPrefix(s.tenant(r), query.Get("prefix")),
+		Language: query.Get("language"),
+		Source:   query.Get("source"),
+		Sort:     query.Get("sort"),
+		Offset:   offset,
+		Limit:    limit,
+	})
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleEmbeddingStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.LLM.EmbeddingStats(aillm.TenantPrefix(s.tenant(r), r.URL.Query().Get("prefix")))
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) handleGetEmbedding(w http.ResponseWriter, r *http.Request) {
+	llm := s.LLM
+	object, err := llm.GetEmbedding(r.PathValue("index"), s.scopeOptions(r)...)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, object)
+}
+
+func (s *Server) handleGetDocument(w http.ResponseWriter, r *http.Request) {
+	document, err := s.LLM.GetEmbeddedDocument(r.PathValue("index"), r.PathValue("id"), s.scopeOptions(r)...)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, document)
+}
+
+func (s *Server) handleUpdateDocument(w http.ResponseWriter, r *http.Request) {
+	var req UpdateDocumentRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	index, id := r.PathValue("index"), r.PathValue("id")
+	if _, err := s.LLM.GetEmbeddedDocument(index, id, s.scopeOptions(r)...); err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+	object, err := s.LLM.UpdateEmbeddingContent(index, aillm.LLMEmbeddingContent{
+		Id:       id,
+		Title:    req.Title,
+		Text:     req.Text,
+		Language: req.Language,
+		Sources:  req.Sources,
+	}, s.scopeOptions(r)...)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, object)
+}
+
+func (s *Server) handleRemoveDocument(w http.ResponseWriter, r *http.Request) {
+	index, id := r.PathValue("index"), r.PathValue("id")
+	if _, err := s.LLM.GetEmbeddedDocument(index, id, s.scopeOptions(r)...); err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err := s.LLM.RemoveEmbeddingSubKey(index, id, s.scopeOptions(r)...); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleReembedDocument(w http.ResponseWriter, r *http.Request) {
+	index, id := r.PathValue("index"), r.PathValue("id")
+	if _, err := s.LLM.GetEmbeddedDocument(index, id, s.scopeOptions(r)...); err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+	object, err := s.LLM.ReembedDocument(index, id, s.scopeOptions(r)...)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, object)
+}
+
+// scopeOptions returns the tenant of the request and the embedding prefix of the "prefix" query parameter.
+func (s *Server) scopeOptions(r *http.Request) []aillm.LLMCallOption {
+	return []aillm.LLMCallOption{s.LLM.WithTenant(s.tenant(r)), s.LLM.WithEmbeddingPrefix(r.URL.Query().Get("prefix"))}
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	aillm "github.com/RezaArani/aillm/controller"
+	"github.com/google/uuid"
+)
+
+// ConversationIDHeader is the request header mapping an OpenAI-compatible chat to an aillm session.
+const ConversationIDHeader = "X-Conversation-ID"
+
+// chatMessage is a message of an OpenAI chat completion request or response.
+//
+// Content is a string in responses; in requests it may also be an array of content parts.
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// text returns the text of the message content.
+func (m chatMessage) text() string {
+	var content string
+	if json.Unmarshal(m.Content, &content) == nil {
+		return content
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	json.Unmarshal(m.Content, &parts)
+	texts := []string{}
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// chatCompletionRequest is the body of POST /v1/chat/completions.
+type chatCompletionRequest struct {
+	Model          string        `json:"model"`
+	Messages       []chatMessage `json:"messages"`
+	Stream         bool          `json:"stream"`
+	User           string        `json:"user,omitempty"`
+	ConversationID string        `json:"conversation_id,omitempty"`
+}
+
+type chatCompletionChoice struct {
+	Index        int              `json:"index"`
+	Message      *responseMessage `json:"message,omitempty"`
+	Delta        *responseMessage `json:"delta,omitempty"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+type responseMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type chatCompletionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type chatCompletionResponse struct {
+	ID      string                 `json:"id"`
+	Object  string                 `json:"object"`
+	Created int64                  `json:"created"`
+	Model   string                 `json:"model"`
+	Choices []chatCompletionChoice `json:"choices"`
+	Usage   *chatCompletionUsage   `json:"usage,omitempty"`
+}
+
+// openAIError is the error body of the OpenAI API.
+type openAIError struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// modelName returns the model name reported to OpenAI-compatible clients.
+func (s *Server) modelName() string {
+	if s.ModelName != "" {
+		return s.ModelName
+	}
+	return "aillm"
+}
+
+// handleModels lists the RAG pipeline as a single model, chat UIs query it to populate the model picker.
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"object": "list",
+		"data": []map[string]interface{}{{
+			"id":       s.modelName(),
+			"object":   "model",
+			"created":  0,
+			"owned_by": "aillm",
+		}},
+	})
+}
+
+// handleChatCompletions answers an OpenAI chat completion request with the RAG pipeline.
+//
+// The last user message is the query. The conversation is mapped to an aillm session with the
+// X-Conversation-ID header, the "conversation_id" field or the "user" field, in this order, so the
+// session memory of the container provides the chat history. Without any of them the earlier messages
+// of the request are the chat history, kept in a session removed after the answer.
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatCompletionRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		s.writeOpenAIError(w, http.StatusBadRequest, err)
+		return
+	}
+	query := ""
+	queryIdx := -1
+	for idx := len(req.Messages) - 1; idx >= 0; idx-- {
+		if req.Messages[idx].Role == "user" {
+			query = req.Messages[idx].text()
+			queryIdx = idx
+			break
+		}
+	}
+	if strings.TrimSpace(query) == "" {
+		s.writeOpenAIError(w, http.StatusBadRequest, errors.New("a user message is required"))
+		return
+	}
+	sessionID := r.Header.Get(ConversationIDHeader)
+	if sessionID == "" {
+		sessionID = req.ConversationID
+	}
+	if sessionID == "" {
+		sessionID = req.User
+	}
+
+	llm := s.LLM
+	tenantID := s.tenant(r)
+	options := []aillm.LLMCallOption{llm.WithTenant(tenantID)}
+	if sessionID == "" {
+		historySessionID, cleanup := s.historySession(tenantID, req.Messages[:queryIdx])
+		defer cleanup()
+		if historySessionID != "" {
+			options = append(options, llm.WithSessionID(historySessionID), llm.WithPersistentMemory(false))
+		}
+	} else {
+		options = append(options, llm.WithSessionID(sessionID))
+	}
+	response := chatCompletionResponse{
+		ID:      "chatcmpl-" + uuid.New().String(),
+		Created: time.Now().Unix(),
+		Model:   s.modelName(),
+	}
+	stop := "stop"
+
+	if !req.Stream {
+		result, err := llm.AskLLMContext(r.Context(), query, options...)
+		if err != nil {
+			s.writeOpenAIError(w, askErrorStatus(w, err), err)
+			return
+		}
+		answer := newAskResponse(result).Answer
+		response.Object = "chat.completion"
+		response.Choices = []chatCompletionChoice{{Message: &responseMessage{Role: "assistant", Content: answer}, FinishReason: &stop}}
+		usage := result.TokenReport.CompletionTokens
+		response.Usage = &chatCompletionUsage{
+			PromptTokens:     usage.InputTokens,
+			CompletionTokens: usage.OutputTokens,
+			TotalTokens:      usage.InputTokens + usage.OutputTokens,
+		}
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	sse, err := aillm.NewSSEWriter(w, r, 0)
+	if err != nil {
+		s.writeOpenAIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer sse.Close()
+	response.Object = "chat.completion.chunk"
+	writeChunk := func(delta responseMessage, finishReason *string) error {
+		response.Choices = []chatCompletionChoice{{Delta: &delta, FinishReason: finishReason}}
+		return sse.WriteJSON("", response)
+	}
+	writeChunk(responseMessage{Role: "assistant"}, nil)
+	options = append(options, llm.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+		return writeChunk(responseMessage{Content: string(chunk)}, nil)
+	}))
+	if _, err := llm.AskLLMContext(r.Context(), query, options...); err != nil {
+		s.logger().Error("chat completion failed", "error", err)
+		errorBody := openAIError{}
+		errorBody.Error.Message = err.Error()
+		errorBody.Error.Type = "server_error"
+		sse.WriteJSON("", errorBody)
+	} else {
+		writeChunk(responseMessage{}, &stop)
+	}
+	sse.WriteEvent("", []byte("[DONE]"))
+}
+
+// historySession stores the earlier messages of a request in a new session of the session memory, for
+// clients sending the whole chat with every request. It returns the session ID, empty without history,
+// and a function removing the session.
+func (s *Server) historySession(tenantID string, messages []chatMessage) (string, func()) {
+	var store aillm.MemoryStore
+	if s.LLM.SessionMemory != nil {
+		store = s.LLM.SessionMemory
+	} else if s.LLM.MemoryManager != nil {
+		store = s.LLM.MemoryManager
+	}
+	history := []aillm.MemoryData{}
+	for _, message := range messages {
+		switch message.Role {
+		case "user":
+			history = append(history, aillm.MemoryData{Question: message.text()})
+		case "assistant":
+			if len(history) > 0 && history[len(history)-1].Answer == "" {
+				history[len(history)-1].Answer = message.text()
+			}
+		}
+	}
+	if store == nil || len(history) == 0 {
+		return "", func() {}
+	}
+	sessionID := "chatcmpl-" + uuid.New().String()
+	// Sessions of tenant calls are kept under the tenant namespace
+	memoryKey := aillm.TenantPrefix(tenantID, sessionID)
+	store.AddMemory(memoryKey, history)
+	return sessionID, func() {
+		store.DeleteMemory(memoryKey)
+	}
+}
+
+func (s *Server) writeOpenAIError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		s.logger().Error("chat completion failed", "error", err)
+	}
+	errorBody := openAIError{}
+	errorBody.Error.Message = err.Error()
+	errorBody.Error.Type = "invalid_request_error"
+	if status >= http.StatusInternalServerError {
+		errorBody.Error.Type = "server_error"
+	}
+	writeJSON(w, status, errorBody)
+}
@@ -0,0 +1,462 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server exposes an initialized LLMContainer over HTTP, so the library can be deployed
+// as a standalone RAG service.
+//
+// Endpoints:
+//   - POST /ask: Answers a query, streamed as Server-Sent Events when "stream" is true.
+//   - POST /embed: Embeds a text into an index, in the background when "async" is true.
+//   - GET /jobs/{id}: The state of a background embedding job.
+//   - DELETE /embeddings/{index}: Removes the embeddings of an index.
+//   - GET /embeddings, GET /embeddings/{index} and /embeddings/{index}/documents/{id}: Lists,
+//     reads, updates, removes and re-embeds the embedded documents for content management UIs.
+//   - GET /stats/embeddings: The embedding stats of a prefix, see LLMContainer.EmbeddingStats.
+//   - GET /sessions: Lists the sessions held in memory.
+//   - DELETE /sessions/{id}: Deletes all data stored about a session, see LLMContainer.PurgeUserData.
+//   - GET /traces/{id}, POST /traces/{id}/feedback: The trace of an answer and the feedback of users on it.
+//   - GET /feedback?since=: The feedback aggregated by search algorithm and document, see LLMContainer.FeedbackReport.
+//   - POST /v1/chat/completions, GET /v1/models: OpenAI-compatible chat completions, so chat UIs
+//     such as Open WebUI or LibreChat can use the RAG pipeline as a model.
+//   - GET /ws: Realtime chat over a WebSocket, see WebSocketMessage.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	aillm "github.com/RezaArani/aillm/controller"
+)
+
+// maxRequestBody limits the size of request bodies (10MB).
+const maxRequestBody = 10 << 20
+
+// Server serves the endpoints of an LLMContainer.
+//
+// Fields:
+//   - LLM: The initialized container answering the requests.
+//   - Logger: Logger for request errors, defaults to slog.Default().
+//   - ModelName: The model name reported by the OpenAI-compatible endpoints, default "aillm".
+//   - WebSocketOrigins: Host patterns of cross origin pages allowed to open the chat WebSocket, e.g. "chat.example.com".
+//   - TenantFunc: Optional function returning the tenant of a request, e.g. from its authentication token,
+//     every call of the request is then made WithTenant.
+type Server struct {
+	LLM              *aillm.LLMContainer
+	Logger           *slog.Logger
+	ModelName        string
+	WebSocketOrigins []string
+	TenantFunc       func(r *http.Request) string
+	mux              *http.ServeMux
+	mu               sync.Mutex
+	httpServer       *http.Server
+}
+
+// New creates a server for an initialized container.
+//
+// Parameters:
+//   - llm: The container, Init must have been called.
+//
+// Returns:
+//   - *Server: The server, use it as an http.Handler or call ListenAndServe.
+//
+// Example Usage:
+//
+//	llm.Init()
+//	srv := server.New(&llm)
+//	log.Fatal(srv.ListenAndServe(":8080"))
+func New(llm *aillm.LLMContainer) *Server {
+	s := &Server{LLM: llm, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /ask", s.handleAsk)
+	s.mux.HandleFunc("POST /embed", s.handleEmbed)
+	s.mux.HandleFunc("DELETE /embeddings/{index}", s.handleRemoveEmbedding)
+	s.mux.HandleFunc("GET /embeddings", s.handleListEmbeddings)
+	s.mux.HandleFunc("GET /embeddings/{index}", s.handleGetEmbedding)
+	s.mux.HandleFunc("GET /embeddings/{index}/documents/{id}", s.handleGetDocument)
+	s.mux.HandleFunc("PUT /embeddings/{index}/documents/{id}", s.handleUpdateDocument)
+	s.mux.HandleFunc("DELETE /embeddings/{index}/documents/{id}", s.handleRemoveDocument)
+	s.mux.HandleFunc("POST /embeddings/{index}/documents/{id}/reembed", s.handleReembedDocument)
+	s.mux.HandleFunc("GET /stats/embeddings", s.handleEmbeddingStats)
+	s.mux.HandleFunc("GET /sessions", s.handleSessions)
+	s.mux.HandleFunc("DELETE /sessions/{id}", s.handlePurgeSession)
+	s.mux.HandleFunc("GET /jobs/{id}", s.handleJob)
+	s.mux.HandleFunc("GET /traces/{id}", s.handleTrace)
+	s.mux.HandleFunc("POST /traces/{id}/feedback", s.handleFeedback)
+	s.mux.HandleFunc("GET /feedback", s.handleFeedbackReport)
+	s.mux.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	s.mux.HandleFunc("GET /v1/models", s.handleModels)
+	s.mux.HandleFunc("GET /ws", s.handleWebSocket)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe listens on the TCP address and serves the endpoints.
+//
+// It returns http.ErrServerClosed after Shutdown.
+func (s *Server) ListenAndServe(addr string) error {
+	s.mu.Lock()
+	s.httpServer = &http.Server{Addr: addr, Handler: s}
+	httpServer := s.httpServer
+	s.mu.Unlock()
+	return httpServer.ListenAndServe()
+}
+
+// Shutdown stops accepting connections, waits for the active requests and shuts the container down.
+//
+// Parameters:
+//   - ctx: Bounds the wait, see LLMContainer.Shutdown.
+//
+// Example Usage:
+//
+//	go srv.ListenAndServe(":8080")
+//	<-ctx.Done() // e.g. signal.NotifyContext(context.Background(), syscall.SIGTERM)
+//	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	srv.Shutdown(shutdownCtx)
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	httpServer := s.httpServer
+	s.mu.Unlock()
+	var err error
+	if httpServer != nil {
+		err = httpServer.Shutdown(ctx)
+	}
+	if llmErr := s.LLM.Shutdown(ctx); err == nil {
+		err = llmErr
+	}
+	return err
+}
+
+// AskRequest is the body of POST /ask.
+type AskRequest struct {
+	Query     string `json:"query"`
+	SessionID string `json:"sessionId,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+	Index     string `json:"index,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+	Stream    bool   `json:"stream,omitempty"`
+	Character string `json:"character,omitempty"`
+}
+
+// AskResponse is the answer of POST /ask, sent as the "done" event when streaming.
+type AskResponse struct {
+	Answer          string                  `json:"answer"`
+	FailedToRespond bool                    `json:"failedToRespond"`
+	References      []string                `json:"references,omitempty"`
+	Citations       []aillm.Citation        `json:"citations,omitempty"`
+	TokenReport     aillm.TokenReport       `json:"tokenReport"`
+	RequestID       string                  `json:"requestId,omitempty"`
+	TraceID         string                  `json:"traceId,omitempty"`
+	Confidence      *aillm.Confidence       `json:"confidence,omitempty"`
+	WebSources      []aillm.WebSearchResult `json:"webSources,omitempty"`
+}
+
+// EmbedRequest is the body of POST /embed.
+type EmbedRequest struct {
+	Index    string `json:"index"`
+	Prefix   string `json:"prefix,omitempty"`
+	Id       string `json:"id,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Text     string `json:"text"`
+	Language string `json:"language,omitempty"`
+	Sources  string `json:"sources,omitempty"`
+	Async    bool   `json:"async,omitempty"`
+}
+
+// errorResponse is the body of failed requests.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
+	var req AskRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Query == "" {
+		s.writeError(w, http.StatusBadRequest, errors.New("query is required"))
+		return
+	}
+	llm := s.LLM
+	options := []aillm.LLMCallOption{
+		llm.WithTenant(s.tenant(r)),
+		llm.WithSessionID(req.SessionID),
+		llm.WithLanguage(aillm.LanguageTag(req.Language)),
+		llm.WithEmbeddingPrefix(req.Prefix),
+		llm.WithEmbeddingIndex(req.Index),
+		llm.WithRequestID(req.RequestID),
+		llm.WithCharacterName(req.Character),
+	}
+	if !req.Stream {
+		result, err := llm.AskLLMContext(r.Context(), req.Query, options...)
+		if err != nil {
+			s.writeError(w, askErrorStatus(w, err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newAskResponse(result))
+		return
+	}
+
+	sse, err := aillm.NewSSEWriter(w, r, 0)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer sse.Close()
+	options = append(options, llm.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+		return sse.WriteJSON("chunk", map[string]string{"content": string(chunk)})
+	}))
+	result, err := llm.AskLLMContext(r.Context(), req.Query, options...)
+	if err != nil {
+		sse.WriteJSON("error", errorResponse{Error: err.Error()})
+	} else {
+		sse.WriteJSON("done", newAskResponse(result))
+	}
+}
+
+func (s *Server) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	var req EmbedRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Index == "" || req.Text == "" {
+		s.writeError(w, http.StatusBadRequest, errors.New("index and text are required"))
+		return
+	}
+	llm := s.LLM
+	contents := aillm.LLMEmbeddingContent{
+		Id:       req.Id,
+		Title:    req.Title,
+		Text:     req.Text,
+		Language: req.Language,
+		Sources:  req.Sources,
+	}
+	if req.Async {
+		jobID := llm.EmbeddTextAsync(req.Index, contents, llm.WithTenant(s.tenant(r)), llm.WithEmbeddingPrefix(req.Prefix))
+		writeJSON(w, http.StatusAccepted, map[string]string{"jobId": jobID})
+		return
+	}
+	result, err := llm.EmbeddText(req.Index, contents, llm.WithTenant(s.tenant(r)), llm.WithEmbeddingPrefix(req.Prefix))
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleRemoveEmbedding(w http.ResponseWriter, r *http.Request) {
+	llm := s.LLM
+	err := llm.RemoveEmbedding(r.PathValue("index"), s.scopeOptions(r)...)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	sessions := []string{}
+	if s.LLM.MemoryManager != nil {
+		// Sessions of tenants are stored as tenant:<id>:<session>
+		tenantPrefix := ""
+		if tenant := s.tenant(r); tenant != "" {
+			tenantPrefix = "tenant:" + tenant + ":"
+		}
+		for _, session := range s.LLM.MemoryManager.ListSessions() {
+			if tenantPrefix != "" && strings.HasPrefix(session, tenantPrefix) {
+				sessions = append(sessions, strings.TrimPrefix(session, tenantPrefix))
+			} else if tenantPrefix == "" && !strings.HasPrefix(session, "tenant:") {
+				sessions = append(sessions, session)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"sessions": sessions})
+}
+
+func (s *Server) handlePurgeSession(w http.ResponseWriter, r *http.Request) {
+	report, err := s.LLM.PurgeUserData(r.PathValue("id"), s.scopeOptions(r)...)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	job, exists := s.LLM.GetIngestionJob(r.PathValue("id"))
+	if !exists {
+		s.writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) handleTrace(w http.ResponseWriter, r *http.Request) {
+	trace, ok := s.trace(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, trace)
+}
+
+// trace loads the trace of the request path, traces of other tenants are not found.
+func (s *Server) trace(w http.ResponseWriter, r *http.Request) (aillm.Trace, bool) {
+	trace, err := s.LLM.GetTrace(r.PathValue("id"))
+	if err == nil && trace.TenantID != s.tenant(r) {
+		err = aillm.ErrTraceNotFound
+	}
+	if errors.Is(err, aillm.ErrTraceNotFound) {
+		s.writeError(w, http.StatusNotFound, err)
+		return trace, false
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return trace, false
+	}
+	return trace, true
+}
+
+// FeedbackRequest is the body of POST /traces/{id}/feedback.
+type FeedbackRequest struct {
+	Rating  int    `json:"rating"`
+	Comment string `json:"comment,omitempty"`
+}
+
+func (s *Server) handleFeedback(w http.ResponseWriter, r *http.Request) {
+	var req FeedbackRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	trace, ok := s.trace(w, r)
+	if !ok {
+		return
+	}
+	if err := s.LLM.SubmitFeedback(trace.ID, req.Rating, req.Comment); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleFeedbackReport(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			s.writeError(w, http.StatusBadRequest, errors.New("since must be an RFC 3339 time"))
+			return
+		}
+	}
+	report, err := s.LLM.FeedbackReport(since, s.LLM.WithTenant(s.tenant(r)))
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// newAskResponse converts an AskLLM result to the response body.
+func newAskResponse(result aillm.LLMResult) AskResponse {
+	response := AskResponse{
+		FailedToRespond: result.FailedToRespond,
+		References:      result.LLMReferences,
+		Citations:       result.Citations,
+		TokenReport:     result.TokenReport,
+		RequestID:       result.RequestID,
+		TraceID:         result.TraceID,
+		Confidence:      result.Confidence,
+		WebSources:      result.WebSources,
+	}
+	if result.Response != nil && len(result.Response.Choices) > 0 {
+		response.Answer = result.Response.Choices[0].Content
+	}
+	return response
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		s.logger().Error("request failed", "error", err)
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// askErrorStatus returns the status of an AskLLM error, quota errors return 429 with a Retry-After header
+// calls rejected by Shutdown or failing to reach Redis return 503, queries blocked by the security check or
+// moderation return 400, inputs exceeding the InputLimits of the container return 413 and searches of prefixes
+// without index return 404.
+func askErrorStatus(w http.ResponseWriter, err error) int {
+	var quotaErr *aillm.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(quotaErr.RetryAfter.Seconds()))))
+		return http.StatusTooManyRequests
+	}
+	if errors.Is(err, aillm.ErrShutdown) || errors.Is(err, aillm.ErrNoRedis) {
+		return http.StatusServiceUnavailable
+	}
+	if errors.Is(err, aillm.ErrIndexNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, aillm.ErrInputTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	if errors.Is(err, aillm.ErrQueryNotSecure) || errors.Is(err, aillm.ErrModerationBlocked) || errors.Is(err, aillm.ErrInvalidOptions) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// tenant returns the tenant of a request, empty without TenantFunc.
+func (s *Server) tenant(r *http.Request) string {
+	if s.TenantFunc == nil {
+		return ""
+	}
+	return s.TenantFunc(r)
+}
+
+func (s *Server) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return slog.Default()
+}
+
+// decodeJSON decodes a size limited JSON request body.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2025 Reza Arani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	aillm "github.com/RezaArani/aillm/controller"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+// WebSocket message types.
+const (
+	// Client to server
+	wsTypeMessage = "message"
+	wsTypeCancel  = "cancel"
+	// Server to client
+	wsTypeChunk  = "chunk"
+	wsTypeAction = "action"
+	wsTypeDone   = "done"
+	wsTypeError  = "error"
+)
+
+// WebSocketMessage is a message of the GET /ws chat protocol, encoded as a JSON text frame.
+//
+// Client messages:
+//   - "message": Asks Query, with the optional Language, Prefix, Index and RequestID. Messages are
+//     answered in order, a message sent while an answer is streamed waits for it.
+//   - "cancel": Stops the answer being streamed.
+//
+// Server messages:
+//   - "chunk": A streamed part of the answer in Content.
+//   - "action": A progress action of the pipeline in Action.
+//   - "done": The complete answer in Result.
+//   - "error": A failed or cancelled message, described by Error.
+type WebSocketMessage struct {
+	Type      string           `json:"type"`
+	Query     string           `json:"query,omitempty"`
+	Language  string           `json:"language,omitempty"`
+	Prefix    string           `json:"prefix,omitempty"`
+	Index     string           `json:"index,omitempty"`
+	RequestID string           `json:"requestId,omitempty"`
+	Content   string           `json:"content,omitempty"`
+	Action    *aillm.LLMAction `json:"action,omitempty"`
+	Result    *AskResponse     `json:"result,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// handleWebSocket serves a realtime chat over a WebSocket connection.
+//
+// The "sessionId" query parameter selects the session whose memory holds the chat history, so
+// reconnecting with the same id continues the conversation.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: s.WebSocketOrigins})
+	if err != nil {
+		// Accept has already written the error response
+		s.logger().Warn("websocket handshake failed", "error", err)
+		return
+	}
+	defer conn.CloseNow()
+	ctx := r.Context()
+	sessionID := r.URL.Query().Get("sessionId")
+
+	var (
+		mu     sync.Mutex
+		cancel context.CancelFunc = func() {}
+	)
+	queue := make(chan WebSocketMessage, 16)
+	go func() {
+		defer close(queue)
+		for {
+			var msg WebSocketMessage
+			if err := wsjson.Read(ctx, conn, &msg); err != nil {
+				if websocket.CloseStatus(err) != websocket.StatusNormalClosure && !errors.Is(err, context.Canceled) {
+					s.logger().Debug("websocket read failed", "error", err)
+				}
+				mu.Lock()
+				cancel()
+				mu.Unlock()
+				return
+			}
+			switch msg.Type {
+			case wsTypeMessage:
+				select {
+				case queue <- msg:
+				default:
+					wsjson.Write(ctx, conn, WebSocketMessage{Type: wsTypeError, RequestID: msg.RequestID, Error: "too many pending messages"})
+				}
+			case wsTypeCancel:
+				mu.Lock()
+				cancel()
+				mu.Unlock()
+			default:
+				wsjson.Write(ctx, conn, WebSocketMessage{Type: wsTypeError, Error: "unknown message type: " + msg.Type})
+			}
+		}
+	}()
+
+	for msg := range queue {
+		if msg.Query == "" {
+			wsjson.Write(ctx, conn, WebSocketMessage{Type: wsTypeError, RequestID: msg.RequestID, Error: "query is required"})
+			continue
+		}
+		askCtx, askCancel := context.WithCancel(ctx)
+		mu.Lock()
+		cancel = askCancel
+		mu.Unlock()
+		s.answerWebSocketMessage(ctx, askCtx, conn, s.tenant(r), sessionID, msg)
+		askCancel()
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+}
+
+// answerWebSocketMessage streams the answer of a message.
+//
+// Writes use the connection context ctx, a cancelled write context would close the connection;
+// askCtx is cancelled by a "cancel" message or a disconnect and stops the call at its current stage.
+func (s *Server) answerWebSocketMessage(ctx, askCtx context.Context, conn *websocket.Conn, tenant, sessionID string, msg WebSocketMessage) {
+	llm := s.LLM
+	options := []aillm.LLMCallOption{
+		llm.WithTenant(tenant),
+		llm.WithSessionID(sessionID),
+		llm.WithLanguage(aillm.LanguageTag(msg.Language)),
+		llm.WithEmbeddingPrefix(msg.Prefix),
+		llm.WithEmbeddingIndex(msg.Index),
+		llm.WithRequestID(msg.RequestID),
+		llm.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+			if err := askCtx.Err(); err != nil {
+				return err
+			}
+			return wsjson.Write(ctx, conn, WebSocketMessage{Type: wsTypeChunk, RequestID: msg.RequestID, Content: string(chunk)})
+		}),
+		llm.WithActionCallFunc(func(action aillm.LLMAction) {
+			wsjson.Write(ctx, conn, WebSocketMessage{Type: wsTypeAction, RequestID: msg.RequestID, Action: &action})
+		}),
+	}
+	result, err := llm.AskLLMContext(askCtx, msg.Query, options...)
+	if err != nil {
+		if askCtx.Err() != nil {
+			err = errors.New("cancelled")
+		} else {
+			s.logger().Error("websocket message failed", "error", err)
+		}
+		wsjson.Write(ctx, conn, WebSocketMessage{Type: wsTypeError, RequestID: msg.RequestID, Error: err.Error()})
+		return
+	}
+	response := newAskResponse(result)
+	wsjson.Write(ctx, conn, WebSocketMessage{Type: wsTypeDone, RequestID: msg.RequestID, Result: &response})
+}